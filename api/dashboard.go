@@ -0,0 +1,220 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package api
+
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// HandleDashboard serves the web dashboard of the test. Everything but the root path is still
+// handled by the fallback, like before the dashboard was added.
+func HandleDashboard(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			fallback.ServeHTTP(rw, r)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if _, err := rw.Write([]byte(dashboardHTML)); err != nil {
+			log.WithError(err).Error("Error while writing the dashboard")
+		}
+	})
+}
+
+// The dashboard is a single page without any dependencies, so it works offline and doesn't need
+// any assets to be embedded. The charts are drawn from the snapshots of /v1/metrics/stream, the
+// checks and the status are polled from the API.
+const dashboardHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>k6 dashboard</title>
+<style>
+  body { margin: 0; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; background: #f4f5f7; color: #222; }
+  header { display: flex; align-items: baseline; gap: 24px; padding: 12px 24px; background: #222; color: #fff; }
+  header h1 { margin: 0; font-size: 20px; }
+  header span { color: #aaa; }
+  main { display: grid; grid-template-columns: repeat(auto-fit, minmax(420px, 1fr)); gap: 16px; padding: 16px 24px; }
+  section { background: #fff; border-radius: 4px; padding: 12px 16px; box-shadow: 0 1px 2px rgba(0,0,0,.15); }
+  section h2 { margin: 0 0 8px; font-size: 15px; font-weight: 600; }
+  svg { width: 100%; height: 180px; }
+  svg text { font-size: 10px; fill: #777; }
+  .legend span { margin-right: 12px; font-size: 12px; }
+  .legend i { display: inline-block; width: 10px; height: 10px; margin-right: 4px; }
+  table { width: 100%; border-collapse: collapse; font-size: 13px; }
+  td, th { text-align: left; padding: 4px 6px; border-bottom: 1px solid #eee; }
+  td.num { text-align: right; font-variant-numeric: tabular-nums; }
+  .ok { color: #2a8a3e; } .fail { color: #c0392b; } .muted { color: #999; }
+</style>
+</head>
+<body>
+<header>
+  <h1>k6</h1>
+  <span id="status">connecting...</span>
+  <span id="elapsed"></span>
+</header>
+<main>
+  <section><h2>Virtual users</h2><svg id="vus"></svg><div class="legend" id="vus-legend"></div></section>
+  <section><h2>Requests per second</h2><svg id="rps"></svg><div class="legend" id="rps-legend"></div></section>
+  <section><h2>Request duration (ms)</h2><svg id="latency"></svg><div class="legend" id="latency-legend"></div></section>
+  <section><h2>Thresholds</h2><table id="thresholds"></table></section>
+  <section><h2>Checks</h2><table id="checks"></table></section>
+  <section><h2>Metrics</h2><table id="metrics"></table></section>
+</main>
+<script>
+(function () {
+  "use strict";
+
+  var maxPoints = 300;
+  var colors = ["#7d64ff", "#2a8a3e", "#e67e22", "#c0392b", "#3498db"];
+  var series = { vus: {}, rps: {}, latency: {} };
+  var lastReqs = null;
+
+  function push(chart, name, t, v) {
+    var s = series[chart][name] = series[chart][name] || [];
+    s.push([t, v]);
+    if (s.length > maxPoints) { s.shift(); }
+  }
+
+  function esc(s) {
+    return String(s).replace(/[&<>"]/g, function (c) {
+      return { "&": "&amp;", "<": "&lt;", ">": "&gt;", "\"": "&quot;" }[c];
+    });
+  }
+
+  function fmt(v) {
+    if (v === undefined || v === null) { return ""; }
+    return Math.abs(v) >= 100 || v === Math.round(v) ? String(Math.round(v)) : v.toFixed(2);
+  }
+
+  function draw(id) {
+    var svg = document.getElementById(id), w = svg.clientWidth, h = svg.clientHeight, pad = 30;
+    var names = Object.keys(series[id]), minT = Infinity, maxT = -Infinity, maxV = 0;
+    names.forEach(function (n) {
+      series[id][n].forEach(function (p) {
+        minT = Math.min(minT, p[0]); maxT = Math.max(maxT, p[0]); maxV = Math.max(maxV, p[1]);
+      });
+    });
+    if (!names.length || maxT <= minT) { return; }
+    maxV = maxV || 1;
+    var x = function (t) { return pad + (t - minT) / (maxT - minT) * (w - pad - 4); };
+    var y = function (v) { return h - 16 - v / maxV * (h - 24); };
+    var out = "<line x1='" + pad + "' y1='" + y(0) + "' x2='" + w + "' y2='" + y(0) + "' stroke='#ddd'/>" +
+      "<text x='0' y='" + (y(maxV) + 8) + "'>" + fmt(maxV) + "</text>" +
+      "<text x='0' y='" + y(0) + "'>0</text>" +
+      "<text x='" + pad + "' y='" + h + "'>" + fmt(minT) + "s</text>" +
+      "<text x='" + (w - 40) + "' y='" + h + "'>" + fmt(maxT) + "s</text>";
+    var legend = "";
+    names.forEach(function (n, i) {
+      var c = colors[i % colors.length];
+      var pts = series[id][n].map(function (p) { return x(p[0]).toFixed(1) + "," + y(p[1]).toFixed(1); });
+      out += "<polyline fill='none' stroke-width='1.5' stroke='" + c + "' points='" + pts.join(" ") + "'/>";
+      var last = series[id][n][series[id][n].length - 1];
+      legend += "<span><i style='background:" + c + "'></i>" + esc(n) + ": " + fmt(last[1]) + "</span>";
+    });
+    svg.innerHTML = out;
+    document.getElementById(id + "-legend").innerHTML = legend;
+  }
+
+  function render(snapshot) {
+    var metrics = snapshot.metrics || {}, t = parseDuration(snapshot.elapsed);
+    document.getElementById("elapsed").textContent = snapshot.elapsed;
+
+    if (metrics.vus) { push("vus", "active", t, metrics.vus.sample.value || 0); }
+    if (metrics.vus_max) { push("vus", "max", t, metrics.vus_max.sample.value || 0); }
+
+    var reqs = metrics.http_reqs ? metrics.http_reqs.sample.count : 0;
+    if (lastReqs && t > lastReqs.t) { push("rps", "http_reqs", t, (reqs - lastReqs.count) / (t - lastReqs.t)); }
+    lastReqs = { t: t, count: reqs };
+
+    var d = metrics.http_req_duration;
+    if (d) { ["avg", "med", "p(90)", "p(95)"].forEach(function (k) { push("latency", k, t, d.sample[k] || 0); }); }
+
+    ["vus", "rps", "latency"].forEach(draw);
+
+    var thresholds = "", rows = "";
+    Object.keys(metrics).sort().forEach(function (name) {
+      var m = metrics[name];
+      if (m.tainted !== null && m.tainted !== undefined) {
+        thresholds += "<tr><td>" + esc(name) + "</td><td class='" + (m.tainted ? "fail'>failing" : "ok'>passing") + "</td></tr>";
+      }
+      var values = Object.keys(m.sample).map(function (k) { return esc(k) + "=" + fmt(m.sample[k]); });
+      rows += "<tr><td>" + esc(name) + "</td><td class='muted'>" + esc(m.type) + "</td><td>" + values.join(" ") + "</td></tr>";
+    });
+    document.getElementById("thresholds").innerHTML = thresholds || "<tr><td class='muted'>No thresholds</td></tr>";
+    document.getElementById("metrics").innerHTML = rows;
+  }
+
+  function parseDuration(s) {
+    var total = 0, re = /([\d.]+)(h|ms|m|s|µs|us|ns)/g, m;
+    var units = { h: 3600, m: 60, s: 1, ms: 1e-3, "µs": 1e-6, us: 1e-6, ns: 1e-9 };
+    while ((m = re.exec(s || "")) !== null) { total += parseFloat(m[1]) * units[m[2]]; }
+    return total;
+  }
+
+  function get(path, fn) {
+    var xhr = new XMLHttpRequest();
+    xhr.open("GET", path);
+    xhr.onload = function () { if (xhr.status === 200) { fn(JSON.parse(xhr.responseText)); } };
+    xhr.send();
+  }
+
+  function poll() {
+    get("/v1/status", function (doc) {
+      var s = doc.data.attributes;
+      document.getElementById("status").textContent = s.stopped ? "stopped" : s.paused ? "paused" :
+        s.running ? "running" : "not running";
+    });
+    get("/v1/groups", function (doc) {
+      var rows = "";
+      doc.data.forEach(function (g) {
+        (g.attributes.checks || []).forEach(function (c) {
+          var total = c.passes + c.fails;
+          rows += "<tr><td>" + esc(c.path) + "</td><td class='num ok'>" + c.passes + "</td><td class='num " +
+            (c.fails ? "fail" : "muted") + "'>" + c.fails + "</td><td class='num'>" +
+            (total ? fmt(100 * c.passes / total) + "%" : "") + "</td></tr>";
+        });
+      });
+      document.getElementById("checks").innerHTML = rows ?
+        "<tr><th>Check</th><th>Passes</th><th>Fails</th><th>Rate</th></tr>" + rows :
+        "<tr><td class='muted'>No checks</td></tr>";
+    });
+  }
+
+  function connect() {
+    var proto = location.protocol === "https:" ? "wss://" : "ws://";
+    var ws = new WebSocket(proto + location.host + "/v1/metrics/stream?interval=1s");
+    ws.onmessage = function (e) { render(JSON.parse(e.data).data.attributes); };
+    ws.onclose = function () {
+      document.getElementById("status").textContent = "disconnected";
+      setTimeout(connect, 2000);
+    };
+  }
+
+  connect();
+  poll();
+  setInterval(poll, 2000);
+})();
+</script>
+</body>
+</html>
+`
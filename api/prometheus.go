@@ -0,0 +1,176 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/loadimpact/k6/api/common"
+	"github.com/loadimpact/k6/stats"
+	log "github.com/sirupsen/logrus"
+)
+
+// PrometheusQuantiles are the quantiles of the trend metrics exposed to Prometheus.
+var PrometheusQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+var prometheusInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// HandlePrometheus exposes the current values of the metrics of the test in the Prometheus text
+// format, so a running test can be scraped without any output being configured for it.
+func HandlePrometheus() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		engine := common.GetEngine(r.Context())
+
+		engine.MetricsLock.Lock()
+		defer engine.MetricsLock.Unlock()
+
+		rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := WritePrometheus(rw, engine.Metrics); err != nil {
+			log.WithError(err).Error("Error while writing the Prometheus metrics")
+		}
+	})
+}
+
+// WritePrometheus writes the metrics in the Prometheus text format. Every metric is prefixed with
+// k6_, and its sub-metrics are written with it, with their tags as labels:
+//
+//   - counters are counters, with a _total suffix
+//   - gauges are gauges
+//   - rates are gauges of the rate, with a _rate suffix
+//   - trends are summaries of some quantiles, in seconds or bytes if they contain times or data
+func WritePrometheus(w io.Writer, metrics map[string]*stats.Metric) error {
+	families := make(map[string][]*stats.Metric)
+	var names []string
+	for name, m := range metrics {
+		parent := name
+		if m.Sub.Parent != "" {
+			parent = m.Sub.Parent
+		}
+		if _, ok := families[parent]; !ok {
+			names = append(names, parent)
+		}
+		families[parent] = append(families[parent], m)
+	}
+	sort.Strings(names)
+
+	buf := bufio.NewWriter(w)
+	for _, name := range names {
+		family := families[name]
+		// The metric itself goes first, then its sub-metrics.
+		sort.Slice(family, func(i, j int) bool {
+			if isSub := family[i].Sub.Parent != ""; isSub != (family[j].Sub.Parent != "") {
+				return !isSub
+			}
+			return family[i].Name < family[j].Name
+		})
+		writePrometheusFamily(buf, name, family)
+	}
+	return buf.Flush()
+}
+
+func writePrometheusFamily(w *bufio.Writer, name string, family []*stats.Metric) {
+	m := family[0]
+	promName := "k6_" + prometheusInvalidChars.ReplaceAllString(name, "_")
+	scale := 1.0
+	switch m.Contains {
+	case stats.Time:
+		promName, scale = promName+"_seconds", 1.0/1000
+	case stats.Data:
+		promName += "_bytes"
+	}
+
+	var promType string
+	switch m.Type {
+	case stats.Counter:
+		promName, promType = promName+"_total", "counter"
+	case stats.Gauge:
+		promType = "gauge"
+	case stats.Rate:
+		promName, promType = promName+"_rate", "gauge"
+	case stats.Trend:
+		promType = "summary"
+	default:
+		return
+	}
+	_, _ = fmt.Fprintf(w, "# HELP %s The k6 %s metric %s.\n", promName, strings.Trim(m.Type.String(), `"`), name)
+	_, _ = fmt.Fprintf(w, "# TYPE %s %s\n", promName, promType)
+
+	for _, m := range family {
+		var labels []string
+		if m.Sub.Tags != nil {
+			for k, v := range m.Sub.Tags.CloneTags() {
+				labels = append(labels, prometheusLabel(k, v))
+			}
+			sort.Strings(labels)
+		}
+
+		switch sink := m.Sink.(type) {
+		case *stats.CounterSink:
+			writePrometheusSample(w, promName, labels, sink.Value*scale)
+		case *stats.GaugeSink:
+			writePrometheusSample(w, promName, labels, sink.Value*scale)
+		case *stats.RateSink:
+			var rate float64
+			if sink.Total > 0 {
+				rate = float64(sink.Trues) / float64(sink.Total)
+			}
+			writePrometheusSample(w, promName, labels, rate)
+		case *stats.TrendSink:
+			for _, q := range PrometheusQuantiles {
+				quantile := prometheusLabel("quantile", strconv.FormatFloat(q, 'f', -1, 64))
+				writePrometheusSample(w, promName, append(labels[:len(labels):len(labels)], quantile), sink.P(q)*scale)
+			}
+			writePrometheusSample(w, promName+"_sum", labels, sink.Sum*scale)
+			writePrometheusSample(w, promName+"_count", labels, float64(sink.Count))
+		}
+	}
+}
+
+func writePrometheusSample(w *bufio.Writer, name string, labels []string, value float64) {
+	_, _ = w.WriteString(name)
+	if len(labels) > 0 {
+		_, _ = w.WriteString("{" + strings.Join(labels, ",") + "}")
+	}
+	var v string
+	switch {
+	case math.IsNaN(value):
+		v = "NaN"
+	case math.IsInf(value, 1):
+		v = "+Inf"
+	case math.IsInf(value, -1):
+		v = "-Inf"
+	default:
+		v = strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	_, _ = w.WriteString(" " + v + "\n")
+}
+
+func prometheusLabel(key, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return prometheusInvalidChars.ReplaceAllString(key, "_") + `="` + value + `"`
+}
@@ -0,0 +1,102 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/loadimpact/k6/api/common"
+	"github.com/loadimpact/k6/core"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	newMetric := func(name string, typ stats.MetricType, vt stats.ValueType, values ...float64) *stats.Metric {
+		m := stats.New(name, typ, vt)
+		if parent, sm := stats.NewSubmetric(name); parent != name {
+			m.Sub = *sm
+		}
+		for _, v := range values {
+			m.Sink.Add(stats.Sample{Value: v})
+		}
+		return m
+	}
+	metrics := map[string]*stats.Metric{}
+	for _, m := range []*stats.Metric{
+		newMetric("vus", stats.Gauge, stats.Default, 10),
+		newMetric("http_reqs", stats.Counter, stats.Default, 1, 1, 1),
+		newMetric("checks", stats.Rate, stats.Default, 1, 0, 1, 1),
+		newMetric("http_req_duration", stats.Trend, stats.Time, 100, 200, 300),
+		newMetric(`http_req_duration{status:200,name:"a\b"}`, stats.Trend, stats.Time, 100),
+		newMetric("data_sent", stats.Counter, stats.Data, 2048),
+	} {
+		metrics[m.Name] = m
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePrometheus(&buf, metrics))
+	assert.Equal(t, `# HELP k6_checks_rate The k6 rate metric checks.
+# TYPE k6_checks_rate gauge
+k6_checks_rate 0.75
+# HELP k6_data_sent_bytes_total The k6 counter metric data_sent.
+# TYPE k6_data_sent_bytes_total counter
+k6_data_sent_bytes_total 2048
+# HELP k6_http_req_duration_seconds The k6 trend metric http_req_duration.
+# TYPE k6_http_req_duration_seconds summary
+k6_http_req_duration_seconds{quantile="0.5"} 0.2
+k6_http_req_duration_seconds{quantile="0.9"} 0.28
+k6_http_req_duration_seconds{quantile="0.95"} 0.29
+k6_http_req_duration_seconds{quantile="0.99"} 0.298
+k6_http_req_duration_seconds_sum 0.6
+k6_http_req_duration_seconds_count 3
+k6_http_req_duration_seconds{name="a\\b",status="200",quantile="0.5"} 0.1
+k6_http_req_duration_seconds{name="a\\b",status="200",quantile="0.9"} 0.1
+k6_http_req_duration_seconds{name="a\\b",status="200",quantile="0.95"} 0.1
+k6_http_req_duration_seconds{name="a\\b",status="200",quantile="0.99"} 0.1
+k6_http_req_duration_seconds_sum{name="a\\b",status="200"} 0.1
+k6_http_req_duration_seconds_count{name="a\\b",status="200"} 1
+# HELP k6_http_reqs_total The k6 counter metric http_reqs.
+# TYPE k6_http_reqs_total counter
+k6_http_reqs_total 3
+# HELP k6_vus The k6 gauge metric vus.
+# TYPE k6_vus gauge
+k6_vus 10
+`, buf.String())
+}
+
+func TestHandlePrometheus(t *testing.T) {
+	engine, err := core.NewEngine(nil, lib.Options{})
+	require.NoError(t, err)
+	engine.Metrics = map[string]*stats.Metric{"vus": stats.New("vus", stats.Gauge)}
+
+	rw := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	NewHandler().ServeHTTP(rw, r.WithContext(common.WithEngine(r.Context(), engine)))
+	res := rw.Result()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", res.Header.Get("Content-Type"))
+	assert.Contains(t, rw.Body.String(), "\nk6_vus 0\n")
+}
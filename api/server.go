@@ -21,12 +21,17 @@
 package api
 
 import (
+	"crypto/subtle"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/loadimpact/k6/api/common"
 	"github.com/loadimpact/k6/api/v1"
 	"github.com/loadimpact/k6/core"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/negroni"
 )
@@ -35,19 +40,46 @@ func NewHandler() http.Handler {
 	mux := http.NewServeMux()
 	mux.Handle("/v1/", v1.NewHandler())
 	mux.Handle("/ping", HandlePing())
-	mux.Handle("/", HandlePing())
+	mux.Handle("/metrics", HandlePrometheus())
+	mux.Handle("/", HandleDashboard(HandlePing()))
 	return mux
 }
 
-func ListenAndServe(addr string, engine *core.Engine) error {
+// Config is the configuration of the API server.
+type Config struct {
+	// If set, the requests that change the test have to be authenticated with this token.
+	Token string
+
+	// If both are set, the API is served over TLS with this certificate and key.
+	TLSCert string
+	TLSKey  string
+}
+
+// Validate checks that the TLS certificate and key are either both set, or neither is.
+func (c Config) Validate() error {
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("both a TLS certificate and a key are needed to serve the API over TLS")
+	}
+	return nil
+}
+
+func ListenAndServe(addr string, engine *core.Engine, conf Config) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
 	mux := NewHandler()
 
 	n := negroni.New()
 	n.Use(negroni.NewRecovery())
 	n.UseFunc(WithEngine(engine))
 	n.UseFunc(NewLogger(log.StandardLogger()))
+	n.UseFunc(WithToken(conf.Token))
 	n.UseHandler(mux)
 
+	if conf.TLSCert != "" {
+		return http.ListenAndServeTLS(addr, conf.TLSCert, conf.TLSKey, n)
+	}
 	return http.ListenAndServe(addr, n)
 }
 
@@ -67,6 +99,42 @@ func WithEngine(engine *core.Engine) negroni.HandlerFunc {
 	})
 }
 
+// WithToken requires the requests that can change the test to have the token as a bearer token
+// in their Authorization header. Everything can still be read without it, except for reads that
+// add sub-metrics to the engine, and nothing is required if the token is empty.
+func WithToken(token string) negroni.HandlerFunc {
+	return negroni.HandlerFunc(func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if _, ok := r.URL.Query()["submetric"]; !ok {
+				next(rw, r)
+				return
+			}
+		}
+		if token == "" {
+			next(rw, r)
+			return
+		}
+
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+			rw.Header().Set("WWW-Authenticate", `Bearer realm="k6"`)
+			rw.WriteHeader(http.StatusUnauthorized)
+			doc := v1.ErrorResponse{Errors: []v1.Error{{
+				Status: strconv.Itoa(http.StatusUnauthorized),
+				Title:  "Unauthorized",
+				Detail: "a valid API token is needed to change the test",
+			}}}
+			if err := json.NewEncoder(rw).Encode(doc); err != nil {
+				log.WithError(err).Error("Error while writing the response")
+			}
+			return
+		}
+		next(rw, r)
+	})
+}
+
 func HandlePing() http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		rw.Header().Add("Content-Type", "text/plain; charset=utf-8")
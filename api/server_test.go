@@ -21,12 +21,14 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/loadimpact/k6/api/common"
+	"github.com/loadimpact/k6/api/v1"
 	"github.com/loadimpact/k6/core"
 	"github.com/loadimpact/k6/lib"
 	log "github.com/sirupsen/logrus"
@@ -96,3 +98,64 @@ func TestPing(t *testing.T) {
 	assert.Equal(t, http.StatusOK, res.StatusCode)
 	assert.Equal(t, []byte{'o', 'k'}, rw.Body.Bytes())
 }
+
+func TestWithToken(t *testing.T) {
+	testdata := map[string]struct {
+		token, method, auth, query string
+		status                     int
+	}{
+		"NoToken":            {"", "PATCH", "", "", http.StatusOK},
+		"Read":               {"secret", "GET", "", "", http.StatusOK},
+		"Missing":            {"secret", "PATCH", "", "", http.StatusUnauthorized},
+		"Wrong":              {"secret", "POST", "Bearer nope", "", http.StatusUnauthorized},
+		"NotBearer":          {"secret", "PUT", "Basic secret", "", http.StatusUnauthorized},
+		"Valid":              {"secret", "PATCH", "Bearer secret", "", http.StatusOK},
+		"ValidReadAnyways":   {"secret", "GET", "Bearer secret", "", http.StatusOK},
+		"SubmetricNoToken":   {"", "GET", "", "?submetric=a{b:c}", http.StatusOK},
+		"SubmetricMissing":   {"secret", "GET", "", "?submetric=a{b:c}", http.StatusUnauthorized},
+		"SubmetricValid":     {"secret", "GET", "Bearer secret", "?submetric=a{b:c}", http.StatusOK},
+		"SubmetricOtherRead": {"secret", "GET", "", "?interval=1s", http.StatusOK},
+	}
+	for name, data := range testdata {
+		t.Run(name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			r := httptest.NewRequest(data.method, "http://example.com/v1/status"+data.query, nil)
+			if data.auth != "" {
+				r.Header.Set("Authorization", data.auth)
+			}
+			WithToken(data.token)(rw, r, testHTTPHandler)
+
+			res := rw.Result()
+			assert.Equal(t, data.status, res.StatusCode)
+			if data.status == http.StatusUnauthorized {
+				var errs v1.ErrorResponse
+				assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &errs))
+				if assert.Len(t, errs.Errors, 1) {
+					assert.Equal(t, "Unauthorized", errs.Errors[0].Title)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.NoError(t, Config{Token: "secret", TLSCert: "cert.pem", TLSKey: "key.pem"}.Validate())
+	assert.Error(t, Config{TLSCert: "cert.pem"}.Validate())
+	assert.Error(t, Config{TLSKey: "key.pem"}.Validate())
+}
+
+func TestDashboard(t *testing.T) {
+	mux := NewHandler()
+
+	rw := httptest.NewRecorder()
+	mux.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	res := rw.Result()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+	assert.Contains(t, rw.Body.String(), "/v1/metrics/stream")
+
+	rw = httptest.NewRecorder()
+	mux.ServeHTTP(rw, httptest.NewRequest("GET", "/something", nil))
+	assert.Equal(t, []byte{'o', 'k'}, rw.Body.Bytes())
+}
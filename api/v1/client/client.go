@@ -28,6 +28,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/manyminds/api2go/jsonapi"
 
@@ -36,10 +37,21 @@ import (
 
 type Client struct {
 	BaseURL *url.URL
+
+	// If set, it's sent as a bearer token with every request.
+	Token string
+
+	// The HTTP client used for the requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
 }
 
+// New returns a client for the API server at the base address. It's served over plain HTTP,
+// unless the address has another scheme, like https://localhost:6565.
 func New(base string) (*Client, error) {
-	baseURL, err := url.Parse("http://" + base)
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	baseURL, err := url.Parse(base)
 	if err != nil {
 		return nil, err
 	}
@@ -62,8 +74,15 @@ func (c *Client) call(ctx context.Context, method string, rel *url.URL, body, ou
 		Body:   bodyReader,
 	}
 	req = req.WithContext(ctx)
+	if c.Token != "" {
+		req.Header = http.Header{"Authorization": {"Bearer " + c.Token}}
+	}
 
-	res, err := http.DefaultClient.Do(req)
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,39 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/loadimpact/k6/api/v1"
+)
+
+var SnapshotURL = &url.URL{Path: "/v1/snapshot"}
+
+// Snapshot returns the current values of all metrics, including the given sub-metrics once they
+// have samples.
+func (c *Client) Snapshot(ctx context.Context, submetrics ...string) (ret v1.Snapshot, err error) {
+	u := *SnapshotURL
+	if len(submetrics) > 0 {
+		u.RawQuery = url.Values{"submetric": submetrics}.Encode()
+	}
+	return ret, c.call(ctx, "GET", &u, nil, &ret)
+}
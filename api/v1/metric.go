@@ -23,6 +23,7 @@ package v1
 import (
 	"bytes"
 	"encoding/json"
+	"math"
 	"time"
 
 	"github.com/loadimpact/k6/stats"
@@ -82,12 +83,20 @@ type Metric struct {
 }
 
 func NewMetric(m *stats.Metric, t time.Duration) Metric {
+	// Values like the rate of a counter at the very start of a test can't be encoded as JSON.
+	sample := m.Sink.Format(t)
+	for k, v := range sample {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			delete(sample, k)
+		}
+	}
+
 	return Metric{
 		Name:     m.Name,
 		Type:     NullMetricType{m.Type, true},
 		Contains: NullValueType{m.Contains, true},
 		Tainted:  m.Tainted,
-		Sample:   m.Sink.Format(t),
+		Sample:   sample,
 	}
 }
 
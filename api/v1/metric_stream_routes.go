@@ -0,0 +1,106 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/loadimpact/k6/api/common"
+	"github.com/manyminds/api2go/jsonapi"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMetricsStreamInterval is how often snapshots are streamed if no interval is given.
+	DefaultMetricsStreamInterval = 1 * time.Second
+	// MinMetricsStreamInterval is the shortest interval snapshots can be streamed at.
+	MinMetricsStreamInterval = 100 * time.Millisecond
+)
+
+var metricsStreamUpgrader = websocket.Upgrader{}
+
+// HandleMetricsStream upgrades the connection to a WebSocket, and pushes a snapshot of the
+// metrics through it right away and every ?interval from then on, until it's closed. Like with
+// GET /v1/snapshot, the sub-metrics in the submetric query parameters are included.
+func HandleMetricsStream(rw http.ResponseWriter, r *http.Request) {
+	engine := common.GetEngine(r.Context())
+	query := r.URL.Query()
+
+	interval := DefaultMetricsStreamInterval
+	if v := query.Get("interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			apiError(rw, "Invalid interval", err.Error(), http.StatusBadRequest)
+			return
+		}
+		if d < MinMetricsStreamInterval {
+			apiError(rw, "Invalid interval",
+				fmt.Sprintf("the interval can't be shorter than %s", MinMetricsStreamInterval), http.StatusBadRequest)
+			return
+		}
+		interval = d
+	}
+	for _, name := range query["submetric"] {
+		if err := engine.AddSubmetric(name); err != nil {
+			apiError(rw, "Invalid sub-metric", err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	conn, err := metricsStreamUpgrader.Upgrade(rw, r, nil)
+	if err != nil {
+		// The upgrader has already responded with the error.
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Nothing is expected from the client, but reading is the only way to notice it's gone.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		data, err := jsonapi.Marshal(NewSnapshot(engine))
+		if err != nil {
+			log.WithError(err).Error("Couldn't encode a metrics snapshot")
+			return
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/loadimpact/k6/api/common"
+	"github.com/loadimpact/k6/core"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/manyminds/api2go/jsonapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsStream(t *testing.T) {
+	engine, err := core.NewEngine(nil, lib.Options{})
+	require.NoError(t, err)
+	engine.Metrics = map[string]*stats.Metric{
+		"my_metric": stats.New("my_metric", stats.Counter),
+	}
+
+	handler := NewHandler()
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(rw, r.WithContext(common.WithEngine(r.Context(), engine)))
+	}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/metrics/stream"
+
+	t.Run("stream", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(url+"?interval=100ms&submetric=my_metric{a:1}", nil)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+		assert.Len(t, engine.Metrics["my_metric"].Submetrics, 1)
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, data, err := conn.ReadMessage()
+			require.NoError(t, err)
+
+			var snapshot Snapshot
+			require.NoError(t, jsonapi.Unmarshal(data, &snapshot))
+			assert.Contains(t, snapshot.Metrics, "my_metric")
+		}
+		assert.True(t, time.Since(start) >= 200*time.Millisecond)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, query := range []string{"?interval=blah", "?interval=1ms", "?submetric=my_metric"} {
+			_, res, err := websocket.DefaultDialer.Dial(url+query, nil)
+			assert.Error(t, err, query)
+			if assert.NotNil(t, res, query) {
+				assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+			}
+		}
+	})
+	t.Run("not a websocket", func(t *testing.T) {
+		res, err := http.Get(srv.URL + "/v1/metrics/stream")
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
@@ -95,4 +95,11 @@ func TestNewMetric(t *testing.T) {
 	assert.True(t, m.Tainted.Valid)
 	assert.Equal(t, stats.Time, m.Contains.Type)
 	assert.NotEmpty(t, m.Sample)
+
+	counter := stats.New("counter", stats.Counter)
+	counter.Sink.Add(stats.Sample{Value: 2})
+	m = NewMetric(counter, 0)
+	assert.Equal(t, map[string]float64{"count": 2}, m.Sample)
+	_, err := json.Marshal(m)
+	assert.NoError(t, err)
 }
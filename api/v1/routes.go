@@ -35,6 +35,8 @@ func NewHandler() http.Handler {
 	router.GET("/v1/metrics", HandleGetMetrics)
 	router.GET("/v1/metrics/:id", HandleGetMetric)
 
+	router.GET("/v1/snapshot", HandleGetSnapshot)
+
 	router.GET("/v1/groups", HandleGetGroups)
 	router.GET("/v1/groups/:id", HandleGetGroup)
 
@@ -44,5 +46,9 @@ func NewHandler() http.Handler {
 
 	router.POST("/v1/teardown", HandleRunTeardown)
 
-	return router
+	// The router can't have the stream next to the metric IDs, so it's routed before it.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/metrics/stream", HandleMetricsStream)
+	mux.Handle("/", router)
+	return mux
 }
@@ -0,0 +1,65 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package v1
+
+import (
+	"time"
+
+	"github.com/loadimpact/k6/core"
+	"github.com/loadimpact/k6/lib/types"
+)
+
+// A Snapshot holds the current values of all metrics of a test, including its sub-metrics.
+type Snapshot struct {
+	// When the snapshot was taken, and how long the test has been running then.
+	Time    time.Time      `json:"time" yaml:"time"`
+	Elapsed types.Duration `json:"elapsed" yaml:"elapsed"`
+
+	Metrics map[string]Metric `json:"metrics" yaml:"metrics"`
+}
+
+// NewSnapshot returns a snapshot of the metrics of the engine.
+func NewSnapshot(engine *core.Engine) Snapshot {
+	var t time.Duration
+	if engine.Executor != nil {
+		t = engine.Executor.GetTime()
+	}
+
+	engine.MetricsLock.Lock()
+	defer engine.MetricsLock.Unlock()
+
+	metrics := make(map[string]Metric, len(engine.Metrics))
+	for name, m := range engine.Metrics {
+		metrics[name] = NewMetric(m, t)
+	}
+	return Snapshot{Time: time.Now(), Elapsed: types.Duration(t), Metrics: metrics}
+}
+
+func (s Snapshot) GetName() string {
+	return "snapshot"
+}
+
+func (s Snapshot) GetID() string {
+	return "default"
+}
+
+func (s Snapshot) SetID(id string) error {
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/loadimpact/k6/core"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/manyminds/api2go/jsonapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSnapshot(t *testing.T) {
+	engine, err := core.NewEngine(nil, lib.Options{})
+	require.NoError(t, err)
+
+	sub := stats.New("my_metric{a:1}", stats.Trend, stats.Time)
+	sub.Sink.Add(stats.Sample{Value: 10})
+	engine.Metrics = map[string]*stats.Metric{
+		"my_metric":      stats.New("my_metric", stats.Trend, stats.Time),
+		"my_metric{a:1}": sub,
+	}
+
+	t.Run("all", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		NewHandler().ServeHTTP(rw, newRequestWithEngine(engine, "GET", "/v1/snapshot", nil))
+		res := rw.Result()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var snapshot Snapshot
+		require.NoError(t, jsonapi.Unmarshal(rw.Body.Bytes(), &snapshot))
+		assert.False(t, snapshot.Time.IsZero())
+		assert.Len(t, snapshot.Metrics, 2)
+		if assert.Contains(t, snapshot.Metrics, "my_metric{a:1}") {
+			m := snapshot.Metrics["my_metric{a:1}"]
+			assert.Equal(t, stats.Trend, m.Type.Type)
+			assert.Equal(t, 10.0, m.Sample["max"])
+		}
+	})
+	t.Run("submetric", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		url := "/v1/snapshot?submetric=my_metric%7Bb:2%7D"
+		NewHandler().ServeHTTP(rw, newRequestWithEngine(engine, "GET", url, nil))
+		assert.Equal(t, http.StatusOK, rw.Result().StatusCode)
+		assert.Len(t, engine.Metrics["my_metric"].Submetrics, 1)
+	})
+	t.Run("invalid submetric", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		NewHandler().ServeHTTP(rw, newRequestWithEngine(engine, "GET", "/v1/snapshot?submetric=my_metric", nil))
+		assert.Equal(t, http.StatusBadRequest, rw.Result().StatusCode)
+	})
+}
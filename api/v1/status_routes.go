@@ -56,6 +56,10 @@ func HandlePatchStatus(rw http.ResponseWriter, r *http.Request, p httprouter.Par
 		return
 	}
 
+	if status.Stopped.Valid && !status.Stopped.Bool && engine.IsStopped() {
+		apiError(rw, "Couldn't resume", "a stopped test can't be resumed", http.StatusBadRequest)
+		return
+	}
 	if status.VUsMax.Valid {
 		if err := engine.Executor.SetVUsMax(status.VUsMax.Int64); err != nil {
 			apiError(rw, "Couldn't change cap", err.Error(), http.StatusBadRequest)
@@ -71,6 +75,9 @@ func HandlePatchStatus(rw http.ResponseWriter, r *http.Request, p httprouter.Par
 	if status.Paused.Valid {
 		engine.Executor.SetPaused(status.Paused.Bool)
 	}
+	if status.Stopped.Bool {
+		engine.Stop()
+	}
 
 	data, err := jsonapi.Marshal(NewStatus(engine))
 	if err != nil {
@@ -72,6 +72,7 @@ func TestPatchStatus(t *testing.T) {
 		"max vus":      {200, Status{VUsMax: null.IntFrom(10)}},
 		"too many vus": {400, Status{VUs: null.IntFrom(10), VUsMax: null.IntFrom(0)}},
 		"vus":          {200, Status{VUs: null.IntFrom(10), VUsMax: null.IntFrom(10)}},
+		"stopped":      {200, Status{Stopped: null.BoolFrom(true)}},
 	}
 
 	for name, indata := range testdata {
@@ -105,6 +106,9 @@ func TestPatchStatus(t *testing.T) {
 			if indata.Status.VUsMax.Valid {
 				assert.Equal(t, indata.Status.VUsMax, status.VUsMax)
 			}
+			if indata.Status.Stopped.Valid {
+				assert.Equal(t, indata.Status.Stopped, status.Stopped)
+			}
 		})
 	}
 }
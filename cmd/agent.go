@@ -0,0 +1,267 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/api"
+	"github.com/loadimpact/k6/k6exec"
+	"github.com/loadimpact/k6/lib"
+	jsonc "github.com/loadimpact/k6/stats/json"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/urfave/negroni"
+)
+
+const (
+	// agentErrorTrailer is the trailer of the metrics stream with the error of the test, if
+	// it failed on the agent.
+	agentErrorTrailer = "K6-Agent-Error"
+
+	// agentFlushInterval is how often the metrics are sent to the coordinator.
+	agentFlushInterval = 200 * time.Millisecond
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run parts of distributed tests for a coordinator",
+	Long: `Run parts of distributed tests for a coordinator.
+
+The agent waits for tests from a coordinator started with 'k6 coordinator run'
+and runs its own execution segment of every test it receives, one at a time.
+The metrics are streamed back to the coordinator, which evaluates the
+thresholds and prints the summary.
+
+The agent listens on the global --address. The tests can run any script, so
+agents that are reachable by others should require a token with --api-token,
+and can be served over TLS with --api-tls-cert and --api-tls-key.`,
+	Example: `
+  # Wait for tests on all network interfaces, only from coordinators with the token.
+  k6 agent --address 0.0.0.0:6565 --api-token secret`[1:],
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf := apiConfig()
+		if err := conf.Validate(); err != nil {
+			return err
+		}
+
+		n := negroni.New()
+		n.Use(negroni.NewRecovery())
+		n.UseFunc(api.NewLogger(log.StandardLogger()))
+		n.UseFunc(api.WithToken(conf.Token))
+		n.UseHandler((&agent{}).handler())
+
+		log.WithField("address", address).Info("Waiting for tests from a coordinator")
+		if conf.TLSCert != "" {
+			return http.ListenAndServeTLS(address, conf.TLSCert, conf.TLSKey, n)
+		}
+		return http.ListenAndServe(address, n)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(agentCmd)
+}
+
+// agent runs the tests that it receives from coordinators. A test is first prepared from an
+// archive, with its VUs initialized, and then started, so that all agents can start at once.
+type agent struct {
+	lock    sync.Mutex
+	test    *k6exec.Test // the prepared or running test
+	running bool
+}
+
+func (a *agent) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/prepare", a.handlePrepare)
+	mux.HandleFunc("/v1/start", a.handleStart)
+	mux.HandleFunc("/v1/stop", a.handleStop)
+	mux.Handle("/ping", api.HandlePing())
+	return mux
+}
+
+// handlePrepare initializes the test in the archive of the request body, replacing any
+// test that was prepared but not started.
+func (a *agent) handlePrepare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.running {
+		http.Error(w, "another test is already running", http.StatusConflict)
+		return
+	}
+	a.test = nil
+
+	// Setup and teardown are run once by the coordinator, not by every agent.
+	test, err := k6exec.New(&lib.SourceData{Data: data, Filename: "/archive.tar"}, k6exec.Config{
+		FS:           afero.NewMemMapFs(),
+		NoThresholds: true,
+	})
+	if err != nil {
+		log.WithError(err).Error("Couldn't prepare the test")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	test.Engine.Executor.SetRunSetup(false)
+	test.Engine.Executor.SetRunTeardown(false)
+	a.test = test
+
+	opts := test.Runner.GetOptions()
+	log.WithFields(log.Fields{
+		"segment": opts.ExecutionSegment,
+		"vus":     opts.VUs.Int64,
+		"vusMax":  opts.VUsMax.Int64,
+	}).Info("The test is prepared")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleStart runs the prepared test with the setup data in the request body, and streams
+// its metrics in the JSON output format until it's finished. An error of the test is sent
+// in the trailer, since the response has already started by then.
+func (a *agent) handleStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	setupData, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.lock.Lock()
+	test := a.test
+	if test == nil || a.running {
+		a.lock.Unlock()
+		http.Error(w, "no test is prepared", http.StatusConflict)
+		return
+	}
+	a.running = true
+	a.lock.Unlock()
+	defer func() {
+		a.lock.Lock()
+		a.test, a.running = nil, false
+		a.lock.Unlock()
+	}()
+
+	if len(setupData) > 0 {
+		test.Runner.SetSetupData(setupData)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Trailer", agentErrorTrailer)
+	w.WriteHeader(http.StatusOK)
+
+	out := &flushWriter{w: w}
+	test.Subscribe(jsonc.NewFromWriter(out).Collect)
+	flushCtx, stopFlushing := context.WithCancel(context.Background())
+	flushDone := make(chan struct{})
+	go func() {
+		defer close(flushDone)
+		out.run(flushCtx, agentFlushInterval)
+	}()
+
+	log.Info("Starting the test")
+	_, err = test.Run(r.Context())
+	stopFlushing()
+	<-flushDone
+	if err != nil {
+		log.WithError(err).Error("The test failed")
+		w.Header().Set(agentErrorTrailer, strings.Replace(err.Error(), "\n", " ", -1))
+		return
+	}
+	log.Info("The test is finished")
+}
+
+// handleStop stops the running test, or discards the prepared one.
+func (a *agent) handleStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	switch {
+	case a.running:
+		log.Info("Stopping the test")
+		a.test.Engine.Stop()
+	case a.test != nil:
+		log.Info("Discarding the prepared test")
+		a.test = nil
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// flushWriter is a writer for a streamed response, which is flushed periodically, so that
+// the metrics reach the coordinator while the test is running.
+type flushWriter struct {
+	lock sync.Mutex
+	w    http.ResponseWriter
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	fw.lock.Lock()
+	defer fw.lock.Unlock()
+	return fw.w.Write(p)
+}
+
+// run flushes the response every interval until the context is done, and once more after.
+func (fw *flushWriter) run(ctx context.Context, interval time.Duration) {
+	flusher, ok := fw.w.(http.Flusher)
+	if !ok {
+		<-ctx.Done()
+		return
+	}
+	flush := func() {
+		fw.lock.Lock()
+		defer fw.lock.Unlock()
+		flusher.Flush()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			flush()
+			return
+		}
+	}
+}
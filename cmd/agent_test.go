@@ -0,0 +1,51 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentErrors(t *testing.T) {
+	handler := (&agent{}).handler()
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rw
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, request("GET", "/v1/prepare", "").Code)
+	assert.Equal(t, http.StatusConflict, request("POST", "/v1/start", "").Code)
+	assert.Equal(t, http.StatusNoContent, request("POST", "/v1/stop", "").Code)
+
+	rw := request("POST", "/v1/prepare", "export default function() {")
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	assert.Contains(t, rw.Body.String(), "SyntaxError")
+
+	assert.Equal(t, http.StatusNoContent, request("POST", "/v1/prepare", "export default function() {}").Code)
+	assert.Equal(t, http.StatusNoContent, request("POST", "/v1/stop", "").Code)
+	assert.Equal(t, http.StatusConflict, request("POST", "/v1/start", "").Code, "the test should be discarded")
+}
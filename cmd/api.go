@@ -0,0 +1,79 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/loadimpact/k6/api"
+	"github.com/loadimpact/k6/api/v1/client"
+	"github.com/pkg/errors"
+)
+
+// apiConfig returns the configuration of the API server from the global flags.
+func apiConfig() api.Config {
+	return api.Config{Token: apiToken, TLSCert: apiTLSCert, TLSKey: apiTLSKey}
+}
+
+// newAPIClient returns a client for the API server at the global --address. If a TLS
+// certificate is given, the server is reached over HTTPS and the certificate is trusted.
+func newAPIClient() (*client.Client, error) {
+	base := address
+	if apiTLSCert != "" && !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	c, err := client.New(base)
+	if err != nil {
+		return nil, err
+	}
+	c.Token = apiToken
+
+	if apiTLSCert != "" {
+		if c.HTTPClient, err = newAPIHTTPClient(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// newAPIHTTPClient returns an HTTP client that trusts the global --api-tls-cert, if it's set.
+func newAPIHTTPClient() (*http.Client, error) {
+	if apiTLSCert == "" {
+		return &http.Client{}, nil
+	}
+	pem, err := ioutil.ReadFile(apiTLSCert)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.Errorf("no certificates found in %s", apiTLSCert)
+	}
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}}, nil
+}
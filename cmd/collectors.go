@@ -29,10 +29,16 @@ import (
 	"github.com/kelseyhightower/envconfig"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/stats/cloud"
+	"github.com/loadimpact/k6/stats/cloudwatch"
+	"github.com/loadimpact/k6/stats/csv"
 	"github.com/loadimpact/k6/stats/datadog"
+	"github.com/loadimpact/k6/stats/elasticsearch"
+	"github.com/loadimpact/k6/stats/graphite"
 	"github.com/loadimpact/k6/stats/influxdb"
 	jsonc "github.com/loadimpact/k6/stats/json"
 	"github.com/loadimpact/k6/stats/kafka"
+	"github.com/loadimpact/k6/stats/newrelic"
+	"github.com/loadimpact/k6/stats/otlp"
 	"github.com/loadimpact/k6/stats/statsd"
 	"github.com/loadimpact/k6/stats/statsd/common"
 	"github.com/pkg/errors"
@@ -40,12 +46,18 @@ import (
 )
 
 const (
-	collectorInfluxDB = "influxdb"
-	collectorJSON     = "json"
-	collectorKafka    = "kafka"
-	collectorCloud    = "cloud"
-	collectorStatsD   = "statsd"
-	collectorDatadog  = "datadog"
+	collectorInfluxDB      = "influxdb"
+	collectorJSON          = "json"
+	collectorCSV           = "csv"
+	collectorKafka         = "kafka"
+	collectorCloud         = "cloud"
+	collectorStatsD        = "statsd"
+	collectorDatadog       = "datadog"
+	collectorElasticsearch = "elasticsearch"
+	collectorCloudWatch    = "cloudwatch"
+	collectorNewRelic      = "newrelic"
+	collectorGraphite      = "graphite"
+	collectorOTLP          = "otlp"
 )
 
 func parseCollector(s string) (t, arg string) {
@@ -64,7 +76,29 @@ func newCollector(collectorName, arg string, src *lib.SourceData, conf Config) (
 	getCollector := func() (lib.Collector, error) {
 		switch collectorName {
 		case collectorJSON:
-			return jsonc.New(afero.NewOsFs(), arg)
+			c, err := jsonc.New(afero.NewOsFs(), arg)
+			if err != nil {
+				return nil, err
+			}
+			c.SetThresholds(conf.Thresholds)
+			return c, nil
+		case collectorCSV:
+			config := csv.NewConfig().Apply(conf.Collectors.CSV)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			if arg != "" {
+				cmdConfig, err := csv.ParseArg(arg)
+				if err != nil {
+					return nil, err
+				}
+				config = config.Apply(cmdConfig)
+			}
+			systemTags := conf.SystemTags
+			if systemTags == nil {
+				systemTags = lib.GetTagSet(lib.DefaultSystemTagList...)
+			}
+			return csv.New(afero.NewOsFs(), systemTags, config)
 		case collectorInfluxDB:
 			config := influxdb.NewConfig().Apply(conf.Collectors.InfluxDB)
 			if err := envconfig.Process("k6", &config); err != nil {
@@ -103,13 +137,92 @@ func newCollector(collectorName, arg string, src *lib.SourceData, conf Config) (
 			if err := envconfig.Process("k6_statsd", &config); err != nil {
 				return nil, err
 			}
+			if arg != "" {
+				cmdConfig, err := common.ParseArg(arg)
+				if err != nil {
+					return nil, err
+				}
+				config = config.Apply(cmdConfig)
+			}
 			return statsd.New(config)
 		case collectorDatadog:
 			config := datadog.NewConfig().Apply(conf.Collectors.Datadog)
 			if err := envconfig.Process("k6_datadog", &config); err != nil {
 				return nil, err
 			}
+			if arg != "" {
+				cmdConfig, err := common.ParseArg(arg)
+				if err != nil {
+					return nil, err
+				}
+				config.Config = config.Config.Apply(cmdConfig)
+			}
 			return datadog.New(config)
+		case collectorElasticsearch:
+			config := elasticsearch.NewConfig().Apply(conf.Collectors.Elasticsearch)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			if arg != "" {
+				cmdConfig, err := elasticsearch.ParseArg(arg)
+				if err != nil {
+					return nil, err
+				}
+				config = config.Apply(cmdConfig)
+			}
+			return elasticsearch.New(config)
+		case collectorCloudWatch:
+			config := cloudwatch.NewConfig().Apply(conf.Collectors.CloudWatch)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			if arg != "" {
+				cmdConfig, err := cloudwatch.ParseArg(arg)
+				if err != nil {
+					return nil, err
+				}
+				config = config.Apply(cmdConfig)
+			}
+			return cloudwatch.New(config)
+		case collectorNewRelic:
+			config := newrelic.NewConfig().Apply(conf.Collectors.NewRelic)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			if arg != "" {
+				cmdConfig, err := newrelic.ParseArg(arg)
+				if err != nil {
+					return nil, err
+				}
+				config = config.Apply(cmdConfig)
+			}
+			return newrelic.New(config)
+		case collectorGraphite:
+			config := graphite.NewConfig().Apply(conf.Collectors.Graphite)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			if arg != "" {
+				cmdConfig, err := graphite.ParseArg(arg)
+				if err != nil {
+					return nil, err
+				}
+				config = config.Apply(cmdConfig)
+			}
+			return graphite.New(config)
+		case collectorOTLP:
+			config := otlp.NewConfig().Apply(conf.Collectors.OTLP)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			if arg != "" {
+				cmdConfig, err := otlp.ParseArg(arg)
+				if err != nil {
+					return nil, err
+				}
+				config = config.Apply(cmdConfig)
+			}
+			return otlp.New(config, conf.Options.RunTags)
 		default:
 			return nil, errors.Errorf("unknown output type: %s", collectorName)
 		}
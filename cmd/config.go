@@ -32,10 +32,17 @@ import (
 	"github.com/kelseyhightower/envconfig"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/scheduler"
+	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats/cloud"
+	"github.com/loadimpact/k6/stats/cloudwatch"
+	"github.com/loadimpact/k6/stats/csv"
 	"github.com/loadimpact/k6/stats/datadog"
+	"github.com/loadimpact/k6/stats/elasticsearch"
+	"github.com/loadimpact/k6/stats/graphite"
 	"github.com/loadimpact/k6/stats/influxdb"
 	"github.com/loadimpact/k6/stats/kafka"
+	"github.com/loadimpact/k6/stats/newrelic"
+	"github.com/loadimpact/k6/stats/otlp"
 	"github.com/loadimpact/k6/stats/statsd/common"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
@@ -52,24 +59,48 @@ func configFlagSet() *pflag.FlagSet {
 	flags.Bool("no-usage-report", false, "don't send anonymous stats to the developers")
 	flags.Bool("no-thresholds", false, "don't run thresholds")
 	flags.Bool("no-summary", false, "don't show the summary at the end of the test")
+	flags.String("summary-export", "", "write the end-of-test summary as JSON to `file`")
+	flags.Duration("summary-interval", 0, "print an interim summary every `interval` during the test")
+	flags.String("summary-interval-mode", summaryModeCumulative, "interim summary `mode`, 'cumulative' or 'windowed'")
+	flags.String("summary-interval-export", "", "also append every interim summary as a JSON line to `file`")
+	flags.String("ci-annotations", "", "report failed thresholds as CI annotations, as `github` or `gitlab[=file]`")
+	flags.String("max-memory", "", "abort before the start if the test is estimated to need more than `size` of memory, e.g. 4GB")
+	flags.Duration("aggregation-period", 0, "aggregate the samples for the outputs in buckets of this `period`, e.g. 1s")
+	flags.StringSlice("aggregation-stats", nil, "the `stats` of trend metrics sent for every aggregation bucket, e.g. 'count,avg,p(95)'")
 	return flags
 }
 
 type Config struct {
 	lib.Options
 
-	Out           []string  `json:"out" envconfig:"out"`
-	Linger        null.Bool `json:"linger" envconfig:"linger"`
-	NoUsageReport null.Bool `json:"noUsageReport" envconfig:"no_usage_report"`
-	NoThresholds  null.Bool `json:"noThresholds" envconfig:"no_thresholds"`
-	NoSummary     null.Bool `json:"noSummary" envconfig:"no_summary"`
+	Out           []string    `json:"out" envconfig:"out"`
+	Linger        null.Bool   `json:"linger" envconfig:"linger"`
+	NoUsageReport null.Bool   `json:"noUsageReport" envconfig:"no_usage_report"`
+	NoThresholds  null.Bool   `json:"noThresholds" envconfig:"no_thresholds"`
+	NoSummary     null.Bool   `json:"noSummary" envconfig:"no_summary"`
+	SummaryExport null.String `json:"summaryExport" envconfig:"summary_export"`
+	CIAnnotations null.String `json:"ciAnnotations" envconfig:"ci_annotations"`
+	MaxMemory     null.String `json:"maxMemory" envconfig:"max_memory"`
+
+	SummaryInterval       types.NullDuration `json:"summaryInterval" envconfig:"summary_interval"`
+	SummaryIntervalMode   null.String        `json:"summaryIntervalMode" envconfig:"summary_interval_mode"`
+	SummaryIntervalExport null.String        `json:"summaryIntervalExport" envconfig:"summary_interval_export"`
+
+	AggregationPeriod types.NullDuration `json:"aggregationPeriod" envconfig:"aggregation_period"`
+	AggregationStats  []string           `json:"aggregationStats" envconfig:"aggregation_stats"`
 
 	Collectors struct {
-		InfluxDB influxdb.Config `json:"influxdb"`
-		Kafka    kafka.Config    `json:"kafka"`
-		Cloud    cloud.Config    `json:"cloud"`
-		StatsD   common.Config   `json:"statsd"`
-		Datadog  datadog.Config  `json:"datadog"`
+		InfluxDB      influxdb.Config      `json:"influxdb"`
+		Kafka         kafka.Config         `json:"kafka"`
+		CSV           csv.Config           `json:"csv"`
+		Cloud         cloud.Config         `json:"cloud"`
+		StatsD        common.Config        `json:"statsd"`
+		Datadog       datadog.Config       `json:"datadog"`
+		Elasticsearch elasticsearch.Config `json:"elasticsearch"`
+		CloudWatch    cloudwatch.Config    `json:"cloudwatch"`
+		NewRelic      newrelic.Config      `json:"newrelic"`
+		Graphite      graphite.Config      `json:"graphite"`
+		OTLP          otlp.Config          `json:"otlp"`
 	} `json:"collectors"`
 }
 
@@ -90,11 +121,41 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.NoSummary.Valid {
 		c.NoSummary = cfg.NoSummary
 	}
+	if cfg.SummaryExport.Valid {
+		c.SummaryExport = cfg.SummaryExport
+	}
+	if cfg.CIAnnotations.Valid {
+		c.CIAnnotations = cfg.CIAnnotations
+	}
+	if cfg.MaxMemory.Valid {
+		c.MaxMemory = cfg.MaxMemory
+	}
+	if cfg.SummaryInterval.Valid {
+		c.SummaryInterval = cfg.SummaryInterval
+	}
+	if cfg.SummaryIntervalMode.Valid {
+		c.SummaryIntervalMode = cfg.SummaryIntervalMode
+	}
+	if cfg.SummaryIntervalExport.Valid {
+		c.SummaryIntervalExport = cfg.SummaryIntervalExport
+	}
+	if cfg.AggregationPeriod.Valid {
+		c.AggregationPeriod = cfg.AggregationPeriod
+	}
+	if len(cfg.AggregationStats) > 0 {
+		c.AggregationStats = cfg.AggregationStats
+	}
 	c.Collectors.InfluxDB = c.Collectors.InfluxDB.Apply(cfg.Collectors.InfluxDB)
 	c.Collectors.Cloud = c.Collectors.Cloud.Apply(cfg.Collectors.Cloud)
 	c.Collectors.Kafka = c.Collectors.Kafka.Apply(cfg.Collectors.Kafka)
+	c.Collectors.CSV = c.Collectors.CSV.Apply(cfg.Collectors.CSV)
 	c.Collectors.StatsD = c.Collectors.StatsD.Apply(cfg.Collectors.StatsD)
 	c.Collectors.Datadog = c.Collectors.Datadog.Apply(cfg.Collectors.Datadog)
+	c.Collectors.Elasticsearch = c.Collectors.Elasticsearch.Apply(cfg.Collectors.Elasticsearch)
+	c.Collectors.CloudWatch = c.Collectors.CloudWatch.Apply(cfg.Collectors.CloudWatch)
+	c.Collectors.NewRelic = c.Collectors.NewRelic.Apply(cfg.Collectors.NewRelic)
+	c.Collectors.Graphite = c.Collectors.Graphite.Apply(cfg.Collectors.Graphite)
+	c.Collectors.OTLP = c.Collectors.OTLP.Apply(cfg.Collectors.OTLP)
 	return c
 }
 
@@ -108,6 +169,10 @@ func getConfig(flags *pflag.FlagSet) (Config, error) {
 	if err != nil {
 		return Config{}, err
 	}
+	aggregationStats, err := flags.GetStringSlice("aggregation-stats")
+	if err != nil {
+		return Config{}, err
+	}
 	return Config{
 		Options:       opts,
 		Out:           out,
@@ -115,6 +180,16 @@ func getConfig(flags *pflag.FlagSet) (Config, error) {
 		NoUsageReport: getNullBool(flags, "no-usage-report"),
 		NoThresholds:  getNullBool(flags, "no-thresholds"),
 		NoSummary:     getNullBool(flags, "no-summary"),
+		SummaryExport: getNullString(flags, "summary-export"),
+		CIAnnotations: getNullString(flags, "ci-annotations"),
+		MaxMemory:     getNullString(flags, "max-memory"),
+
+		SummaryInterval:       getNullDuration(flags, "summary-interval"),
+		SummaryIntervalMode:   getNullString(flags, "summary-interval-mode"),
+		SummaryIntervalExport: getNullString(flags, "summary-interval-export"),
+
+		AggregationPeriod: getNullDuration(flags, "aggregation-period"),
+		AggregationStats:  aggregationStats,
 	}, nil
 }
 
@@ -173,6 +248,12 @@ func readEnvConfig() (conf Config, err error) {
 		envconfig.Process("k6", &conf.Collectors.Cloud),
 		envconfig.Process("k6", &conf.Collectors.InfluxDB),
 		envconfig.Process("k6", &conf.Collectors.Kafka),
+		envconfig.Process("k6", &conf.Collectors.CSV),
+		envconfig.Process("k6", &conf.Collectors.Elasticsearch),
+		envconfig.Process("k6", &conf.Collectors.CloudWatch),
+		envconfig.Process("k6", &conf.Collectors.NewRelic),
+		envconfig.Process("k6", &conf.Collectors.Graphite),
+		envconfig.Process("k6", &conf.Collectors.OTLP),
 	} {
 		return conf, err
 	}
@@ -250,9 +331,9 @@ func buildExecutionConfig(conf Config) (Config, error) {
 		result.Execution = scheduler.ConfigMap{lib.DefaultSchedulerName: ds}
 
 	default:
-		if conf.Execution != nil { // If someone set this, regardless if its empty
-			//TODO: remove this warning in the next version
-			log.Warnf("The execution settings are not functional in this k6 release, they will be ignored")
+		if _, ok := lib.ExecutionConfig(conf.Execution); !ok && lib.Scenarios(conf.Execution) == nil &&
+			conf.Execution != nil { // If someone set this, regardless if its empty
+			log.Warnf("These execution settings are not functional in this k6 release, they will be ignored")
 		}
 
 		if len(conf.Execution) == 0 { // If unset or set to empty
@@ -286,6 +367,12 @@ func getConsolidatedConfig(fs afero.Fs, cliConf Config, runner lib.Runner) (conf
 	cliConf.Collectors.InfluxDB = influxdb.NewConfig().Apply(cliConf.Collectors.InfluxDB)
 	cliConf.Collectors.Cloud = cloud.NewConfig().Apply(cliConf.Collectors.Cloud)
 	cliConf.Collectors.Kafka = kafka.NewConfig().Apply(cliConf.Collectors.Kafka)
+	cliConf.Collectors.CSV = csv.NewConfig().Apply(cliConf.Collectors.CSV)
+	cliConf.Collectors.Elasticsearch = elasticsearch.NewConfig().Apply(cliConf.Collectors.Elasticsearch)
+	cliConf.Collectors.CloudWatch = cloudwatch.NewConfig().Apply(cliConf.Collectors.CloudWatch)
+	cliConf.Collectors.NewRelic = newrelic.NewConfig().Apply(cliConf.Collectors.NewRelic)
+	cliConf.Collectors.Graphite = graphite.NewConfig().Apply(cliConf.Collectors.Graphite)
+	cliConf.Collectors.OTLP = otlp.NewConfig().Apply(cliConf.Collectors.OTLP)
 
 	fileConf, _, err := readDiskConfig(fs)
 	if err != nil {
@@ -366,6 +366,19 @@ func getConfigConsolidationTestCases() []configConsolidationTestCase {
 			exp{}, verifySharedIters(I(12), I(25)),
 		},
 
+		// Batch limits
+		{
+			opts{
+				fs:  defaultConfig(`{"batch": 30, "batchPerHost": 10}`),
+				cli: []string{"--batch-per-host", "6"},
+			},
+			exp{},
+			func(t *testing.T, c Config) {
+				assert.Equal(t, null.IntFrom(30), c.Options.Batch)
+				assert.Equal(t, null.IntFrom(6), c.Options.BatchPerHost)
+			},
+		},
+
 		// Just in case, verify that no options will result in the same 1 vu 1 iter config
 		{opts{}, exp{}, verifyOneIterPerOneVU},
 		//TODO: test for differences between flagsets
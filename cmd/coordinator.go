@@ -0,0 +1,416 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/loadimpact/k6/core"
+	"github.com/loadimpact/k6/k6exec"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	jsonc "github.com/loadimpact/k6/stats/json"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var coordinatorAgents []string
+
+var coordinatorCmd = &cobra.Command{
+	Use:   "coordinator",
+	Short: "Run tests distributed between k6 agents",
+	Long: `Run tests distributed between k6 agents.
+
+The coordinator plans the test and splits it into execution segments, one for
+every agent started with 'k6 agent'. The agents initialize their parts of the
+test and start together, run setup() and teardown() only once on the
+coordinator, and stream their metrics back to it, where the thresholds are
+evaluated and a single end-of-test summary is printed.`,
+}
+
+var coordinatorRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run a test distributed between k6 agents",
+	Long: `Run a test distributed between k6 agents.
+
+The test is archived and sent to all agents, with an equal execution segment
+for each of them, so that the VUs, iterations and data indices are split the
+same way as with --execution-segment. Once all agents have initialized their
+VUs, setup() is run on the coordinator and the agents are started at the same
+time with its data.
+
+The thresholds are evaluated on the combined metrics of all agents, including
+the ones that abort the test while it's running. The global --api-token and
+--api-tls-cert flags are used to connect to the agents.`,
+	Example: `
+  # Run a test with 300 VUs split between three agents.
+  k6 coordinator run --agent loadgen1:6565 --agent loadgen2:6565 --agent loadgen3:6565 -u 300 -d 10m script.js`[1:],
+	Args: exactArgsWithMsg(1, "arg should be a path to a script file or an archive"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		agents, err := getCoordinatorAgents(coordinatorAgents)
+		if err != nil {
+			return err
+		}
+		r, conf, err := loadFleetTest(cmd.Flags(), args[0])
+		if err != nil {
+			return err
+		}
+		if conf.ExecutionSegmentSequence != nil || conf.ExecutionSegment.String() != "0:1" {
+			return ExitCode{errors.New("the execution segments are assigned by the coordinator"), invalidConfigErrorCode}
+		}
+		archives, err := getCoordinatorArchives(r, conf.Options, agents)
+		if err != nil {
+			return err
+		}
+		client, err := newAPIHTTPClient()
+		if err != nil {
+			return err
+		}
+		c := &coordinator{client: client, agents: agents, token: apiToken}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sigC := make(chan os.Signal, 2)
+		signal.Notify(sigC, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(sigC)
+		go func() {
+			select {
+			case sig := <-sigC:
+				log.WithField("sig", sig).Info("Stopping all agents in response to signal...")
+				c.stop()
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case sig := <-sigC:
+				log.WithField("sig", sig).Error("Aborting all agents in response to signal")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		if err = firstError(c.prepare(ctx, archives)); err != nil {
+			c.stop()
+			return err
+		}
+		if c.isStopped() {
+			c.stop() // discard the tests that were prepared after it was stopped
+			return errors.New("the test was stopped before it started")
+		}
+		results := newFleetResults(conf.Thresholds)
+		if err = runCoordinatorStep(ctx, results, r.Setup); err != nil {
+			c.stop()
+			return errors.Wrap(err, "setup() failed")
+		}
+
+		checkCtx, stopChecking := context.WithCancel(ctx)
+		checkDone := make(chan struct{})
+		go func() {
+			defer close(checkDone)
+			if runCoordinatorThresholds(checkCtx, abortingThresholds(conf.Thresholds), results) {
+				log.Warn("Some thresholds have failed, stopping all agents...")
+				c.stop()
+			}
+		}()
+		errs := c.start(ctx, r.GetSetupData(), results)
+		stopChecking()
+		<-checkDone
+
+		if err = runCoordinatorStep(ctx, results, r.Teardown); err != nil {
+			log.WithError(err).Error("teardown() failed")
+		}
+		results.Calc()
+		return finishFleetRun(conf, results, "agent", agents, errs)
+	},
+}
+
+func coordinatorRunCmdFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.SortFlags = false
+	flags.AddFlagSet(optionFlagSet())
+	flags.AddFlagSet(runtimeOptionFlagSet(false))
+	flags.StringSliceVar(&coordinatorAgents, "agent", nil, "`address` of an agent to run a part of the test, can be repeated")
+	return flags
+}
+
+func init() {
+	RootCmd.AddCommand(coordinatorCmd)
+	coordinatorCmd.AddCommand(coordinatorRunCmd)
+	coordinatorRunCmd.Flags().SortFlags = false
+	coordinatorRunCmd.Flags().AddFlagSet(coordinatorRunCmdFlagSet())
+}
+
+// getCoordinatorAgents returns the base URLs of the agents with the supplied addresses, which
+// are reached over HTTPS if a TLS certificate is given.
+func getCoordinatorAgents(addrs []string) ([]string, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("at least one agent should be specified with --agent")
+	}
+	agents := make([]string, len(addrs))
+	for i, addr := range addrs {
+		if !strings.Contains(addr, "://") {
+			if apiTLSCert != "" {
+				addr = "https://" + addr
+			} else {
+				addr = "http://" + addr
+			}
+		}
+		agents[i] = strings.TrimSuffix(addr, "/")
+	}
+	return agents, nil
+}
+
+// getCoordinatorArchives returns the archives of the test for the agents, each of them with
+// its own equal execution segment.
+func getCoordinatorArchives(r lib.Runner, opts lib.Options, agents []string) ([][]byte, error) {
+	seq := lib.NewEqualExecutionSegmentSequence(len(agents))
+	arc := r.MakeArchive()
+	archives := make([][]byte, len(agents))
+	for i, es := range seq.Segments() {
+		arc.Options = opts
+		arc.Options.ExecutionSegment = es
+		arc.Options.ExecutionSegmentSequence = seq
+
+		planned, err := k6exec.ApplyExecutionSegment(arc.Options)
+		if err != nil {
+			return nil, err
+		}
+		if planned.VUsMax.Int64 == 0 {
+			return nil, errors.Errorf("the test has only %d max VUs, which can't be split between %d agents",
+				opts.VUsMax.Int64, len(agents))
+		}
+		log.WithFields(log.Fields{
+			"agent":   agents[i],
+			"segment": es,
+			"vus":     planned.VUs.Int64,
+			"vusMax":  planned.VUsMax.Int64,
+		}).Debug("Planned the part of the agent")
+
+		var buf bytes.Buffer
+		if err = arc.Write(&buf); err != nil {
+			return nil, err
+		}
+		archives[i] = buf.Bytes()
+	}
+	return archives, nil
+}
+
+// runCoordinatorStep runs setup() or teardown() on the coordinator, and adds its metrics to
+// the results.
+func runCoordinatorStep(
+	ctx context.Context, results *jsonc.Results, fn func(context.Context, chan<- stats.SampleContainer) error,
+) error {
+	var buf bytes.Buffer
+	collector := jsonc.NewFromWriter(&buf)
+	samples := make(chan stats.SampleContainer, 100)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for sc := range samples {
+			collector.Collect([]stats.SampleContainer{sc})
+		}
+	}()
+
+	err := fn(ctx, samples)
+	close(samples)
+	<-collected
+	if rerr := results.Read(&buf); rerr != nil && err == nil {
+		err = errors.Wrap(rerr, "invalid metrics")
+	}
+	return err
+}
+
+// abortingThresholds returns the thresholds that can abort the test.
+func abortingThresholds(thresholds map[string]stats.Thresholds) map[string]stats.Thresholds {
+	result := make(map[string]stats.Thresholds)
+	for name, ts := range thresholds {
+		for _, th := range ts.Thresholds {
+			if th.AbortOnFail {
+				result[name] = ts
+				break
+			}
+		}
+	}
+	return result
+}
+
+// runCoordinatorThresholds evaluates the thresholds on the results that were received so far,
+// until one of them aborts the test or the context is done. It returns whether the test
+// should be aborted.
+func runCoordinatorThresholds(ctx context.Context, thresholds map[string]stats.Thresholds, results *jsonc.Results) bool {
+	if len(thresholds) == 0 {
+		return false
+	}
+	ticker := time.NewTicker(core.ThresholdsRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			abort := false
+			results.Process(func(metrics map[string]*stats.Metric, t time.Duration) {
+				evaluateFleetThresholds(thresholds, metrics, t)
+				for name := range thresholds {
+					if m, ok := metrics[name]; ok && m.Thresholds.Abort {
+						abort = true
+					}
+				}
+			})
+			if abort {
+				return true
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// coordinator sends the parts of a test to the agents and controls their execution.
+type coordinator struct {
+	client *http.Client
+	agents []string // the base URLs of the agents
+	token  string
+
+	stopped int32
+}
+
+// request sends a request to an agent, and returns an error for unsuccessful responses.
+func (c *coordinator) request(ctx context.Context, agent, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, agent+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+		_ = res.Body.Close()
+		return nil, errors.Errorf("%s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+	return res, nil
+}
+
+// each calls fn for all agents in parallel, and returns the errors for every agent.
+func (c *coordinator) each(fn func(i int, agent string) error) []error {
+	errs := make([]error, len(c.agents))
+	wg := sync.WaitGroup{}
+	for i, agent := range c.agents {
+		wg.Add(1)
+		go func(i int, agent string) {
+			defer wg.Done()
+			errs[i] = fn(i, agent)
+		}(i, agent)
+	}
+	wg.Wait()
+	return errs
+}
+
+// prepare sends the archives to the respective agents, and waits until all of them have
+// initialized their VUs.
+func (c *coordinator) prepare(ctx context.Context, archives [][]byte) []error {
+	return c.each(func(i int, agent string) error {
+		log.WithField("agent", agent).Info("Preparing the test")
+		res, err := c.request(ctx, agent, "/v1/prepare", bytes.NewReader(archives[i]))
+		if err != nil {
+			return errors.Wrapf(err, "couldn't prepare the test on %s", agent)
+		}
+		return res.Body.Close()
+	})
+}
+
+// start starts the prepared test on all agents with the setup data, and reads their metrics
+// into the results until all of them are done.
+func (c *coordinator) start(ctx context.Context, setupData []byte, results *jsonc.Results) []error {
+	return c.each(func(i int, agent string) error {
+		logger := log.WithField("agent", agent)
+		logger.Info("Starting the test")
+		res, err := c.request(ctx, agent, "/v1/start", bytes.NewReader(setupData))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = res.Body.Close() }()
+
+		readErr := results.Read(res.Body)
+		if readErr != nil {
+			// Drain the rest of the metrics, so the trailer with the error can be read
+			_, _ = io.Copy(ioutil.Discard, res.Body)
+		}
+		if msg := res.Trailer.Get(agentErrorTrailer); msg != "" {
+			return errors.New(msg)
+		}
+		if readErr != nil {
+			return errors.Wrap(readErr, "invalid metrics output")
+		}
+		logger.Info("The test is finished")
+		return nil
+	})
+}
+
+// stop stops the running tests on all agents, or discards the prepared ones.
+func (c *coordinator) stop() {
+	atomic.StoreInt32(&c.stopped, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i, err := range c.each(func(i int, agent string) error {
+		res, err := c.request(ctx, agent, "/v1/stop", nil)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}) {
+		if err != nil {
+			log.WithField("agent", c.agents[i]).WithError(err).Warn("Couldn't stop the test")
+		}
+	}
+}
+
+// isStopped returns whether the test was stopped.
+func (c *coordinator) isStopped() bool {
+	return atomic.LoadInt32(&c.stopped) == 1
+}
+
+// firstError returns the first of the errors that isn't nil.
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/api"
+	"github.com/loadimpact/k6/k6exec"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	jsonc "github.com/loadimpact/k6/stats/json"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestGetCoordinatorAgents(t *testing.T) {
+	agents, err := getCoordinatorAgents([]string{"one:6565", "https://two:6565/"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://one:6565", "https://two:6565"}, agents)
+
+	_, err = getCoordinatorAgents(nil)
+	assert.EqualError(t, err, "at least one agent should be specified with --agent")
+}
+
+func TestAbortingThresholds(t *testing.T) {
+	aborting := stats.Thresholds{Thresholds: []*stats.Threshold{{AbortOnFail: true}}}
+	other := stats.Thresholds{Thresholds: []*stats.Threshold{{}}}
+	assert.Equal(t, map[string]stats.Thresholds{
+		"http_req_duration":             aborting,
+		"http_req_duration{status:200}": aborting,
+	}, abortingThresholds(map[string]stats.Thresholds{
+		"http_req_duration":             aborting,
+		"http_req_duration{status:200}": aborting,
+		"checks":                        other,
+	}))
+}
+
+func TestRunCoordinatorThresholds(t *testing.T) {
+	var buf bytes.Buffer
+	m := stats.New("http_req_duration", stats.Trend, stats.Time)
+	jsonc.NewFromWriter(&buf).Collect([]stats.SampleContainer{stats.Sample{Metric: m, Time: time.Now(), Value: 100}})
+	results := jsonc.NewResults()
+	require.NoError(t, results.Read(&buf))
+
+	var ts stats.Thresholds
+	require.NoError(t, json.Unmarshal([]byte(`[{"threshold":"p(95)<50","abortOnFail":true}]`), &ts))
+	thresholds := map[string]stats.Thresholds{m.Name: ts}
+	assert.True(t, runCoordinatorThresholds(context.Background(), thresholds, results))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, runCoordinatorThresholds(ctx, thresholds, results))
+	assert.False(t, runCoordinatorThresholds(context.Background(), nil, results))
+}
+
+func TestCoordinator(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	r, err := newRunner(&lib.SourceData{Filename: "/script.js", Data: []byte(`
+		import { check } from "k6";
+		export let options = { vus: 3, iterations: 10 };
+		export function setup() { return { v: 1 }; }
+		export default function(data) { check(data, { "has setup data": (d) => d.v === 1 }); }
+		export function teardown(data) { check(data, { "has setup data": (d) => d.v === 1 }); }
+	`)}, typeJS, fs, lib.RuntimeOptions{})
+	require.NoError(t, err)
+	opts := k6exec.ApplyExecutionDefaults(k6exec.DefaultOptions().Apply(r.GetOptions()))
+	opts.SystemTags = lib.GetTagSet(lib.DefaultSystemTagList...)
+	require.NoError(t, r.SetOptions(opts))
+
+	var agents []string
+	for i := 0; i < 2; i++ {
+		n := negroni.New()
+		n.UseFunc(api.WithToken("secret"))
+		n.UseHandler((&agent{}).handler())
+		srv := httptest.NewServer(n)
+		defer srv.Close()
+		agents = append(agents, srv.URL)
+	}
+	archives, err := getCoordinatorArchives(r, opts, agents)
+	require.NoError(t, err)
+	require.Len(t, archives, 2)
+
+	ctx := context.Background()
+	unauthorized := &coordinator{client: &http.Client{}, agents: agents}
+	assert.Error(t, firstError(unauthorized.prepare(ctx, archives)))
+
+	c := &coordinator{client: &http.Client{}, agents: agents, token: "secret"}
+	require.NoError(t, firstError(c.prepare(ctx, archives)))
+	results := jsonc.NewResults()
+	require.NoError(t, runCoordinatorStep(ctx, results, r.Setup))
+	errs := c.start(ctx, r.GetSetupData(), results)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	require.NoError(t, runCoordinatorStep(ctx, results, r.Teardown))
+	results.Calc()
+
+	require.Contains(t, results.Metrics, "iterations")
+	assert.Equal(t, 10.0, results.Metrics["iterations"].Sink.(*stats.CounterSink).Value)
+	require.Contains(t, results.RootGroup.Checks, "has setup data")
+	check := results.RootGroup.Checks["has setup data"]
+	assert.Equal(t, int64(10), check.Passes)
+	assert.Equal(t, int64(0), check.Fails)
+	require.Contains(t, results.RootGroup.Groups, "teardown")
+	assert.Equal(t, int64(1), results.RootGroup.Groups["teardown"].Checks["has setup data"].Passes)
+
+	t.Run("TooManyAgents", func(t *testing.T) {
+		_, err := getCoordinatorArchives(r, k6exec.ApplyExecutionDefaults(lib.Options{VUs: null.IntFrom(1)}), agents)
+		assert.EqualError(t, err, "the test has only 1 max VUs, which can't be split between 2 agents")
+	})
+}
@@ -0,0 +1,501 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/loadimpact/k6/k6exec"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	jsonc "github.com/loadimpact/k6/stats/json"
+	"github.com/loadimpact/k6/ui"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	null "gopkg.in/guregu/null.v3"
+)
+
+var (
+	fleetHostsFile string
+	fleetSSH       = "ssh"
+)
+
+// fleetHost describes a single machine that a part of the test is executed on.
+type fleetHost struct {
+	Host    string   `json:"host"`
+	User    string   `json:"user"`
+	Port    int      `json:"port"`
+	K6      string   `json:"k6"`      // the command that runs k6 on the machine
+	SSHArgs []string `json:"sshArgs"` // extra arguments for ssh, e.g. ["-i", "key.pem"]
+	Weight  float64  `json:"weight"`  // the share of the load, relative to the other hosts
+}
+
+// fleetConfig is the format of the hosts file.
+type fleetConfig struct {
+	Defaults fleetHost   `json:"defaults"`
+	Hosts    []fleetHost `json:"hosts"`
+}
+
+var fleetCmd = &cobra.Command{
+	Use:   "fleet",
+	Short: "Run tests on multiple machines over SSH",
+	Long: `Run tests on multiple machines over SSH.
+
+A simple distributed mode, which only needs SSH access to machines with k6
+installed. The test is archived locally and sent to all machines, the load is
+split between them, and their metrics are streamed back and merged into a
+single end-of-test summary.`,
+}
+
+var fleetRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run a test on multiple machines over SSH",
+	Long: `Run a test on multiple machines over SSH.
+
+The hosts file is a YAML file with a list of machines and the defaults for them:
+
+  defaults:
+    user: ubuntu
+    k6: /usr/local/bin/k6
+    sshArgs: ["-i", "~/.ssh/loadgen.pem"]
+  hosts:
+    - host: loadgen1.example.com
+    - host: loadgen2.example.com
+      port: 2222
+      weight: 2
+
+The system ssh client is used, so its configuration, keys and known hosts apply.
+The VUs, iterations, RPS limit and stage targets are split between the hosts in
+proportion to their weights, while the thresholds are evaluated locally on the
+merged metrics.`,
+	Example: `
+  # Run a test with 300 VUs split between the machines in hosts.yaml.
+  k6 fleet run --hosts hosts.yaml -u 300 -d 10m script.js`[1:],
+	Args: exactArgsWithMsg(1, "arg should be a path to a script file or an archive"),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hosts, err := readFleetConfig(afero.NewOsFs(), fleetHostsFile)
+		if err != nil {
+			return err
+		}
+		r, conf, err := loadFleetTest(cmd.Flags(), args[0])
+		if err != nil {
+			return err
+		}
+
+		weights := make([]float64, len(hosts))
+		for i, h := range hosts {
+			weights[i] = h.Weight
+		}
+		hostOpts, err := getFleetOptions(conf.Options, weights)
+		if err != nil {
+			return err
+		}
+		arc := r.MakeArchive()
+		archives := make([][]byte, len(hosts))
+		for i := range hosts {
+			arc.Options = hostOpts[i]
+			var buf bytes.Buffer
+			if err = arc.Write(&buf); err != nil {
+				return err
+			}
+			archives[i] = buf.Bytes()
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sigC := make(chan os.Signal, 1)
+		signal.Notify(sigC, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(sigC)
+		go func() {
+			select {
+			case sig := <-sigC:
+				log.WithField("sig", sig).Debug("Stopping all hosts in response to signal")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		results := newFleetResults(conf.Thresholds)
+		errs := runFleetHosts(ctx, hosts, archives, results)
+		results.Calc()
+
+		names := make([]string, len(hosts))
+		for i, h := range hosts {
+			names[i] = h.Host
+		}
+		return finishFleetRun(conf, results, "host", names, errs)
+	},
+}
+
+func fleetRunCmdFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.SortFlags = false
+	flags.AddFlagSet(optionFlagSet())
+	flags.AddFlagSet(runtimeOptionFlagSet(false))
+	flags.StringVar(&fleetHostsFile, "hosts", "", "YAML `file` with the machines the test should run on")
+	flags.StringVar(&fleetSSH, "ssh", fleetSSH, "the `command` used to connect to the machines")
+	return flags
+}
+
+func init() {
+	RootCmd.AddCommand(fleetCmd)
+	fleetCmd.AddCommand(fleetRunCmd)
+	fleetRunCmd.Flags().SortFlags = false
+	fleetRunCmd.Flags().AddFlagSet(fleetRunCmdFlagSet())
+}
+
+// loadFleetTest loads the script or archive and consolidates and validates its configuration
+// the same way `k6 run` does, for a test that is run on several machines.
+func loadFleetTest(flags *pflag.FlagSet, arg string) (lib.Runner, Config, error) {
+	fs := afero.NewOsFs()
+	pwd, err := os.Getwd()
+	if err != nil {
+		return nil, Config{}, err
+	}
+	src, err := readSource(arg, pwd, fs, os.Stdin)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	runtimeOptions, err := getRuntimeOptions(flags)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	r, err := newRunner(src, runType, fs, runtimeOptions)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	cliOpts, err := getOptions(flags)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	conf, err := getConsolidatedConfig(fs, Config{Options: cliOpts}, r)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	conf.Options = k6exec.ApplyExecutionDefaults(conf.Options)
+	if cerr := validateConfig(conf); cerr != nil {
+		return nil, Config{}, ExitCode{cerr, invalidConfigErrorCode}
+	}
+	if err = r.SetOptions(conf.Options); err != nil {
+		return nil, Config{}, err
+	}
+	if len(conf.SummaryTrendStats) > 0 {
+		ui.UpdateTrendColumns(conf.SummaryTrendStats)
+	}
+	return r, conf, nil
+}
+
+// finishFleetRun evaluates the thresholds on the merged results, prints the end-of-test summary
+// and returns the error that the command should exit with. The errors are the ones of the
+// machines with the supplied names, which are logged under the kind of the machines.
+func finishFleetRun(conf Config, results *jsonc.Results, kind string, names []string, errs []error) error {
+	thresholdsFailed := evaluateFleetThresholds(conf.Thresholds, results.Metrics, results.Duration())
+	if !conf.NoSummary.Bool {
+		fprintf(stdout, "\n")
+		ui.Summarize(stdout, "", ui.SummaryData{
+			Opts:    conf.Options,
+			Root:    results.RootGroup,
+			Metrics: results.Metrics,
+			Time:    results.Duration(),
+		})
+		fprintf(stdout, "\n")
+	}
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			log.WithField(kind, names[i]).WithError(err).Error("The test failed")
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("the test failed on %d of %d %ss", failed, len(names), kind)
+	}
+	if thresholdsFailed {
+		return ExitCode{errors.New("some thresholds have failed"), thresholdHaveFailedErroCode}
+	}
+	return nil
+}
+
+// readFleetConfig reads the hosts file and fills in the defaults for every host.
+func readFleetConfig(fs afero.Fs, filename string) ([]fleetHost, error) {
+	if filename == "" {
+		return nil, errors.New("a hosts file should be specified with --hosts")
+	}
+	data, err := afero.ReadFile(fs, filename)
+	if err != nil {
+		return nil, err
+	}
+	var conf fleetConfig
+	if err = yaml.Unmarshal(data, &conf); err != nil {
+		return nil, errors.Wrap(err, filename)
+	}
+	if len(conf.Hosts) == 0 {
+		return nil, errors.Errorf("no hosts are specified in %s", filename)
+	}
+
+	def := conf.Defaults
+	if def.K6 == "" {
+		def.K6 = "k6"
+	}
+	if def.Weight == 0 {
+		def.Weight = 1
+	}
+	for i, h := range conf.Hosts {
+		if h.Host == "" {
+			return nil, errors.Errorf("host %d in %s doesn't have an address", i, filename)
+		}
+		if h.User == "" {
+			h.User = def.User
+		}
+		if h.Port == 0 {
+			h.Port = def.Port
+		}
+		if h.K6 == "" {
+			h.K6 = def.K6
+		}
+		if h.SSHArgs == nil {
+			h.SSHArgs = def.SSHArgs
+		}
+		if h.Weight == 0 {
+			h.Weight = def.Weight
+		}
+		if h.Weight < 0 {
+			return nil, errors.Errorf("the weight of %s can't be negative", h.Host)
+		}
+		conf.Hosts[i] = h
+	}
+	return conf.Hosts, nil
+}
+
+// splitInt splits the total between the weights with the largest remainder method, so
+// that the parts are proportional to the weights and add up exactly to the total.
+func splitInt(total int64, weights []float64) []int64 {
+	sum := 0.0
+	for _, w := range weights {
+		sum += w
+	}
+
+	parts := make([]int64, len(weights))
+	remainders := make([]float64, len(weights))
+	left := total
+	for i, w := range weights {
+		exact := float64(total) * w / sum
+		parts[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(parts[i])
+		left -= parts[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for i := int64(0); i < left; i++ {
+		parts[order[i]]++
+	}
+	return parts
+}
+
+// getFleetOptions returns the options for every host, with the VUs, the iterations, the
+// RPS limit and the stage targets split between the hosts according to their weights.
+func getFleetOptions(opts lib.Options, weights []float64) ([]lib.Options, error) {
+	result := make([]lib.Options, len(weights))
+	for i := range result {
+		result[i] = opts
+	}
+
+	split := func(v null.Int, set func(o *lib.Options, v null.Int)) {
+		if !v.Valid {
+			return
+		}
+		for i, part := range splitInt(v.Int64, weights) {
+			set(&result[i], null.IntFrom(part))
+		}
+	}
+	split(opts.VUs, func(o *lib.Options, v null.Int) { o.VUs = v })
+	split(opts.VUsMax, func(o *lib.Options, v null.Int) { o.VUsMax = v })
+	split(opts.Iterations, func(o *lib.Options, v null.Int) { o.Iterations = v })
+	split(opts.RPS, func(o *lib.Options, v null.Int) {
+		if v.Int64 == 0 && opts.RPS.Int64 > 0 {
+			v.Int64 = 1 // 0 would mean no limit at all
+		}
+		o.RPS = v
+	})
+	for i := range result {
+		result[i].Stages = make([]lib.Stage, len(opts.Stages))
+		copy(result[i].Stages, opts.Stages)
+	}
+	for s, stage := range opts.Stages {
+		s := s
+		split(stage.Target, func(o *lib.Options, v null.Int) { o.Stages[s].Target = v })
+	}
+	if opts.Stages == nil {
+		for i := range result {
+			result[i].Stages = nil
+		}
+	}
+
+	for i := range result {
+		if result[i].VUsMax.Int64 == 0 {
+			return nil, errors.Errorf("the test has only %d max VUs, which can't be split between %d hosts",
+				opts.VUsMax.Int64, len(weights))
+		}
+	}
+	return result, nil
+}
+
+// fleetRemoteCommand returns the shell command that runs k6 on a host. The archive is
+// read from stdin and the JSON output is written to stdout, which is streamed back
+// over SSH, while the k6 logs are sent back through stderr.
+func fleetRemoteCommand(h fleetHost) string {
+	return h.K6 + " run --quiet --no-summary --no-thresholds --no-usage-report --out json=/dev/fd/3 - 3>&1 1>/dev/null"
+}
+
+// fleetSSHArgs returns the arguments for the ssh command that runs k6 on a host.
+func fleetSSHArgs(h fleetHost) []string {
+	args := append([]string{}, h.SSHArgs...)
+	args = append(args, "-o", "BatchMode=yes")
+	if h.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(h.Port))
+	}
+	target := h.Host
+	if h.User != "" {
+		target = h.User + "@" + target
+	}
+	return append(args, target, fleetRemoteCommand(h))
+}
+
+// runFleetHosts runs the archives on the respective hosts in parallel and reads their
+// metrics into the results, until all hosts are done or the context is cancelled. It
+// returns the errors for every host.
+func runFleetHosts(ctx context.Context, hosts []fleetHost, archives [][]byte, results *jsonc.Results) []error {
+	errs := make([]error, len(hosts))
+	wg := sync.WaitGroup{}
+	for i, h := range hosts {
+		wg.Add(1)
+		go func(i int, h fleetHost) {
+			defer wg.Done()
+			errs[i] = runFleetHost(ctx, h, archives[i], results)
+		}(i, h)
+	}
+	wg.Wait()
+	return errs
+}
+
+func runFleetHost(ctx context.Context, h fleetHost, archive []byte, results *jsonc.Results) error {
+	logger := log.WithField("host", h.Host)
+
+	cmd := exec.CommandContext(ctx, fleetSSH, fleetSSHArgs(h)...)
+	cmd.Stdin = bytes.NewReader(archive)
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Starting the test")
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	logsDone := make(chan struct{})
+	go func() {
+		defer close(logsDone)
+		scanner := bufio.NewScanner(stderrPipe)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				logger.Info(line)
+			}
+		}
+	}()
+
+	readErr := results.Read(stdoutPipe)
+	if readErr != nil {
+		// Drain the rest of the output, so the remote k6 isn't blocked
+		_, _ = io.Copy(ioutil.Discard, stdoutPipe)
+	}
+	<-logsDone
+	if err = cmd.Wait(); err != nil {
+		return err
+	}
+	if readErr != nil {
+		return errors.Wrap(readErr, "invalid metrics output")
+	}
+	logger.Info("The test is finished")
+	return nil
+}
+
+// newFleetResults returns the results that the metrics of all machines are merged into, with
+// the submetrics of the thresholds, so that their points are picked out while they're read.
+func newFleetResults(thresholds map[string]stats.Thresholds) *jsonc.Results {
+	results := jsonc.NewResults()
+	for name := range thresholds {
+		if strings.Contains(name, "{") {
+			results.AddSubmetric(name)
+		}
+	}
+	return results
+}
+
+// evaluateFleetThresholds runs the thresholds on the merged metrics and returns whether
+// any of them have failed.
+func evaluateFleetThresholds(
+	thresholds map[string]stats.Thresholds, metrics map[string]*stats.Metric, t time.Duration,
+) bool {
+	failed := false
+	for name, ts := range thresholds {
+		m, ok := metrics[name]
+		if !ok {
+			continue
+		}
+		m.Thresholds = ts
+		succ, err := m.Thresholds.Run(m.Sink, t)
+		if err != nil {
+			log.WithField("m", name).WithError(err).Error("Threshold error")
+			continue
+		}
+		m.Tainted = null.BoolFrom(!succ)
+		failed = failed || !succ
+	}
+	return failed
+}
@@ -0,0 +1,210 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	jsonc "github.com/loadimpact/k6/stats/json"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestReadFleetConfig(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/hosts.yaml", []byte(`
+defaults:
+  user: ubuntu
+  sshArgs: ["-i", "key.pem"]
+hosts:
+  - host: one.example.com
+  - host: two.example.com
+    user: root
+    port: 2222
+    k6: /opt/k6
+    weight: 2
+`), 0644))
+
+	hosts, err := readFleetConfig(fs, "/hosts.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []fleetHost{
+		{Host: "one.example.com", User: "ubuntu", K6: "k6", SSHArgs: []string{"-i", "key.pem"}, Weight: 1},
+		{Host: "two.example.com", User: "root", Port: 2222, K6: "/opt/k6", SSHArgs: []string{"-i", "key.pem"}, Weight: 2},
+	}, hosts)
+
+	assert.Equal(t, []string{
+		"-i", "key.pem", "-o", "BatchMode=yes", "-p", "2222", "root@two.example.com",
+		"/opt/k6 run --quiet --no-summary --no-thresholds --no-usage-report --out json=/dev/fd/3 - 3>&1 1>/dev/null",
+	}, fleetSSHArgs(hosts[1]))
+
+	invalid := map[string]string{
+		"hosts: []":                      "no hosts are specified in /invalid.yaml",
+		"hosts: [{user: root}]":          "host 0 in /invalid.yaml doesn't have an address",
+		"hosts: [{host: a, weight: -1}]": "the weight of a can't be negative",
+	}
+	for data, errMsg := range invalid {
+		require.NoError(t, afero.WriteFile(fs, "/invalid.yaml", []byte(data), 0644))
+		_, err := readFleetConfig(fs, "/invalid.yaml")
+		assert.EqualError(t, err, errMsg, data)
+	}
+	_, err = readFleetConfig(fs, "")
+	assert.EqualError(t, err, "a hosts file should be specified with --hosts")
+}
+
+func TestSplitInt(t *testing.T) {
+	assert.Equal(t, []int64{4, 3, 3}, splitInt(10, []float64{1, 1, 1}))
+	assert.Equal(t, []int64{3, 7}, splitInt(10, []float64{1, 2}))
+	assert.Equal(t, []int64{0, 1, 0}, splitInt(1, []float64{1, 2, 1}))
+	assert.Equal(t, []int64{0, 0}, splitInt(0, []float64{1, 1}))
+}
+
+func TestGetFleetOptions(t *testing.T) {
+	opts := lib.Options{
+		VUs:      null.IntFrom(10),
+		VUsMax:   null.IntFrom(20),
+		Duration: types.NullDurationFrom(time.Minute),
+		RPS:      null.IntFrom(1),
+		Stages: []lib.Stage{
+			{Duration: types.NullDurationFrom(time.Minute), Target: null.IntFrom(20)},
+			{Duration: types.NullDurationFrom(time.Minute)},
+		},
+	}
+	result, err := getFleetOptions(opts, []float64{1, 3})
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+
+	assert.Equal(t, null.IntFrom(3), result[0].VUs)
+	assert.Equal(t, null.IntFrom(7), result[1].VUs)
+	assert.Equal(t, null.IntFrom(5), result[0].VUsMax)
+	assert.Equal(t, null.IntFrom(15), result[1].VUsMax)
+	assert.Equal(t, null.IntFrom(1), result[0].RPS)
+	assert.Equal(t, null.IntFrom(1), result[1].RPS)
+	assert.False(t, result[0].Iterations.Valid)
+	assert.Equal(t, opts.Duration, result[1].Duration)
+	assert.Equal(t, null.IntFrom(5), result[0].Stages[0].Target)
+	assert.Equal(t, null.IntFrom(15), result[1].Stages[0].Target)
+	assert.False(t, result[1].Stages[1].Target.Valid)
+	assert.Equal(t, null.IntFrom(20), opts.Stages[0].Target, "the original options shouldn't be modified")
+
+	_, err = getFleetOptions(lib.Options{VUsMax: null.IntFrom(1)}, []float64{1, 1})
+	assert.EqualError(t, err, "the test has only 1 max VUs, which can't be split between 2 hosts")
+}
+
+func TestRunFleetHosts(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	dir, err := ioutil.TempDir("", "k6-fleet")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// A fake ssh command that checks that the archive was received and outputs some metrics
+	fakeSSH := filepath.Join(dir, "ssh")
+	require.NoError(t, ioutil.WriteFile(fakeSSH, []byte(`#!/bin/sh
+for last; do :; done
+[ "$(cat)" = "archive" ] || { echo "unexpected archive" >&2; exit 1; }
+case "$last" in fail*) echo "something went wrong" >&2; exit 1;; esac
+echo '{"type":"Metric","data":{"name":"iterations","type":"counter","contains":"default"},"metric":"iterations"}'
+echo '{"type":"Point","data":{"time":"2019-01-01T00:00:00Z","value":2,"tags":null},"metric":"iterations"}'
+`), 0755))
+	defer func(old string) { fleetSSH = old }(fleetSSH)
+	fleetSSH = fakeSSH
+
+	hosts := []fleetHost{{Host: "one", K6: "k6"}, {Host: "two", K6: "k6"}, {Host: "three", K6: "fail"}}
+	archives := [][]byte{[]byte("archive"), []byte("archive"), []byte("archive")}
+	results := jsonc.NewResults()
+	errs := runFleetHosts(context.Background(), hosts, archives, results)
+	results.Calc()
+
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.Error(t, errs[2])
+	require.Contains(t, results.Metrics, "iterations")
+	assert.Equal(t, 4.0, results.Metrics["iterations"].Sink.(*stats.CounterSink).Value)
+}
+
+func TestEvaluateFleetThresholds(t *testing.T) {
+	m := stats.New("http_req_duration", stats.Trend, stats.Time)
+	m.Sink.Add(stats.Sample{Value: 100})
+	metrics := map[string]*stats.Metric{m.Name: m}
+
+	passing, err := stats.NewThresholds([]string{"p(95)<200"})
+	require.NoError(t, err)
+	assert.False(t, evaluateFleetThresholds(map[string]stats.Thresholds{m.Name: passing}, metrics, time.Second))
+	assert.Equal(t, null.BoolFrom(false), m.Tainted)
+
+	failing, err := stats.NewThresholds([]string{"p(95)<50"})
+	require.NoError(t, err)
+	sub, err := stats.NewThresholds([]string{"p(95)<1"})
+	require.NoError(t, err)
+	assert.True(t, evaluateFleetThresholds(map[string]stats.Thresholds{
+		m.Name:                          failing,
+		"http_req_duration{status:200}": sub,
+		"nonexistent":                   failing,
+	}, metrics, time.Second))
+	assert.Equal(t, null.BoolFrom(true), m.Tainted)
+}
+
+func TestFleetSubmetricThresholds(t *testing.T) {
+	ok, err := stats.NewThresholds([]string{"p(95)<50"})
+	require.NoError(t, err)
+	failing, err := stats.NewThresholds([]string{"p(95)<50"})
+	require.NoError(t, err)
+	thresholds := map[string]stats.Thresholds{
+		"http_req_duration{status:200}": ok,
+		"http_req_duration{status:500}": failing,
+	}
+	results := newFleetResults(thresholds)
+
+	// The outputs of the machines are read separately, the submetrics get the matching
+	// samples of all of them.
+	m := stats.New("http_req_duration", stats.Trend, stats.Time)
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		jsonc.NewFromWriter(&buf).Collect([]stats.SampleContainer{stats.Samples{
+			{Metric: m, Time: time.Now(), Value: 10, Tags: stats.IntoSampleTags(&map[string]string{"status": "200"})},
+			{Metric: m, Time: time.Now(), Value: 100, Tags: stats.IntoSampleTags(&map[string]string{"status": "500"})},
+		}})
+		require.NoError(t, results.Read(&buf))
+	}
+	results.Calc()
+
+	assert.True(t, evaluateFleetThresholds(thresholds, results.Metrics, results.Duration()))
+	for name, tainted := range map[string]bool{
+		"http_req_duration{status:200}": false,
+		"http_req_duration{status:500}": true,
+	} {
+		require.Contains(t, results.Metrics, name)
+		assert.Equal(t, uint64(2), results.Metrics[name].Sink.(*stats.TrendSink).Count)
+		assert.Equal(t, null.BoolFrom(tainted), results.Metrics[name].Tainted)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/ui"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Possible values for the summary interval mode.
+const (
+	summaryModeCumulative = "cumulative"
+	summaryModeWindowed   = "windowed"
+)
+
+// interimSummary is a collector that periodically prints a summary of the metrics
+// collected so far (or since the previous summary, in windowed mode), so that long
+// running tests produce checkpoints even if the k6 process dies before the end.
+type interimSummary struct {
+	interval time.Duration
+	windowed bool
+	timeUnit string
+	out      io.Writer
+	export   io.WriteCloser // optional, every summary is also appended to it as a JSON line
+
+	lock        sync.Mutex
+	metrics     map[string]*stats.Metric
+	start       time.Time
+	windowStart time.Time
+}
+
+// interimSummaryExport is what's written in the export file for every summary.
+type interimSummaryExport struct {
+	Time    time.Time                     `json:"time"`
+	Elapsed string                        `json:"elapsed"`
+	Window  string                        `json:"window,omitempty"`
+	Metrics map[string]map[string]float64 `json:"metrics"`
+}
+
+var _ lib.Collector = &interimSummary{}
+
+func newInterimSummary(interval time.Duration, mode, timeUnit string, out io.Writer) (*interimSummary, error) {
+	if interval <= 0 {
+		return nil, errors.New("the summary interval should be positive")
+	}
+	if mode != "" && mode != summaryModeCumulative && mode != summaryModeWindowed {
+		return nil, errors.Errorf("unknown summary interval mode '%s', use '%s' or '%s'",
+			mode, summaryModeCumulative, summaryModeWindowed)
+	}
+	return &interimSummary{
+		interval: interval,
+		windowed: mode == summaryModeWindowed,
+		timeUnit: timeUnit,
+		out:      out,
+		metrics:  make(map[string]*stats.Metric),
+	}, nil
+}
+
+// Init does nothing, it's only included to satisfy the lib.Collector interface
+func (s *interimSummary) Init() error { return nil }
+
+// Run prints a summary on every interval until the context is done.
+func (s *interimSummary) Run(ctx context.Context) {
+	s.lock.Lock()
+	s.start = time.Now()
+	s.windowStart = s.start
+	s.lock.Unlock()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case t := <-ticker.C:
+			if err := s.summarize(t); err != nil {
+				log.WithError(err).Error("Couldn't write the interim summary")
+			}
+		case <-ctx.Done():
+			if s.export != nil {
+				if err := s.export.Close(); err != nil {
+					log.WithError(err).Error("Couldn't close the interim summary export file")
+				}
+			}
+			return
+		}
+	}
+}
+
+// Collect adds the samples to the metrics of the current summary.
+func (s *interimSummary) Collect(scs []stats.SampleContainer) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, sc := range scs {
+		for _, sample := range sc.GetSamples() {
+			m, ok := s.metrics[sample.Metric.Name]
+			if !ok {
+				m = stats.New(sample.Metric.Name, sample.Metric.Type, sample.Metric.Contains)
+				s.metrics[m.Name] = m
+			}
+			m.Sink.Add(sample)
+		}
+	}
+}
+
+func (s *interimSummary) summarize(t time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	elapsed := t.Sub(s.start).Round(time.Second)
+	window := t.Sub(s.windowStart).Round(time.Second)
+
+	var buf bytes.Buffer
+	if s.windowed {
+		fprintf(&buf, "\ninterim summary at %s (last %s):\n\n", ui.ValueColor.Sprint(elapsed), window)
+		ui.SummarizeMetrics(&buf, "  ", window, s.timeUnit, s.metrics)
+	} else {
+		fprintf(&buf, "\ninterim summary at %s:\n\n", ui.ValueColor.Sprint(elapsed))
+		ui.SummarizeMetrics(&buf, "  ", elapsed, s.timeUnit, s.metrics)
+	}
+	fprintf(&buf, "\n")
+	if _, err := s.out.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	if s.export != nil {
+		data := interimSummaryExport{
+			Time:    t,
+			Elapsed: elapsed.String(),
+			Metrics: make(map[string]map[string]float64, len(s.metrics)),
+		}
+		sinkTime := elapsed
+		if s.windowed {
+			data.Window = window.String()
+			sinkTime = window
+		}
+		for name, m := range s.metrics {
+			data.Metrics[name] = m.Sink.Format(sinkTime)
+		}
+		row, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if _, err := s.export.Write(append(row, '\n')); err != nil {
+			return err
+		}
+	}
+
+	if s.windowed {
+		s.metrics = make(map[string]*stats.Metric)
+		s.windowStart = t
+	}
+	return nil
+}
+
+// Link returns an empty string, it's only included to satisfy the lib.Collector interface
+func (s *interimSummary) Link() string { return "" }
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (s *interimSummary) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
+
+// SetRunStatus does nothing, it's only included to satisfy the lib.Collector interface
+func (s *interimSummary) SetRunStatus(status lib.RunStatus) {}
@@ -0,0 +1,84 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopWriteCloser struct {
+	bytes.Buffer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestInterimSummary(t *testing.T) {
+	_, err := newInterimSummary(time.Minute, "sliding", "", &bytes.Buffer{})
+	assert.EqualError(t, err, "unknown summary interval mode 'sliding', use 'cumulative' or 'windowed'")
+	_, err = newInterimSummary(0, "", "", &bytes.Buffer{})
+	assert.Error(t, err)
+
+	counter := stats.New("my_counter", stats.Counter)
+	collect := func(s *interimSummary, value float64) {
+		s.Collect([]stats.SampleContainer{stats.Sample{Metric: counter, Time: time.Now(), Value: value}})
+	}
+
+	for _, mode := range []string{summaryModeCumulative, summaryModeWindowed} {
+		mode := mode
+		t.Run(mode, func(t *testing.T) {
+			var out bytes.Buffer
+			export := &nopWriteCloser{}
+			s, err := newInterimSummary(time.Minute, mode, "", &out)
+			require.NoError(t, err)
+			s.export = export
+			s.start = time.Now()
+			s.windowStart = s.start
+
+			collect(s, 10)
+			require.NoError(t, s.summarize(s.start.Add(time.Minute)))
+			collect(s, 5)
+			require.NoError(t, s.summarize(s.start.Add(2*time.Minute)))
+
+			assert.Equal(t, 2, strings.Count(out.String(), "interim summary at"))
+			lines := strings.Split(strings.TrimSpace(export.String()), "\n")
+			require.Len(t, lines, 2)
+
+			var last interimSummaryExport
+			require.NoError(t, json.Unmarshal([]byte(lines[1]), &last))
+			assert.Equal(t, "2m0s", last.Elapsed)
+			if mode == summaryModeWindowed {
+				assert.Equal(t, "1m0s", last.Window)
+				assert.Equal(t, 5.0, last.Metrics["my_counter"]["count"])
+			} else {
+				assert.Equal(t, "", last.Window)
+				assert.Equal(t, 15.0, last.Metrics["my_counter"]["count"])
+			}
+		})
+	}
+}
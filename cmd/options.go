@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/netext"
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 	"github.com/loadimpact/k6/ui"
@@ -50,21 +51,32 @@ func optionFlagSet() *pflag.FlagSet {
 	flags.Int64P("iterations", "i", 0, "script total iteration limit (among all VUs)")
 	flags.StringSliceP("stage", "s", nil, "add a `stage`, as `[duration]:[target]`")
 	flags.BoolP("paused", "p", false, "start the test in a paused state")
+	flags.String("execution-segment", "", "only run the `segment` of the test, e.g. '1/3:2/3' for the second of three instances")
+	flags.String("execution-segment-sequence", "", "the `points` that all instances split the test at, e.g. '0,1/3,2/3,1'")
 	flags.Int64("max-redirects", 10, "follow at most n redirects")
 	flags.Int64("batch", 20, "max parallel batch reqs")
 	flags.Int64("batch-per-host", 20, "max parallel batch reqs per host")
 	flags.Int64("rps", 0, "limit requests per second")
 	flags.String("user-agent", fmt.Sprintf("k6/%s (https://k6.io/)", Version), "user agent for http requests")
+	flags.String("tracing", "", "propagate a trace context with http requests, with the `propagator`'s headers: w3c, b3 or b3-multi")
 	flags.String("http-debug", "", "log all HTTP requests and responses. Excludes body by default. To include body use '--http-debug=full'")
 	flags.Lookup("http-debug").NoOptDefVal = "headers"
 	flags.Bool("insecure-skip-tls-verify", false, "skip verification of TLS certificates")
 	flags.Bool("no-connection-reuse", false, "disable keep-alive connections")
 	flags.Bool("no-vu-connection-reuse", false, "don't reuse connections between iterations")
+	flags.Duration("http-request-timeout", 0, "default `timeout` of HTTP requests, 60s if not set")
 	flags.Duration("min-iteration-duration", 0, "minimum amount of time k6 will take executing a single iteration")
+	flags.Duration("graceful-stop", 0, "how long running iterations get to finish when the test ends, before VUs are interrupted")
 	flags.BoolP("throw", "w", false, "throw warnings (like failed http requests) as errors")
 	flags.StringSlice("blacklist-ip", nil, "blacklist an `ip range` from being called")
+	flags.StringSlice("block-hostnames", nil, "block a `hostname` or a wildcard like '*.example.com' from being called")
+	flags.StringSlice("host", nil, "override the DNS resolution of a `host`, as `[name]=[ip]`")
+	flags.String("proxy", "", "send all requests through the proxy at this `url`, ignoring HTTP(S)_PROXY")
+	flags.String("dns", "", "set the DNS `settings`, the servers and the IP selection, e.g. 'server=1.1.1.1,select=roundRobin,policy=preferIPv4'")
+	flags.String("network-faults", "", "inject network `faults`, e.g. 'rate=0.1,latency=200ms,bandwidth=65536,dropRate=0.05'")
 	flags.StringSlice("summary-trend-stats", nil, "define `stats` for trend metrics (response times), one or more as 'avg,p(95),...'")
 	flags.String("summary-time-unit", "", "define the time unit used to display the trend stats. Possible units are: 's', 'ms' and 'us'")
+	flags.Float64("trend-accuracy", 0.01, "relative `accuracy` of the percentiles of trend metrics with too many values to keep in memory")
 	flags.StringSlice("system-tags", lib.DefaultSystemTagList, "only include these system tags in metrics")
 	flags.StringSlice("tag", nil, "add a `tag` to be applied to all samples, as `[name]=[value]`")
 	flags.String("console-output", "", "redirects the console logging to the provided output file")
@@ -81,15 +93,20 @@ func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 		Paused:                getNullBool(flags, "paused"),
 		MaxRedirects:          getNullInt64(flags, "max-redirects"),
 		Batch:                 getNullInt64(flags, "batch"),
+		BatchPerHost:          getNullInt64(flags, "batch-per-host"),
 		RPS:                   getNullInt64(flags, "rps"),
 		UserAgent:             getNullString(flags, "user-agent"),
+		Tracing:               getNullString(flags, "tracing"),
 		HttpDebug:             getNullString(flags, "http-debug"),
 		InsecureSkipTLSVerify: getNullBool(flags, "insecure-skip-tls-verify"),
 		NoConnectionReuse:     getNullBool(flags, "no-connection-reuse"),
 		NoVUConnectionReuse:   getNullBool(flags, "no-vu-connection-reuse"),
+		HTTPRequestTimeout:    getNullDuration(flags, "http-request-timeout"),
 		MinIterationDuration:  getNullDuration(flags, "min-iteration-duration"),
+		GracefulStop:          getNullDuration(flags, "graceful-stop"),
 		Throw:                 getNullBool(flags, "throw"),
 		DiscardResponseBodies: getNullBool(flags, "discard-response-bodies"),
+		TrendAccuracy:         getNullFloat64(flags, "trend-accuracy"),
 		// Default values for options without CLI flags:
 		// TODO: find a saner and more dev-friendly and error-proof way to handle options
 		SetupTimeout:    types.NullDuration{Duration: types.Duration(10 * time.Second), Valid: false},
@@ -129,6 +146,85 @@ func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 		opts.BlacklistIPs = append(opts.BlacklistIPs, net)
 	}
 
+	blockedHostnames, err := flags.GetStringSlice("block-hostnames")
+	if err != nil {
+		return opts, err
+	}
+	for _, pattern := range blockedHostnames {
+		if err := netext.ValidateHostnamePattern(pattern); err != nil {
+			return opts, errors.Wrap(err, "block-hostnames")
+		}
+		opts.BlockedHostnames = append(opts.BlockedHostnames, pattern)
+	}
+
+	hostStrings, err := flags.GetStringSlice("host")
+	if err != nil {
+		return opts, err
+	}
+	for _, s := range hostStrings {
+		name, ip, err := parseHostOverride(s)
+		if err != nil {
+			return opts, errors.Wrap(err, "host")
+		}
+		if opts.Hosts == nil {
+			opts.Hosts = make(map[string]net.IP, len(hostStrings))
+		}
+		opts.Hosts[name] = ip
+	}
+
+	if flags.Lookup("proxy").Changed {
+		proxy, err := flags.GetString("proxy")
+		if err != nil {
+			return opts, err
+		}
+		if _, err := netext.ParseProxyURL(proxy); proxy != "" && err != nil {
+			return opts, err
+		}
+		opts.Proxy = null.StringFrom(proxy)
+	}
+
+	if flags.Lookup("execution-segment").Changed {
+		segment, err := flags.GetString("execution-segment")
+		if err != nil {
+			return opts, err
+		}
+		if opts.ExecutionSegment, err = lib.NewExecutionSegmentFromString(segment); err != nil {
+			return opts, err
+		}
+	}
+
+	if flags.Lookup("execution-segment-sequence").Changed {
+		sequence, err := flags.GetString("execution-segment-sequence")
+		if err != nil {
+			return opts, err
+		}
+		if opts.ExecutionSegmentSequence, err = lib.NewExecutionSegmentSequenceFromString(sequence); err != nil {
+			return opts, err
+		}
+	}
+
+	if flags.Lookup("dns").Changed {
+		dns, err := flags.GetString("dns")
+		if err != nil {
+			return opts, err
+		}
+		opts.DNS = &lib.DNSConfig{}
+		if err := opts.DNS.UnmarshalText([]byte(dns)); err != nil {
+			return opts, errors.Wrap(err, "dns")
+		}
+	}
+
+	if flags.Lookup("network-faults").Changed {
+		networkFaults, err := flags.GetString("network-faults")
+		if err != nil {
+			return opts, err
+		}
+		opts.NetworkFaults = &lib.FaultInjection{}
+		if err := opts.NetworkFaults.UnmarshalText([]byte(networkFaults)); err != nil {
+			return opts, errors.Wrap(err, "network-faults")
+		}
+	}
+
 	trendStatStrings, err := flags.GetStringSlice("summary-trend-stats")
 	if err != nil {
 		return opts, err
@@ -203,3 +299,16 @@ func parseTagNameValue(nv string) (string, string, error) {
 		return nv[:idx], nv[idx+1:], nil
 	}
 }
+
+// parseHostOverride parses a `name=ip` DNS override of the --host flag.
+func parseHostOverride(s string) (string, net.IP, error) {
+	idx := strings.IndexRune(s, '=')
+	if idx <= 0 {
+		return "", nil, errors.Errorf("invalid override '%s', it should be in the name=ip format", s)
+	}
+	ip := net.ParseIP(s[idx+1:])
+	if ip == nil {
+		return "", nil, errors.Errorf("invalid IP '%s' for %s", s[idx+1:], s[:idx])
+	}
+	return s[:idx], ip, nil
+}
@@ -21,6 +21,7 @@
 package cmd
 
 import (
+	"net"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -82,3 +83,26 @@ func TestParseTagKeyValue(t *testing.T) {
 	}
 
 }
+
+func TestParseHostOverride(t *testing.T) {
+	name, ip, err := parseHostOverride("api.example.com=10.0.0.12")
+	assert.NoError(t, err)
+	assert.Equal(t, "api.example.com", name)
+	assert.Equal(t, net.ParseIP("10.0.0.12"), ip)
+
+	name, ip, err = parseHostOverride("api.example.com=fd00::12")
+	assert.NoError(t, err)
+	assert.Equal(t, "api.example.com", name)
+	assert.Equal(t, net.ParseIP("fd00::12"), ip)
+
+	invalid := map[string]string{
+		"api.example.com":                  "invalid override 'api.example.com', it should be in the name=ip format",
+		"=10.0.0.12":                       "invalid override '=10.0.0.12', it should be in the name=ip format",
+		"api.example.com=":                 "invalid IP '' for api.example.com",
+		"api.example.com=staging.internal": "invalid IP 'staging.internal' for api.example.com",
+	}
+	for s, errMsg := range invalid {
+		_, _, err := parseHostOverride(s)
+		assert.EqualError(t, err, errMsg, s)
+	}
+}
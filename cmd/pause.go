@@ -24,7 +24,6 @@ import (
 	"context"
 
 	"github.com/loadimpact/k6/api/v1"
-	"github.com/loadimpact/k6/api/v1/client"
 	"github.com/loadimpact/k6/ui"
 	"github.com/spf13/cobra"
 	"gopkg.in/guregu/null.v3"
@@ -36,9 +35,10 @@ var pauseCmd = &cobra.Command{
 	Short: "Pause a running test",
 	Long: `Pause a running test.
 
-  Use the global --address flag to specify the URL to the API server.`,
+  Use the global --address flag to specify the URL to the API server, and --api-token
+  if the server requires a token.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		c, err := client.New(address)
+		c, err := newAPIClient()
 		if err != nil {
 			return err
 		}
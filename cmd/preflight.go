@@ -0,0 +1,149 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+const (
+	// Every VU usually keeps a few connections open, e.g. to a couple of hosts with
+	// keep-alive, and k6 itself needs some file descriptors for the outputs, logs, etc.
+	fdsPerVU    = 4
+	fdsForK6    = 64
+	procMeminfo = "/proc/meminfo"
+)
+
+// runPreflightChecks checks if the machine has enough resources for the VUs of the test,
+// so that k6 fails or at least warns before the start, instead of dying in the middle of
+// the test with cryptic socket or out of memory errors. If the open file limit is too low,
+// it's raised as much as allowed.
+func runPreflightChecks(r lib.Runner, conf Config) error {
+	vus := conf.VUsMax.Int64
+
+	neededFDs := uint64(vus)*fdsPerVU + fdsForK6
+	if cur, max, err := getFileLimit(); err != nil {
+		log.WithError(err).Debug("Couldn't get the open file limit")
+	} else if cur < neededFDs {
+		newLimit := neededFDs
+		if max < newLimit {
+			newLimit = max
+		}
+		if err = setFileLimit(newLimit); err != nil {
+			log.WithError(err).Debug("Couldn't raise the open file limit")
+			newLimit = cur
+		} else {
+			log.Debugf("Raised the open file limit from %d to %d", cur, newLimit)
+		}
+		if newLimit < neededFDs {
+			log.Warnf(
+				"The open file limit is %d, but %d VUs may need around %d, so some connections "+
+					"may fail with 'too many open files' errors. Raise the limit with 'ulimit -n'.",
+				newLimit, vus, neededFDs,
+			)
+		}
+	}
+
+	var maxMemory uint64
+	if conf.MaxMemory.Valid && conf.MaxMemory.String != "" {
+		var err error
+		if maxMemory, err = humanize.ParseBytes(conf.MaxMemory.String); err != nil {
+			return errors.Wrap(err, "invalid max memory")
+		}
+	}
+	perVU, err := estimateVUMemory(r)
+	if err != nil {
+		return err
+	}
+	return checkMemory(vus, perVU, maxMemory, getAvailableMemory(afero.NewOsFs()))
+}
+
+// estimateVUMemory measures how much memory a single VU of the runner needs.
+func estimateVUMemory(r lib.Runner) (uint64, error) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	vu, err := r.NewVU(make(chan stats.SampleContainer, 1))
+	if err != nil {
+		return 0, err
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(vu)
+	if after.HeapAlloc < before.HeapAlloc {
+		return 0, nil
+	}
+	return after.HeapAlloc - before.HeapAlloc, nil
+}
+
+// checkMemory returns an error if the estimated memory needed by the VUs is more than the
+// maximum specified by the user, and warns if it's more than the available memory.
+func checkMemory(vus int64, perVU, maxMemory, available uint64) error {
+	var current runtime.MemStats
+	runtime.ReadMemStats(&current)
+	needed := uint64(vus)*perVU + current.Sys
+
+	log.Debugf("Estimated memory usage: %s (%s per VU)", humanize.Bytes(needed), humanize.Bytes(perVU))
+	if maxMemory > 0 && needed > maxMemory {
+		return errors.Errorf(
+			"the test is estimated to need %s of memory for %d VUs (%s per VU), which is more than the maximum of %s",
+			humanize.Bytes(needed), vus, humanize.Bytes(perVU), humanize.Bytes(maxMemory),
+		)
+	}
+	if available > 0 && needed > available {
+		log.Warnf(
+			"The test is estimated to need %s of memory for %d VUs (%s per VU), but only %s is available",
+			humanize.Bytes(needed), vus, humanize.Bytes(perVU), humanize.Bytes(available),
+		)
+	}
+	return nil
+}
+
+// getAvailableMemory returns the memory that's available for new processes, or 0 if it's
+// unknown. It's currently only supported on Linux.
+func getAvailableMemory(fs afero.Fs) uint64 {
+	data, err := afero.ReadFile(fs, procMeminfo)
+	if err != nil {
+		return 0
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "MemAvailable:" && fields[2] == "kB" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestCheckMemory(t *testing.T) {
+	assert.NoError(t, checkMemory(10, 1024, 0, 0))
+	assert.NoError(t, checkMemory(10, 1024, 1<<50, 1<<50))
+	assert.NoError(t, checkMemory(1000, 1<<30, 0, 1024)) // only a warning
+	assert.Error(t, checkMemory(1000, 1<<30, 1<<30, 0))
+}
+
+func TestGetAvailableMemory(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.Equal(t, uint64(0), getAvailableMemory(fs))
+
+	require.NoError(t, afero.WriteFile(fs, procMeminfo, []byte(
+		"MemTotal:        8048716 kB\nMemFree:          512000 kB\nMemAvailable:    4096000 kB\n",
+	), 0644))
+	assert.Equal(t, uint64(4096000*1024), getAvailableMemory(fs))
+}
+
+func TestRunPreflightChecks(t *testing.T) {
+	r := &lib.MiniRunner{Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+		return nil
+	}}
+
+	perVU, err := estimateVUMemory(r)
+	require.NoError(t, err)
+	assert.True(t, perVU < 1<<20)
+
+	conf := Config{Options: lib.Options{VUsMax: null.IntFrom(1)}}
+	assert.NoError(t, runPreflightChecks(r, conf))
+
+	conf.MaxMemory = null.StringFrom("1 kB")
+	assert.Error(t, runPreflightChecks(r, conf))
+
+	conf.MaxMemory = null.StringFrom("a lot")
+	assert.Error(t, runPreflightChecks(r, conf))
+}
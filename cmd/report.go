@@ -0,0 +1,118 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"io"
+	"path/filepath"
+
+	"github.com/loadimpact/k6/lib"
+	jsonc "github.com/loadimpact/k6/stats/json"
+	"github.com/loadimpact/k6/ui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	null "gopkg.in/guregu/null.v3"
+)
+
+var (
+	reportFormat     string
+	reportOutput     string
+	reportTrendStats []string
+	reportTimeUnit   string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report [file]",
+	Short: "Generate a report from the results of a previous test run",
+	Long: `Generate a report from the results of a previous test run.
+
+Reads a file produced with the JSON output (-o json=file.json) and renders the
+end-of-test summary again, either as text, as an HTML page, as a JUnit XML
+file with the checks and thresholds as test cases or as JSON. This allows the
+results to be re-analyzed without running the test again.`,
+	Example: `
+  # Save the metrics of a test run and show the end-of-test summary again later.
+  k6 run -o json=results.json script.js
+  k6 report results.json
+
+  # Generate an HTML report with some extra trend stats.
+  k6 report --format html --output report.html --summary-trend-stats "avg,p(99)" results.json
+
+  # Generate a JUnit report for a CI server.
+  k6 report --format junit --output junit.xml results.json`[1:],
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch reportFormat {
+		case ui.ReportText, ui.ReportHTML, ui.ReportJUnit, ui.ReportJSON:
+		default:
+			return errors.Errorf("unknown report format '%s'", reportFormat)
+		}
+		if len(reportTrendStats) > 0 {
+			for _, stat := range reportTrendStats {
+				if err := ui.VerifyTrendColumnStat(stat); err != nil {
+					return errors.Wrapf(err, "stat '%s'", stat)
+				}
+			}
+			ui.UpdateTrendColumns(reportTrendStats)
+		}
+
+		filePath, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		f, err := defaultFs.Open(filePath)
+		if err != nil {
+			return err
+		}
+		res, err := jsonc.ReadResults(f)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+
+		data := ui.SummaryData{
+			Opts:    lib.Options{SummaryTimeUnit: null.NewString(reportTimeUnit, reportTimeUnit != "")},
+			Root:    res.RootGroup,
+			Metrics: res.Metrics,
+			Time:    res.Duration(),
+		}
+
+		var w io.Writer = defaultWriter
+		if reportOutput != "" && reportOutput != "-" {
+			out, err := defaultFs.Create(reportOutput)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = out.Close() }()
+			w = out
+		}
+		return ui.WriteReport(w, reportFormat, data)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().SortFlags = false
+	reportCmd.Flags().StringVar(&reportFormat, "format", ui.ReportText, "report `format`: text, html, junit or json")
+	reportCmd.Flags().StringVar(&reportOutput, "output", "-", "`file` to write the report to, - for stdout")
+	reportCmd.Flags().StringSliceVar(&reportTrendStats, "summary-trend-stats", nil, "define `stats` for trend metrics, as in k6 run")
+	reportCmd.Flags().StringVar(&reportTimeUnit, "summary-time-unit", "", "define the time `unit` used to display the trend stats, as in k6 run")
+}
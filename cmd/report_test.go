@@ -0,0 +1,59 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+	jsonc "github.com/loadimpact/k6/stats/json"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportThresholds(t *testing.T) {
+	defer func(fs afero.Fs, w io.Writer, format string) {
+		defaultFs, defaultWriter, reportFormat = fs, w, format
+	}(defaultFs, defaultWriter, reportFormat)
+
+	// The JSON output of a test run with a failed threshold
+	thresholds, err := stats.NewThresholds([]string{"p(95)<50"})
+	require.NoError(t, err)
+	var output bytes.Buffer
+	collector := jsonc.NewFromWriter(&output)
+	collector.SetThresholds(map[string]stats.Thresholds{"http_req_duration": thresholds})
+	m := stats.New("http_req_duration", stats.Trend, stats.Time)
+	collector.Collect([]stats.SampleContainer{stats.Sample{Metric: m, Time: time.Now(), Value: 100}})
+
+	defaultFs = afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(defaultFs, "/results.json", output.Bytes(), 0644))
+	var buf bytes.Buffer
+	defaultWriter = &buf
+	reportFormat = "junit"
+	require.NoError(t, reportCmd.RunE(reportCmd, []string{"/results.json"}))
+
+	assert.Contains(t, buf.String(), `failures="1"`)
+	assert.Contains(t, buf.String(), "threshold &#39;p(95)&lt;50&#39; for metric &#39;http_req_duration&#39; has failed")
+}
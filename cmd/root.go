@@ -23,6 +23,7 @@ package cmd
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	golog "log"
 	"os"
 	"path/filepath"
@@ -30,8 +31,10 @@ import (
 	"sync"
 
 	"github.com/fatih/color"
+	"github.com/loadimpact/k6/ui"
 	"github.com/mattn/go-colorable"
 	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
 	"github.com/shibukawa/configdir"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -75,8 +78,19 @@ var (
 	verbose bool
 	quiet   bool
 	noColor bool
-	logFmt  string
+	ascii   bool
 	address string
+
+	// Log format, level and output, see setupLoggers().
+	logFmt    = os.Getenv("K6_LOG_FORMAT")
+	logLevel  = os.Getenv("K6_LOG_LEVEL")
+	logOutput = os.Getenv("K6_LOG_OUTPUT")
+	logFile   *os.File // The file of the file= log output, closed by closeLogFile().
+
+	// Authentication and TLS for the API server, see apiConfig().
+	apiToken   = os.Getenv("K6_API_TOKEN")
+	apiTLSCert = os.Getenv("K6_API_TLS_CERT")
+	apiTLSKey  = os.Getenv("K6_API_TLS_KEY")
 )
 
 // RootCmd represents the base command when called without any subcommands.
@@ -86,13 +100,20 @@ var RootCmd = &cobra.Command{
 	Long:          BannerColor.Sprintf("\n%s", Banner),
 	SilenceUsage:  true,
 	SilenceErrors: true,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		setupLoggers(logFmt)
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupLoggers(logFmt, logLevel, logOutput); err != nil {
+			return err
+		}
 		if noColor {
 			stdout.Writer = colorable.NewNonColorable(os.Stdout)
 			stderr.Writer = colorable.NewNonColorable(os.Stderr)
 		}
+		if ascii {
+			ui.UseASCII()
+			Banner = strings.Replace(Banner, "‾", "-", -1)
+		}
 		golog.SetOutput(log.StandardLogger().Writer())
+		return nil
 	},
 }
 
@@ -101,11 +122,13 @@ var RootCmd = &cobra.Command{
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
 		log.Error(err.Error())
+		closeLogFile()
 		if e, ok := err.(ExitCode); ok {
 			os.Exit(e.Code)
 		}
 		os.Exit(-1)
 	}
+	closeLogFile()
 }
 
 func rootCmdPersistentFlagSet() *pflag.FlagSet {
@@ -114,8 +137,18 @@ func rootCmdPersistentFlagSet() *pflag.FlagSet {
 	flags.BoolVarP(&verbose, "verbose", "v", false, "enable debug logging")
 	flags.BoolVarP(&quiet, "quiet", "q", false, "disable progress updates")
 	flags.BoolVar(&noColor, "no-color", false, "disable colored output")
-	flags.StringVar(&logFmt, "logformat", "", "log output format")
+	flags.BoolVar(&ascii, "ascii", false, "only use ASCII characters in the banner and summary")
+	flags.StringVar(&logFmt, "log-format", logFmt, "log output `format`, one of text, json or raw")
+	flags.StringVar(&logFmt, "logformat", logFmt, "log output format")
+	must(flags.MarkDeprecated("logformat", "use --log-format instead"))
+	flags.StringVar(&logLevel, "log-level", logLevel, "minimum log `level`, one of debug, info, warning or error; --verbose is the same as debug")
+	flags.StringVar(&logOutput, "log-output", logOutput, "where to write the logs to, one of stderr, stdout, none or file=`path`; stderr if not set")
 	flags.StringVarP(&address, "address", "a", "localhost:6565", "address for the api server")
+	flags.StringVar(&apiToken, "api-token", apiToken, "`token` needed to change the test through the api server")
+	// Don't show the token from the environment in the usage message
+	flags.Lookup("api-token").DefValue = ""
+	flags.StringVar(&apiTLSCert, "api-tls-cert", apiTLSCert, "certificate `file` to serve the api over TLS with, or to trust as a client")
+	flags.StringVar(&apiTLSKey, "api-tls-key", apiTLSKey, "key `file` to serve the api over TLS with")
 
 	//TODO: Fix... This default value needed, so both CLI flags and environment variables work
 	flags.StringVarP(&configFilePath, "config", "c", configFilePath, "JSON config file")
@@ -155,11 +188,30 @@ func (f RawFormater) Format(entry *log.Entry) ([]byte, error) {
 	return append([]byte(entry.Message), '\n'), nil
 }
 
-func setupLoggers(logFmt string) {
+// setupLoggers sets up the standard logger with the given format, level and output. The level is
+// info, or debug with --verbose, if it isn't set.
+func setupLoggers(logFmt, logLevel, logOutput string) error {
+	level := log.InfoLevel
 	if verbose {
-		log.SetLevel(log.DebugLevel)
+		level = log.DebugLevel
 	}
-	log.SetOutput(stderr)
+	if logLevel != "" {
+		var err error
+		if level, err = log.ParseLevel(logLevel); err != nil {
+			return errors.Errorf("invalid log level '%s', it has to be debug, info, warning or error", logLevel)
+		}
+	}
+	log.SetLevel(level)
+
+	output, tty, err := getLogOutput(logOutput)
+	if err != nil {
+		return err
+	}
+	closeLogFile()
+	if f, ok := output.(*os.File); ok {
+		logFile = f
+	}
+	log.SetOutput(output)
 
 	switch logFmt {
 	case "raw":
@@ -168,9 +220,58 @@ func setupLoggers(logFmt string) {
 	case "json":
 		log.SetFormatter(&log.JSONFormatter{})
 		log.Debug("Logger format: JSON")
-	default:
-		log.SetFormatter(&log.TextFormatter{ForceColors: stderrTTY, DisableColors: noColor})
+	case "", "text":
+		log.SetFormatter(&log.TextFormatter{ForceColors: tty, DisableColors: noColor})
 		log.Debug("Logger format: TEXT")
+	default:
+		return errors.Errorf("invalid log format '%s', it has to be text, json or raw", logFmt)
 	}
+	return nil
+}
+
+// closeLogFile flushes and closes the file of the file= log output, if there's one, and
+// switches the logs back to stderr.
+func closeLogFile() {
+	if logFile == nil {
+		return
+	}
+	log.SetOutput(stderr)
+	if err := logFile.Sync(); err != nil {
+		log.WithError(err).Warn("Couldn't sync the log file")
+	}
+	if err := logFile.Close(); err != nil {
+		log.WithError(err).Warn("Couldn't close the log file")
+	}
+	logFile = nil
+}
 
+// getLogOutput returns the writer for a log output, like stderr or file=./k6.log, and whether
+// it's a terminal.
+func getLogOutput(logOutput string) (io.Writer, bool, error) {
+	kind, value := logOutput, ""
+	if i := strings.IndexByte(logOutput, '='); i >= 0 {
+		kind, value = logOutput[:i], logOutput[i+1:]
+	}
+
+	switch kind {
+	case "", "stderr":
+		return stderr, stderrTTY, nil
+	case "stdout":
+		return stdout, stdoutTTY, nil
+	case "none":
+		return ioutil.Discard, false, nil
+	case "file":
+		if value == "" {
+			return nil, false, errors.New("the file log output needs a path, like file=./k6.log")
+		}
+		f, err := os.OpenFile(value, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "couldn't open the log file")
+		}
+		return f, false, nil
+	default:
+		return nil, false, errors.Errorf(
+			"invalid log output '%s', it has to be stderr, stdout, none or file=<path>", logOutput,
+		)
+	}
 }
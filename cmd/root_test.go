@@ -0,0 +1,113 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupLoggers(t *testing.T) {
+	defer func() {
+		log.SetLevel(log.InfoLevel)
+		log.SetFormatter(&log.TextFormatter{})
+		log.SetOutput(stderr)
+	}()
+
+	testdata := map[string]struct {
+		format, level string
+		verbose       bool
+		formatter     log.Formatter
+		logLevel      log.Level
+		err           string
+	}{
+		"default":        {"", "", false, &log.TextFormatter{}, log.InfoLevel, ""},
+		"verbose":        {"text", "", true, &log.TextFormatter{}, log.DebugLevel, ""},
+		"json":           {"json", "warning", false, &log.JSONFormatter{}, log.WarnLevel, ""},
+		"level":          {"raw", "error", true, &RawFormater{}, log.ErrorLevel, ""},
+		"invalid format": {"xml", "", false, nil, 0, "invalid log format 'xml'"},
+		"invalid level":  {"json", "loud", false, nil, 0, "invalid log level 'loud'"},
+	}
+	for name, data := range testdata {
+		t.Run(name, func(t *testing.T) {
+			verbose = data.verbose
+			defer func() { verbose = false }()
+
+			err := setupLoggers(data.format, data.level, "")
+			if data.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), data.err)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.IsType(t, data.formatter, log.StandardLogger().Formatter)
+			assert.Equal(t, data.logLevel, log.GetLevel())
+		})
+	}
+}
+
+func TestGetLogOutput(t *testing.T) {
+	defer func() {
+		log.SetFormatter(&log.TextFormatter{})
+		log.SetOutput(stderr)
+	}()
+
+	w, _, err := getLogOutput("")
+	assert.NoError(t, err)
+	assert.Equal(t, stderr, w)
+	w, _, err = getLogOutput("stdout")
+	assert.NoError(t, err)
+	assert.Equal(t, stdout, w)
+	w, tty, err := getLogOutput("none")
+	assert.NoError(t, err)
+	assert.Equal(t, ioutil.Discard, w)
+	assert.False(t, tty)
+
+	for _, output := range []string{"file", "file=", "loki=http://localhost", "syslog"} {
+		_, _, err := getLogOutput(output)
+		assert.Error(t, err, output)
+	}
+
+	dir, err := ioutil.TempDir("", "k6-log-output")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "k6.log")
+
+	require.NoError(t, setupLoggers("json", "", "file="+path))
+	log.WithField("a", 1).Warn("to the file")
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"msg":"to the file"`)
+	assert.Contains(t, string(data), `"a":1`)
+
+	f := logFile
+	require.NotNil(t, f)
+	closeLogFile()
+	assert.Nil(t, logFile)
+	assert.Equal(t, stderr, log.StandardLogger().Out)
+	assert.Error(t, f.Close(), "the log file should already be closed")
+}
@@ -33,6 +33,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -41,16 +42,17 @@ import (
 	"github.com/loadimpact/k6/core"
 	"github.com/loadimpact/k6/core/local"
 	"github.com/loadimpact/k6/js"
+	"github.com/loadimpact/k6/k6exec"
 	"github.com/loadimpact/k6/lib"
-	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/loader"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/stats/aggregator"
 	"github.com/loadimpact/k6/ui"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	null "gopkg.in/guregu/null.v3"
 )
 
 const (
@@ -63,6 +65,8 @@ const (
 	genericTimeoutErrorCode     = 102
 	genericEngineErrorCode      = 103
 	invalidConfigErrorCode      = 104
+	lowResourcesErrorCode       = 105
+	testAbortedErrorCode        = 106
 )
 
 var (
@@ -70,6 +74,7 @@ var (
 	runType       = os.Getenv("K6_TYPE")
 	runNoSetup    = os.Getenv("K6_NO_SETUP") != ""
 	runNoTeardown = os.Getenv("K6_NO_TEARDOWN") != ""
+	runWatch      = os.Getenv("K6_WATCH") != ""
 )
 
 // runCmd represents the run command.
@@ -100,16 +105,24 @@ a commandline interface for interacting with it.`,
   k6 run -o influxdb=http://1.2.3.4:8086/k6`[1:],
 	Args: exactArgsWithMsg(1, "arg should either be \"-\", if reading script from stdin, or a path to a script file"),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		//TODO: disable in quiet mode?
-		_, _ = BannerColor.Fprintf(stdout, "\n%s\n\n", Banner)
+		// In quiet mode, only the end-of-test summary (and the logs) are written out.
+		if !quiet {
+			_, _ = BannerColor.Fprintf(stdout, "\n%s\n\n", Banner)
+		}
 
 		initBar := ui.ProgressBar{
 			Width: 60,
 			Left:  func() string { return "    init" },
 		}
+		// The init steps are redrawn in place, which only makes sense on a terminal.
+		printInitStep := func(step string) {
+			if !quiet && stdoutTTY {
+				fprintf(stdout, "%s %s\r", initBar.String(), step)
+			}
+		}
 
 		// Create the Runner.
-		fprintf(stdout, "%s runner\r", initBar.String())
+		printInitStep("runner")
 		pwd, err := os.Getwd()
 		if err != nil {
 			return err
@@ -131,7 +144,7 @@ a commandline interface for interacting with it.`,
 			return err
 		}
 
-		fprintf(stdout, "%s options\r", initBar.String())
+		printInitStep("options")
 
 		cliConf, err := getConfig(cmd.Flags())
 		if err != nil {
@@ -142,20 +155,10 @@ a commandline interface for interacting with it.`,
 			return err
 		}
 
-		// If -m/--max isn't specified, figure out the max that should be needed.
-		if !conf.VUsMax.Valid {
-			conf.VUsMax = null.NewInt(conf.VUs.Int64, conf.VUs.Valid)
-			for _, stage := range conf.Stages {
-				if stage.Target.Valid && stage.Target.Int64 > conf.VUsMax.Int64 {
-					conf.VUsMax = stage.Target
-				}
-			}
-		}
-
-		// If -d/--duration, -i/--iterations and -s/--stage are all unset, run to one iteration.
-		if !conf.Duration.Valid && !conf.Iterations.Valid && len(conf.Stages) == 0 {
-			conf.Iterations = null.IntFrom(1)
-		}
+		// If -m/--max isn't specified, figure out the max that should be needed. If -d/--duration,
+		// -i/--iterations and -s/--stage are all unset, run to one iteration. If duration is
+		// explicitly set to 0, it means run forever.
+		conf.Options = k6exec.ApplyExecutionDefaults(conf.Options)
 
 		if conf.Iterations.Valid && conf.Iterations.Int64 < conf.VUsMax.Int64 {
 			log.Warnf(
@@ -166,29 +169,48 @@ a commandline interface for interacting with it.`,
 
 		//TODO: move a bunch of the logic above to a config "constructor" and to the Validate() method
 
-		// If duration is explicitly set to 0, it means run forever.
-		//TODO: just... handle this differently, e.g. as a part of the manual executor
-		if conf.Duration.Valid && conf.Duration.Duration == 0 {
-			conf.Duration = types.NullDuration{}
-		}
-
 		if cerr := validateConfig(conf); cerr != nil {
 			return ExitCode{cerr, invalidConfigErrorCode}
 		}
 
+		// Only run the execution segment of the test, if it's split between several instances.
+		if conf.Options, err = k6exec.ApplyExecutionSegment(conf.Options); err != nil {
+			return ExitCode{err, invalidConfigErrorCode}
+		}
+
 		// If summary trend stats are defined, update the UI to reflect them
 		if len(conf.SummaryTrendStats) > 0 {
 			ui.UpdateTrendColumns(conf.SummaryTrendStats)
 		}
 
+		// Set the accuracy of the percentiles of the trends that grow too big to keep all values.
+		if conf.TrendAccuracy.Valid {
+			if a := conf.TrendAccuracy.Float64; a <= 0 || a >= 1 {
+				return ExitCode{errors.New("the trend accuracy should be between 0 and 1, e.g. 0.01 for 1%"), invalidConfigErrorCode}
+			}
+			stats.TrendSinkAccuracy = conf.TrendAccuracy.Float64
+		}
+
+		// The CI annotations are only written after the test, so check their format before it.
+		if conf.CIAnnotations.Valid && conf.CIAnnotations.String != "" {
+			if err := validateCIAnnotations(conf.CIAnnotations.String); err != nil {
+				return ExitCode{err, invalidConfigErrorCode}
+			}
+		}
+
 		// Write options back to the runner too.
 		if err = r.SetOptions(conf.Options); err != nil {
 			return err
 		}
 
+		// Make sure the machine can handle the test before starting it.
+		if perr := runPreflightChecks(r, conf); perr != nil {
+			return ExitCode{perr, lowResourcesErrorCode}
+		}
+
 		// Create a local executor wrapping the runner.
-		fprintf(stdout, "%s executor\r", initBar.String())
-		ex := local.New(r)
+		printInitStep("executor")
+		ex := local.NewForExecution(r, conf.Execution)
 		if runNoSetup {
 			ex.SetRunSetup(false)
 		}
@@ -197,7 +219,7 @@ a commandline interface for interacting with it.`,
 		}
 
 		// Create an engine.
-		fprintf(stdout, "%s   engine\r", initBar.String())
+		printInitStep("  engine")
 		engine, err := core.NewEngine(ex, conf.Options)
 		if err != nil {
 			return err
@@ -212,7 +234,7 @@ a commandline interface for interacting with it.`,
 		}
 
 		// Create a collector and assign it to the engine if requested.
-		fprintf(stdout, "%s   collector\r", initBar.String())
+		printInitStep("  collector")
 		for _, out := range conf.Out {
 			t, arg := parseCollector(out)
 			collector, err := newCollector(t, arg, src, conf)
@@ -222,19 +244,32 @@ a commandline interface for interacting with it.`,
 			if err := collector.Init(); err != nil {
 				return err
 			}
+			// The cloud collector has its own aggregation, which needs the raw samples.
+			if conf.AggregationPeriod.Valid && conf.AggregationPeriod.Duration > 0 && t != collectorCloud {
+				collector, err = aggregator.New(
+					collector, time.Duration(conf.AggregationPeriod.Duration), conf.AggregationStats,
+				)
+				if err != nil {
+					return err
+				}
+			}
 			engine.Collectors = append(engine.Collectors, collector)
 		}
 
 		// Create an API server.
-		fprintf(stdout, "%s   server\r", initBar.String())
+		printInitStep("  server")
+		apiConf := apiConfig()
+		if err := apiConf.Validate(); err != nil {
+			return err
+		}
 		go func() {
-			if err := api.ListenAndServe(address, engine); err != nil {
+			if err := api.ListenAndServe(address, engine, apiConf); err != nil {
 				log.WithError(err).Warn("Error from API server")
 			}
 		}()
 
 		// Write the big banner.
-		{
+		if !quiet {
 			out := "-"
 			link := ""
 			if engine.Collectors != nil {
@@ -279,11 +314,33 @@ a commandline interface for interacting with it.`,
 			fprintf(stdout, "\n")
 		}
 
-		// Run the engine with a cancellable context.
-		fprintf(stdout, "%s starting\r", initBar.String())
-		ctx, cancel := context.WithCancel(context.Background())
-		errC := make(chan error)
-		go func() { errC <- engine.Run(ctx) }()
+		// Print interim summaries during the test, if requested.
+		if conf.SummaryInterval.Valid && conf.SummaryInterval.Duration > 0 {
+			summary, err := newInterimSummary(
+				time.Duration(conf.SummaryInterval.Duration), conf.SummaryIntervalMode.String,
+				conf.SummaryTimeUnit.String, stdout,
+			)
+			if err != nil {
+				return err
+			}
+			if conf.SummaryIntervalExport.String != "" {
+				if summary.export, err = fs.Create(conf.SummaryIntervalExport.String); err != nil {
+					return err
+				}
+			}
+			engine.Collectors = append(engine.Collectors, summary)
+		}
+
+		// In watch mode, the collectors have to keep running between the test runs, so they
+		// are started here, independently from the engine.
+		if runWatch {
+			if filename == "-" {
+				return errors.New("the script can't be watched for changes when it's read from stdin")
+			}
+			var stopCollectors func()
+			engine.Collectors, stopCollectors = startPersistentCollectors(engine.Collectors)
+			defer stopCollectors()
+		}
 
 		// Trap Interrupts, SIGINTs and SIGTERMs.
 		sigC := make(chan os.Signal, 1)
@@ -319,150 +376,237 @@ a commandline interface for interacting with it.`,
 			}()
 		}
 
-		// Prepare a progress bar.
-		progress := ui.ProgressBar{
-			Width: 60,
-			Left: func() string {
-				if engine.Executor.IsPaused() {
-					return "  paused"
-				} else if engine.Executor.IsRunning() {
-					return " running"
-				} else {
-					return "    done"
-				}
-			},
-			Right: func() string {
-				if endIt := engine.Executor.GetEndIterations(); endIt.Valid {
-					return fmt.Sprintf("%d / %d", engine.Executor.GetIterations(), endIt.Int64)
-				}
-				precision := 100 * time.Millisecond
-				atT := engine.Executor.GetTime()
-				stagesEndT := lib.SumStages(engine.Executor.GetStages())
-				endT := engine.Executor.GetEndTime()
-				if !endT.Valid || (stagesEndT.Valid && endT.Duration > stagesEndT.Duration) {
-					endT = stagesEndT
-				}
-				if endT.Valid {
-					return fmt.Sprintf("%s / %s",
-						(atT/precision)*precision,
-						(time.Duration(endT.Duration)/precision)*precision,
-					)
-				}
-				return ((atT / precision) * precision).String()
-			},
-		}
-
-		// Ticker for progress bar updates. Less frequent updates for non-TTYs, none if quiet.
-		updateFreq := 50 * time.Millisecond
-		if !stdoutTTY {
-			updateFreq = 1 * time.Second
-		}
-		ticker := time.NewTicker(updateFreq)
-		if quiet || conf.HttpDebug.Valid && conf.HttpDebug.String != "" {
-			ticker.Stop()
-		}
-	mainLoop:
+		// The error of the script aborting the last test run, if it did.
+		var abortErr error
+	watchLoop:
 		for {
-			select {
-			case <-ticker.C:
-				if quiet || !stdoutTTY {
-					l := log.WithFields(log.Fields{
-						"t": engine.Executor.GetTime(),
-						"i": engine.Executor.GetIterations(),
-					})
-					fn := l.Info
-					if quiet {
-						fn = l.Debug
-					}
+			// Watch the script and all files loaded by it for changes, if requested.
+			var changes <-chan struct{}
+			watchCtx, stopWatching := context.WithCancel(context.Background())
+			defer stopWatching() // it's also stopped explicitly below, before the next test run
+			if runWatch {
+				watched := getWatchedFiles(fs, src.Filename, engine.Executor.GetRunner())
+				changes = watchFiles(watchCtx, fs, watched, watchInterval)
+			}
+			reload, interrupted := false, false
+			abortErr = nil
+
+			// Run the engine with a cancellable context.
+			printInitStep("starting")
+			ctx, cancel := context.WithCancel(context.Background())
+			errC := make(chan error)
+			go func() { errC <- engine.Run(ctx) }()
+
+			// Prepare a progress bar.
+			progress := ui.ProgressBar{
+				Width: 60,
+				Left: func() string {
 					if engine.Executor.IsPaused() {
-						fn("Paused")
+						return "  paused"
+					} else if engine.Executor.IsRunning() {
+						return " running"
 					} else {
-						fn("Running")
+						return "    done"
 					}
-					break
-				}
-
-				var prog float64
-				if endIt := engine.Executor.GetEndIterations(); endIt.Valid {
-					prog = float64(engine.Executor.GetIterations()) / float64(endIt.Int64)
-				} else {
+				},
+				Right: func() string {
+					if endIt := engine.Executor.GetEndIterations(); endIt.Valid {
+						return fmt.Sprintf("%d / %d", engine.Executor.GetIterations(), endIt.Int64)
+					}
+					precision := 100 * time.Millisecond
+					atT := engine.Executor.GetTime()
 					stagesEndT := lib.SumStages(engine.Executor.GetStages())
 					endT := engine.Executor.GetEndTime()
 					if !endT.Valid || (stagesEndT.Valid && endT.Duration > stagesEndT.Duration) {
 						endT = stagesEndT
 					}
 					if endT.Valid {
-						prog = float64(engine.Executor.GetTime()) / float64(endT.Duration)
+						return fmt.Sprintf("%s / %s",
+							(atT/precision)*precision,
+							(time.Duration(endT.Duration)/precision)*precision,
+						)
+					}
+					return ((atT / precision) * precision).String()
+				},
+			}
+
+			// Ticker for progress bar updates. Less frequent updates for non-TTYs, none if quiet.
+			updateFreq := 50 * time.Millisecond
+			if !stdoutTTY {
+				updateFreq = 1 * time.Second
+			}
+			ticker := time.NewTicker(updateFreq)
+			if quiet || conf.HttpDebug.Valid && conf.HttpDebug.String != "" {
+				ticker.Stop()
+			}
+		mainLoop:
+			for {
+				select {
+				case <-ticker.C:
+					if quiet || !stdoutTTY {
+						l := log.WithFields(log.Fields{
+							"t": engine.Executor.GetTime(),
+							"i": engine.Executor.GetIterations(),
+						})
+						fn := l.Info
+						if quiet {
+							fn = l.Debug
+						}
+						if engine.Executor.IsPaused() {
+							fn("Paused")
+						} else {
+							fn("Running")
+						}
+						break
+					}
+
+					var prog float64
+					if endIt := engine.Executor.GetEndIterations(); endIt.Valid {
+						prog = float64(engine.Executor.GetIterations()) / float64(endIt.Int64)
+					} else {
+						stagesEndT := lib.SumStages(engine.Executor.GetStages())
+						endT := engine.Executor.GetEndTime()
+						if !endT.Valid || (stagesEndT.Valid && endT.Duration > stagesEndT.Duration) {
+							endT = stagesEndT
+						}
+						if endT.Valid {
+							prog = float64(engine.Executor.GetTime()) / float64(endT.Duration)
+						}
+					}
+					progress.Progress = prog
+					fprintf(stdout, "%s\x1b[0K\r", progress.String())
+				case err := <-errC:
+					cancel()
+					if err == nil {
+						log.Debug("Engine terminated cleanly")
+						break mainLoop
 					}
-				}
-				progress.Progress = prog
-				fprintf(stdout, "%s\x1b[0K\r", progress.String())
-			case err := <-errC:
-				cancel()
-				if err == nil {
-					log.Debug("Engine terminated cleanly")
-					break mainLoop
-				}
 
-				switch e := errors.Cause(err).(type) {
-				case lib.TimeoutError:
-					switch string(e) {
-					case "setup":
-						log.WithError(err).Error("Setup timeout")
-						return ExitCode{errors.New("Setup timeout"), setupTimeoutErrorCode}
-					case "teardown":
-						log.WithError(err).Error("Teardown timeout")
-						return ExitCode{errors.New("Teardown timeout"), teardownTimeoutErrorCode}
+					switch e := errors.Cause(err).(type) {
+					case lib.TestAbortError:
+						// Finish the test normally, with the summary, but with a separate exit code.
+						log.Error(e.String())
+						abortErr = err
+						break mainLoop
+					case lib.TimeoutError:
+						switch string(e) {
+						case "setup":
+							log.WithError(err).Error("Setup timeout")
+							return ExitCode{errors.New("Setup timeout"), setupTimeoutErrorCode}
+						case "teardown":
+							log.WithError(err).Error("Teardown timeout")
+							return ExitCode{errors.New("Teardown timeout"), teardownTimeoutErrorCode}
+						default:
+							log.WithError(err).Error("Engine timeout")
+							return ExitCode{errors.New("Engine timeout"), genericTimeoutErrorCode}
+						}
 					default:
-						log.WithError(err).Error("Engine timeout")
-						return ExitCode{errors.New("Engine timeout"), genericTimeoutErrorCode}
+						log.WithError(err).Error("Engine error")
+						return ExitCode{errors.New("Engine Error"), genericEngineErrorCode}
 					}
-				default:
-					log.WithError(err).Error("Engine error")
-					return ExitCode{errors.New("Engine Error"), genericEngineErrorCode}
+				case sig := <-sigC:
+					log.WithField("sig", sig).Debug("Exiting in response to signal")
+					interrupted = true
+					cancel()
+				case <-changes:
+					log.Info("Script changes detected; restarting the test...")
+					reload = true
+					cancel()
 				}
-			case sig := <-sigC:
-				log.WithField("sig", sig).Debug("Exiting in response to signal")
-				cancel()
 			}
-		}
-		if quiet || !stdoutTTY {
-			e := log.WithFields(log.Fields{
-				"t": engine.Executor.GetTime(),
-				"i": engine.Executor.GetIterations(),
-			})
-			fn := e.Info
-			if quiet {
-				fn = e.Debug
+			ticker.Stop()
+			if quiet || !stdoutTTY {
+				e := log.WithFields(log.Fields{
+					"t": engine.Executor.GetTime(),
+					"i": engine.Executor.GetIterations(),
+				})
+				fn := e.Info
+				if quiet {
+					fn = e.Debug
+				}
+				fn("Test finished")
+			} else {
+				progress.Progress = 1
+				fprintf(stdout, "%s\x1b[0K\n", progress.String())
 			}
-			fn("Test finished")
-		} else {
-			progress.Progress = 1
-			fprintf(stdout, "%s\x1b[0K\n", progress.String())
-		}
 
-		// Warn if no iterations could be completed.
-		if engine.Executor.GetIterations() == 0 {
-			log.Warn("No data generated, because no script iterations finished, consider making the test duration longer")
-		}
+			// Warn if no iterations could be completed.
+			if engine.Executor.GetIterations() == 0 {
+				log.Warn("No data generated, because no script iterations finished, consider making the test duration longer")
+			}
 
-		// Print the end-of-test summary.
-		if !conf.NoSummary.Bool {
-			fprintf(stdout, "\n")
-			ui.Summarize(stdout, "", ui.SummaryData{
+			// Print the end-of-test summary.
+			summaryData := ui.SummaryData{
 				Opts:    conf.Options,
 				Root:    engine.Executor.GetRunner().GetDefaultGroup(),
 				Metrics: engine.Metrics,
 				Time:    engine.Executor.GetTime(),
-			})
+			}
+			if !conf.NoSummary.Bool {
+				handled, err := handleSummary(engine.Executor.GetRunner(), summaryData)
+				if err != nil {
+					log.WithError(err).Error("Couldn't handle the summary, showing the default one")
+				}
+				if !handled {
+					fprintf(stdout, "\n")
+					ui.Summarize(stdout, "", summaryData)
+					fprintf(stdout, "\n")
+				}
+			}
+
+			if conf.SummaryExport.Valid && conf.SummaryExport.String != "" {
+				if err := writeSummaryExport(conf.SummaryExport.String, summaryData); err != nil {
+					log.WithError(err).Error("Couldn't export the summary")
+				}
+			}
+
+			if conf.CIAnnotations.Valid && conf.CIAnnotations.String != "" {
+				failed := ui.GetFailedThresholds(engine.Metrics, src.Data)
+				if err := writeCIAnnotations(conf.CIAnnotations.String, filename, failed); err != nil {
+					log.WithError(err).Error("Couldn't write the CI annotations")
+				}
+			}
+
+			if !runWatch || interrupted {
+				stopWatching()
+				break
+			}
+
+			// Wait for the script to be changed, unless that's why the test was stopped.
+			for {
+				if !reload {
+					log.Info("Watch mode enabled; waiting for script changes or Ctrl+C...")
+					select {
+					case <-changes:
+					case sig := <-sigC:
+						log.WithField("sig", sig).Debug("Exiting in response to signal")
+						stopWatching()
+						break watchLoop
+					}
+				}
+				reload = false
+
+				log.Info("Reloading the script...")
+				newSrc, newConf, err := reloadEngine(engine, filename, pwd, fs, cliConf, runtimeOptions)
+				if err != nil {
+					log.WithError(err).Error("Couldn't reload the script, fix it and save it again")
+					continue
+				}
+				src, conf = newSrc, newConf
+				break
+			}
+			stopWatching()
 			fprintf(stdout, "\n")
 		}
 
-		if conf.Linger.Bool {
+		if conf.Linger.Bool && !runWatch {
 			log.Info("Linger set; waiting for Ctrl+C...")
 			<-sigC
 		}
 
+		if abortErr != nil {
+			return ExitCode{abortErr, testAbortedErrorCode}
+		}
 		if engine.IsTainted() {
 			return ExitCode{errors.New("some thresholds have failed"), thresholdHaveFailedErroCode}
 		}
@@ -491,6 +635,8 @@ func runCmdFlagSet() *pflag.FlagSet {
 	flags.Lookup("no-setup").DefValue = falseStr
 	flags.BoolVar(&runNoTeardown, "no-teardown", runNoTeardown, "don't run teardown()")
 	flags.Lookup("no-teardown").DefValue = falseStr
+	flags.BoolVar(&runWatch, "watch", runWatch, "restart the test when the script or any of its files are changed")
+	flags.Lookup("watch").DefValue = falseStr
 	return flags
 }
 
@@ -540,6 +686,92 @@ func newRunner(src *lib.SourceData, typ string, fs afero.Fs, rtOpts lib.RuntimeO
 	}
 }
 
+// handleSummary lets the runner render the end-of-test summary if it can, e.g. if the script
+// exports handleSummary(), and writes the result. It returns false if the default summary
+// should be shown instead.
+func handleSummary(r lib.Runner, data ui.SummaryData) (bool, error) {
+	handler, ok := r.(lib.SummaryHandler)
+	if !ok {
+		return false, nil
+	}
+	var buf bytes.Buffer
+	if err := ui.WriteJSONReport(&buf, data); err != nil {
+		return false, err
+	}
+	result, ok, err := handler.HandleSummary(context.Background(), buf.Bytes())
+	if err != nil || !ok {
+		return false, err
+	}
+
+	paths := make([]string, 0, len(result))
+	for path := range result {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		switch path {
+		case "stdout":
+			_, err = io.WriteString(stdout, result[path])
+		case "stderr":
+			_, err = io.WriteString(stderr, result[path])
+		default:
+			err = afero.WriteFile(defaultFs, path, []byte(result[path]), 0644)
+		}
+		if err != nil {
+			return true, errors.Wrapf(err, "couldn't write the summary to %s", path)
+		}
+	}
+	return true, nil
+}
+
+// writeSummaryExport writes the end-of-test summary as a JSON report to the given file.
+func writeSummaryExport(filename string, data ui.SummaryData) error {
+	f, err := defaultFs.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := ui.WriteJSONReport(f, data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// validateCIAnnotations checks that the CI annotation format is one of the supported ones.
+func validateCIAnnotations(format string) error {
+	switch typ, _ := parseCollector(format); typ {
+	case ui.AnnotationsGitHub, ui.AnnotationsGitLab:
+		return nil
+	default:
+		return errors.Errorf("unknown CI annotations format: %s", typ)
+	}
+}
+
+// writeCIAnnotations writes the failed thresholds in the requested CI annotation format. GitHub
+// annotations are written to stdout, while GitLab reports are written to the specified file.
+func writeCIAnnotations(format, filename string, failed []ui.FailedThreshold) error {
+	typ, arg := parseCollector(format)
+	switch typ {
+	case ui.AnnotationsGitHub:
+		return ui.WriteGitHubAnnotations(stdout, filename, failed)
+	case ui.AnnotationsGitLab:
+		if arg == "" {
+			arg = "gl-code-quality-report.json"
+		}
+		f, err := defaultFs.Create(arg)
+		if err != nil {
+			return err
+		}
+		if err := ui.WriteGitLabCodeQuality(f, filename, failed); err != nil {
+			_ = f.Close()
+			return err
+		}
+		return f.Close()
+	default:
+		return errors.Errorf("unknown CI annotations format: %s", typ)
+	}
+}
+
 func detectType(data []byte) string {
 	if _, err := tar.NewReader(bytes.NewReader(data)).Next(); err == nil {
 		return typeArchive
@@ -0,0 +1,99 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/ui"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type summaryHandlerRunner struct {
+	lib.MiniRunner
+	result  map[string]string
+	ok      bool
+	err     error
+	summary []byte
+}
+
+func (r *summaryHandlerRunner) HandleSummary(ctx context.Context, summary []byte) (map[string]string, bool, error) {
+	r.summary = summary
+	return r.result, r.ok, r.err
+}
+
+func TestHandleSummary(t *testing.T) {
+	var buf bytes.Buffer
+	oldStdout, oldFs := stdout, defaultFs
+	stdout, defaultFs = consoleWriter{&buf, false, outMutex}, afero.NewMemMapFs()
+	defer func() { stdout, defaultFs = oldStdout, oldFs }()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	data := ui.SummaryData{Root: root}
+
+	t.Run("NotSupported", func(t *testing.T) {
+		handled, err := handleSummary(&lib.MiniRunner{}, data)
+		assert.NoError(t, err)
+		assert.False(t, handled)
+	})
+
+	t.Run("NotExported", func(t *testing.T) {
+		handled, err := handleSummary(&summaryHandlerRunner{}, data)
+		assert.NoError(t, err)
+		assert.False(t, handled)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		handled, err := handleSummary(&summaryHandlerRunner{ok: true, err: errors.New("oops")}, data)
+		assert.Error(t, err)
+		assert.False(t, handled)
+	})
+
+	t.Run("Handled", func(t *testing.T) {
+		r := &summaryHandlerRunner{ok: true, result: map[string]string{
+			"stdout":        "custom summary\n",
+			"/summary.html": "<html></html>",
+		}}
+		handled, err := handleSummary(r, data)
+		require.NoError(t, err)
+		assert.True(t, handled)
+		assert.True(t, json.Valid(r.summary))
+		assert.Equal(t, "custom summary\n", buf.String())
+		content, err := afero.ReadFile(defaultFs, "/summary.html")
+		require.NoError(t, err)
+		assert.Equal(t, "<html></html>", string(content))
+	})
+}
+
+func TestValidateCIAnnotations(t *testing.T) {
+	assert.NoError(t, validateCIAnnotations("github"))
+	assert.NoError(t, validateCIAnnotations("gitlab"))
+	assert.NoError(t, validateCIAnnotations("gitlab=report.json"))
+	assert.EqualError(t, validateCIAnnotations("jenkins"), "unknown CI annotations format: jenkins")
+}
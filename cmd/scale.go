@@ -24,7 +24,6 @@ import (
 	"context"
 
 	"github.com/loadimpact/k6/api/v1"
-	"github.com/loadimpact/k6/api/v1/client"
 	"github.com/loadimpact/k6/ui"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -36,7 +35,8 @@ var scaleCmd = &cobra.Command{
 	Short: "Scale a running test",
 	Long: `Scale a running test.
 
-  Use the global --address flag to specify the URL to the API server.`,
+  Use the global --address flag to specify the URL to the API server, and --api-token
+  if the server requires a token.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		vus := getNullInt64(cmd.Flags(), "vus")
 		max := getNullInt64(cmd.Flags(), "max")
@@ -44,7 +44,7 @@ var scaleCmd = &cobra.Command{
 			return errors.New("Specify either -u/--vus or -m/--max")
 		}
 
-		c, err := client.New(address)
+		c, err := newAPIClient()
 		if err != nil {
 			return err
 		}
@@ -23,7 +23,6 @@ package cmd
 import (
 	"context"
 
-	"github.com/loadimpact/k6/api/v1/client"
 	"github.com/loadimpact/k6/ui"
 	"github.com/spf13/cobra"
 )
@@ -36,7 +35,7 @@ var statsCmd = &cobra.Command{
 
   Use the global --address flag to specify the URL to the API server.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		c, err := client.New(address)
+		c, err := newAPIClient()
 		if err != nil {
 			return err
 		}
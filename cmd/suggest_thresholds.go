@@ -0,0 +1,165 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/loadimpact/k6/stats"
+	jsonc "github.com/loadimpact/k6/stats/json"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	suggestHeadroom   float64
+	suggestPercentile float64
+	suggestMetrics    []string
+)
+
+var suggestThresholdsCmd = &cobra.Command{
+	Use:   "suggest-thresholds [file]",
+	Short: "Suggest thresholds based on the results of a previous test run",
+	Long: `Suggest thresholds based on the results of a previous test run.
+
+Reads a file produced with the JSON output (-o json=file.json) and prints a
+thresholds block that can be used as a starting point for the test options.
+Trend metrics get a percentile threshold, while rate metrics get a threshold
+that allows the observed error budget to grow by the same headroom.`,
+	Example: `
+  # Save the metrics of a test run and suggest thresholds with 20% headroom.
+  k6 run -o json=results.json script.js
+  k6 suggest-thresholds results.json
+
+  # Suggest a p(99) threshold with 50% headroom only for http_req_duration.
+  k6 suggest-thresholds --percentile 99 --headroom 50 --metric http_req_duration results.json`[1:],
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if suggestPercentile <= 0 || suggestPercentile > 100 {
+			return errors.New("the percentile should be in the (0, 100] range")
+		}
+		if suggestHeadroom < 0 {
+			return errors.New("the headroom can't be negative")
+		}
+
+		filePath, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		f, err := defaultFs.Open(filePath)
+		if err != nil {
+			return err
+		}
+		res, err := jsonc.ReadResults(f)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+
+		metrics := res.Metrics
+		if len(suggestMetrics) > 0 {
+			metrics = make(map[string]*stats.Metric, len(suggestMetrics))
+			for _, name := range suggestMetrics {
+				m, ok := res.Metrics[name]
+				if !ok {
+					return errors.Errorf("metric '%s' wasn't found in %s", name, args[0])
+				}
+				metrics[name] = m
+			}
+		}
+
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"thresholds": suggestThresholds(metrics, suggestPercentile, suggestHeadroom/100),
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(defaultWriter, string(data))
+		return err
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(suggestThresholdsCmd)
+	suggestThresholdsCmd.Flags().SortFlags = false
+	suggestThresholdsCmd.Flags().Float64Var(&suggestHeadroom, "headroom", 20, "percentage added on top of the observed values")
+	suggestThresholdsCmd.Flags().Float64Var(&suggestPercentile, "percentile", 95, "percentile used for trend metrics")
+	suggestThresholdsCmd.Flags().StringSliceVar(&suggestMetrics, "metric", nil, "only suggest thresholds for these `metrics`")
+}
+
+// suggestThresholds returns threshold sources for all trend and rate metrics that have
+// any data. Counters and gauges are skipped, since their values depend too much on the
+// duration and the load profile of the particular test run.
+func suggestThresholds(metrics map[string]*stats.Metric, pct, headroom float64) map[string][]string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make(map[string][]string)
+	for _, name := range names {
+		switch sink := metrics[name].Sink.(type) {
+		case *stats.TrendSink:
+			if sink.Count == 0 {
+				continue
+			}
+			limit := roundThresholdValue(sink.P(pct/100)*(1+headroom), math.Ceil)
+			result[name] = []string{fmt.Sprintf("p(%s)<%s", formatThresholdValue(pct), formatThresholdValue(limit))}
+		case *stats.RateSink:
+			if sink.Total == 0 {
+				continue
+			}
+			rate := float64(sink.Trues) / float64(sink.Total)
+			// Rates above 50% are assumed to be success rates (e.g. checks), so we allow the
+			// failures to grow by the headroom. Lower rates are treated as error rates.
+			if rate >= 0.5 {
+				limit := 1 - (1-rate)*(1+headroom)
+				result[name] = []string{"rate>=" + formatThresholdValue(roundThresholdValue(limit, math.Floor))}
+			} else {
+				limit := math.Min(rate*(1+headroom), 1)
+				result[name] = []string{"rate<=" + formatThresholdValue(roundThresholdValue(limit, math.Ceil))}
+			}
+		}
+	}
+	return result
+}
+
+// roundThresholdValue rounds the value to a few significant decimal places in the
+// direction specified by the supplied rounding function, so the thresholds stay readable.
+func roundThresholdValue(v float64, round func(float64) float64) float64 {
+	if v == 0 || math.Abs(v) >= 100 {
+		return round(v)
+	}
+	scale := math.Pow(10, 2-math.Floor(math.Log10(math.Abs(v))))
+	// Get rid of any floating point noise first, so e.g. 300.00000000000006 isn't rounded up
+	scaled := math.Round(v*scale*1e6) / 1e6
+	return round(scaled) / scale
+}
+
+func formatThresholdValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
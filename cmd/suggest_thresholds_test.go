@@ -0,0 +1,70 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestThresholds(t *testing.T) {
+	addSamples := func(m *stats.Metric, values ...float64) *stats.Metric {
+		for _, v := range values {
+			m.Sink.Add(stats.Sample{Metric: m, Time: time.Now(), Value: v})
+		}
+		return m
+	}
+
+	metrics := map[string]*stats.Metric{
+		"http_req_duration": addSamples(stats.New("http_req_duration", stats.Trend, stats.Time), 100, 200, 300),
+		"checks":            addSamples(stats.New("checks", stats.Rate), 1, 1, 1, 0),
+		"errors":            addSamples(stats.New("errors", stats.Rate), 0, 0, 0, 1),
+		"iterations":        addSamples(stats.New("iterations", stats.Counter), 1, 1),
+		"empty":             stats.New("empty", stats.Trend),
+	}
+
+	assert.Equal(t, map[string][]string{
+		"http_req_duration": {"p(95)<348"},
+		"checks":            {"rate>=0.7"},
+		"errors":            {"rate<=0.3"},
+	}, suggestThresholds(metrics, 95, 0.2))
+}
+
+func TestRoundThresholdValue(t *testing.T) {
+	testdata := []struct {
+		value    float64
+		round    func(float64) float64
+		expected float64
+	}{
+		{1234.5, math.Ceil, 1235},
+		{12.345, math.Ceil, 12.4},
+		{0.98765, math.Floor, 0.987},
+		{0.012345, math.Ceil, 0.0124},
+		{0, math.Ceil, 0},
+	}
+	for _, data := range testdata {
+		assert.Equal(t, data.expected, roundThresholdValue(data.value, data.round))
+	}
+}
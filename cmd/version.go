@@ -23,6 +23,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/loadimpact/k6/js/modules"
 	"github.com/spf13/cobra"
 )
 
@@ -33,6 +34,12 @@ var versionCmd = &cobra.Command{
 	Long:  `Show the application version and exit.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("k6 v" + Version)
+		if exts := modules.GetExtensions(); len(exts) > 0 {
+			fmt.Println("Extensions:")
+			for _, ext := range exts {
+				fmt.Println("  " + ext)
+			}
+		}
 	},
 }
 
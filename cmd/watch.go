@@ -0,0 +1,180 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/core"
+	"github.com/loadimpact/k6/core/local"
+	"github.com/loadimpact/k6/k6exec"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/spf13/afero"
+)
+
+// How often the watched files are checked for changes.
+const watchInterval = 500 * time.Millisecond
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func getFileStates(fs afero.Fs, paths []string) map[string]fileState {
+	states := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		if fi, err := fs.Stat(path); err == nil {
+			states[path] = fileState{fi.ModTime(), fi.Size()}
+		}
+	}
+	return states
+}
+
+// watchFiles polls the supplied files and sends to the returned channel every time any of
+// them is changed, until the context is done. Files that don't exist are ignored, unless
+// they are created later.
+func watchFiles(ctx context.Context, fs afero.Fs, paths []string, interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	go func() {
+		states := getFileStates(fs, paths)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				newStates := getFileStates(fs, paths)
+				changed := len(newStates) != len(states)
+				for path, state := range newStates {
+					if states[path] != state {
+						changed = true
+					}
+				}
+				states = newStates
+				if changed {
+					select {
+					case changes <- struct{}{}:
+					default: // there's already a pending notification
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes
+}
+
+// getWatchedFiles returns the main script and all local files that were loaded by it.
+func getWatchedFiles(fs afero.Fs, filename string, r lib.Runner) []string {
+	paths := map[string]bool{filename: true}
+	if arc := r.MakeArchive(); arc != nil {
+		for path := range arc.Scripts {
+			paths[path] = true
+		}
+		for path := range arc.Files {
+			paths[path] = true
+		}
+	}
+
+	result := make([]string, 0, len(paths))
+	for path := range paths {
+		if _, err := fs.Stat(path); err == nil || path == filename {
+			result = append(result, path)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// persistentCollector wraps a collector that is already running independently from the
+// engine, so that it isn't stopped at the end of every engine run in watch mode.
+type persistentCollector struct {
+	lib.Collector
+}
+
+// Run just blocks until the context is done, since the wrapped collector is already running
+func (c persistentCollector) Run(ctx context.Context) {
+	<-ctx.Done()
+}
+
+// Collect passes the samples to the wrapped collector
+func (c persistentCollector) Collect(scs []stats.SampleContainer) {
+	c.Collector.Collect(scs)
+}
+
+// startPersistentCollectors starts all of the supplied collectors and returns them wrapped
+// in persistentCollector, along with a function that stops them and waits for them to finish.
+func startPersistentCollectors(collectors []lib.Collector) ([]lib.Collector, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+	wrapped := make([]lib.Collector, len(collectors))
+	for i, c := range collectors {
+		wg.Add(1)
+		go func(c lib.Collector) {
+			c.Run(ctx)
+			wg.Done()
+		}(c)
+		wrapped[i] = persistentCollector{c}
+	}
+	return wrapped, func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// reloadEngine creates a new runner from the current contents of the script and resets
+// the engine with it, so the test can be started again with the new code.
+func reloadEngine(
+	engine *core.Engine, filename, pwd string, fs afero.Fs, cliConf Config, rtOpts lib.RuntimeOptions,
+) (*lib.SourceData, Config, error) {
+	src, err := readSource(filename, pwd, fs, os.Stdin)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	r, err := newRunner(src, runType, fs, rtOpts)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	conf, err := getConsolidatedConfig(fs, cliConf, r)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	conf.Options = k6exec.ApplyExecutionDefaults(conf.Options)
+	if conf.Options, err = k6exec.ApplyExecutionSegment(conf.Options); err != nil {
+		return nil, Config{}, err
+	}
+	if err = r.SetOptions(conf.Options); err != nil {
+		return nil, Config{}, err
+	}
+
+	ex := local.NewForExecution(r, conf.Execution)
+	ex.SetRunSetup(!runNoSetup)
+	ex.SetRunTeardown(!runNoTeardown)
+	if err = engine.Reset(ex, conf.Options); err != nil {
+		return nil, Config{}, err
+	}
+	return src, conf, nil
+}
@@ -0,0 +1,89 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/stats/dummy"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/script.js", []byte("export default function() {}"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := watchFiles(ctx, fs, []string{"/script.js", "/data.json"}, 10*time.Millisecond)
+
+	assertChange := func(t *testing.T, expected bool) {
+		select {
+		case <-changes:
+			assert.True(t, expected, "unexpected change")
+		case <-time.After(100 * time.Millisecond):
+			assert.False(t, expected, "no change detected")
+		}
+	}
+
+	t.Run("NoChanges", func(t *testing.T) {
+		assertChange(t, false)
+	})
+	t.Run("Modified", func(t *testing.T) {
+		require.NoError(t, afero.WriteFile(fs, "/script.js", []byte("export default function() { }"), 0644))
+		assertChange(t, true)
+		assertChange(t, false)
+	})
+	t.Run("Created", func(t *testing.T) {
+		require.NoError(t, afero.WriteFile(fs, "/data.json", []byte("{}"), 0644))
+		assertChange(t, true)
+	})
+	t.Run("Removed", func(t *testing.T) {
+		require.NoError(t, fs.Remove("/data.json"))
+		assertChange(t, true)
+	})
+}
+
+func TestPersistentCollectors(t *testing.T) {
+	c := &dummy.Collector{}
+	wrapped, stop := startPersistentCollectors([]lib.Collector{c})
+	require.Len(t, wrapped, 1)
+
+	// Runs of the wrapped collector shouldn't affect the real one
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		wrapped[0].Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+	wrapped[0].Collect([]stats.SampleContainer{stats.Sample{Metric: stats.New("my_metric", stats.Gauge), Value: 1}})
+	assert.Len(t, c.Samples, 1)
+
+	stop()
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
 	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/guregu/null.v3"
 )
@@ -65,9 +66,15 @@ type Engine struct {
 	// Assigned to metrics upon first received sample.
 	thresholds map[string]stats.Thresholds
 	submetrics map[string][]*stats.Submetric
+	// How many sub-metrics were added with AddSubmetric, they're limited to MaxAddedSubmetrics.
+	addedSubmetrics int
 
 	// Are thresholds tainted?
 	thresholdsTainted bool
+
+	// Closed by Stop() to end the run.
+	stopC    chan struct{}
+	stopLock sync.Mutex
 }
 
 func NewEngine(ex lib.Executor, o lib.Options) (*Engine, error) {
@@ -75,24 +82,50 @@ func NewEngine(ex lib.Executor, o lib.Options) (*Engine, error) {
 		ex = local.New(nil)
 	}
 
-	e := &Engine{
-		Executor: ex,
-		Options:  o,
-		Metrics:  make(map[string]*stats.Metric),
-		Samples:  make(chan stats.SampleContainer, o.MetricSamplesBufferSize.Int64),
+	e := &Engine{logger: log.StandardLogger()}
+	if err := e.init(ex, o); err != nil {
+		return nil, err
 	}
-	e.SetLogger(log.StandardLogger())
+	return e, nil
+}
+
+// Reset prepares the engine for a new Run() with the supplied executor and options, e.g.
+// after the script was changed. The collectors and the engine settings are preserved, but
+// all metrics and threshold results from previous runs are discarded.
+func (e *Engine) Reset(ex lib.Executor, o lib.Options) error {
+	e.runLock.Lock()
+	defer e.runLock.Unlock()
+	e.MetricsLock.Lock()
+	defer e.MetricsLock.Unlock()
+
+	e.thresholdsTainted = false
+	return e.init(ex, o)
+}
+
+func (e *Engine) init(ex lib.Executor, o lib.Options) error {
+	e.Executor = ex
+	e.Options = o
+	e.Metrics = make(map[string]*stats.Metric)
+	e.Samples = make(chan stats.SampleContainer, o.MetricSamplesBufferSize.Int64)
+	e.SetLogger(e.logger)
+
+	e.stopLock.Lock()
+	e.stopC = make(chan struct{})
+	e.stopLock.Unlock()
 
 	if err := ex.SetVUsMax(o.VUsMax.Int64); err != nil {
-		return nil, err
+		return err
 	}
 	if err := ex.SetVUs(o.VUs.Int64); err != nil {
-		return nil, err
+		return err
 	}
 	ex.SetPaused(o.Paused.Bool)
 	ex.SetStages(o.Stages)
 	ex.SetEndTime(o.Duration)
 	ex.SetEndIterations(o.Iterations)
+	ex.SetArrivalRate(lib.GetArrivalRate(o.Execution))
+	ex.SetVUIterations(lib.GetVUIterations(o.Execution))
+	ex.SetGracefulStop(o.GracefulStop)
 
 	e.thresholds = o.Thresholds
 	e.submetrics = make(map[string][]*stats.Submetric)
@@ -105,7 +138,7 @@ func NewEngine(ex lib.Executor, o lib.Options) (*Engine, error) {
 		e.submetrics[parent] = append(e.submetrics[parent], sm)
 	}
 
-	return e, nil
+	return nil
 }
 
 func (e *Engine) setRunStatus(status lib.RunStatus) {
@@ -221,6 +254,10 @@ func (e *Engine) Run(ctx context.Context) error {
 		collectorwg.Wait()
 	}()
 
+	e.stopLock.Lock()
+	stopC := e.stopC
+	e.stopLock.Unlock()
+
 	ticker := time.NewTicker(CollectRate)
 	for {
 		select {
@@ -235,7 +272,11 @@ func (e *Engine) Run(ctx context.Context) error {
 			errC = nil
 			if err != nil {
 				e.logger.WithError(err).Debug("run: executor returned an error")
-				e.setRunStatus(lib.RunStatusAbortedSystem)
+				if _, ok := errors.Cause(err).(lib.TestAbortError); ok {
+					e.setRunStatus(lib.RunStatusAbortedUser)
+				} else {
+					e.setRunStatus(lib.RunStatusAbortedSystem)
+				}
 				return err
 			}
 			e.logger.Debug("run: executor terminated")
@@ -244,14 +285,77 @@ func (e *Engine) Run(ctx context.Context) error {
 			e.logger.Debug("run: context expired; exiting...")
 			e.setRunStatus(lib.RunStatusAbortedUser)
 			return nil
+		case <-stopC:
+			e.logger.Debug("run: stopped; exiting...")
+			e.setRunStatus(lib.RunStatusAbortedUser)
+			return nil
 		}
 	}
 }
 
+// Stop ends the test run, e.g. when it was requested through the REST API. The test can't be
+// resumed afterwards.
+func (e *Engine) Stop() {
+	e.stopLock.Lock()
+	defer e.stopLock.Unlock()
+	if !e.isStopped() {
+		close(e.stopC)
+	}
+}
+
+// IsStopped returns whether Stop() was called.
+func (e *Engine) IsStopped() bool {
+	e.stopLock.Lock()
+	defer e.stopLock.Unlock()
+	return e.isStopped()
+}
+
+func (e *Engine) isStopped() bool {
+	select {
+	case <-e.stopC:
+		return true
+	default:
+		return false
+	}
+}
+
 func (e *Engine) IsTainted() bool {
 	return e.thresholdsTainted
 }
 
+// MaxAddedSubmetrics is how many sub-metrics can be added with AddSubmetric. They're kept
+// until the end of the test, so they can't be added without a limit.
+const MaxAddedSubmetrics = 100
+
+// AddSubmetric starts aggregating the samples that match the sub-metric with the given name,
+// like "http_req_duration{status:200}", if it isn't done already. Only the samples processed
+// from then on are included, it shows up in Metrics once one of them matches. At most
+// MaxAddedSubmetrics can be added.
+func (e *Engine) AddSubmetric(name string) error {
+	if !strings.Contains(name, "{") {
+		return errors.Errorf("%s isn't a sub-metric, it has no tags", name)
+	}
+	parent, sm := stats.NewSubmetric(name)
+
+	e.MetricsLock.Lock()
+	defer e.MetricsLock.Unlock()
+
+	for _, existing := range e.submetrics[parent] {
+		if existing.Name == name {
+			return nil
+		}
+	}
+	if e.addedSubmetrics >= MaxAddedSubmetrics {
+		return errors.Errorf("can't add %s, only %d sub-metrics can be added", name, MaxAddedSubmetrics)
+	}
+	e.addedSubmetrics++
+	e.submetrics[parent] = append(e.submetrics[parent], sm)
+	if m, ok := e.Metrics[parent]; ok {
+		m.Submetrics = append(m.Submetrics, sm)
+	}
+	return nil
+}
+
 func (e *Engine) SetLogger(l *log.Logger) {
 	e.logger = l
 	e.Executor.SetLogger(l)
@@ -219,6 +219,35 @@ func TestNewEngineOptions(t *testing.T) {
 	})
 }
 
+func TestEngineReset(t *testing.T) {
+	e, err := newTestEngine(nil, lib.Options{
+		VUs:        null.IntFrom(2),
+		VUsMax:     null.IntFrom(2),
+		Iterations: null.IntFrom(10),
+	})
+	require.NoError(t, err)
+	c := &dummy.Collector{}
+	e.Collectors = []lib.Collector{c}
+	require.NoError(t, e.Run(context.Background()))
+	assert.Equal(t, int64(10), e.Executor.GetIterations())
+	assert.Contains(t, e.Metrics, metrics.Iterations.Name)
+
+	ex := local.New(nil)
+	require.NoError(t, e.Reset(ex, lib.Options{
+		VUs:                     null.IntFrom(1),
+		VUsMax:                  null.IntFrom(1),
+		Iterations:              null.IntFrom(5),
+		MetricSamplesBufferSize: null.IntFrom(200),
+	}))
+	assert.Equal(t, ex, e.Executor)
+	assert.Empty(t, e.Metrics)
+	assert.Equal(t, []lib.Collector{c}, e.Collectors)
+
+	require.NoError(t, e.Run(context.Background()))
+	assert.Equal(t, int64(5), e.Executor.GetIterations())
+	assert.Equal(t, int64(1), e.Executor.GetVUsMax())
+}
+
 func TestEngineRun(t *testing.T) {
 	log.SetLevel(log.DebugLevel)
 	t.Run("exits with context", func(t *testing.T) {
@@ -242,6 +271,23 @@ func TestEngineRun(t *testing.T) {
 		assert.NoError(t, e.Run(context.Background()))
 		assert.Equal(t, int64(100), e.Executor.GetIterations())
 	})
+	t.Run("exits when stopped", func(t *testing.T) {
+		e, err := newTestEngine(nil, lib.Options{})
+		assert.NoError(t, err)
+
+		errC := make(chan error)
+		go func() { errC <- e.Run(context.Background()) }()
+		assert.False(t, e.IsStopped())
+		e.Stop()
+		e.Stop()
+		assert.True(t, e.IsStopped())
+		select {
+		case err := <-errC:
+			assert.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("the engine didn't stop")
+		}
+	})
 
 	// Make sure samples are discarded after context close (using "cutoff" timestamp in local.go)
 	t.Run("collects samples", func(t *testing.T) {
@@ -371,6 +417,33 @@ func TestEngine_processSamples(t *testing.T) {
 		assert.IsType(t, &stats.GaugeSink{}, e.Metrics["my_metric"].Sink)
 		assert.IsType(t, &stats.GaugeSink{}, e.Metrics["my_metric{a:1}"].Sink)
 	})
+	t.Run("added submetric", func(t *testing.T) {
+		e, err := newTestEngine(nil, lib.Options{})
+		assert.NoError(t, err)
+
+		sample := stats.Sample{Metric: metric, Value: 1.25, Tags: stats.IntoSampleTags(&map[string]string{"a": "1"})}
+		e.processSamples([]stats.SampleContainer{sample})
+
+		assert.Error(t, e.AddSubmetric("my_metric"))
+		assert.NoError(t, e.AddSubmetric("my_metric{a:1}"))
+		assert.NoError(t, e.AddSubmetric("my_metric{a:1}"))
+		assert.NoError(t, e.AddSubmetric("my_metric{a:2}"))
+		assert.Len(t, e.Metrics["my_metric"].Submetrics, 2)
+		assert.NotContains(t, e.Metrics, "my_metric{a:1}")
+
+		sample.Value = 2.5
+		e.processSamples([]stats.SampleContainer{sample})
+		if assert.Contains(t, e.Metrics, "my_metric{a:1}") {
+			assert.Equal(t, 2.5, e.Metrics["my_metric{a:1}"].Sink.(*stats.GaugeSink).Value)
+		}
+		assert.NotContains(t, e.Metrics, "my_metric{a:2}")
+
+		for i := 3; i <= MaxAddedSubmetrics; i++ {
+			assert.NoError(t, e.AddSubmetric(fmt.Sprintf("my_metric{a:%d}", i)))
+		}
+		assert.Error(t, e.AddSubmetric("my_metric{a:0}"))
+		assert.NoError(t, e.AddSubmetric("my_metric{a:1}"))
+	})
 }
 
 func TestEngine_runThresholds(t *testing.T) {
@@ -735,7 +808,7 @@ func TestRunTags(t *testing.T) {
 
 	systemMetrics := []*stats.Metric{
 		metrics.VUs, metrics.VUsMax, metrics.Iterations, metrics.IterationDuration,
-		metrics.GroupDuration, metrics.DataSent, metrics.DataReceived,
+		metrics.IterationFailed, metrics.GroupDuration, metrics.DataSent, metrics.DataReceived,
 	}
 
 	getExpectedOverVal := func(metricName string) string {
@@ -44,6 +44,17 @@ import (
 
 var _ lib.Executor = &Executor{}
 
+// detachedContext carries the values of its parent, but isn't cancelled with it. The VUs run
+// with one, so they can get a graceful stop after the test's context is cancelled.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c detachedContext) Done() <-chan struct{}             { return nil }
+func (c detachedContext) Err() error                        { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
 type vuHandle struct {
 	sync.RWMutex
 	vu     lib.VU
@@ -51,12 +62,14 @@ type vuHandle struct {
 	cancel context.CancelFunc
 }
 
-func (h *vuHandle) run(logger *log.Logger, flow <-chan int64, iterDone chan<- struct{}) {
+func (h *vuHandle) run(
+	logger *log.Logger, flow <-chan int64, iterDone chan<- struct{}, aborted chan<- error, maxIters int64,
+) {
 	h.RLock()
 	ctx := h.ctx
 	h.RUnlock()
 
-	for {
+	for iters := int64(0); maxIters < 0 || iters < maxIters; iters++ {
 		select {
 		case _, ok := <-flow:
 			if !ok {
@@ -68,15 +81,27 @@ func (h *vuHandle) run(logger *log.Logger, flow <-chan int64, iterDone chan<- st
 
 		if h.vu != nil {
 			err := h.vu.RunOnce(ctx)
+			if _, ok := errors.Cause(err).(lib.TestAbortError); ok {
+				// Only the first abort matters, the test is ending anyway.
+				select {
+				case aborted <- err:
+				default:
+				}
+				return
+			}
 			select {
 			case <-ctx.Done():
 			// Don't log errors or emit iterations metrics from cancelled iterations
 			default:
 				if err != nil {
+					entry := log.NewEntry(logger)
+					if ierr, ok := err.(lib.IterationError); ok {
+						entry = entry.WithFields(ierr.LogFields())
+					}
 					if s, ok := err.(fmt.Stringer); ok {
-						logger.Error(s.String())
+						entry.Error(s.String())
 					} else {
-						logger.Error(err.Error())
+						entry.Error(err.Error())
 					}
 				}
 				iterDone <- struct{}{}
@@ -103,18 +128,28 @@ type Executor struct {
 	numVUsMax int64
 	nextVUID  int64
 
+	// The partition of the execution segment, which the IDs of the VUs are numbered in, so
+	// that they're unique among the instances that run the segments of the test. The arrival
+	// rate is the one of the whole test, the segment starts its part of those iterations.
+	partition *lib.ExecutionPartition
+
 	iters     int64 // Completed iterations
 	partIters int64 // Partial, incomplete iterations
 	endIters  int64 // End test at this many iterations
+	vuIters   int64 // Every VU stops after this many iterations
 
 	time    int64 // Current time
 	endTime int64 // End test at this timestamp
 
+	gracefulStop int64 // Time running iterations get to finish when the test ends
+
 	pauseLock sync.RWMutex
 	pause     chan interface{}
 
 	stages []lib.Stage
 
+	arrivalRate *lib.ArrivalRate
+
 	// Lock for: ctx, flow, out
 	lock sync.RWMutex
 
@@ -127,25 +162,36 @@ type Executor struct {
 	// Channel on which VUs sigal that iterations are completed
 	iterDone chan struct{}
 
+	// Channel on which VUs signal that the script aborted the test.
+	aborted chan error
+
 	// Flow control for VUs; iterations are run only after reading from this channel.
 	flow chan int64
 }
 
 func New(r lib.Runner) *Executor {
 	var bufferSize int64
+	var partition *lib.ExecutionPartition
 	if r != nil {
-		bufferSize = r.GetOptions().MetricSamplesBufferSize.Int64
+		opts := r.GetOptions()
+		bufferSize = opts.MetricSamplesBufferSize.Int64
+		// The options are validated, so the segment can be partitioned.
+		partition, _ = lib.NewExecutionPartition(opts.ExecutionSegment, opts.ExecutionSegmentSequence)
 	}
 
 	return &Executor{
-		Runner:      r,
-		Logger:      log.StandardLogger(),
-		runSetup:    true,
-		runTeardown: true,
-		endIters:    -1,
-		endTime:     -1,
-		vuOut:       make(chan stats.SampleContainer, bufferSize),
-		iterDone:    make(chan struct{}),
+		Runner:       r,
+		Logger:       log.StandardLogger(),
+		runSetup:     true,
+		runTeardown:  true,
+		endIters:     -1,
+		vuIters:      -1,
+		endTime:      -1,
+		gracefulStop: -1,
+		vuOut:        make(chan stats.SampleContainer, bufferSize),
+		iterDone:     make(chan struct{}),
+		aborted:      make(chan error, 1),
+		partition:    partition,
 	}
 }
 
@@ -159,7 +205,11 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 		}
 	}
 
-	ctx, cancel := context.WithCancel(parent)
+	// The VUs aren't cancelled with the parent context, see the graceful stop below.
+	ctx, cancel := context.WithCancel(detachedContext{parent})
+	if lib.GetExecutionStart(ctx).IsZero() {
+		ctx = lib.WithExecutionStart(ctx, time.Now())
+	}
 	vuFlow := make(chan int64)
 	e.lock.Lock()
 	vuOut := e.vuOut
@@ -169,7 +219,21 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 	e.lock.Unlock()
 
 	var cutoff time.Time
+	var graceful bool
 	defer func() {
+		close(vuFlow)
+
+		// If the test ran out of time or was stopped, the iterations that are still running may
+		// get to finish before the VUs are interrupted. Their samples are kept, and the ones of
+		// the iterations that had to be interrupted are tagged with interrupted=true.
+		// Without one, a stopped test still interrupts the VUs right away, not after the teardown.
+		if graceful && e.waitGracefully(vuOut, iterDone, engineOut) {
+			cutoff = time.Time{}
+			cancel()
+		} else if parent.Err() != nil {
+			cancel()
+		}
+
 		if e.Runner != nil && e.runTeardown {
 			err := e.Runner.Teardown(parent, engineOut)
 			if reterr == nil {
@@ -179,7 +243,6 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 			}
 		}
 
-		close(vuFlow)
 		cancel()
 
 		e.lock.Lock()
@@ -221,6 +284,12 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 		}
 	}()
 
+	// The part of a test that runs on several instances can be left without any iterations.
+	if atomic.LoadInt64(&e.endIters) == 0 {
+		e.Logger.Debug("Local: No iterations to run")
+		return nil
+	}
+
 	startVUs := atomic.LoadInt64(&e.numVUs)
 	if err := e.scale(ctx, lib.Max(0, startVUs)); err != nil {
 		return err
@@ -229,6 +298,9 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 	ticker := time.NewTicker(1 * time.Millisecond)
 	defer ticker.Stop()
 
+	// Iterations that had to be started by the last tick, if there's an arrival rate.
+	var dueIters int64
+
 	lastTick := time.Now()
 	for {
 		// If the test is paused, sleep until either the pause or the test ends.
@@ -243,7 +315,7 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 			case <-pause:
 				e.Logger.Debug("Local: No longer paused")
 				lastTick = time.Now().Add(-leftovers)
-			case <-ctx.Done():
+			case <-parent.Done():
 				e.Logger.Debug("Local: Terminated while in paused state")
 				return nil
 			}
@@ -258,6 +330,10 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 		if end >= 0 && partials >= end {
 			flow = nil
 		}
+		if arrivalRate := e.arrivalRate; arrivalRate != nil &&
+			partials >= arrivalRate.PartitionIterationsAt(time.Duration(atomic.LoadInt64(&e.time)), e.partition) {
+			flow = nil
+		}
 
 		select {
 		case flow <- partials:
@@ -274,7 +350,10 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 			at := time.Duration(atomic.AddInt64(&e.time, int64(d)))
 			if end >= 0 && at >= end {
 				e.Logger.WithFields(log.Fields{"at": at, "end": end}).Debug("Local: Hit time limit")
-				cutoff = time.Now()
+				cutoff, graceful = time.Now(), true
+				if endIters := atomic.LoadInt64(&e.endIters); endIters >= 0 {
+					e.emitDroppedIterations(endIters-atomic.LoadInt64(&e.partIters), engineOut)
+				}
 				return nil
 			}
 
@@ -283,7 +362,7 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 				vus, keepRunning := ProcessStages(startVUs, stages, at)
 				if !keepRunning {
 					e.Logger.WithField("at", at).Debug("Local: Ran out of stages")
-					cutoff = time.Now()
+					cutoff, graceful = time.Now(), true
 					return nil
 				}
 				if vus.Valid {
@@ -292,38 +371,131 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 					}
 				}
 			}
+
+			if arrivalRate := e.arrivalRate; arrivalRate != nil {
+				if err := e.processArrivalRate(dueIters, engineOut); err != nil {
+					return err
+				}
+				dueIters = arrivalRate.PartitionIterationsAt(at, e.partition)
+			}
 		case sampleContainer := <-vuOut:
 			engineOut <- sampleContainer
 		case <-iterDone:
 			// Every iteration ends with a write to iterDone. Check if we've hit the end point.
 			// If not, make sure to include an Iterations bump in the list!
-			var tags *stats.SampleTags
-			if e.Runner != nil {
-				tags = e.Runner.GetOptions().RunTags
-			}
-			engineOut <- stats.Sample{
-				Time:   time.Now(),
-				Metric: metrics.Iterations,
-				Value:  1,
-				Tags:   tags,
-			}
-
 			end := atomic.LoadInt64(&e.endIters)
-			at := atomic.AddInt64(&e.iters, 1)
+			at := e.emitIteration(engineOut)
 			if end >= 0 && at >= end {
 				e.Logger.WithFields(log.Fields{"at": at, "end": end}).Debug("Local: Hit iteration limit")
 				return nil
 			}
-		case <-ctx.Done():
+		case err := <-e.aborted:
+			e.Logger.WithError(err).Debug("Local: Aborted by the script")
+			cutoff = time.Now()
+			return err
+		case <-parent.Done():
 			// If the test is cancelled, just set the cutoff point to now and proceed down the same
 			// logic as if the time limit was hit.
 			e.Logger.Debug("Local: Exiting with context")
-			cutoff = time.Now()
+			cutoff, graceful = time.Now(), true
 			return nil
 		}
 	}
 }
 
+// waitGracefully gives the iterations that are still running up to the graceful stop to finish,
+// forwarding their samples and counting them in the meantime. It returns false if there's no
+// graceful stop.
+func (e *Executor) waitGracefully(
+	vuOut <-chan stats.SampleContainer, iterDone <-chan struct{}, engineOut chan<- stats.SampleContainer,
+) bool {
+	gracefulStop := time.Duration(atomic.LoadInt64(&e.gracefulStop))
+	if gracefulStop <= 0 {
+		return false
+	}
+	e.Logger.WithField("gracefulStop", gracefulStop).Debug("Local: Waiting for running iterations")
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(gracefulStop)
+	defer timer.Stop()
+	for {
+		select {
+		case <-done:
+			return true
+		case <-timer.C:
+			e.Logger.Debug("Local: Interrupting the iterations still running after the graceful stop")
+			return true
+		case sampleContainer := <-vuOut:
+			engineOut <- sampleContainer
+		case <-iterDone:
+			e.emitIteration(engineOut)
+		}
+	}
+}
+
+// emitIteration counts a completed iteration and emits a sample for it, returning the new count.
+func (e *Executor) emitIteration(engineOut chan<- stats.SampleContainer) int64 {
+	var tags *stats.SampleTags
+	if e.Runner != nil {
+		tags = e.Runner.GetOptions().RunTags
+	}
+	engineOut <- stats.Sample{
+		Time:   time.Now(),
+		Metric: metrics.Iterations,
+		Value:  1,
+		Tags:   tags,
+	}
+	return atomic.AddInt64(&e.iters, 1)
+}
+
+// processArrivalRate makes sure all the iterations that were due by the last tick have
+// started. If all active VUs are busy, more are activated, up to the max; if there are
+// still not enough VUs, the rest of the overdue iterations are dropped.
+func (e *Executor) processArrivalRate(due int64, engineOut chan<- stats.SampleContainer) error {
+	overdue := due - atomic.LoadInt64(&e.partIters)
+	if overdue <= 0 {
+		return nil
+	}
+
+	vus := atomic.LoadInt64(&e.numVUs)
+	if added := lib.Min(overdue, atomic.LoadInt64(&e.numVUsMax)-vus); added > 0 {
+		e.Logger.WithField("vus", vus+added).Debug("Local: Not enough VUs for the arrival rate")
+		if err := e.SetVUs(vus + added); err != nil {
+			return err
+		}
+		overdue -= added
+	}
+	if overdue <= 0 {
+		return nil
+	}
+
+	atomic.AddInt64(&e.partIters, overdue)
+	e.emitDroppedIterations(overdue, engineOut)
+	return nil
+}
+
+// emitDroppedIterations emits a sample with the number of iterations that weren't started.
+func (e *Executor) emitDroppedIterations(n int64, engineOut chan<- stats.SampleContainer) {
+	if n <= 0 {
+		return
+	}
+	var tags *stats.SampleTags
+	if e.Runner != nil {
+		tags = e.Runner.GetOptions().RunTags
+	}
+	engineOut <- stats.Sample{
+		Time:   time.Now(),
+		Metric: metrics.DroppedIterations,
+		Value:  float64(n),
+		Tags:   tags,
+	}
+}
+
 func (e *Executor) scale(ctx context.Context, num int64) error {
 	e.Logger.WithField("num", num).Debug("Local: Scaling...")
 
@@ -334,6 +506,7 @@ func (e *Executor) scale(ctx context.Context, num int64) error {
 	flow := e.flow
 	iterDone := e.iterDone
 	e.lock.RUnlock()
+	vuIters := atomic.LoadInt64(&e.vuIters)
 
 	for i, handle := range e.vus {
 		handle := handle
@@ -350,14 +523,15 @@ func (e *Executor) scale(ctx context.Context, num int64) error {
 				handle.Unlock()
 
 				if handle.vu != nil {
-					if err := handle.vu.Reconfigure(atomic.AddInt64(&e.nextVUID, 1)); err != nil {
+					id := e.partition.Index(atomic.AddInt64(&e.nextVUID, 1)-1) + 1
+					if err := handle.vu.Reconfigure(id); err != nil {
 						return err
 					}
 				}
 
 				e.wg.Add(1)
 				go func() {
-					handle.run(e.Logger, flow, iterDone)
+					handle.run(e.Logger, flow, iterDone, e.aborted, vuIters)
 					e.wg.Done()
 				}()
 			}
@@ -399,6 +573,14 @@ func (e *Executor) SetStages(s []lib.Stage) {
 	e.stages = s
 }
 
+func (e *Executor) GetArrivalRate() *lib.ArrivalRate {
+	return e.arrivalRate
+}
+
+func (e *Executor) SetArrivalRate(r *lib.ArrivalRate) {
+	e.arrivalRate = r
+}
+
 func (e *Executor) GetIterations() int64 {
 	return atomic.LoadInt64(&e.iters)
 }
@@ -419,6 +601,22 @@ func (e *Executor) SetEndIterations(i null.Int) {
 	atomic.StoreInt64(&e.endIters, i.Int64)
 }
 
+func (e *Executor) GetVUIterations() null.Int {
+	v := atomic.LoadInt64(&e.vuIters)
+	if v < 0 {
+		return null.Int{}
+	}
+	return null.IntFrom(v)
+}
+
+func (e *Executor) SetVUIterations(i null.Int) {
+	if !i.Valid {
+		i.Int64 = -1
+	}
+	e.Logger.WithField("i", i.Int64).Debug("Local: Setting iterations per VU")
+	atomic.StoreInt64(&e.vuIters, i.Int64)
+}
+
 func (e *Executor) GetTime() time.Duration {
 	return time.Duration(atomic.LoadInt64(&e.time))
 }
@@ -439,6 +637,22 @@ func (e *Executor) SetEndTime(t types.NullDuration) {
 	atomic.StoreInt64(&e.endTime, int64(t.Duration))
 }
 
+func (e *Executor) GetGracefulStop() types.NullDuration {
+	v := atomic.LoadInt64(&e.gracefulStop)
+	if v < 0 {
+		return types.NullDuration{}
+	}
+	return types.NullDurationFrom(time.Duration(v))
+}
+
+func (e *Executor) SetGracefulStop(d types.NullDuration) {
+	if !d.Valid {
+		d.Duration = -1
+	}
+	e.Logger.WithField("d", d.Duration).Debug("Local: Setting graceful stop")
+	atomic.StoreInt64(&e.gracefulStop, int64(d.Duration))
+}
+
 func (e *Executor) IsPaused() bool {
 	e.pauseLock.RLock()
 	defer e.pauseLock.RUnlock()
@@ -24,6 +24,7 @@ import (
 	"context"
 	"net"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -33,9 +34,11 @@ import (
 	"github.com/loadimpact/k6/js"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/scheduler"
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
@@ -144,6 +147,29 @@ func TestExecutorSetupTeardownRun(t *testing.T) {
 	})
 }
 
+type testIterationError struct{ error }
+
+func (testIterationError) LogFields() log.Fields {
+	return log.Fields{"group": "::login"}
+}
+
+func TestExecutorIterationErrorFields(t *testing.T) {
+	e := New(&lib.MiniRunner{Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+		return testIterationError{errors.New("login failed")}
+	}})
+	l, hook := logtest.NewNullLogger()
+	e.SetLogger(l)
+	assert.NoError(t, e.SetVUsMax(1))
+	assert.NoError(t, e.SetVUs(1))
+	e.SetEndIterations(null.IntFrom(1))
+	assert.NoError(t, e.Run(context.Background(), make(chan stats.SampleContainer, 100)))
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "login failed", entry.Message)
+	assert.Equal(t, log.Fields{"group": "::login"}, entry.Data)
+}
+
 func TestExecutorSetLogger(t *testing.T) {
 	logger, _ := logtest.NewNullLogger()
 	e := New(nil)
@@ -296,6 +322,260 @@ func TestExecutorEndIterations(t *testing.T) {
 	}
 }
 
+func TestExecutorExecutionStart(t *testing.T) {
+	var mutex sync.Mutex
+	var starts []time.Time
+	e := New(&lib.MiniRunner{Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		starts = append(starts, lib.GetExecutionStart(ctx))
+		return nil
+	}})
+	assert.NoError(t, e.SetVUsMax(2))
+	assert.NoError(t, e.SetVUs(2))
+	e.SetEndIterations(null.IntFrom(10))
+
+	before := time.Now()
+	assert.NoError(t, e.Run(context.Background(), make(chan stats.SampleContainer, 100)))
+	require.NotEmpty(t, starts)
+	for _, start := range starts {
+		assert.False(t, start.Before(before))
+		assert.Equal(t, starts[0], start)
+	}
+}
+
+func TestExecutorAbortTest(t *testing.T) {
+	var teardown int64
+	e := New(&lib.MiniRunner{
+		Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			return lib.NewTestAbortError("stop")
+		},
+		TeardownFn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			atomic.AddInt64(&teardown, 1)
+			return nil
+		},
+	})
+	assert.NoError(t, e.SetVUsMax(5))
+	assert.NoError(t, e.SetVUs(5))
+
+	err := e.Run(context.Background(), make(chan stats.SampleContainer, 100))
+	assert.Equal(t, lib.NewTestAbortError("stop"), err)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&teardown))
+}
+
+func TestExecutorGracefulStop(t *testing.T) {
+	run := func(t *testing.T, ctx context.Context, gracefulStop types.NullDuration) (int64, int64) {
+		var interrupted int64
+		e := New(&lib.MiniRunner{Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			select {
+			case <-time.After(100 * time.Millisecond):
+				return nil
+			case <-ctx.Done():
+				atomic.AddInt64(&interrupted, 1)
+				return ctx.Err()
+			}
+		}})
+		require.NoError(t, e.SetVUsMax(2))
+		require.NoError(t, e.SetVUs(2))
+		e.SetVUIterations(null.IntFrom(1))
+		e.SetEndTime(types.NullDurationFrom(50 * time.Millisecond))
+		e.SetGracefulStop(gracefulStop)
+		assert.Equal(t, gracefulStop, e.GetGracefulStop())
+
+		require.NoError(t, e.Run(ctx, make(chan stats.SampleContainer, 100)))
+		return e.GetIterations(), atomic.LoadInt64(&interrupted)
+	}
+
+	t.Run("None", func(t *testing.T) {
+		iters, interrupted := run(t, context.Background(), types.NullDuration{})
+		assert.Equal(t, int64(0), iters)
+		assert.Equal(t, int64(2), interrupted)
+	})
+	t.Run("Finished", func(t *testing.T) {
+		iters, interrupted := run(t, context.Background(), types.NullDurationFrom(time.Second))
+		assert.Equal(t, int64(2), iters)
+		assert.Equal(t, int64(0), interrupted)
+	})
+	t.Run("RanOut", func(t *testing.T) {
+		iters, interrupted := run(t, context.Background(), types.NullDurationFrom(10*time.Millisecond))
+		assert.Equal(t, int64(0), iters)
+		assert.Equal(t, int64(2), interrupted)
+	})
+	t.Run("Stopped", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		iters, interrupted := run(t, ctx, types.NullDurationFrom(time.Second))
+		assert.Equal(t, int64(2), iters)
+		assert.Equal(t, int64(0), interrupted)
+	})
+}
+
+func TestExecutorStoppedWithoutGracefulStop(t *testing.T) {
+	interrupted := make(chan struct{}, 1)
+	var teardownStart, vuStop time.Time
+	e := New(&lib.MiniRunner{
+		Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			<-ctx.Done()
+			vuStop = time.Now()
+			interrupted <- struct{}{}
+			return ctx.Err()
+		},
+		TeardownFn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			teardownStart = time.Now()
+			time.Sleep(200 * time.Millisecond)
+			return nil
+		},
+	})
+	require.NoError(t, e.SetVUsMax(1))
+	require.NoError(t, e.SetVUs(1))
+	e.SetVUIterations(null.IntFrom(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.NoError(t, e.Run(ctx, make(chan stats.SampleContainer, 100)))
+	select {
+	case <-interrupted:
+		assert.True(t, vuStop.Before(teardownStart.Add(100*time.Millisecond)),
+			"the VU was interrupted after the teardown")
+	default:
+		t.Fatal("the VU wasn't interrupted")
+	}
+}
+
+func TestExecutorEndTimeDroppedIterations(t *testing.T) {
+	e := New(&lib.MiniRunner{Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}})
+	require.NoError(t, e.SetVUsMax(1))
+	require.NoError(t, e.SetVUs(1))
+	e.SetEndIterations(null.IntFrom(100))
+	e.SetEndTime(types.NullDurationFrom(100 * time.Millisecond))
+
+	samples := make(chan stats.SampleContainer, 100)
+	var dropped float64
+	done := make(chan struct{})
+	go func() {
+		for sc := range samples {
+			for _, s := range sc.GetSamples() {
+				if s.Metric == metrics.DroppedIterations {
+					dropped += s.Value
+				}
+			}
+		}
+		close(done)
+	}()
+	require.NoError(t, e.Run(context.Background(), samples))
+	close(samples)
+	<-done
+	assert.InDelta(t, 95, dropped, 5)
+	assert.True(t, float64(e.GetIterations())+dropped <= 100)
+}
+
+func TestExecutorVUIterations(t *testing.T) {
+	// The first iteration is slow, so without a limit the other VU would run all the rest.
+	var calls int64
+	e := New(&lib.MiniRunner{Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+		if atomic.AddInt64(&calls, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		return nil
+	}})
+	require.NoError(t, e.SetVUsMax(2))
+	require.NoError(t, e.SetVUs(2))
+	e.SetEndIterations(null.IntFrom(6))
+	e.SetVUIterations(null.IntFrom(3))
+	assert.Equal(t, null.IntFrom(3), e.GetVUIterations())
+
+	samples := make(chan stats.SampleContainer, 100)
+	defer close(samples)
+	go func() {
+		for range samples {
+		}
+	}()
+	err := make(chan error, 1)
+	go func() { err <- e.Run(context.Background(), samples) }()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int64(3), e.GetIterations())
+	assert.NoError(t, <-err)
+	assert.Equal(t, int64(6), e.GetIterations())
+}
+
+func TestExecutorArrivalRate(t *testing.T) {
+	run := func(iterDuration time.Duration, vus, vusMax int64) (e *Executor, iters, dropped float64) {
+		e = New(&lib.MiniRunner{Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			select {
+			case <-ctx.Done():
+			case <-time.After(iterDuration):
+			}
+			return nil
+		}})
+		require.NoError(t, e.SetVUsMax(vusMax))
+		require.NoError(t, e.SetVUs(vus))
+		e.SetEndTime(types.NullDurationFrom(500 * time.Millisecond))
+		e.SetArrivalRate(&lib.ArrivalRate{Rate: 10, TimeUnit: 100 * time.Millisecond})
+		assert.Equal(t, &lib.ArrivalRate{Rate: 10, TimeUnit: 100 * time.Millisecond}, e.GetArrivalRate())
+
+		samples := make(chan stats.SampleContainer, 100)
+		done := make(chan struct{})
+		go func() {
+			for sc := range samples {
+				for _, s := range sc.GetSamples() {
+					switch s.Metric {
+					case metrics.Iterations:
+						iters += s.Value
+					case metrics.DroppedIterations:
+						dropped += s.Value
+					}
+				}
+			}
+			close(done)
+		}()
+		require.NoError(t, e.Run(context.Background(), samples))
+		close(samples)
+		<-done
+		return e, iters, dropped
+	}
+
+	t.Run("enough VUs", func(t *testing.T) {
+		e, iters, dropped := run(0, 2, 2)
+		assert.InDelta(t, 50, iters, 10)
+		assert.Equal(t, float64(0), dropped)
+		assert.Equal(t, int64(2), e.GetVUs())
+	})
+	t.Run("more VUs", func(t *testing.T) {
+		e, iters, dropped := run(20*time.Millisecond, 1, 5)
+		assert.InDelta(t, 50, iters, 10)
+		assert.Equal(t, float64(0), dropped)
+		assert.True(t, e.GetVUs() > 1)
+	})
+	t.Run("dropped iterations", func(t *testing.T) {
+		e, iters, dropped := run(100*time.Millisecond, 1, 2)
+		assert.True(t, iters < 15, "iterations: %f", iters)
+		assert.True(t, dropped > 20, "dropped iterations: %f", dropped)
+		assert.Equal(t, int64(2), e.GetVUs())
+	})
+	t.Run("ramping", func(t *testing.T) {
+		e := New(&lib.MiniRunner{})
+		require.NoError(t, e.SetVUsMax(1))
+		e.SetEndTime(types.NullDurationFrom(500 * time.Millisecond))
+		e.SetArrivalRate(&lib.ArrivalRate{Rate: 0, TimeUnit: 10 * time.Millisecond, Stages: []scheduler.Stage{
+			{Duration: types.NullDurationFrom(500 * time.Millisecond), Target: null.IntFrom(2)},
+		}})
+
+		samples := make(chan stats.SampleContainer, 100)
+		go func() {
+			for range samples {
+			}
+		}()
+		require.NoError(t, e.Run(context.Background(), samples))
+		close(samples)
+		assert.InDelta(t, 50, e.GetIterations(), 10)
+		assert.Equal(t, int64(1), e.GetVUs())
+	})
+}
+
 func TestExecutorIsRunning(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	e := New(nil)
@@ -567,20 +847,24 @@ func TestRealTimeAndSetupTeardownMetrics(t *testing.T) {
 	expectIn(0, 5000, getSample(1, testCounter, "group", "::setup", "place", "setupBeforeSleep"))
 	expectIn(900, 1100, getSample(2, testCounter, "group", "::setup", "place", "setupAfterSleep"))
 	expectIn(0, 100, getDummyTrail("::setup"))
+	expectIn(0, 100, getSample(0, metrics.IterationFailed, "group", "::setup"))
 
 	expectIn(0, 100, getSample(5, testCounter, "group", "", "place", "defaultBeforeSleep"))
 	expectIn(900, 1100, getSample(6, testCounter, "group", "", "place", "defaultAfterSleep"))
 	expectIn(0, 100, getDummyTrail(""))
+	expectIn(0, 100, getSample(0, metrics.IterationFailed, "group", ""))
 	expectIn(0, 100, getSample(1, metrics.Iterations))
 
 	expectIn(0, 100, getSample(5, testCounter, "group", "", "place", "defaultBeforeSleep"))
 	expectIn(900, 1100, getSample(6, testCounter, "group", "", "place", "defaultAfterSleep"))
 	expectIn(0, 100, getDummyTrail(""))
+	expectIn(0, 100, getSample(0, metrics.IterationFailed, "group", ""))
 	expectIn(0, 100, getSample(1, metrics.Iterations))
 
 	expectIn(0, 1000, getSample(3, testCounter, "group", "::teardown", "place", "teardownBeforeSleep"))
 	expectIn(900, 1100, getSample(4, testCounter, "group", "::teardown", "place", "teardownAfterSleep"))
 	expectIn(0, 100, getDummyTrail("::teardown"))
+	expectIn(0, 100, getSample(0, metrics.IterationFailed, "group", "::teardown"))
 
 	for {
 		select {
@@ -0,0 +1,395 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/scheduler"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	null "gopkg.in/guregu/null.v3"
+)
+
+var _ lib.Executor = &ScenariosExecutor{}
+
+// NewForExecution returns a ScenariosExecutor if the execution config has scenarios, and a plain
+// Executor otherwise.
+func NewForExecution(r lib.Runner, execution scheduler.ConfigMap) lib.Executor {
+	if scenarios := lib.Scenarios(execution); scenarios != nil {
+		return NewScenarios(r, scenarios)
+	}
+	return New(r)
+}
+
+// scenarioRunner makes an Executor spawn the VUs of a scenario and tag its samples.
+type scenarioRunner struct {
+	lib.Runner
+	conf scheduler.BaseConfig
+	tags *stats.SampleTags
+}
+
+func (r scenarioRunner) NewVU(out chan<- stats.SampleContainer) (lib.VU, error) {
+	if sr, ok := r.Runner.(lib.ScenarioRunner); ok {
+		return sr.NewScenarioVU(out, r.conf)
+	}
+	if r.conf.Exec.Valid {
+		return nil, errors.New("exec isn't supported for this script")
+	}
+	return r.Runner.NewVU(out)
+}
+
+func (r scenarioRunner) GetOptions() lib.Options {
+	opts := r.Runner.GetOptions()
+	opts.RunTags = r.tags
+	return opts
+}
+
+type scenario struct {
+	conf     scheduler.Config
+	opts     lib.Options // Execution options derived from the config
+	executor *Executor
+}
+
+// A ScenariosExecutor runs the scenarios of a test at the same time, each one with its own
+// Executor, VUs and schedule, starting at its startTime. The VUs, stages, end time and iterations
+// of the test are set by the scenarios, they can't be changed.
+type ScenariosExecutor struct {
+	Runner lib.Runner
+	Logger *log.Logger
+
+	runLock sync.Mutex
+
+	runSetup    bool
+	runTeardown bool
+
+	scenarios  []*scenario
+	configured bool
+
+	// Graceful stop of the scenarios that don't set their own.
+	gracefulStop types.NullDuration
+
+	running int32
+	paused  int32
+	time    int64 // Current time
+}
+
+// NewScenarios returns a ScenariosExecutor for the scheduler configs.
+func NewScenarios(r lib.Runner, configs []scheduler.Config) *ScenariosExecutor {
+	e := &ScenariosExecutor{
+		Runner:      r,
+		Logger:      log.StandardLogger(),
+		runSetup:    true,
+		runTeardown: true,
+	}
+	for _, conf := range configs {
+		var sr lib.Runner
+		if r != nil {
+			bc := conf.GetBaseConfig()
+			sr = scenarioRunner{Runner: r, conf: bc, tags: lib.ScenarioTags(r.GetOptions().RunTags, bc)}
+		}
+		ex := New(sr)
+		ex.SetRunSetup(false)
+		ex.SetRunTeardown(false)
+		e.scenarios = append(e.scenarios, &scenario{
+			conf:     conf,
+			opts:     lib.ApplySchedulerOptions(lib.Options{}, conf),
+			executor: ex,
+		})
+	}
+	return e
+}
+
+func (e *ScenariosExecutor) Run(parent context.Context, engineOut chan<- stats.SampleContainer) (reterr error) {
+	e.runLock.Lock()
+	defer e.runLock.Unlock()
+
+	if e.Runner != nil && e.runSetup {
+		if err := e.Runner.Setup(parent, engineOut); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	ctx = lib.WithExecutionStart(ctx, time.Now())
+	atomic.StoreInt32(&e.running, 1)
+	defer atomic.StoreInt32(&e.running, 0)
+
+	errs := make(chan error, len(e.scenarios))
+	started := make([]bool, len(e.scenarios))
+	var running, waiting int
+	startScenarios := func() {
+		at := e.GetTime()
+		waiting = 0
+		for i, s := range e.scenarios {
+			if started[i] {
+				continue
+			}
+			if startTime := time.Duration(s.conf.GetBaseConfig().StartTime.Duration); at < startTime {
+				waiting++
+				continue
+			}
+			e.Logger.WithFields(log.Fields{"scenario": s.conf.GetBaseConfig().Name, "at": at}).
+				Debug("Local: Starting scenario")
+			started[i] = true
+			running++
+			go func(ex *Executor) { errs <- ex.Run(ctx, engineOut) }(s.executor)
+		}
+	}
+	startScenarios()
+
+	ticker := time.NewTicker(1 * time.Millisecond)
+	defer ticker.Stop()
+
+	lastTick := time.Now()
+	done := ctx.Done()
+	for running > 0 || (done != nil && waiting > 0) {
+		select {
+		case t := <-ticker.C:
+			if !e.IsPaused() {
+				atomic.AddInt64(&e.time, int64(t.Sub(lastTick)))
+			}
+			lastTick = t
+			if done != nil {
+				startScenarios()
+			}
+		case err := <-errs:
+			running--
+			if err != nil && reterr == nil {
+				reterr = err
+				cancel()
+			}
+		case <-done:
+			// Don't start any more scenarios, but wait for the running ones to stop.
+			done = nil
+		}
+	}
+
+	if e.Runner != nil && e.runTeardown {
+		err := e.Runner.Teardown(parent, engineOut)
+		if reterr == nil {
+			reterr = err
+		} else if err != nil {
+			reterr = fmt.Errorf("teardown error %#v\nPrevious error: %#v", err, reterr)
+		}
+	}
+	return reterr
+}
+
+func (e *ScenariosExecutor) IsRunning() bool {
+	return atomic.LoadInt32(&e.running) == 1
+}
+
+func (e *ScenariosExecutor) GetRunner() lib.Runner {
+	return e.Runner
+}
+
+func (e *ScenariosExecutor) SetLogger(l *log.Logger) {
+	e.Logger = l
+	for _, s := range e.scenarios {
+		s.executor.SetLogger(l)
+	}
+}
+
+func (e *ScenariosExecutor) GetLogger() *log.Logger {
+	return e.Logger
+}
+
+// GetStages returns nil, every scenario has its own stages.
+func (e *ScenariosExecutor) GetStages() []lib.Stage {
+	return nil
+}
+
+// SetStages does nothing, the stages are set by the scenarios.
+func (e *ScenariosExecutor) SetStages(s []lib.Stage) {}
+
+// GetIterations returns the completed iterations of all scenarios.
+func (e *ScenariosExecutor) GetIterations() int64 {
+	var iters int64
+	for _, s := range e.scenarios {
+		iters += s.executor.GetIterations()
+	}
+	return iters
+}
+
+// GetEndIterations returns the iterations of all scenarios, if they all end after a number of them.
+func (e *ScenariosExecutor) GetEndIterations() null.Int {
+	var end null.Int
+	for _, s := range e.scenarios {
+		if !s.opts.Iterations.Valid {
+			return null.Int{}
+		}
+		end = null.IntFrom(end.Int64 + s.opts.Iterations.Int64)
+	}
+	return end
+}
+
+// SetEndIterations does nothing, the iterations are set by the scenarios.
+func (e *ScenariosExecutor) SetEndIterations(i null.Int) {}
+
+func (e *ScenariosExecutor) GetTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&e.time))
+}
+
+// GetEndTime returns when the last scenario ends, if they all have a duration.
+func (e *ScenariosExecutor) GetEndTime() types.NullDuration {
+	var end types.NullDuration
+	for _, s := range e.scenarios {
+		if !s.opts.Duration.Valid {
+			return types.NullDuration{}
+		}
+		d := s.conf.GetBaseConfig().StartTime.Duration + s.opts.Duration.Duration
+		if !end.Valid || d > end.Duration {
+			end = types.NullDuration{Duration: d, Valid: true}
+		}
+	}
+	return end
+}
+
+// SetEndTime does nothing, the durations are set by the scenarios.
+func (e *ScenariosExecutor) SetEndTime(t types.NullDuration) {}
+
+func (e *ScenariosExecutor) IsPaused() bool {
+	return atomic.LoadInt32(&e.paused) == 1
+}
+
+func (e *ScenariosExecutor) SetPaused(paused bool) {
+	if paused {
+		atomic.StoreInt32(&e.paused, 1)
+	} else {
+		atomic.StoreInt32(&e.paused, 0)
+	}
+	for _, s := range e.scenarios {
+		s.executor.SetPaused(paused)
+	}
+}
+
+// GetVUs returns the active VUs of all scenarios.
+func (e *ScenariosExecutor) GetVUs() int64 {
+	var vus int64
+	for _, s := range e.scenarios {
+		if e.configured {
+			vus += s.executor.GetVUs()
+		} else {
+			vus += s.opts.VUs.Int64
+		}
+	}
+	return vus
+}
+
+// SetVUs returns an error unless the number is the same as the current one, since every scenario
+// has its own VUs.
+func (e *ScenariosExecutor) SetVUs(num int64) error {
+	if vus := e.GetVUs(); num != vus {
+		return errors.Errorf("can't change the vu count of a test with scenarios (to %d from %d)", num, vus)
+	}
+	return nil
+}
+
+// GetVUsMax returns the max VUs of all scenarios.
+func (e *ScenariosExecutor) GetVUsMax() int64 {
+	var max int64
+	for _, s := range e.scenarios {
+		max += s.conf.GetMaxVUs()
+	}
+	return max
+}
+
+// SetVUsMax allocates the VUs of all scenarios and sets up their executors the first time it's
+// called. The number has to be the sum of the max VUs of the scenarios.
+func (e *ScenariosExecutor) SetVUsMax(max int64) error {
+	if vusMax := e.GetVUsMax(); max != vusMax {
+		return errors.Errorf("can't change the vu cap of a test with scenarios (to %d from %d)", max, vusMax)
+	}
+	if e.configured {
+		return nil
+	}
+
+	for _, s := range e.scenarios {
+		ex := s.executor
+		if err := ex.SetVUsMax(s.conf.GetMaxVUs()); err != nil {
+			return errors.Wrapf(err, "scenario %s", s.conf.GetBaseConfig().Name)
+		}
+		if err := ex.SetVUs(s.opts.VUs.Int64); err != nil {
+			return errors.Wrapf(err, "scenario %s", s.conf.GetBaseConfig().Name)
+		}
+		ex.SetStages(s.opts.Stages)
+		ex.SetEndTime(s.opts.Duration)
+		ex.SetEndIterations(s.opts.Iterations)
+		ex.SetArrivalRate(lib.SchedulerArrivalRate(s.conf))
+		ex.SetVUIterations(lib.SchedulerVUIterations(s.conf))
+		ex.SetGracefulStop(e.scenarioGracefulStop(s))
+	}
+	e.configured = true
+	return nil
+}
+
+func (e *ScenariosExecutor) SetRunSetup(r bool) {
+	e.runSetup = r
+}
+
+func (e *ScenariosExecutor) SetRunTeardown(r bool) {
+	e.runTeardown = r
+}
+
+// GetArrivalRate returns nil, every scenario has its own arrival rate.
+func (e *ScenariosExecutor) GetArrivalRate() *lib.ArrivalRate {
+	return nil
+}
+
+// SetArrivalRate does nothing, the arrival rates are set by the scenarios.
+func (e *ScenariosExecutor) SetArrivalRate(r *lib.ArrivalRate) {}
+
+// GetVUIterations returns an invalid value, every scenario has its own iterations.
+func (e *ScenariosExecutor) GetVUIterations() null.Int {
+	return null.Int{}
+}
+
+// SetVUIterations does nothing, the iterations are set by the scenarios.
+func (e *ScenariosExecutor) SetVUIterations(i null.Int) {}
+
+// GetGracefulStop returns the graceful stop of the scenarios that don't set their own.
+func (e *ScenariosExecutor) GetGracefulStop() types.NullDuration {
+	return e.gracefulStop
+}
+
+// SetGracefulStop sets the graceful stop of the scenarios that don't set their own.
+func (e *ScenariosExecutor) SetGracefulStop(d types.NullDuration) {
+	e.gracefulStop = d
+	for _, s := range e.scenarios {
+		s.executor.SetGracefulStop(e.scenarioGracefulStop(s))
+	}
+}
+
+func (e *ScenariosExecutor) scenarioGracefulStop(s *scenario) types.NullDuration {
+	if s.opts.GracefulStop.Valid {
+		return s.opts.GracefulStop
+	}
+	return e.gracefulStop
+}
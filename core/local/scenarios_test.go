@@ -0,0 +1,138 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package local
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/scheduler"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestScenariosExecutor(t *testing.T) {
+	var setups, teardowns int64
+	r := &lib.MiniRunner{
+		Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error { return nil },
+		SetupFn: func(ctx context.Context, out chan<- stats.SampleContainer) ([]byte, error) {
+			atomic.AddInt64(&setups, 1)
+			return nil, nil
+		},
+		TeardownFn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			atomic.AddInt64(&teardowns, 1)
+			return nil
+		},
+	}
+
+	sic := scheduler.NewSharedIterationsConfig("browse")
+	sic.VUs = null.IntFrom(2)
+	sic.Iterations = null.IntFrom(10)
+	pvic := scheduler.NewPerVUIterationsConfig("checkout")
+	pvic.VUs = null.IntFrom(1)
+	pvic.Iterations = null.IntFrom(5)
+	pvic.StartTime = types.NullDurationFrom(100 * time.Millisecond)
+	pvic.Tags = map[string]string{"flow": "buy"}
+
+	ex := NewForExecution(r, scheduler.ConfigMap{"browse": sic, "checkout": pvic})
+	e, ok := ex.(*ScenariosExecutor)
+	require.True(t, ok)
+	assert.Equal(t, int64(3), e.GetVUsMax())
+	assert.Equal(t, int64(3), e.GetVUs())
+	assert.Equal(t, null.IntFrom(15), e.GetEndIterations())
+	assert.False(t, e.GetEndTime().Valid)
+
+	assert.EqualError(t, e.SetVUsMax(10), "can't change the vu cap of a test with scenarios (to 10 from 3)")
+	require.NoError(t, e.SetVUsMax(3))
+	require.NoError(t, e.SetVUs(3))
+	assert.EqualError(t, e.SetVUs(1), "can't change the vu count of a test with scenarios (to 1 from 3)")
+
+	samples := make(chan stats.SampleContainer, 100)
+	iters := map[string]float64{}
+	done := make(chan struct{})
+	go func() {
+		for sc := range samples {
+			for _, s := range sc.GetSamples() {
+				if s.Metric != metrics.Iterations {
+					continue
+				}
+				scenario, _ := s.Tags.Get("scenario")
+				iters[scenario] += s.Value
+				if scenario == "checkout" {
+					flow, _ := s.Tags.Get("flow")
+					assert.Equal(t, "buy", flow)
+				}
+			}
+		}
+		close(done)
+	}()
+	require.NoError(t, e.Run(context.Background(), samples))
+	close(samples)
+	<-done
+
+	assert.Equal(t, map[string]float64{"browse": 10, "checkout": 5}, iters)
+	assert.Equal(t, int64(15), e.GetIterations())
+	assert.True(t, e.GetTime() >= 100*time.Millisecond)
+	assert.Equal(t, int64(1), setups)
+	assert.Equal(t, int64(1), teardowns)
+	assert.False(t, e.IsRunning())
+}
+
+func TestScenariosExecutorExec(t *testing.T) {
+	clvc := scheduler.NewConstantLoopingVUsConfig("admin")
+	clvc.VUs = null.IntFrom(1)
+	clvc.Duration = types.NullDurationFrom(time.Second)
+	clvc.Exec = null.StringFrom("admin")
+	e := NewScenarios(&lib.MiniRunner{}, []scheduler.Config{clvc})
+	assert.Equal(t, types.NullDurationFrom(time.Second), e.GetEndTime())
+	assert.EqualError(t, e.SetVUsMax(1), "scenario admin: exec isn't supported for this script")
+}
+
+func TestScenariosExecutorCancel(t *testing.T) {
+	clvc := scheduler.NewConstantLoopingVUsConfig("a")
+	clvc.VUs = null.IntFrom(1)
+	clvc.Duration = types.NullDurationFrom(time.Hour)
+	late := scheduler.NewConstantLoopingVUsConfig("b")
+	late.VUs = null.IntFrom(1)
+	late.Duration = types.NullDurationFrom(time.Hour)
+	late.StartTime = types.NullDurationFrom(time.Hour)
+
+	e := NewScenarios(&lib.MiniRunner{}, []scheduler.Config{clvc, late})
+	require.NoError(t, e.SetVUsMax(2))
+
+	samples := make(chan stats.SampleContainer, 100)
+	defer close(samples)
+	go func() {
+		for range samples {
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, e.Run(ctx, samples))
+	assert.Equal(t, int64(0), e.scenarios[1].executor.GetIterations())
+}
@@ -267,6 +267,22 @@ func TestProcessStages(t *testing.T) {
 				{10 * time.Second, true, null.NewInt(10, true)},
 			},
 		},
+		"ramp": {
+			0,
+			[]lib.Stage{
+				{Duration: types.NullDurationFrom(2 * time.Minute), Target: null.IntFrom(50)},
+				{Duration: types.NullDurationFrom(5 * time.Minute), Target: null.IntFrom(200)},
+			},
+			[]checkpoint{
+				{0 * time.Second, true, null.NewInt(0, true)},
+				{1 * time.Minute, true, null.NewInt(25, true)},
+				{2 * time.Minute, true, null.NewInt(50, true)},
+				{3 * time.Minute, true, null.NewInt(80, true)},
+				{4*time.Minute + 30*time.Second, true, null.NewInt(125, true)},
+				{7 * time.Minute, true, null.NewInt(200, true)},
+				{7*time.Minute + 1*time.Second, false, null.NewInt(200, true)},
+			},
+		},
 		"infinite": {
 			0,
 			[]lib.Stage{{}},
@@ -84,7 +84,7 @@ func NewBundle(src *lib.SourceData, fs afero.Fs, rtOpts lib.RuntimeOptions) (*Bu
 		BaseInitContext: NewInitContext(rt, compiler, new(context.Context), cachedFS, loader.Dir(src.Filename)),
 		Env:             rtOpts.Env,
 	}
-	if err := bundle.instantiate(rt, bundle.BaseInitContext); err != nil {
+	if err := bundle.instantiate(rt, bundle.BaseInitContext, bundle.Env); err != nil {
 		return nil, err
 	}
 
@@ -125,6 +125,10 @@ func NewBundle(src *lib.SourceData, fs afero.Fs, rtOpts lib.RuntimeOptions) (*Bu
 			if _, ok := goja.AssertFunction(v); !ok {
 				return nil, errors.New("exported 'teardown' must be a function")
 			}
+		case "handleSummary":
+			if _, ok := goja.AssertFunction(v); !ok {
+				return nil, errors.New("exported 'handleSummary' must be a function")
+			}
 		}
 	}
 
@@ -197,7 +201,13 @@ func (b *Bundle) makeArchive() *lib.Archive {
 }
 
 // Instantiate creates a new runtime from this bundle.
-func (b *Bundle) Instantiate() (bi *BundleInstance, instErr error) {
+func (b *Bundle) Instantiate() (*BundleInstance, error) {
+	return b.instantiateWithEnv(b.Env)
+}
+
+// instantiateWithEnv creates a new runtime from this bundle, with the supplied environment
+// variables as __ENV, so that the init code already sees them.
+func (b *Bundle) instantiateWithEnv(env map[string]string) (bi *BundleInstance, instErr error) {
 	// Placeholder for a real context.
 	ctxPtr := new(context.Context)
 
@@ -205,7 +215,7 @@ func (b *Bundle) Instantiate() (bi *BundleInstance, instErr error) {
 	// runtime, but no state, to allow module-provided types to function within the init context.
 	rt := goja.New()
 	init := newBoundInitContext(b.BaseInitContext, ctxPtr, rt)
-	if err := b.instantiate(rt, init); err != nil {
+	if err := b.instantiate(rt, init, env); err != nil {
 		return nil, err
 	}
 
@@ -239,7 +249,7 @@ func (b *Bundle) Instantiate() (bi *BundleInstance, instErr error) {
 
 // Instantiates the bundle into an existing runtime. Not public because it also messes with a bunch
 // of other things, will potentially thrash data and makes a mess in it if the operation fails.
-func (b *Bundle) instantiate(rt *goja.Runtime, init *InitContext) error {
+func (b *Bundle) instantiate(rt *goja.Runtime, init *InitContext, env map[string]string) error {
 	rt.SetFieldNameMapper(common.FieldNameMapper{})
 	rt.SetRandSource(common.NewRandSource())
 
@@ -253,7 +263,7 @@ func (b *Bundle) instantiate(rt *goja.Runtime, init *InitContext) error {
 	_ = module.Set("exports", exports)
 	rt.Set("module", module)
 
-	rt.Set("__ENV", b.Env)
+	rt.Set("__ENV", env)
 
 	*init.ctxPtr = common.WithRuntime(context.Background(), rt)
 	unbindInit := common.BindToGlobal(rt, common.Bind(rt, init, init.ctxPtr))
@@ -80,10 +80,14 @@ func FieldName(t reflect.Type, f reflect.StructField) string {
 // the name of the method in js
 //nolint: gochecknoglobals
 var methodNameExceptions = map[string]string{
-	"JSON": "json",
-	"HTML": "html",
-	"URL":  "url",
-	"OCSP": "ocsp",
+	"JSON":          "json",
+	"HTML":          "html",
+	"URL":           "url",
+	"OCSP":          "ocsp",
+	"GraphQL":       "graphql",
+	"GraphQLData":   "graphqlData",
+	"GraphQLErrors": "graphqlErrors",
+	"UUID":          "uuid",
 }
 
 // MethodName Returns the JS name for an exported method. The first letter of the method's name is
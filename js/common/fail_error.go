@@ -0,0 +1,53 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import "github.com/dop251/goja"
+
+// FailError is the error thrown by fail() of the k6 module, with the group and the check the VU was
+// in when it was called, if any.
+type FailError struct {
+	Message string
+	Group   string
+	Check   string
+}
+
+func (e *FailError) Error() string {
+	return e.Message
+}
+
+// GetFailError returns the FailError thrown by fail(), if err is the exception it caused, or nil.
+func GetFailError(err error) *FailError {
+	ex, ok := err.(*goja.Exception)
+	if !ok {
+		return nil
+	}
+	obj, ok := ex.Value().(*goja.Object)
+	if !ok {
+		return nil
+	}
+	v := obj.Get("value")
+	if v == nil {
+		return nil
+	}
+	fe, _ := v.Export().(*FailError)
+	return fe
+}
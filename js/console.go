@@ -26,10 +26,12 @@ import (
 	"strconv"
 
 	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/lib"
 	log "github.com/sirupsen/logrus"
 )
 
-// console represents a JS console implemented as a logrus.Logger.
+// console represents a JS console implemented as a logrus.Logger. In a VU, the messages have the
+// VU, the iteration and the group they were logged in as fields.
 type console struct {
 	Logger *log.Logger
 }
@@ -51,6 +53,10 @@ func newFileConsole(filepath string) (*console, error) {
 
 	//TODO: refactor to not rely on global variables, albeit external ones
 	l.SetFormatter(log.StandardLogger().Formatter)
+	if _, ok := l.Formatter.(*log.TextFormatter); ok {
+		// The terminal may be colored, but the file shouldn't be.
+		l.SetFormatter(&log.TextFormatter{DisableColors: true})
+	}
 
 	return &console{l}, nil
 }
@@ -65,6 +71,15 @@ func (c console) log(ctx *context.Context, level log.Level, msgobj goja.Value, a
 	}
 
 	fields := make(log.Fields)
+	if ctx != nil && *ctx != nil {
+		if state := lib.GetState(*ctx); state != nil {
+			fields["vu"] = state.Vu
+			fields["iter"] = state.Iteration
+			if state.Group != nil && state.Group.Path != "" {
+				fields["group"] = state.Group.Path
+			}
+		}
+	}
 	for i, arg := range args {
 		fields[strconv.Itoa(i)] = arg.String()
 	}
@@ -37,6 +37,7 @@ import (
 	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConsoleContext(t *testing.T) {
@@ -115,9 +116,10 @@ func TestConsole(t *testing.T) {
 						assert.Equal(t, level, entry.Level)
 						assert.Equal(t, result.Message, entry.Message)
 
-						data := result.Data
-						if data == nil {
-							data = make(log.Fields)
+						// Messages logged in a VU have its ID and iteration as fields.
+						data := log.Fields{"vu": int64(0), "iter": int64(0)}
+						for k, v := range result.Data {
+							data[k] = v
 						}
 						assert.Equal(t, data, entry.Data)
 					}
@@ -127,6 +129,31 @@ func TestConsole(t *testing.T) {
 	}
 }
 
+func TestConsoleFields(t *testing.T) {
+	r, err := New(&lib.SourceData{
+		Filename: "/script",
+		Data: []byte(`
+			import { group } from "k6";
+			export default function() { group("login", () => { console.warn("in", "login"); }); }
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	vu, err := r.newVU(make(chan stats.SampleContainer, 100))
+	require.NoError(t, err)
+	require.NoError(t, vu.Reconfigure(5))
+	logger, hook := logtest.NewNullLogger()
+	vu.Console.Logger = logger
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, vu.RunOnce(context.Background()))
+	}
+	if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+		assert.Equal(t, "in", entry.Message)
+		assert.Equal(t, log.Fields{"vu": int64(5), "iter": int64(1), "group": "::login", "0": "login"}, entry.Data)
+	}
+}
+
 func TestFileConsole(t *testing.T) {
 	var (
 		levels = map[string]log.Level{
@@ -212,9 +239,10 @@ func TestFileConsole(t *testing.T) {
 								assert.Equal(t, level, entry.Level)
 								assert.Equal(t, result.Message, entry.Message)
 
-								data := result.Data
-								if data == nil {
-									data = make(log.Fields)
+								// Messages logged in a VU have its ID and iteration as fields.
+								data := log.Fields{"vu": int64(0), "iter": int64(0)}
+								for k, v := range result.Data {
+									data[k] = v
 								}
 								assert.Equal(t, data, entry.Data)
 
@@ -24,6 +24,7 @@ import (
 	"context"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
@@ -56,6 +57,29 @@ type InitContext struct {
 	// Cache of loaded programs and files.
 	programs map[string]programWithSource
 	files    map[string][]byte
+
+	// The instances of the modules that have one per VU, so that they are shared by all the
+	// files of the script.
+	moduleInstances map[string]interface{}
+
+	// The instances of the modules that have one per bundle, shared by all of its VUs.
+	bundleModules *bundleModuleInstances
+}
+
+type bundleModuleInstances struct {
+	mutex     sync.Mutex
+	instances map[string]interface{}
+}
+
+func (b *bundleModuleInstances) get(name string, perBundle modules.HasModuleInstancePerBundle) interface{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	instance, ok := b.instances[name]
+	if !ok {
+		instance = perBundle.NewModuleInstancePerBundle()
+		b.instances[name] = instance
+	}
+	return instance
 }
 
 // NewInitContext creates a new initcontext with the provided arguments
@@ -71,6 +95,9 @@ func NewInitContext(
 
 		programs: make(map[string]programWithSource),
 		files:    make(map[string][]byte),
+
+		moduleInstances: make(map[string]interface{}),
+		bundleModules:   &bundleModuleInstances{instances: make(map[string]interface{})},
 	}
 }
 
@@ -95,6 +122,9 @@ func newBoundInitContext(base *InitContext, ctxPtr *context.Context, rt *goja.Ru
 
 		programs: programs,
 		files:    base.files,
+
+		moduleInstances: make(map[string]interface{}),
+		bundleModules:   base.bundleModules,
 	}
 }
 
@@ -122,8 +152,22 @@ func (i *InitContext) Require(arg string) goja.Value {
 func (i *InitContext) requireModule(name string) (goja.Value, error) {
 	mod, ok := modules.Index[name]
 	if !ok {
+		if strings.HasPrefix(name, modules.ExtensionPrefix) {
+			return nil, errors.Errorf("unknown extension module: %s, make sure that k6 was built with it", name)
+		}
 		return nil, errors.Errorf("unknown builtin module: %s", name)
 	}
+	if perBundle, ok := mod.(modules.HasModuleInstancePerBundle); ok {
+		mod = i.bundleModules.get(name, perBundle)
+	}
+	if perVU, ok := mod.(modules.HasModuleInstancePerVU); ok {
+		if instance, ok := i.moduleInstances[name]; ok {
+			mod = instance
+		} else {
+			mod = perVU.NewModuleInstancePerVU()
+			i.moduleInstances[name] = mod
+		}
+	}
 	return i.runtime.ToValue(common.Bind(i.runtime, mod, i.ctxPtr)), nil
 }
 
@@ -33,6 +33,7 @@ import (
 
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/js/modules"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/netext"
 	"github.com/loadimpact/k6/stats"
@@ -42,6 +43,21 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+type testExtension struct{}
+
+func (testExtension) Greet(name string) string { return "hello, " + name }
+
+type testPerVUExtension struct{ count int }
+
+func (e *testPerVUExtension) Inc() int { e.count++; return e.count }
+
+func (e *testPerVUExtension) NewModuleInstancePerVU() interface{} { return &testPerVUExtension{} }
+
+func init() {
+	modules.Register("initcontext-test", testExtension{})
+	modules.Register("initcontext-per-vu-test", &testPerVUExtension{})
+}
+
 func TestInitContextRequire(t *testing.T) {
 	t.Run("Modules", func(t *testing.T) {
 		t.Run("Nonexistent", func(t *testing.T) {
@@ -49,6 +65,64 @@ func TestInitContextRequire(t *testing.T) {
 			assert.EqualError(t, err, "GoError: unknown builtin module: k6/NONEXISTENT")
 		})
 
+		t.Run("NonexistentExtension", func(t *testing.T) {
+			_, err := getSimpleBundle("/script.js", `import "k6/x/NONEXISTENT";`)
+			assert.EqualError(t, err,
+				"GoError: unknown extension module: k6/x/NONEXISTENT, make sure that k6 was built with it")
+		})
+
+		t.Run("Extension", func(t *testing.T) {
+			b, err := getSimpleBundle("/script.js", `
+					import ext from "k6/x/initcontext-test";
+					export let greeting = ext.greet("world");
+					export default function() {}
+			`)
+			if !assert.NoError(t, err) {
+				return
+			}
+			bi, err := b.Instantiate()
+			if assert.NoError(t, err) {
+				assert.Equal(t, "hello, world", bi.Runtime.Get("exports").ToObject(bi.Runtime).Get("greeting").String())
+			}
+		})
+
+		t.Run("ExtensionPerVU", func(t *testing.T) {
+			b, err := getSimpleBundle("/script.js", `
+					const a = require("k6/x/initcontext-per-vu-test");
+					const b = require("k6/x/initcontext-per-vu-test");
+					a.inc();
+					export let count = b.inc();
+					export default function() {}
+			`)
+			if !assert.NoError(t, err) {
+				return
+			}
+			for i := 0; i < 2; i++ {
+				bi, err := b.Instantiate()
+				if assert.NoError(t, err) {
+					assert.Equal(t, int64(2), bi.Runtime.Get("exports").ToObject(bi.Runtime).Get("count").ToInteger())
+				}
+			}
+		})
+
+		t.Run("SharedArrayPerBundle", func(t *testing.T) {
+			for _, value := range []string{"first", "second"} {
+				b, err := getSimpleBundle("/script.js", `
+					import { SharedArray } from "k6/data";
+					const arr = new SharedArray("values", function() { return ["`+value+`"]; });
+					export let value = arr.get(0);
+					export default function() {}
+				`)
+				if !assert.NoError(t, err) {
+					return
+				}
+				bi, err := b.Instantiate()
+				if assert.NoError(t, err) {
+					assert.Equal(t, value, bi.Runtime.Get("exports").ToObject(bi.Runtime).Get("value").String())
+				}
+			}
+		})
+
 		t.Run("k6", func(t *testing.T) {
 			b, err := getSimpleBundle("/script.js", `
 					import k6 from "k6";
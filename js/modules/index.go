@@ -21,22 +21,92 @@
 package modules
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
 	"github.com/loadimpact/k6/js/modules/k6"
 	"github.com/loadimpact/k6/js/modules/k6/crypto"
+	"github.com/loadimpact/k6/js/modules/k6/data"
+	"github.com/loadimpact/k6/js/modules/k6/data/csv"
 	"github.com/loadimpact/k6/js/modules/k6/encoding"
+	"github.com/loadimpact/k6/js/modules/k6/execution"
+	"github.com/loadimpact/k6/js/modules/k6/faker"
+	"github.com/loadimpact/k6/js/modules/k6/grpc"
 	"github.com/loadimpact/k6/js/modules/k6/html"
 	"github.com/loadimpact/k6/js/modules/k6/http"
 	"github.com/loadimpact/k6/js/modules/k6/metrics"
+	"github.com/loadimpact/k6/js/modules/k6/sse"
+	"github.com/loadimpact/k6/js/modules/k6/tcp"
 	"github.com/loadimpact/k6/js/modules/k6/ws"
 )
 
 // Index of module implementations.
 var Index = map[string]interface{}{
-	"k6":          k6.New(),
-	"k6/crypto":   crypto.New(),
-	"k6/encoding": encoding.New(),
-	"k6/http":     http.New(),
-	"k6/metrics":  metrics.New(),
-	"k6/html":     html.New(),
-	"k6/ws":       ws.New(),
+	"k6":           k6.New(),
+	"k6/crypto":    crypto.New(),
+	"k6/data":      data.New(),
+	"k6/data/csv":  csv.New(),
+	"k6/encoding":  encoding.New(),
+	"k6/execution": execution.New(),
+	"k6/faker":     faker.New(),
+	"k6/grpc":      grpc.New(),
+	"k6/http":      http.New(),
+	"k6/metrics":   metrics.New(),
+	"k6/sse":       sse.New(),
+	"k6/tcp":       tcp.New(),
+	"k6/html":      html.New(),
+	"k6/ws":        ws.New(),
+}
+
+// HasModuleInstancePerVU is implemented by modules that need a separate instance in every VU,
+// e.g. because they keep state that is set by the init context of the script.
+type HasModuleInstancePerVU interface {
+	NewModuleInstancePerVU() interface{}
+}
+
+// HasModuleInstancePerBundle is implemented by modules that keep state shared by all the VUs
+// of a test, which mustn't leak into other tests run by the same process.
+type HasModuleInstancePerBundle interface {
+	NewModuleInstancePerBundle() interface{}
+}
+
+// ExtensionPrefix is the import path prefix of all modules registered with Register().
+const ExtensionPrefix = "k6/x/"
+
+var extensionNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+(/[a-zA-Z0-9_-]+)*$`)
+
+// Register makes a module implemented in Go available to scripts as `k6/x/<name>`. The
+// module is bound to the JS runtime the same way the builtin modules are, so its exported
+// methods are accessible with their names in camelCase and can take a context.Context as
+// their first argument.
+//
+// It's meant to be called from the init() function of the package implementing the module,
+// so custom k6 binaries can include extensions simply by importing their packages. It panics
+// if the name is invalid or a module with the same name was already registered.
+func Register(name string, mod interface{}) {
+	if !extensionNameRegexp.MatchString(name) {
+		panic(fmt.Sprintf("invalid extension module name '%s'", name))
+	}
+	if mod == nil {
+		panic(fmt.Sprintf("extension module '%s' is nil", name))
+	}
+	path := ExtensionPrefix + name
+	if _, ok := Index[path]; ok {
+		panic(fmt.Sprintf("extension module '%s' is already registered", path))
+	}
+	Index[path] = mod
+}
+
+// GetExtensions returns the sorted import paths of all registered extension modules.
+func GetExtensions() []string {
+	var result []string
+	for path := range Index {
+		if strings.HasPrefix(path, ExtensionPrefix) {
+			result = append(result, path)
+		}
+	}
+	sort.Strings(result)
+	return result
 }
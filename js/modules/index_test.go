@@ -0,0 +1,48 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package modules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	defer func() {
+		delete(Index, "k6/x/register-test")
+		delete(Index, "k6/x/register-test/sub")
+	}()
+
+	mod := struct{}{}
+	assert.NotPanics(t, func() { Register("register-test", mod) })
+	assert.NotPanics(t, func() { Register("register-test/sub", mod) })
+	assert.Equal(t, mod, Index["k6/x/register-test"])
+	assert.Equal(t, []string{"k6/x/register-test", "k6/x/register-test/sub"}, GetExtensions())
+
+	assert.PanicsWithValue(t, "extension module 'k6/x/register-test' is already registered", func() {
+		Register("register-test", mod)
+	})
+	for _, name := range []string{"", "a b", "../http", "trailing/", "/leading"} {
+		assert.Panics(t, func() { Register(name, mod) }, name)
+	}
+	assert.Panics(t, func() { Register("nil-module", nil) })
+}
@@ -140,6 +140,9 @@ func (*Crypto) CreateHash(ctx context.Context, algorithm string) *Hasher {
 		hasher.hash = sha512.New()
 	case "ripemd160":
 		hasher.hash = ripemd160.New()
+	default:
+		err := errors.New("Invalid algorithm: " + algorithm)
+		common.Throw(common.GetRuntime(hasher.ctx), err)
 	}
 
 	return &hasher
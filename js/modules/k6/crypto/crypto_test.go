@@ -238,6 +238,27 @@ func TestStreamingApi(t *testing.T) {
 
 		assert.NoError(t, err)
 	})
+
+	// Binary data, like the bodies of responses with responseType: "binary"
+	t.Run("UpdateBinary", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		const correctHex = "5eb63bbbe01eeed093cb22bb8f5acdc3";
+
+		let hasher = crypto.createHash("md5");
+		hasher.update([104, 101, 108, 108, 111, 32, 119, 111, 114, 108, 100]);
+
+		const resultHex = hasher.digest("hex");
+		if (resultHex !== correctHex) {
+			throw new Error("Hex encoding mismatch: " + resultHex);
+		}`)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("InvalidAlgorithm", func(t *testing.T) {
+		_, err := common.RunString(rt, `crypto.createHash("md6");`)
+		assert.EqualError(t, err, "GoError: Invalid algorithm: md6")
+	})
 }
 
 func TestOutputEncoding(t *testing.T) {
@@ -0,0 +1,196 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package csv
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"unicode/utf8"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+// CSV is the k6/data/csv module, for parsing CSV data, usually read with open().
+type CSV struct{}
+
+// New returns the k6/data/csv module.
+func New() *CSV {
+	return &CSV{}
+}
+
+// Parser reads the records of CSV data one by one, so that big files don't have to be parsed
+// into JS all at once.
+type Parser struct {
+	ctx    *context.Context
+	reader *csv.Reader
+	header []string
+}
+
+// options are the options of parse() and Parser. With header, which is the default, the first
+// record is the header and the other records are objects with its fields as keys, otherwise
+// they're arrays.
+type options struct {
+	header    bool
+	delimiter rune
+	comment   rune
+}
+
+func parseOptions(rt *goja.Runtime, v goja.Value) (options, error) {
+	opts := options{header: true, delimiter: ','}
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return opts, nil
+	}
+	getRune := func(k string, v goja.Value) (rune, error) {
+		s := v.String()
+		r, size := utf8.DecodeRuneInString(s)
+		if size == 0 || size != len(s) {
+			return 0, errors.Errorf("the %s option has to be a single character, got '%s'", k, s)
+		}
+		return r, nil
+	}
+
+	obj := v.ToObject(rt)
+	var err error
+	for _, k := range obj.Keys() {
+		v := obj.Get(k)
+		switch k {
+		case "header":
+			opts.header = v.ToBoolean()
+		case "delimiter":
+			opts.delimiter, err = getRune(k, v)
+		case "comment":
+			opts.comment, err = getRune(k, v)
+		default:
+			err = errors.Errorf("unknown CSV option %s", k)
+		}
+		if err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// XParser is the JS constructor of parsers, with the CSV data and the optional options.
+func (*CSV) XParser(ctxPtr *context.Context, data goja.Value, opts goja.Value) (interface{}, error) {
+	rt := common.GetRuntime(*ctxPtr)
+	p, err := newParser(ctxPtr, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return common.Bind(rt, p, ctxPtr), nil
+}
+
+func newParser(ctxPtr *context.Context, data goja.Value, optsV goja.Value) (*Parser, error) {
+	rt := common.GetRuntime(*ctxPtr)
+	opts, err := parseOptions(rt, optsV)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	switch d := data.Export().(type) {
+	case []byte:
+		b = d
+	default:
+		b = []byte(data.String())
+	}
+
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comma = opts.delimiter
+	r.Comment = opts.comment
+	r.ReuseRecord = true
+	p := &Parser{ctx: ctxPtr, reader: r}
+	if opts.header {
+		header, err := r.Read()
+		if err == io.EOF {
+			return nil, errors.New("the CSV data is empty, it doesn't have a header")
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.header = append([]string{}, header...)
+	}
+	return p, nil
+}
+
+// Next returns the next record as {done: false, value: record}, or {done: true} after the
+// last one, like JS iterators.
+func (p *Parser) Next() (goja.Value, error) {
+	rt := common.GetRuntime(*p.ctx)
+	result := rt.NewObject()
+	record, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+	_ = result.Set("done", record == nil)
+	if record != nil {
+		_ = result.Set("value", record)
+	}
+	return result, nil
+}
+
+// read returns the next record, or nil if there are no more records.
+func (p *Parser) read() (goja.Value, error) {
+	rt := common.GetRuntime(*p.ctx)
+	fields, err := p.reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.header == nil {
+		values := make([]interface{}, len(fields))
+		for i, f := range fields {
+			values[i] = f
+		}
+		return rt.ToValue(values), nil
+	}
+	record := rt.NewObject()
+	for i, f := range fields {
+		if err := record.Set(p.header[i], f); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// Parse parses all the records of CSV data at once, with the same options as Parser.
+func (*CSV) Parse(ctx context.Context, data goja.Value, opts goja.Value) ([]interface{}, error) {
+	p, err := newParser(&ctx, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	records := []interface{}{}
+	for {
+		record, err := p.read()
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			return records, nil
+		}
+		records = append(records, record)
+	}
+}
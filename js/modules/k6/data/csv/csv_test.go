@@ -0,0 +1,100 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package csv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRuntime() *goja.Runtime {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("csv", common.Bind(rt, New(), &ctx))
+	rt.Set("data", "name,email\nalice,alice@example.com\n\"bob, jr\",bob@example.com\n")
+	return rt
+}
+
+func TestParse(t *testing.T) {
+	rt := newRuntime()
+
+	t.Run("Header", func(t *testing.T) {
+		v, err := common.RunString(rt, `JSON.stringify(csv.parse(data))`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			{"name": "alice", "email": "alice@example.com"},
+			{"name": "bob, jr", "email": "bob@example.com"}
+		]`, v.String())
+	})
+
+	t.Run("NoHeader", func(t *testing.T) {
+		v, err := common.RunString(rt, `JSON.stringify(csv.parse(data, { header: false }))`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[
+			["name", "email"], ["alice", "alice@example.com"], ["bob, jr", "bob@example.com"]
+		]`, v.String())
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		v, err := common.RunString(rt, `
+		JSON.stringify(csv.parse("# users\nname;age\nalice;30", { delimiter: ";", comment: "#" }))
+		`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"name": "alice", "age": "30"}]`, v.String())
+	})
+
+	testdata := map[string]string{
+		`csv.parse("")`:                         "the CSV data is empty",
+		`csv.parse("a,b\n1,2,3")`:               "wrong number of fields",
+		`csv.parse(data, { delimiter: ",," })`:  "the delimiter option has to be a single character",
+		`csv.parse(data, { separator: ";" })`:   "unknown CSV option separator",
+		`new csv.Parser(data, { comment: "" })`: "the comment option has to be a single character",
+	}
+	for script, errMsg := range testdata {
+		script, errMsg := script, errMsg
+		t.Run(script, func(t *testing.T) {
+			_, err := common.RunString(rt, script)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), errMsg)
+		})
+	}
+}
+
+func TestParser(t *testing.T) {
+	rt := newRuntime()
+	v, err := common.RunString(rt, `
+	let parser = new csv.Parser(data);
+	let names = [];
+	for (let r = parser.next(); !r.done; r = parser.next()) {
+		names.push(r.value.name);
+	}
+	if (!parser.next().done) { throw new Error("not done after the last record"); }
+	names.join("|");
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, "alice|bob, jr", v.String())
+}
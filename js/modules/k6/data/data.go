@@ -0,0 +1,130 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/pkg/errors"
+)
+
+// ErrSharedArrayInVUContext is returned when a SharedArray is created outside of the init context.
+var ErrSharedArrayInVUContext = errors.New("new SharedArray must be called in the init context")
+
+// Data is the k6/data module. Every test gets its own instance, shared by all of its VUs, so
+// that the data of the shared arrays is only kept in memory once, instead of once in every VU.
+type Data struct {
+	mutex  sync.Mutex
+	arrays map[string]*sharedArray
+}
+
+// sharedArray is the data of a SharedArray, its elements are kept as JSON and are only parsed
+// when they're accessed, so that they aren't modified by the VUs.
+type sharedArray struct {
+	elements []string
+}
+
+// New returns the k6/data module.
+func New() *Data {
+	return &Data{arrays: make(map[string]*sharedArray)}
+}
+
+// NewModuleInstancePerBundle returns a new instance of the module without any shared arrays,
+// so tests that are run one after the other by the same process don't see each other's data.
+func (*Data) NewModuleInstancePerBundle() interface{} {
+	return New()
+}
+
+// XSharedArray is the JS constructor of shared arrays. The function is only called once for
+// every name, by the first VU that creates the array, and has to return an array of
+// JSON-serializable elements. The other VUs get the same data.
+func (d *Data) XSharedArray(ctxPtr *context.Context, name string, fn goja.Value) (interface{}, error) {
+	if lib.GetState(*ctxPtr) != nil {
+		return nil, ErrSharedArrayInVUContext
+	}
+	if name == "" {
+		return nil, errors.New("empty name provided to SharedArray's constructor")
+	}
+	call, ok := goja.AssertFunction(fn)
+	if !ok {
+		return nil, errors.New("a function is expected as the second argument of SharedArray's constructor")
+	}
+
+	rt := common.GetRuntime(*ctxPtr)
+	arr, err := d.getOrCreate(rt, name, call)
+	if err != nil {
+		return nil, err
+	}
+	return common.Bind(rt, &SharedArray{Length: len(arr.elements), arr: arr}, ctxPtr), nil
+}
+
+func (d *Data) getOrCreate(rt *goja.Runtime, name string, call goja.Callable) (*sharedArray, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if arr, ok := d.arrays[name]; ok {
+		return arr, nil
+	}
+
+	v, err := call(goja.Undefined())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.Export().([]interface{}); !ok {
+		return nil, errors.Errorf("only arrays can be made into a SharedArray, got %s", v)
+	}
+	obj := v.ToObject(rt)
+	stringify, _ := goja.AssertFunction(rt.Get("JSON").ToObject(rt).Get("stringify"))
+	arr := &sharedArray{elements: make([]string, obj.Get("length").ToInteger())}
+	for i := range arr.elements {
+		s, err := stringify(goja.Undefined(), obj.Get(rt.ToValue(i).String()))
+		if err != nil {
+			return nil, err
+		}
+		if goja.IsUndefined(s) {
+			return nil, errors.Errorf("element %d of SharedArray %s can't be serialized to JSON", i, name)
+		}
+		arr.elements[i] = s.String()
+	}
+	d.arrays[name] = arr
+	return arr, nil
+}
+
+// SharedArray is the view of a VU on the data of a shared array.
+type SharedArray struct {
+	Length int `js:"length"`
+
+	arr *sharedArray
+}
+
+// Get returns the element at the index, or undefined if the index is out of range. Every call
+// returns a new copy of the element, so changes to it aren't visible to other calls or VUs.
+func (s *SharedArray) Get(ctx context.Context, index int) (goja.Value, error) {
+	if index < 0 || index >= len(s.arr.elements) {
+		return goja.Undefined(), nil
+	}
+	rt := common.GetRuntime(ctx)
+	parse, _ := goja.AssertFunction(rt.Get("JSON").ToObject(rt).Get("parse"))
+	return parse(goja.Undefined(), rt.ToValue(s.arr.elements[index]))
+}
@@ -0,0 +1,97 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRuntime(d *Data) (*goja.Runtime, *context.Context) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("data", common.Bind(rt, d, &ctx))
+	return rt, &ctx
+}
+
+func TestSharedArray(t *testing.T) {
+	d := New()
+	calls := 0
+	script := `
+	let arr = new data.SharedArray("users", function() {
+		generate();
+		return [{ name: "a", id: 1 }, { name: "b", id: 2 }, "c"];
+	});
+	`
+
+	// Every runtime is like a VU, only the first one generates the data
+	for i := 0; i < 3; i++ {
+		rt, _ := newRuntime(d)
+		rt.Set("generate", func() { calls++ })
+		_, err := common.RunString(rt, script)
+		require.NoError(t, err)
+		_, err = common.RunString(rt, `
+		if (arr.length !== 3) { throw new Error("wrong length: " + arr.length); }
+		let user = arr.get(1);
+		if (user.name !== "b" || user.id !== 2) { throw new Error("wrong element: " + JSON.stringify(user)); }
+		if (arr.get(2) !== "c") { throw new Error("wrong element: " + arr.get(2)); }
+		if (arr.get(3) !== undefined || arr.get(-1) !== undefined) { throw new Error("out of range elements"); }
+		user.name = "changed";
+		if (arr.get(1).name !== "b") { throw new Error("the element was changed"); }
+		`)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, calls)
+	assert.Len(t, d.arrays, 1)
+
+	t.Run("InVUContext", func(t *testing.T) {
+		rt, ctx := newRuntime(d)
+		*ctx = lib.WithState(*ctx, &lib.State{})
+		_, err := common.RunString(rt, script)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ErrSharedArrayInVUContext.Error())
+	})
+
+	testdata := map[string]string{
+		`new data.SharedArray("", function() { return []; })`:                     "empty name provided to SharedArray's constructor",
+		`new data.SharedArray("noFunction", [1, 2])`:                              "a function is expected as the second argument",
+		`new data.SharedArray("notArray", function() { return {}; })`:             "only arrays can be made into a SharedArray",
+		`new data.SharedArray("func", function() { return [function() {}]; })`:    "element 0 of SharedArray func can't be serialized to JSON",
+		`new data.SharedArray("throws", function() { throw new Error("oops"); })`: "oops",
+	}
+	for script, errMsg := range testdata {
+		script, errMsg := script, errMsg
+		t.Run(script, func(t *testing.T) {
+			rt, _ := newRuntime(d)
+			_, err := common.RunString(rt, script)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), errMsg)
+		})
+	}
+	assert.Len(t, d.arrays, 1)
+}
@@ -0,0 +1,139 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package execution
+
+import (
+	"context"
+	"time"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+)
+
+// ErrStatsInInitContext is returned when the execution stats are requested in the init context.
+var ErrStatsInInitContext = common.NewInitContextError("Getting the execution stats in the init context is not supported")
+
+// defaultScenario is the name of the scenario of the VUs of tests without scenarios.
+const defaultScenario = "default"
+
+// Execution is the k6/execution module, which gives scripts information about the VU and the
+// iteration they run in, e.g. to partition test data between the VUs.
+type Execution struct{}
+
+// New returns the k6/execution module.
+func New() *Execution {
+	return &Execution{}
+}
+
+// VUStats is the information about the current VU.
+type VUStats struct {
+	// The ID of the VU, the same as __VU. It's unique across all of the instances that run
+	// segments of the same test.
+	ID int64 `js:"id"`
+	// The number of the current iteration of the VU, starting from 0, the same as __ITER.
+	Iteration int64 `js:"iteration"`
+}
+
+// ScenarioStats is the information about the scenario of the current VU.
+type ScenarioStats struct {
+	// The name of the scenario, "default" for tests without scenarios.
+	Name string `js:"name"`
+	// When the scenario started, in milliseconds since the Unix epoch.
+	StartTime int64 `js:"startTime"`
+	// The number of the current iteration among the ones started by all of the VUs of the
+	// scenario, starting from 0. It's unique in the scenario, unlike the iteration of the VU,
+	// also across all of the instances that run segments of the same test.
+	Iteration int64 `js:"iteration"`
+}
+
+// TestInstanceStats is the information about the test run.
+type TestInstanceStats struct {
+	// When the test started, in milliseconds since the Unix epoch.
+	StartTime int64 `js:"startTime"`
+	// How long the test has been running, in milliseconds.
+	CurrentTestRunDuration float64 `js:"currentTestRunDuration"`
+}
+
+func getState(ctx context.Context) (*lib.State, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrStatsInInitContext
+	}
+	return state, nil
+}
+
+// unixMillis returns the time in milliseconds since the Unix epoch, or 0 for the zero time,
+// e.g. when the test hasn't started yet in setup().
+func unixMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// GetVUStats returns the information about the current VU.
+func (*Execution) GetVUStats(ctx context.Context) (*VUStats, error) {
+	state, err := getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &VUStats{ID: state.Vu, Iteration: state.Iteration}, nil
+}
+
+// GetScenarioStats returns the information about the scenario of the current VU.
+func (*Execution) GetScenarioStats(ctx context.Context) (*ScenarioStats, error) {
+	state, err := getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := &ScenarioStats{
+		Name:      defaultScenario,
+		StartTime: unixMillis(state.StartTime),
+		Iteration: state.ScenarioIteration,
+	}
+	if state.Scenario != nil {
+		stats.Name = state.Scenario.Name
+		if !state.StartTime.IsZero() {
+			stats.StartTime = unixMillis(state.StartTime.Add(time.Duration(state.Scenario.StartTime.Duration)))
+		}
+	}
+	return stats, nil
+}
+
+// GetTestInstanceStats returns the information about the test run.
+func (*Execution) GetTestInstanceStats(ctx context.Context) (*TestInstanceStats, error) {
+	state, err := getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := &TestInstanceStats{StartTime: unixMillis(state.StartTime)}
+	if !state.StartTime.IsZero() {
+		stats.CurrentTestRunDuration = float64(time.Since(state.StartTime)) / float64(time.Millisecond)
+	}
+	return stats, nil
+}
+
+// AbortTest stops the whole test, not only the current iteration, with an optional reason. It
+// interrupts the script, so it can't be prevented with try/catch. teardown() still runs, unless
+// the test is aborted in it or in setup(), and k6 exits with a separate exit code.
+func (*Execution) AbortTest(ctx context.Context, reason string) {
+	common.GetRuntime(ctx).Interrupt(lib.NewTestAbortError(reason))
+}
@@ -0,0 +1,80 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package execution
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/scheduler"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecution(t *testing.T) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("execution", common.Bind(rt, New(), &ctx))
+
+	t.Run("InitContext", func(t *testing.T) {
+		for _, fn := range []string{"getVUStats", "getScenarioStats", "getTestInstanceStats"} {
+			_, err := common.RunString(rt, `execution.`+fn+`()`)
+			assert.Contains(t, err.Error(), "in the init context is not supported", fn)
+		}
+	})
+
+	startTime := time.Unix(1500000000, 0)
+	state := &lib.State{Vu: 3, Iteration: 5, ScenarioIteration: 42, StartTime: startTime}
+	ctx = lib.WithState(common.WithRuntime(context.Background(), rt), state)
+
+	t.Run("VUStats", func(t *testing.T) {
+		v, err := common.RunString(rt, `JSON.stringify(execution.getVUStats())`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id": 3, "iteration": 5}`, v.String())
+	})
+
+	t.Run("ScenarioStats", func(t *testing.T) {
+		v, err := common.RunString(rt, `JSON.stringify(execution.getScenarioStats())`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name": "default", "startTime": 1500000000000, "iteration": 42}`, v.String())
+
+		state.Scenario = &scheduler.BaseConfig{Name: "login", StartTime: types.NullDurationFrom(10 * time.Second)}
+		defer func() { state.Scenario = nil }()
+		v, err = common.RunString(rt, `JSON.stringify(execution.getScenarioStats())`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name": "login", "startTime": 1500000010000, "iteration": 42}`, v.String())
+	})
+
+	t.Run("TestInstanceStats", func(t *testing.T) {
+		v, err := common.RunString(rt, `
+		let stats = execution.getTestInstanceStats();
+		if (stats.startTime !== 1500000000000) { throw new Error("wrong start time " + stats.startTime); }
+		stats.currentTestRunDuration`)
+		require.NoError(t, err)
+		assert.True(t, v.ToFloat() >= float64(time.Since(startTime)/time.Millisecond)-1000)
+	})
+}
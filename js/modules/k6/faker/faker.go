@@ -0,0 +1,181 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package faker
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+)
+
+// Faker is the k6/faker module, for generating realistic fake data without having to bundle a
+// JS faker library. Its functions use a random generator of the VU, which can be seeded with
+// seed(), and new Generator(seed) returns independent generators with their own seeds.
+type Faker struct {
+	*Generator `js:"-"`
+}
+
+// New returns the k6/faker module.
+func New() *Faker {
+	return &Faker{Generator: newGenerator(randomSeed())}
+}
+
+// NewModuleInstancePerVU returns a copy of the module with its own random generator, since
+// math/rand generators can't be used concurrently.
+func (*Faker) NewModuleInstancePerVU() interface{} {
+	return New()
+}
+
+// XGenerator is the JS constructor of generators, with an optional seed. Generators with the
+// same seed return the same sequence of values.
+func (*Faker) XGenerator(ctxPtr *context.Context, seed goja.Value) interface{} {
+	rt := common.GetRuntime(*ctxPtr)
+	s := randomSeed()
+	if seed != nil && !goja.IsUndefined(seed) && !goja.IsNull(seed) {
+		s = seed.ToInteger()
+	}
+	return common.Bind(rt, newGenerator(s), ctxPtr)
+}
+
+func randomSeed() int64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
+// Generator generates fake data from a seedable random source.
+type Generator struct {
+	rnd *rand.Rand
+}
+
+func newGenerator(seed int64) *Generator {
+	return &Generator{rnd: rand.New(rand.NewSource(seed))} //nolint:gosec
+}
+
+// Address is a fake postal address.
+type Address struct {
+	Street  string `js:"street"`
+	City    string `js:"city"`
+	ZipCode string `js:"zipCode"`
+	Country string `js:"country"`
+}
+
+func (g *Generator) pick(list []string) string {
+	return list[g.rnd.Intn(len(list))]
+}
+
+// Seed reseeds the generator.
+func (g *Generator) Seed(seed int64) {
+	g.rnd.Seed(seed)
+}
+
+// FirstName returns a random first name.
+func (g *Generator) FirstName() string {
+	return g.pick(firstNames)
+}
+
+// LastName returns a random last name.
+func (g *Generator) LastName() string {
+	return g.pick(lastNames)
+}
+
+// Name returns a random full name.
+func (g *Generator) Name() string {
+	return g.FirstName() + " " + g.LastName()
+}
+
+// Username returns a random username, like "jane.doe42".
+func (g *Generator) Username() string {
+	return fmt.Sprintf("%s.%s%d",
+		strings.ToLower(g.FirstName()), strings.ToLower(g.LastName()), g.rnd.Intn(100))
+}
+
+// Email returns a random email address, with one of the domains reserved for examples, so
+// tests don't send anything to real mailboxes by accident.
+func (g *Generator) Email() string {
+	return g.Username() + "@" + g.pick(emailDomains)
+}
+
+// UUID returns a random version 4 UUID.
+func (g *Generator) UUID() string {
+	var b [16]byte
+	_, _ = g.rnd.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Address returns a random postal address.
+func (g *Generator) Address() Address {
+	return Address{
+		Street:  fmt.Sprintf("%d %s %s", 1+g.rnd.Intn(9999), g.pick(streetNames), g.pick(streetSuffixes)),
+		City:    g.pick(cities),
+		ZipCode: fmt.Sprintf("%05d", g.rnd.Intn(100000)),
+		Country: g.pick(countries),
+	}
+}
+
+// Word returns a random lorem ipsum word.
+func (g *Generator) Word() string {
+	return g.pick(loremWords)
+}
+
+// Words returns n random lorem ipsum words separated by spaces, 3 by default.
+func (g *Generator) Words(n int) string {
+	if n <= 0 {
+		n = 3
+	}
+	words := make([]string, n)
+	for i := range words {
+		words[i] = g.Word()
+	}
+	return strings.Join(words, " ")
+}
+
+// Sentence returns a lorem ipsum sentence of n words, between 4 and 12 by default.
+func (g *Generator) Sentence(n int) string {
+	if n <= 0 {
+		n = 4 + g.rnd.Intn(9)
+	}
+	s := g.Words(n)
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+// Paragraph returns a lorem ipsum paragraph of n sentences, between 3 and 6 by default.
+func (g *Generator) Paragraph(n int) string {
+	if n <= 0 {
+		n = 3 + g.rnd.Intn(4)
+	}
+	sentences := make([]string, n)
+	for i := range sentences {
+		sentences[i] = g.Sentence(0)
+	}
+	return strings.Join(sentences, " ")
+}
@@ -0,0 +1,114 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package faker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRuntime() *goja.Runtime {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("faker", common.Bind(rt, New(), &ctx))
+	return rt
+}
+
+func TestFaker(t *testing.T) {
+	rt := newRuntime()
+
+	t.Run("Name", func(t *testing.T) {
+		v, err := common.RunString(rt, `faker.name()`)
+		require.NoError(t, err)
+		assert.Regexp(t, `^\S+ \S+$`, v.String())
+	})
+
+	t.Run("Email", func(t *testing.T) {
+		v, err := common.RunString(rt, `faker.email()`)
+		require.NoError(t, err)
+		assert.Regexp(t, `^\S+\.\S+\d*@example\.(com|net|org)$`, v.String())
+	})
+
+	t.Run("UUID", func(t *testing.T) {
+		v, err := common.RunString(rt, `faker.uuid()`)
+		require.NoError(t, err)
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, v.String())
+	})
+
+	t.Run("Address", func(t *testing.T) {
+		v, err := common.RunString(rt, `
+		let a = faker.address();
+		if (!a.street || !a.city || !a.country) { throw new Error("incomplete address: " + JSON.stringify(a)); }
+		a.zipCode`)
+		require.NoError(t, err)
+		assert.Regexp(t, `^\d{5}$`, v.String())
+	})
+
+	t.Run("Lorem", func(t *testing.T) {
+		v, err := common.RunString(rt, `faker.words(5)`)
+		require.NoError(t, err)
+		assert.Len(t, strings.Fields(v.String()), 5)
+
+		v, err = common.RunString(rt, `faker.sentence(7)`)
+		require.NoError(t, err)
+		assert.Regexp(t, `^[A-Z]\w*( \w+){6}\.$`, v.String())
+
+		v, err = common.RunString(rt, `faker.paragraph(2)`)
+		require.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(v.String(), "."))
+	})
+
+	t.Run("Seed", func(t *testing.T) {
+		v, err := common.RunString(rt, `
+		faker.seed(42);
+		let first = [faker.name(), faker.uuid(), faker.sentence()];
+		faker.seed(42);
+		let second = [faker.name(), faker.uuid(), faker.sentence()];
+		JSON.stringify(first) === JSON.stringify(second)`)
+		require.NoError(t, err)
+		assert.True(t, v.ToBoolean())
+	})
+
+	t.Run("Generator", func(t *testing.T) {
+		v, err := common.RunString(rt, `
+		let a = new faker.Generator(1), b = new faker.Generator(1), c = new faker.Generator(2);
+		let values = (g) => JSON.stringify([g.email(), g.address(), g.paragraph()]);
+		let va = values(a);
+		if (va !== values(b)) { throw new Error("generators with the same seed differ"); }
+		va === values(c)`)
+		require.NoError(t, err)
+		assert.False(t, v.ToBoolean())
+	})
+
+	t.Run("PerVU", func(t *testing.T) {
+		mod := New()
+		instance, ok := mod.NewModuleInstancePerVU().(*Faker)
+		require.True(t, ok)
+		assert.False(t, mod.Generator == instance.Generator)
+	})
+}
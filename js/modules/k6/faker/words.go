@@ -0,0 +1,76 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package faker
+
+//nolint:gochecknoglobals
+var (
+	firstNames = []string{
+		"Aaliyah", "Adam", "Aisha", "Alejandro", "Alice", "Amelia", "Andrei", "Anna", "Ben",
+		"Carlos", "Charlotte", "Chen", "Chloe", "Daniel", "David", "Elena", "Emily", "Emma",
+		"Ethan", "Fatima", "Gabriel", "Grace", "Hana", "Harry", "Isabella", "Ivan", "Jack",
+		"James", "Julia", "Kenji", "Laura", "Leo", "Liam", "Lucas", "Lucy", "Maria", "Mateo",
+		"Mia", "Mohammed", "Nina", "Noah", "Olivia", "Oscar", "Priya", "Rahul", "Sara", "Sofia",
+		"Thomas", "Wei", "Yuki", "Zoe",
+	}
+
+	lastNames = []string{
+		"Anderson", "Brown", "Chen", "Clark", "Davis", "Dubois", "Fernandez", "Garcia", "Hansen",
+		"Harris", "Ivanov", "Jackson", "Johansson", "Johnson", "Jones", "Kim", "Kowalski", "Lee",
+		"Lewis", "Lopez", "Martin", "Martinez", "Miller", "Moore", "Nakamura", "Nguyen",
+		"Novak", "Patel", "Perez", "Robinson", "Rossi", "Sanchez", "Schmidt", "Silva", "Singh",
+		"Smith", "Suzuki", "Taylor", "Thomas", "Thompson", "Walker", "Wang", "White", "Williams",
+		"Wilson", "Wright", "Yamamoto", "Young", "Zhang",
+	}
+
+	emailDomains = []string{"example.com", "example.net", "example.org"}
+
+	streetNames = []string{
+		"Acacia", "Ash", "Bay", "Birch", "Cedar", "Chestnut", "Church", "Elm", "Forest", "Garden",
+		"Hill", "Lake", "Laurel", "Maple", "Meadow", "Mill", "Oak", "Park", "Pine", "River",
+		"Spring", "Sunset", "Valley", "Walnut", "Willow",
+	}
+
+	streetSuffixes = []string{"Avenue", "Boulevard", "Court", "Drive", "Lane", "Road", "Street", "Way"}
+
+	cities = []string{
+		"Amsterdam", "Athens", "Auckland", "Barcelona", "Berlin", "Boston", "Buenos Aires",
+		"Cape Town", "Chicago", "Copenhagen", "Dublin", "Helsinki", "Lisbon", "London", "Madrid",
+		"Melbourne", "Montreal", "Mumbai", "Oslo", "Paris", "Prague", "Rome", "Seoul", "Stockholm",
+		"Tokyo", "Toronto", "Vienna", "Warsaw", "Zurich",
+	}
+
+	countries = []string{
+		"Argentina", "Australia", "Austria", "Brazil", "Canada", "Denmark", "Finland", "France",
+		"Germany", "India", "Ireland", "Italy", "Japan", "Netherlands", "New Zealand", "Norway",
+		"Poland", "Portugal", "South Africa", "Spain", "Sweden", "Switzerland", "United Kingdom",
+		"United States",
+	}
+
+	loremWords = []string{
+		"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do",
+		"eiusmod", "tempor", "incididunt", "ut", "labore", "et", "dolore", "magna", "aliqua",
+		"enim", "ad", "minim", "veniam", "quis", "nostrud", "exercitation", "ullamco", "laboris",
+		"nisi", "aliquip", "ex", "ea", "commodo", "consequat", "duis", "aute", "irure", "in",
+		"reprehenderit", "voluptate", "velit", "esse", "cillum", "fugiat", "nulla", "pariatur",
+		"excepteur", "sint", "occaecat", "cupidatat", "non", "proident", "sunt", "culpa", "qui",
+		"officia", "deserunt", "mollit", "anim", "id", "est", "laborum",
+	}
+)
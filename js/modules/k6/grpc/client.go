@@ -0,0 +1,359 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	"golang.org/x/net/http2"
+)
+
+const defaultTimeout = 60 * time.Second
+
+// Client is a gRPC client for the services of the loaded .proto definitions. Only unary methods
+// can be invoked, over a single HTTP/2 connection per client.
+type Client struct {
+	protos *protoSet
+
+	addr      string
+	plaintext bool
+	conn      *http2.ClientConn
+}
+
+// Response is the result of a gRPC method invocation.
+type Response struct {
+	Status   int                 `js:"status"`
+	Message  interface{}         `js:"message"` // The JS object of the message, nil on errors
+	Headers  map[string][]string `js:"headers"`
+	Trailers map[string][]string `js:"trailers"`
+	Error    *ResponseError      `js:"error"`
+}
+
+// ResponseError describes a non-OK status of a response.
+type ResponseError struct {
+	Code    int    `js:"code"`
+	Message string `js:"message"`
+}
+
+// Load parses .proto definitions, usually read with open(), so that their methods can be invoked.
+// Imported files aren't loaded automatically, except for the google.protobuf well-known types.
+func (c *Client) Load(ctx context.Context, sources ...string) (bool, error) {
+	if lib.GetState(ctx) != nil {
+		return false, errors.New("proto definitions must be loaded in the init context")
+	}
+	if err := c.protos.load(sources...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Connect opens the connection to a gRPC server. The optional params can have plaintext: true,
+// to connect without TLS, and a timeout for the connection.
+func (c *Client) Connect(
+	ctx context.Context, addr string, params ...map[string]interface{},
+) (bool, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return false, common.NewInitContextError("connecting to a gRPC server in the init context is not supported")
+	}
+	if c.conn != nil {
+		return false, errors.New("the client is already connected")
+	}
+
+	timeout := defaultTimeout
+	for _, p := range params {
+		for k, v := range p {
+			switch k {
+			case "plaintext":
+				c.plaintext, _ = v.(bool)
+			case "timeout":
+				d, err := types.GetDurationValue(v)
+				if err != nil {
+					return false, errors.Wrap(err, "invalid timeout")
+				}
+				timeout = d
+			default:
+				return false, errors.Errorf("unknown connect param %s", k)
+			}
+		}
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := state.Dialer.DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return false, err
+	}
+	if !c.plaintext {
+		var tlsConfig *tls.Config
+		if state.TLSConfig != nil {
+			tlsConfig = state.TLSConfig.Clone()
+		} else {
+			tlsConfig = &tls.Config{}
+		}
+		tlsConfig.NextProtos = []string{http2.NextProtoTLS}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		_ = tlsConn.SetDeadline(time.Now().Add(timeout))
+		if err := tlsConn.Handshake(); err != nil {
+			_ = conn.Close()
+			return false, err
+		}
+		_ = tlsConn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+
+	t := &http2.Transport{AllowHTTP: c.plaintext}
+	if c.conn, err = t.NewClientConn(conn); err != nil {
+		_ = conn.Close()
+		return false, err
+	}
+	c.addr = addr
+	return true, nil
+}
+
+// Invoke calls a unary method, e.g. "package.Service/Method", with a request message given as a
+// JS object. The optional params can have headers (the request metadata), tags and a timeout.
+func (c *Client) Invoke(
+	ctx context.Context, method string, req goja.Value, params ...map[string]interface{},
+) (*Response, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, common.NewInitContextError("invoking gRPC methods in the init context is not supported")
+	}
+	if c.conn == nil {
+		return nil, errors.New("the client isn't connected, call connect() first")
+	}
+	if !strings.HasPrefix(method, "/") {
+		method = "/" + method
+	}
+	md := c.protos.methods[method]
+	if md == nil {
+		return nil, errors.Errorf("method %s isn't in the loaded proto definitions", method)
+	}
+	if md.clientStream || md.serverStream {
+		return nil, errors.Errorf("method %s is a streaming method, only unary ones are supported", method)
+	}
+
+	var obj map[string]interface{}
+	if req != nil && !goja.IsUndefined(req) && !goja.IsNull(req) {
+		var ok bool
+		if obj, ok = req.Export().(map[string]interface{}); !ok {
+			return nil, errors.New("the request message has to be an object")
+		}
+	}
+	data, err := marshal(md.input, obj)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't marshal the %s request", md.input.fullName)
+	}
+
+	tags := state.Options.RunTags.CloneTags()
+	header := http.Header{}
+	timeout := defaultTimeout
+	for _, p := range params {
+		for k, v := range p {
+			switch k {
+			case "headers":
+				h, ok := v.(map[string]interface{})
+				if !ok {
+					return nil, errors.New("headers have to be an object")
+				}
+				for hk, hv := range h {
+					header.Set(hk, fmt.Sprint(hv))
+				}
+			case "tags":
+				t, ok := v.(map[string]interface{})
+				if !ok {
+					return nil, errors.New("tags have to be an object")
+				}
+				for tk, tv := range t {
+					tags[tk] = fmt.Sprint(tv)
+				}
+			case "timeout":
+				if timeout, err = types.GetDurationValue(v); err != nil {
+					return nil, errors.Wrap(err, "invalid timeout")
+				}
+			default:
+				return nil, errors.Errorf("unknown invoke param %s", k)
+			}
+		}
+	}
+
+	scheme := "https"
+	if c.plaintext {
+		scheme = "http"
+	}
+	u := &url.URL{Scheme: scheme, Host: c.addr, Path: method}
+	if state.Options.SystemTags["url"] {
+		tags["url"] = u.String()
+	}
+	if _, ok := tags["name"]; !ok && state.Options.SystemTags["name"] {
+		tags["name"] = u.String()
+	}
+	if state.Options.SystemTags["method"] {
+		tags["method"] = method
+	}
+	if state.Options.SystemTags["proto"] {
+		tags["proto"] = "HTTP/2.0"
+	}
+	if state.Options.SystemTags["group"] {
+		tags["group"] = state.Group.Path
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	frame := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	httpReq, err := http.NewRequest("POST", u.String(), bytes.NewReader(append(frame, data...)))
+	if err != nil {
+		return nil, err
+	}
+	httpReq = httpReq.WithContext(reqCtx)
+	httpReq.Header = header
+	httpReq.Header.Set("Content-Type", "application/grpc+proto")
+	httpReq.Header.Set("TE", "trailers")
+	httpReq.Header.Set("grpc-timeout", strconv.FormatInt(int64(timeout/time.Millisecond), 10)+"m")
+
+	startTime := time.Now()
+	res := c.roundTrip(httpReq, md)
+	endTime := time.Now()
+
+	if state.Options.SystemTags["status"] {
+		tags["status"] = strconv.Itoa(res.Status)
+	}
+	stats.PushIfNotCancelled(ctx, state.Samples, stats.Sample{
+		Metric: metrics.GRPCReqDuration,
+		Time:   endTime,
+		Tags:   stats.IntoSampleTags(&tags),
+		Value:  stats.D(endTime.Sub(startTime)),
+	})
+	return res, nil
+}
+
+// roundTrip sends a request and reads its response. Errors are reported through the status of
+// the response, so that scripts can check them.
+func (c *Client) roundTrip(req *http.Request, md *methodDesc) *Response {
+	res := &Response{}
+	fail := func(code int, err error) *Response {
+		res.Status = code
+		res.Error = &ResponseError{Code: code, Message: err.Error()}
+		return res
+	}
+	statusFromErr := func() int {
+		if req.Context().Err() == context.DeadlineExceeded {
+			return StatusDeadlineExceeded
+		}
+		return StatusUnavailable
+	}
+
+	httpRes, err := c.conn.RoundTrip(req)
+	if err != nil {
+		return fail(statusFromErr(), err)
+	}
+	defer func() { _ = httpRes.Body.Close() }()
+	res.Headers = httpRes.Header
+
+	body, err := ioutil.ReadAll(httpRes.Body)
+	if err != nil {
+		return fail(statusFromErr(), err)
+	}
+	res.Trailers = httpRes.Trailer
+	if httpRes.StatusCode != http.StatusOK {
+		return fail(StatusUnknown, errors.Errorf("unexpected HTTP status %s", httpRes.Status))
+	}
+
+	// Trailers-only responses have the status in the headers.
+	status := httpRes.Trailer.Get("grpc-status")
+	message := httpRes.Trailer.Get("grpc-message")
+	if status == "" {
+		status, message = httpRes.Header.Get("grpc-status"), httpRes.Header.Get("grpc-message")
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return fail(StatusUnknown, errors.Errorf("invalid grpc-status '%s'", status))
+	}
+	if code != StatusOK {
+		if m, err := url.PathUnescape(message); err == nil {
+			message = m
+		}
+		return fail(code, errors.New(message))
+	}
+
+	msg, err := readMessage(body)
+	if err != nil {
+		return fail(StatusInternal, err)
+	}
+	obj, err := unmarshal(md.output, msg)
+	if err != nil {
+		return fail(StatusInternal, errors.Wrapf(err, "couldn't unmarshal the %s response", md.output.fullName))
+	}
+	res.Status, res.Message = StatusOK, obj
+	return res
+}
+
+// readMessage returns the length-prefixed gRPC message at the start of the body. The length is
+// checked against the size of the body, so a bad prefix can't make it allocate more than that.
+// Compressed messages aren't supported.
+func readMessage(body []byte) ([]byte, error) {
+	if len(body) < 5 {
+		return nil, errors.New("couldn't read the response message: it's too short")
+	}
+	if body[0] != 0 {
+		return nil, errors.New("compressed response messages aren't supported")
+	}
+	length := binary.BigEndian.Uint32(body[1:5])
+	if uint64(length) > uint64(len(body)-5) {
+		return nil, errors.Errorf(
+			"couldn't read the response message: its length is %d bytes, but only %d were received",
+			length, len(body)-5)
+	}
+	return body[5 : 5+int(length)], nil
+}
+
+// Close closes the connection of the client. It can be connected again afterwards.
+func (c *Client) Close() (bool, error) {
+	if c.conn == nil {
+		return false, nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err == nil, err
+}
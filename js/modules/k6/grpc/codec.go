@@ -0,0 +1,473 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package grpc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// The protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func wireType(typeName string) int {
+	switch typeName {
+	case "double", "fixed64", "sfixed64":
+		return wireFixed64
+	case "float", "fixed32", "sfixed32":
+		return wireFixed32
+	case "string", "bytes":
+		return wireBytes
+	case "int32", "int64", "uint32", "uint64", "sint32", "sint64", "bool":
+		return wireVarint
+	default: // Messages and enums
+		return -1
+	}
+}
+
+func (f *fieldDesc) wireType() int {
+	switch {
+	case f.isMap || f.message != nil:
+		return wireBytes
+	case f.enum != nil:
+		return wireVarint
+	default:
+		return wireType(f.typeName)
+	}
+}
+
+// marshal encodes a JS object, as exported by goja, as a protobuf message. The fields can be
+// named either like in the .proto definition or with their lowerCamelCase JSON names.
+func marshal(msg *messageDesc, obj map[string]interface{}) ([]byte, error) {
+	b := proto.NewBuffer(nil)
+	if err := encodeMessage(b, msg, obj); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func encodeMessage(b *proto.Buffer, msg *messageDesc, obj map[string]interface{}) error {
+	for _, f := range msg.fields {
+		v, ok := obj[f.jsonName]
+		if !ok {
+			v, ok = obj[f.name]
+		}
+		if !ok || v == nil {
+			continue
+		}
+		if err := encodeField(b, f, v); err != nil {
+			return errors.Wrapf(err, "field %s", f.name)
+		}
+	}
+	return nil
+}
+
+func encodeTag(b *proto.Buffer, number, wire int) {
+	_ = b.EncodeVarint(uint64(number)<<3 | uint64(wire))
+}
+
+// encodeNested encodes a length-delimited field, like a message or a packed repeated field,
+// with the value written by the function.
+func encodeNested(b *proto.Buffer, number int, fn func(*proto.Buffer) error) error {
+	nested := proto.NewBuffer(nil)
+	if err := fn(nested); err != nil {
+		return err
+	}
+	encodeTag(b, number, wireBytes)
+	return b.EncodeRawBytes(nested.Bytes())
+}
+
+func encodeField(b *proto.Buffer, f *fieldDesc, v interface{}) error {
+	if f.isMap {
+		entries, ok := v.(map[string]interface{})
+		if !ok {
+			return errors.New("a map field has to be an object")
+		}
+		keys := make([]string, 0, len(entries))
+		for k := range entries {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			err := encodeNested(b, f.number, func(entry *proto.Buffer) error {
+				if err := encodeValue(entry, 1, f.keyType, nil, nil, k); err != nil {
+					return err
+				}
+				if entries[k] == nil {
+					return nil
+				}
+				return encodeValue(entry, 2, f.typeName, f.message, f.enum, entries[k])
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !f.repeated {
+		return encodeValue(b, f.number, f.typeName, f.message, f.enum, v)
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return errors.New("a repeated field has to be an array")
+	}
+	if wire := f.wireType(); wire != wireBytes { // Packed, like proto3 does by default
+		return encodeNested(b, f.number, func(packed *proto.Buffer) error {
+			for _, item := range items {
+				if err := encodeScalar(packed, f.typeName, f.enum, item); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	for _, item := range items {
+		if err := encodeValue(b, f.number, f.typeName, f.message, f.enum, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeValue(
+	b *proto.Buffer, number int, typeName string, msg *messageDesc, enum *enumDesc, v interface{},
+) error {
+	if msg != nil {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("a %s message has to be an object", msg.fullName)
+		}
+		return encodeNested(b, number, func(nested *proto.Buffer) error {
+			return encodeMessage(nested, msg, obj)
+		})
+	}
+
+	wire := wireType(typeName)
+	if enum != nil {
+		wire = wireVarint
+	}
+	if wire == wireBytes {
+		data, err := toBytes(typeName, v)
+		if err != nil {
+			return err
+		}
+		encodeTag(b, number, wireBytes)
+		return b.EncodeRawBytes(data)
+	}
+	encodeTag(b, number, wire)
+	return encodeScalar(b, typeName, enum, v)
+}
+
+// encodeScalar encodes the untagged value of a numeric, boolean or enum field.
+func encodeScalar(b *proto.Buffer, typeName string, enum *enumDesc, v interface{}) error {
+	if enum != nil {
+		if name, ok := v.(string); ok {
+			value, ok := enum.values[name]
+			if !ok {
+				return errors.Errorf("unknown value %s of enum %s", name, enum.fullName)
+			}
+			return b.EncodeVarint(uint64(int64(value)))
+		}
+		typeName = "int32"
+	}
+
+	if typeName == "bool" {
+		v, ok := v.(bool)
+		if !ok {
+			return errors.Errorf("expected a bool but got %v", v)
+		}
+		if v {
+			return b.EncodeVarint(1)
+		}
+		return b.EncodeVarint(0)
+	}
+
+	switch typeName {
+	case "double":
+		n, err := toNumber(v)
+		if err != nil {
+			return err
+		}
+		return b.EncodeFixed64(math.Float64bits(n))
+	case "float":
+		n, err := toNumber(v)
+		if err != nil {
+			return err
+		}
+		return b.EncodeFixed32(uint64(math.Float32bits(float32(n))))
+	}
+
+	i, err := toInt(v)
+	if err != nil {
+		return err
+	}
+	switch typeName {
+	case "fixed64", "sfixed64":
+		return b.EncodeFixed64(uint64(i))
+	case "fixed32", "sfixed32":
+		return b.EncodeFixed32(uint64(uint32(i)))
+	case "sint32":
+		return b.EncodeZigzag32(uint64(i))
+	case "sint64":
+		return b.EncodeZigzag64(uint64(i))
+	case "uint32":
+		return b.EncodeVarint(uint64(uint32(i)))
+	default: // int32, int64, uint64
+		return b.EncodeVarint(uint64(i))
+	}
+}
+
+func toNumber(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case string:
+		return strconv.ParseFloat(n, 64)
+	default:
+		return 0, errors.Errorf("expected a number but got %v", v)
+	}
+}
+
+// toInt converts a JS number or a string, which is how 64-bit integers are represented in the
+// JSON mapping, to an integer. Unsigned values above the int64 range wrap around.
+func toInt(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	case float64:
+		return int64(n), nil
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		if err != nil {
+			u, uerr := strconv.ParseUint(n, 10, 64)
+			if uerr != nil {
+				return 0, err
+			}
+			i = int64(u)
+		}
+		return i, nil
+	default:
+		return 0, errors.Errorf("expected a number but got %v", v)
+	}
+}
+
+func toBytes(typeName string, v interface{}) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.Errorf("expected a string but got %v", v)
+	}
+	if typeName == "bytes" { // Base64 encoded, like in the JSON mapping
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return []byte(s), nil
+}
+
+// unmarshal decodes a protobuf message into a JS object, following the JSON mapping of protobuf:
+// the fields have their lowerCamelCase names, 64-bit integers are strings, enums are their names,
+// bytes are base64 encoded and all fields that weren't set have their default values.
+func unmarshal(msg *messageDesc, data []byte) (map[string]interface{}, error) {
+	obj := make(map[string]interface{}, len(msg.fields))
+	for len(data) > 0 {
+		tag, n := proto.DecodeVarint(data)
+		if n <= 0 {
+			return nil, errors.New("invalid protobuf message")
+		}
+		data = data[n:]
+		number, wire := int(tag>>3), int(tag&7)
+
+		var raw []byte
+		var value uint64
+		switch wire {
+		case wireVarint:
+			if value, n = proto.DecodeVarint(data); n <= 0 {
+				return nil, errors.New("invalid protobuf varint")
+			}
+		case wireFixed64, wireFixed32:
+			var err error
+			if value, n, err = decodeFixed(data, wire); err != nil {
+				return nil, errors.New("truncated protobuf message")
+			}
+		case wireBytes:
+			length, m := proto.DecodeVarint(data)
+			if m <= 0 || uint64(len(data)-m) < length {
+				return nil, errors.New("truncated protobuf message")
+			}
+			raw, n = data[m:m+int(length)], m+int(length)
+		default:
+			return nil, errors.Errorf("unsupported protobuf wire type %d", wire)
+		}
+		data = data[n:]
+
+		f := msg.byNumber[number]
+		if f == nil {
+			continue // Unknown fields are ignored
+		}
+		if err := decodeField(obj, f, wire, value, raw); err != nil {
+			return nil, errors.Wrapf(err, "field %s", f.name)
+		}
+	}
+
+	for _, f := range msg.fields {
+		if _, ok := obj[f.jsonName]; !ok {
+			obj[f.jsonName] = defaultValue(f)
+		}
+	}
+	return obj, nil
+}
+
+// decodeFixed decodes a fixed64 or fixed32 value at the start of the data, and returns it with
+// its size.
+func decodeFixed(data []byte, wire int) (uint64, int, error) {
+	b := proto.NewBuffer(data)
+	if wire == wireFixed64 {
+		v, err := b.DecodeFixed64()
+		return v, 8, err
+	}
+	v, err := b.DecodeFixed32()
+	return v, 4, err
+}
+
+func defaultValue(f *fieldDesc) interface{} {
+	switch {
+	case f.isMap:
+		return map[string]interface{}{}
+	case f.repeated:
+		return []interface{}{}
+	case f.message != nil:
+		return nil
+	case f.enum != nil:
+		return scalarValue("enum", f.enum, 0)
+	case f.typeName == "string", f.typeName == "bytes":
+		return ""
+	default:
+		return scalarValue(f.typeName, nil, 0)
+	}
+}
+
+func decodeField(obj map[string]interface{}, f *fieldDesc, wire int, value uint64, raw []byte) error {
+	if f.isMap {
+		entry := &messageDesc{byNumber: map[int]*fieldDesc{
+			1: {name: "key", jsonName: "key", number: 1, typeName: f.keyType},
+			2: {name: "value", jsonName: "value", number: 2, typeName: f.typeName, message: f.message, enum: f.enum},
+		}}
+		entry.fields = []*fieldDesc{entry.byNumber[1], entry.byNumber[2]}
+		kv, err := unmarshal(entry, raw)
+		if err != nil {
+			return err
+		}
+		m, _ := obj[f.jsonName].(map[string]interface{})
+		if m == nil {
+			m = make(map[string]interface{})
+			obj[f.jsonName] = m
+		}
+		m[fmt.Sprint(kv["key"])] = kv["value"]
+		return nil
+	}
+
+	var values []interface{}
+	switch {
+	case f.message != nil:
+		v, err := unmarshal(f.message, raw)
+		if err != nil {
+			return err
+		}
+		values = append(values, v)
+	case wire == wireBytes && f.typeName == "string":
+		values = append(values, string(raw))
+	case wire == wireBytes && f.typeName == "bytes":
+		values = append(values, base64.StdEncoding.EncodeToString(raw))
+	case wire == wireBytes: // Packed scalars
+		packedWire := f.wireType()
+		for len(raw) > 0 {
+			var v uint64
+			var n int
+			switch packedWire {
+			case wireFixed64, wireFixed32:
+				var err error
+				if v, n, err = decodeFixed(raw, packedWire); err != nil {
+					return errors.New("truncated packed field")
+				}
+			default:
+				if v, n = proto.DecodeVarint(raw); n <= 0 {
+					return errors.New("invalid packed field")
+				}
+			}
+			raw = raw[n:]
+			values = append(values, scalarValue(f.typeName, f.enum, v))
+		}
+	default:
+		values = append(values, scalarValue(f.typeName, f.enum, value))
+	}
+
+	if !f.repeated {
+		if len(values) > 0 {
+			obj[f.jsonName] = values[len(values)-1]
+		}
+		return nil
+	}
+	items, _ := obj[f.jsonName].([]interface{})
+	obj[f.jsonName] = append(items, values...)
+	return nil
+}
+
+// scalarValue converts the raw value of a numeric, boolean or enum field to its JSON mapping.
+func scalarValue(typeName string, enum *enumDesc, v uint64) interface{} {
+	if enum != nil {
+		if name, ok := enum.names[int32(v)]; ok {
+			return name
+		}
+		return int64(int32(v))
+	}
+	switch typeName {
+	case "double":
+		return math.Float64frombits(v)
+	case "float":
+		return float64(math.Float32frombits(uint32(v)))
+	case "bool":
+		return v != 0
+	case "int32", "sfixed32":
+		return int64(int32(v))
+	case "uint32", "fixed32":
+		return int64(uint32(v))
+	case "sint32":
+		return int64(int32(uint32(v>>1) ^ -uint32(v&1)))
+	case "sint64":
+		return strconv.FormatInt(int64(v>>1)^-int64(v&1), 10)
+	case "uint64", "fixed64":
+		return strconv.FormatUint(v, 10)
+	default: // int64, sfixed64
+		return strconv.FormatInt(int64(v), 10)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+)
+
+// The gRPC status codes.
+const (
+	StatusOK                 = 0
+	StatusCanceled           = 1
+	StatusUnknown            = 2
+	StatusInvalidArgument    = 3
+	StatusDeadlineExceeded   = 4
+	StatusNotFound           = 5
+	StatusAlreadyExists      = 6
+	StatusPermissionDenied   = 7
+	StatusResourceExhausted  = 8
+	StatusFailedPrecondition = 9
+	StatusAborted            = 10
+	StatusOutOfRange         = 11
+	StatusUnimplemented      = 12
+	StatusInternal           = 13
+	StatusUnavailable        = 14
+	StatusDataLoss           = 15
+	StatusUnauthenticated    = 16
+)
+
+// ErrClientInVUContext is returned when a client is created outside of the init context.
+var ErrClientInVUContext = errors.New("gRPC clients must be created in the init context")
+
+// GRPC is the k6/grpc module, with the client constructor and the status codes, so that scripts
+// can check the status of responses.
+type GRPC struct {
+	StatusOK                 int `js:"StatusOK"`
+	StatusCanceled           int `js:"StatusCanceled"`
+	StatusUnknown            int `js:"StatusUnknown"`
+	StatusInvalidArgument    int `js:"StatusInvalidArgument"`
+	StatusDeadlineExceeded   int `js:"StatusDeadlineExceeded"`
+	StatusNotFound           int `js:"StatusNotFound"`
+	StatusAlreadyExists      int `js:"StatusAlreadyExists"`
+	StatusPermissionDenied   int `js:"StatusPermissionDenied"`
+	StatusResourceExhausted  int `js:"StatusResourceExhausted"`
+	StatusFailedPrecondition int `js:"StatusFailedPrecondition"`
+	StatusAborted            int `js:"StatusAborted"`
+	StatusOutOfRange         int `js:"StatusOutOfRange"`
+	StatusUnimplemented      int `js:"StatusUnimplemented"`
+	StatusInternal           int `js:"StatusInternal"`
+	StatusUnavailable        int `js:"StatusUnavailable"`
+	StatusDataLoss           int `js:"StatusDataLoss"`
+	StatusUnauthenticated    int `js:"StatusUnauthenticated"`
+}
+
+// New returns the k6/grpc module.
+func New() *GRPC {
+	return &GRPC{
+		StatusOK:                 StatusOK,
+		StatusCanceled:           StatusCanceled,
+		StatusUnknown:            StatusUnknown,
+		StatusInvalidArgument:    StatusInvalidArgument,
+		StatusDeadlineExceeded:   StatusDeadlineExceeded,
+		StatusNotFound:           StatusNotFound,
+		StatusAlreadyExists:      StatusAlreadyExists,
+		StatusPermissionDenied:   StatusPermissionDenied,
+		StatusResourceExhausted:  StatusResourceExhausted,
+		StatusFailedPrecondition: StatusFailedPrecondition,
+		StatusAborted:            StatusAborted,
+		StatusOutOfRange:         StatusOutOfRange,
+		StatusUnimplemented:      StatusUnimplemented,
+		StatusInternal:           StatusInternal,
+		StatusUnavailable:        StatusUnavailable,
+		StatusDataLoss:           StatusDataLoss,
+		StatusUnauthenticated:    StatusUnauthenticated,
+	}
+}
+
+// XClient is the JS constructor of gRPC clients.
+func (*GRPC) XClient(ctxPtr *context.Context) (interface{}, error) {
+	if lib.GetState(*ctxPtr) != nil {
+		return nil, ErrClientInVUContext
+	}
+	rt := common.GetRuntime(*ctxPtr)
+	return common.Bind(rt, &Client{protos: newProtoSet()}, ctxPtr), nil
+}
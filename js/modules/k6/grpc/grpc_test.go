@@ -0,0 +1,210 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+// newGreeterServer returns a TLS HTTP/2 server implementing the SayHello method of testProto.
+func newGreeterServer(t *testing.T) *httptest.Server {
+	ps := newProtoSet()
+	require.NoError(t, ps.load(testProto))
+	method := ps.methods["/hello.Greeter/SayHello"]
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		if r.URL.Path != method.fullName {
+			w.Header().Set("Grpc-Status", "12")
+			w.Header().Set("Grpc-Message", "unknown method")
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		req, err := unmarshal(method.input, body[5:])
+		if err != nil || req["name"] == "" {
+			w.Header().Set("Grpc-Status", "3")
+			w.Header().Set("Grpc-Message", "name%20is%20required")
+			return
+		}
+		data, _ := marshal(method.output, map[string]interface{}{
+			"message": "Hello " + req["name"].(string) + ", " + r.Header.Get("x-greeting"),
+		})
+		frame := make([]byte, 5)
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+		_, _ = w.Write(append(frame, data...))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	require.NoError(t, http2.ConfigureServer(srv.Config, nil))
+	srv.TLS = &tls.Config{NextProtos: []string{http2.NextProtoTLS}}
+	srv.StartTLS()
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	srv := newGreeterServer(t)
+	defer srv.Close()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("grpc", common.Bind(rt, New(), &ctx))
+	rt.Set("proto", testProto)
+
+	_, err = common.RunString(rt, `
+	let client = new grpc.Client();
+	client.load(proto);
+	`)
+	require.NoError(t, err)
+
+	t.Run("ConnectInInitContext", func(t *testing.T) {
+		_, err := common.RunString(rt, `client.connect("localhost:1")`)
+		assert.Contains(t, err.Error(), "init context")
+	})
+
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:     root,
+		Dialer:    netext.NewDialer(net.Dialer{Timeout: 10 * time.Second}),
+		TLSConfig: &tls.Config{InsecureSkipVerify: true},
+		Options:   lib.Options{SystemTags: lib.GetTagSet("url", "method", "status", "group")},
+		Samples:   samples,
+	}
+	ctx = lib.WithState(ctx, state)
+	rt.Set("addr", strings.TrimPrefix(srv.URL, "https://"))
+
+	t.Run("LoadInVUContext", func(t *testing.T) {
+		_, err := common.RunString(rt, `client.load(proto)`)
+		assert.Contains(t, err.Error(), "init context")
+	})
+
+	t.Run("NotConnected", func(t *testing.T) {
+		_, err := common.RunString(rt, `client.invoke("hello.Greeter/SayHello", {})`)
+		assert.Contains(t, err.Error(), "isn't connected")
+	})
+
+	_, err = common.RunString(rt, `client.connect(addr, { timeout: "5s" })`)
+	require.NoError(t, err)
+
+	t.Run("Invoke", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let res = client.invoke("hello.Greeter/SayHello", { name: "k6" }, { headers: { "x-greeting": "hi" } });
+		if (res.status !== grpc.StatusOK) { throw new Error("wrong status: " + res.status); }
+		if (res.message.message !== "Hello k6, hi") { throw new Error("wrong message: " + res.message.message); }
+		if (res.error !== null) { throw new Error("unexpected error: " + res.error.message); }
+		`)
+		assert.NoError(t, err)
+
+		bufSamples := stats.GetBufferedSamples(samples)
+		require.Len(t, bufSamples, 1)
+		sample := bufSamples[0].GetSamples()[0]
+		assert.Equal(t, metrics.GRPCReqDuration, sample.Metric)
+		assert.Equal(t, map[string]string{
+			"url":    srv.URL + "/hello.Greeter/SayHello",
+			"method": "/hello.Greeter/SayHello",
+			"status": "0",
+			"group":  "",
+		}, sample.Tags.CloneTags())
+	})
+
+	t.Run("ErrorStatus", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let res = client.invoke("/hello.Greeter/SayHello", {}, { tags: { tag: "value" } });
+		if (res.status !== grpc.StatusInvalidArgument) { throw new Error("wrong status: " + res.status); }
+		if (res.error.message !== "name is required") { throw new Error("wrong error: " + res.error.message); }
+		if (res.message !== null) { throw new Error("unexpected message"); }
+		`)
+		assert.NoError(t, err)
+
+		bufSamples := stats.GetBufferedSamples(samples)
+		require.Len(t, bufSamples, 1)
+		tags := bufSamples[0].GetSamples()[0].Tags.CloneTags()
+		assert.Equal(t, "3", tags["status"])
+		assert.Equal(t, "value", tags["tag"])
+	})
+
+	t.Run("UnknownMethod", func(t *testing.T) {
+		_, err := common.RunString(rt, `client.invoke("hello.Greeter/SayGoodbye", {})`)
+		assert.Contains(t, err.Error(), "isn't in the loaded proto definitions")
+		_, err = common.RunString(rt, `client.invoke("hello.Greeter/Chat", {})`)
+		assert.Contains(t, err.Error(), "streaming method")
+	})
+
+	t.Run("InvalidMessage", func(t *testing.T) {
+		_, err := common.RunString(rt, `client.invoke("hello.Greeter/SayHello", { mood: "SAD" })`)
+		assert.Contains(t, err.Error(), "unknown value SAD")
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		client.close();
+		client.connect(addr);
+		let res = client.invoke("hello.Greeter/SayHello", { name: "again" });
+		if (res.status !== grpc.StatusOK) { throw new Error("wrong status: " + res.status); }
+		client.close();
+		`)
+		assert.NoError(t, err)
+	})
+}
+
+func TestReadMessage(t *testing.T) {
+	msg, err := readMessage([]byte{0, 0, 0, 0, 2, 'h', 'i', 'x'})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hi"), msg)
+
+	msg, err = readMessage([]byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+	assert.Empty(t, msg)
+
+	testdata := map[string][]byte{
+		"too short":                      {0, 0, 0},
+		"compressed":                     {1, 0, 0, 0, 0},
+		"only 2 were received":           {0, 0, 0, 0, 3, 'h', 'i'},
+		"its length is 4294967295 bytes": {0, 0xff, 0xff, 0xff, 0xff},
+	}
+	for errMsg, body := range testdata {
+		_, err := readMessage(body)
+		if assert.Error(t, err, errMsg) {
+			assert.Contains(t, err.Error(), errMsg)
+		}
+	}
+}
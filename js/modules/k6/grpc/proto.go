@@ -0,0 +1,595 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package grpc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// wellKnownTypes are the definitions of the google.protobuf types that .proto files commonly
+// import, so that they don't have to be loaded separately.
+const wellKnownTypes = `
+syntax = "proto3";
+package google.protobuf;
+message Empty {}
+message Timestamp { int64 seconds = 1; int32 nanos = 2; }
+message Duration { int64 seconds = 1; int32 nanos = 2; }
+message DoubleValue { double value = 1; }
+message FloatValue { float value = 1; }
+message Int64Value { int64 value = 1; }
+message UInt64Value { uint64 value = 1; }
+message Int32Value { int32 value = 1; }
+message UInt32Value { uint32 value = 1; }
+message BoolValue { bool value = 1; }
+message StringValue { string value = 1; }
+message BytesValue { bytes value = 1; }
+`
+
+// A field of a message.
+type fieldDesc struct {
+	name     string
+	jsonName string
+	number   int
+	typeName string // The scalar type, or the name of the message or enum as written
+	repeated bool
+
+	// For map fields, typeName is the type of the values.
+	isMap   bool
+	keyType string
+
+	// Set when the types are resolved, if typeName isn't a scalar.
+	message *messageDesc
+	enum    *enumDesc
+}
+
+type messageDesc struct {
+	fullName string
+	fields   []*fieldDesc
+	byNumber map[int]*fieldDesc
+}
+
+type enumDesc struct {
+	fullName string
+	values   map[string]int32
+	names    map[int32]string
+}
+
+type methodDesc struct {
+	fullName     string // e.g. /package.Service/Method
+	inputName    string
+	outputName   string
+	clientStream bool
+	serverStream bool
+
+	input  *messageDesc
+	output *messageDesc
+}
+
+// A protoSet holds the messages, enums and service methods of all loaded .proto sources.
+type protoSet struct {
+	messages map[string]*messageDesc
+	enums    map[string]*enumDesc
+	methods  map[string]*methodDesc
+
+	// The scope in which the type names of the fields and methods were written.
+	scopes map[interface{}]string
+}
+
+func newProtoSet() *protoSet {
+	ps := &protoSet{
+		messages: make(map[string]*messageDesc),
+		enums:    make(map[string]*enumDesc),
+		methods:  make(map[string]*methodDesc),
+		scopes:   make(map[interface{}]string),
+	}
+	if err := ps.load(wellKnownTypes); err != nil {
+		panic(err)
+	}
+	return ps
+}
+
+var scalarTypes = map[string]bool{
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true, "fixed32": true, "fixed64": true, "sfixed32": true,
+	"sfixed64": true, "bool": true, "string": true, "bytes": true,
+}
+
+// load parses .proto sources and adds their definitions to the set. Imports aren't followed, the
+// imported files have to be loaded as well, before or together with the files importing them.
+func (ps *protoSet) load(srcs ...string) error {
+	for _, src := range srcs {
+		p := &protoParser{tokens: tokenize(src), ps: ps}
+		if err := p.parseFile(); err != nil {
+			return err
+		}
+	}
+	return ps.resolve()
+}
+
+// resolve finds the definitions of the message and enum types of all fields and methods.
+func (ps *protoSet) resolve() error {
+	for _, msg := range ps.messages {
+		for _, f := range msg.fields {
+			if scalarTypes[f.typeName] || f.message != nil || f.enum != nil {
+				continue
+			}
+			name, ok := ps.lookup(ps.scopes[f], f.typeName)
+			if !ok {
+				return errors.Errorf("unknown type %s of field %s.%s", f.typeName, msg.fullName, f.name)
+			}
+			f.message, f.enum = ps.messages[name], ps.enums[name]
+		}
+	}
+	for _, m := range ps.methods {
+		if m.input != nil {
+			continue
+		}
+		in, ok := ps.lookup(ps.scopes[m], m.inputName)
+		if !ok || ps.messages[in] == nil {
+			return errors.Errorf("unknown input type %s of method %s", m.inputName, m.fullName)
+		}
+		out, ok := ps.lookup(ps.scopes[m], m.outputName)
+		if !ok || ps.messages[out] == nil {
+			return errors.Errorf("unknown output type %s of method %s", m.outputName, m.fullName)
+		}
+		m.input, m.output = ps.messages[in], ps.messages[out]
+	}
+	return nil
+}
+
+// lookup returns the full name of a type referenced in a scope, searching the enclosing scopes
+// from the innermost one outwards, like protoc does.
+func (ps *protoSet) lookup(scope, name string) (string, bool) {
+	exists := func(n string) bool { return ps.messages[n] != nil || ps.enums[n] != nil }
+	if strings.HasPrefix(name, ".") {
+		return name[1:], exists(name[1:])
+	}
+	for {
+		candidate := name
+		if scope != "" {
+			candidate = scope + "." + name
+		}
+		if exists(candidate) {
+			return candidate, true
+		}
+		if scope == "" {
+			return "", false
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+type protoParser struct {
+	tokens []string
+	pos    int
+	ps     *protoSet
+}
+
+// tokenize splits a .proto source into identifiers, numbers, quoted strings and symbols,
+// dropping the comments.
+func tokenize(src string) []string {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end + 4
+			}
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(src) {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		case isIdentChar(c) || c == '.':
+			j := i
+			for j < len(src) && (isIdentChar(src[j]) || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (p *protoParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *protoParser) next() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", errors.New("unexpected end of the proto definition")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *protoParser) expect(want string) error {
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return errors.Errorf("expected '%s' but got '%s' in the proto definition", want, tok)
+	}
+	return nil
+}
+
+// skipStatement skips everything up to the end of the current statement, including any block.
+func (p *protoParser) skipStatement() error {
+	depth := 0
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+func (p *protoParser) parseFile() error {
+	pkg := ""
+	for p.pos < len(p.tokens) {
+		tok, _ := p.next()
+		switch tok {
+		case "package":
+			name, err := p.next()
+			if err != nil {
+				return err
+			}
+			pkg = name
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case "message":
+			if err := p.parseMessage(pkg); err != nil {
+				return err
+			}
+		case "enum":
+			if err := p.parseEnum(pkg); err != nil {
+				return err
+			}
+		case "service":
+			if err := p.parseService(pkg); err != nil {
+				return err
+			}
+		case "syntax", "import", "option", "extend":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		case ";":
+		default:
+			return errors.Errorf("unexpected '%s' in the proto definition", tok)
+		}
+	}
+	return nil
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func (p *protoParser) parseMessage(scope string) error {
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	msg := &messageDesc{fullName: qualify(scope, name), byNumber: make(map[int]*fieldDesc)}
+	p.ps.messages[msg.fullName] = msg
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	return p.parseMessageBody(msg, false)
+}
+
+func (p *protoParser) parseMessageBody(msg *messageDesc, oneof bool) error {
+	for {
+		tok := p.peek()
+		switch tok {
+		case "}":
+			p.pos++
+			return nil
+		case "":
+			return errors.Errorf("unterminated message %s in the proto definition", msg.fullName)
+		case ";":
+			p.pos++
+		case "message":
+			p.pos++
+			if err := p.parseMessage(msg.fullName); err != nil {
+				return err
+			}
+		case "enum":
+			p.pos++
+			if err := p.parseEnum(msg.fullName); err != nil {
+				return err
+			}
+		case "oneof":
+			p.pos += 2 // The oneof name isn't needed, its fields are regular fields
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			if err := p.parseMessageBody(msg, true); err != nil {
+				return err
+			}
+		case "option", "reserved", "extensions", "extend":
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+		default:
+			if err := p.parseField(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *protoParser) parseField(msg *messageDesc) error {
+	f := &fieldDesc{}
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case "repeated":
+		f.repeated = true
+		tok, err = p.next()
+	case "optional", "required":
+		tok, err = p.next()
+	}
+	if err != nil {
+		return err
+	}
+
+	if tok == "map" {
+		f.isMap = true
+		if err := p.expect("<"); err != nil {
+			return err
+		}
+		if f.keyType, err = p.next(); err != nil {
+			return err
+		}
+		if err := p.expect(","); err != nil {
+			return err
+		}
+		if tok, err = p.next(); err != nil {
+			return err
+		}
+		if err := p.expect(">"); err != nil {
+			return err
+		}
+	}
+	f.typeName = tok
+
+	if f.name, err = p.next(); err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	num, err := p.next()
+	if err != nil {
+		return err
+	}
+	if f.number, err = strconv.Atoi(num); err != nil {
+		return errors.Errorf("invalid number '%s' of field %s.%s", num, msg.fullName, f.name)
+	}
+	if err := p.skipStatement(); err != nil { // Skip the field options, if any
+		return err
+	}
+
+	f.jsonName = jsonName(f.name)
+	msg.fields = append(msg.fields, f)
+	msg.byNumber[f.number] = f
+	p.ps.scopes[f] = msg.fullName
+	return nil
+}
+
+// jsonName returns the lowerCamelCase name of a field, like protoc does for the JSON mapping.
+func jsonName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, c := range name {
+		switch {
+		case c == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(c))
+			upper = false
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+func (p *protoParser) parseEnum(scope string) error {
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	enum := &enumDesc{fullName: qualify(scope, name), values: make(map[string]int32), names: make(map[int32]string)}
+	p.ps.enums[enum.fullName] = enum
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "}":
+			return nil
+		case ";":
+			continue
+		case "option", "reserved":
+			p.pos--
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		num, err := p.next()
+		if err != nil {
+			return err
+		}
+		if num == "-" { // Negative values are tokenized separately
+			if num, err = p.next(); err != nil {
+				return err
+			}
+			num = "-" + num
+		}
+		value, err := strconv.ParseInt(num, 0, 32)
+		if err != nil {
+			return errors.Errorf("invalid value '%s' of enum %s.%s", num, enum.fullName, tok)
+		}
+		enum.values[tok] = int32(value)
+		if _, ok := enum.names[int32(value)]; !ok { // Aliases use the first name
+			enum.names[int32(value)] = tok
+		}
+		if err := p.skipStatement(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *protoParser) parseService(scope string) error {
+	name, err := p.next()
+	if err != nil {
+		return err
+	}
+	service := qualify(scope, name)
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "}":
+			return nil
+		case ";":
+			continue
+		case "rpc":
+		default:
+			p.pos--
+			if err := p.skipStatement(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, err := p.next()
+		if err != nil {
+			return err
+		}
+		m := &methodDesc{fullName: fmt.Sprintf("/%s/%s", service, name)}
+		if m.clientStream, m.inputName, err = p.parseRPCType(); err != nil {
+			return err
+		}
+		if err := p.expect("returns"); err != nil {
+			return err
+		}
+		if m.serverStream, m.outputName, err = p.parseRPCType(); err != nil {
+			return err
+		}
+		if err := p.skipStatement(); err != nil { // Skip the method options, if any
+			return err
+		}
+		p.ps.methods[m.fullName] = m
+		p.ps.scopes[m] = scope
+	}
+}
+
+// parseRPCType parses the parenthesized input or output type of an rpc.
+func (p *protoParser) parseRPCType() (stream bool, name string, err error) {
+	if err = p.expect("("); err != nil {
+		return
+	}
+	if name, err = p.next(); err != nil {
+		return
+	}
+	if name == "stream" {
+		stream = true
+		if name, err = p.next(); err != nil {
+			return
+		}
+	}
+	err = p.expect(")")
+	return
+}
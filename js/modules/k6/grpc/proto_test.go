@@ -0,0 +1,163 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProto = `
+// The greeting service definition.
+syntax = "proto3";
+
+package hello;
+
+import "google/protobuf/empty.proto";
+
+option go_package = "hello";
+
+service Greeter {
+  rpc SayHello (HelloRequest) returns (HelloReply) {}
+  rpc Ping (google.protobuf.Empty) returns (google.protobuf.Empty);
+  rpc Chat (stream HelloRequest) returns (stream HelloReply) { option deprecated = true; }
+}
+
+message HelloRequest {
+  string name = 1;
+  Mood mood = 2;
+  repeated int32 lucky_numbers = 3 [packed = true];
+  map<string, string> labels = 4;
+  int64 big_id = 5;
+  /* oneofs are regular fields */
+  oneof contact {
+    string email = 6;
+    Address address = 7;
+  }
+  reserved 8, 9;
+  bytes avatar = 10;
+  sint32 offset = 11;
+  double score = 12;
+
+  message Address {
+    string city = 1;
+  }
+}
+
+enum Mood {
+  option allow_alias = true;
+  MOOD_UNSPECIFIED = 0;
+  HAPPY = 1;
+  JOYFUL = 1;
+  GRUMPY = -1;
+}
+
+message HelloReply {
+  string message = 1;
+  repeated HelloRequest.Address addresses = 2;
+}
+`
+
+func TestProtoSet(t *testing.T) {
+	ps := newProtoSet()
+	require.NoError(t, ps.load(testProto))
+
+	m := ps.methods["/hello.Greeter/SayHello"]
+	require.NotNil(t, m)
+	assert.Equal(t, "hello.HelloRequest", m.input.fullName)
+	assert.Equal(t, "hello.HelloReply", m.output.fullName)
+	assert.False(t, m.clientStream || m.serverStream)
+
+	chat := ps.methods["/hello.Greeter/Chat"]
+	require.NotNil(t, chat)
+	assert.True(t, chat.clientStream && chat.serverStream)
+	assert.Equal(t, "google.protobuf.Empty", ps.methods["/hello.Greeter/Ping"].input.fullName)
+
+	req := ps.messages["hello.HelloRequest"]
+	require.Len(t, req.fields, 10)
+	assert.Equal(t, "luckyNumbers", req.byNumber[3].jsonName)
+	assert.True(t, req.byNumber[3].repeated)
+	assert.Equal(t, ps.enums["hello.Mood"], req.byNumber[2].enum)
+	assert.True(t, req.byNumber[4].isMap)
+	assert.Equal(t, ps.messages["hello.HelloRequest.Address"], req.byNumber[7].message)
+	assert.Equal(t, ps.messages["hello.HelloRequest.Address"], ps.messages["hello.HelloReply"].byNumber[2].message)
+
+	mood := ps.enums["hello.Mood"]
+	assert.Equal(t, int32(-1), mood.values["GRUMPY"])
+	assert.Equal(t, "HAPPY", mood.names[1])
+
+	assert.Error(t, newProtoSet().load(`message A { B b = 1; }`))
+	assert.Error(t, newProtoSet().load(`service S { rpc M (A) returns (A); }`))
+	assert.Error(t, newProtoSet().load(`message A { string a = x; }`))
+	assert.Error(t, newProtoSet().load(`message A { string a = 1;`))
+}
+
+func TestMarshalling(t *testing.T) {
+	ps := newProtoSet()
+	require.NoError(t, ps.load(testProto))
+	req := ps.messages["hello.HelloRequest"]
+
+	data, err := marshal(req, map[string]interface{}{
+		"name":          "k6",
+		"mood":          "GRUMPY",
+		"lucky_numbers": []interface{}{int64(7), 13.0, int64(-1)},
+		"labels":        map[string]interface{}{"a": "1", "b": "2"},
+		"bigId":         "9007199254740993",
+		"address":       map[string]interface{}{"city": "Stockholm"},
+		"avatar":        "AAEC",
+		"offset":        int64(-3),
+		"score":         0.5,
+	})
+	require.NoError(t, err)
+
+	obj, err := unmarshal(req, data)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":         "k6",
+		"mood":         "GRUMPY",
+		"luckyNumbers": []interface{}{int64(7), int64(13), int64(-1)},
+		"labels":       map[string]interface{}{"a": "1", "b": "2"},
+		"bigId":        "9007199254740993",
+		"email":        "",
+		"address":      map[string]interface{}{"city": "Stockholm"},
+		"avatar":       "AAEC",
+		"offset":       int64(-3),
+		"score":        0.5,
+	}, obj)
+
+	obj, err = unmarshal(req, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "MOOD_UNSPECIFIED", obj["mood"])
+	assert.Equal(t, []interface{}{}, obj["luckyNumbers"])
+	assert.Nil(t, obj["address"])
+	assert.Equal(t, "0", obj["bigId"])
+
+	_, err = marshal(req, map[string]interface{}{"mood": "SAD"})
+	assert.Error(t, err)
+	_, err = marshal(req, map[string]interface{}{"name": 1.0})
+	assert.Error(t, err)
+	_, err = marshal(req, map[string]interface{}{"lucky_numbers": int64(1)})
+	assert.Error(t, err)
+	_, err = unmarshal(req, []byte{0x0a, 0x10, 'a'})
+	assert.Error(t, err)
+}
@@ -103,3 +103,63 @@ func (j HTTPCookieJar) Set(url, name, value string, opts goja.Value) (bool, erro
 	j.jar.SetCookies(u, []*http.Cookie{&c})
 	return true, nil
 }
+
+// Delete removes the cookie with the given name that would be sent to the given url
+func (j HTTPCookieJar) Delete(url, name string) error {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return err
+	}
+	j.deleteCookies(u, []string{name})
+	return nil
+}
+
+// Clear removes all cookies that would be sent to the given url
+func (j HTTPCookieJar) Clear(url string) error {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return err
+	}
+	cookies := j.jar.Cookies(u)
+	names := make([]string, 0, len(cookies))
+	for _, c := range cookies {
+		names = append(names, c.Name)
+	}
+	j.deleteCookies(u, names)
+	return nil
+}
+
+// deleteCookies removes the named cookies for the url. cookiejar.Jar doesn't return the
+// domain and path of its cookies and only removes a cookie if they match, so an expired
+// cookie is set for every domain and path the cookie could have been set with.
+func (j HTTPCookieJar) deleteCookies(u *neturl.URL, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	domains := []string{""} // host-only cookies
+	host := u.Hostname()
+	for d := host; strings.Contains(d, "."); d = d[strings.Index(d, ".")+1:] {
+		domains = append(domains, d)
+	}
+
+	paths := []string{"/"}
+	for i := 1; i < len(u.Path); i++ {
+		if u.Path[i] == '/' {
+			paths = append(paths, u.Path[:i])
+		}
+	}
+	if u.Path != "" && u.Path != "/" {
+		paths = append(paths, strings.TrimSuffix(u.Path, "/"))
+	}
+
+	cookies := make([]*http.Cookie, 0, len(names)*len(domains)*len(paths))
+	for _, name := range names {
+		for _, domain := range domains {
+			for _, path := range paths {
+				cookies = append(cookies, &http.Cookie{Name: name, Domain: domain, Path: path, MaxAge: -1})
+			}
+		}
+	}
+	j.jar.SetCookies(u, cookies)
+}
@@ -0,0 +1,101 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultipartFileUpload(t *testing.T) {
+	t.Parallel()
+	tb, _, _, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	type part struct {
+		Name        string `json:"name"`
+		Filename    string `json:"filename"`
+		ContentType string `json:"contentType"`
+		Data        string `json:"data"`
+	}
+	tb.Mux.HandleFunc("/multipart", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		assert.Equal(t, "multipart/form-data", mediaType)
+		parts := []part{}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			data, err := ioutil.ReadAll(p)
+			require.NoError(t, err)
+			parts = append(parts, part{p.FormName(), p.FileName(), p.Header.Get("Content-Type"), string(data)})
+		}
+		_ = json.NewEncoder(w).Encode(parts)
+	}))
+
+	t.Run("Single", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let res = http.post("HTTPBIN_URL/multipart", {
+			field: "value",
+			file: http.file("file content", "test.txt", "text/plain"),
+		});
+		let parts = res.json();
+		if (parts.length != 2) { throw new Error("wrong number of parts: " + parts.length); }
+		if (parts[0].name != "field" || parts[0].data != "value") { throw new Error("wrong field: " + JSON.stringify(parts[0])); }
+		if (parts[1].name != "file" || parts[1].filename != "test.txt" || parts[1].contentType != "text/plain" || parts[1].data != "file content") {
+			throw new Error("wrong file: " + JSON.stringify(parts[1]));
+		}
+		`))
+		assert.NoError(t, err)
+	})
+	t.Run("Multiple", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let res = http.post("HTTPBIN_URL/multipart", {
+			files: [http.file("one", "1.txt"), http.file("two", "2.txt")],
+			tags: ["a", "b"],
+		});
+		let parts = res.json();
+		let got = parts.map(p => p.name + ":" + p.filename + ":" + p.data).join(",");
+		if (got != "files:1.txt:one,files:2.txt:two,tags::a,tags::b") { throw new Error("wrong parts: " + got); }
+		`))
+		assert.NoError(t, err)
+	})
+	t.Run("FormArray", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let res = http.post("HTTPBIN_URL/post", { tags: ["a", "b"] });
+		let tags = res.json().form.tags;
+		if (tags.length != 2 || tags[0] != "a" || tags[1] != "b") { throw new Error("wrong tags: " + JSON.stringify(tags)); }
+		`))
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,121 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/netext/httpext"
+)
+
+// Matches the type and the optional name of the first operation in a GraphQL document, skipping
+// any leading comments.
+var graphQLOperationRegexp = regexp.MustCompile(
+	`^(?:\s*#[^\n]*\n)*\s*(query|mutation|subscription)\b\s*([_A-Za-z][_0-9A-Za-z]*)?`,
+)
+
+// parseGraphQLOperation returns the type and the name of the operation of a GraphQL query. The
+// shorthand `{ ... }` syntax is an anonymous query.
+func parseGraphQLOperation(query string) (opType, opName string) {
+	m := graphQLOperationRegexp.FindStringSubmatch(query)
+	if m == nil {
+		return "query", ""
+	}
+	return m[1], m[2]
+}
+
+// GraphQL sends a GraphQL query or mutation, with the optional variables, as a JSON POST request.
+// Its metrics are tagged with the type of the operation and its name, which is taken from the
+// query unless it's specified with the operationName param.
+func (h *HTTP) GraphQL(ctx context.Context, url goja.Value, query string, args ...goja.Value) (*Response, error) {
+	u, err := ToURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := map[string]interface{}{"query": query}
+	if len(args) > 0 && !goja.IsUndefined(args[0]) && !goja.IsNull(args[0]) {
+		payload["variables"] = args[0].Export()
+	}
+	var params goja.Value
+	opType, opName := parseGraphQLOperation(query)
+	if len(args) > 1 {
+		params = args[1]
+		if !goja.IsUndefined(params) && !goja.IsNull(params) {
+			rt := common.GetRuntime(ctx)
+			if name := params.ToObject(rt).Get("operationName"); name != nil && !goja.IsUndefined(name) {
+				opName = name.String()
+				payload["operationName"] = opName
+			}
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := h.parseRequest(ctx, HTTP_METHOD_POST, u, string(body), params)
+	if err != nil {
+		return nil, err
+	}
+	if req.Req.Header.Get("Content-Type") == "" {
+		req.Req.Header.Set("Content-Type", "application/json")
+	}
+	if _, ok := req.Tags["operation_type"]; !ok {
+		req.Tags["operation_type"] = opType
+	}
+	if _, ok := req.Tags["operation_name"]; !ok && opName != "" {
+		req.Tags["operation_name"] = opName
+	}
+
+	resp, err := httpext.MakeRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return responseFromHttpext(resp), nil
+}
+
+// GraphQLData returns the data of a GraphQL response, or only the value at the selector path in
+// it, like json() does. It's undefined if the response has no data.
+func (res *Response) GraphQLData(selector ...string) goja.Value {
+	path := "data"
+	if len(selector) > 0 && selector[0] != "" {
+		path += "." + selector[0]
+	}
+	return res.JSON(path)
+}
+
+// GraphQLErrors returns the errors of a GraphQL response, an empty array if there are none.
+func (res *Response) GraphQLErrors() goja.Value {
+	rt := common.GetRuntime(res.GetCtx())
+	v, err := ((*httpext.Response)(res)).JSON("errors")
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	if errs, ok := v.([]interface{}); ok {
+		return rt.ToValue(errs)
+	}
+	return rt.ToValue([]interface{}{})
+}
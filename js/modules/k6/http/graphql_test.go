@@ -0,0 +1,121 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGraphQLOperation(t *testing.T) {
+	testdata := map[string][2]string{
+		`{ user(id: 1) { name } }`:                           {"query", ""},
+		`query { user(id: 1) { name } }`:                     {"query", ""},
+		`query GetUser($id: ID!) { user(id: $id) { name } }`: {"query", "GetUser"},
+		"# a comment\n  mutation AddUser { addUser { id } }": {"mutation", "AddUser"},
+		`subscription OnUser{ userAdded { id } }`:            {"subscription", "OnUser"},
+		`queryish { field }`:                                 {"query", ""},
+	}
+	for query, expected := range testdata {
+		opType, opName := parseGraphQLOperation(query)
+		assert.Equal(t, expected, [2]string{opType, opName}, query)
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	t.Parallel()
+	tb, _, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	tb.Mux.HandleFunc("/graphql", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Query         string                 `json:"query"`
+			Variables     map[string]interface{} `json:"variables"`
+			OperationName string                 `json:"operationName"`
+		}
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.Header().Set("Content-Type", "application/json")
+		if payload.Variables["id"] == nil {
+			_, _ = w.Write([]byte(`{"data": null, "errors": [{"message": "id is required"}]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"user": map[string]interface{}{"id": payload.Variables["id"], "op": payload.OperationName},
+			},
+		})
+	}))
+
+	t.Run("Data", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let res = http.graphql("HTTPBIN_URL/graphql", "query GetUser($id: ID!) { user(id: $id) { id } }", { id: "42" });
+		if (res.status != 200) { throw new Error("wrong status: " + res.status); }
+		if (res.graphqlData("user.id") !== "42") { throw new Error("wrong id: " + res.graphqlData("user.id")); }
+		if (res.graphqlData().user.op !== "") { throw new Error("unexpected operationName"); }
+		if (res.graphqlErrors().length !== 0) { throw new Error("unexpected errors"); }
+		`))
+		assert.NoError(t, err)
+
+		found := false
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				if s.Metric != metrics.HTTPReqs {
+					continue
+				}
+				found = true
+				tags := s.Tags.CloneTags()
+				assert.Equal(t, "query", tags["operation_type"])
+				assert.Equal(t, "GetUser", tags["operation_name"])
+				assert.Equal(t, "POST", tags["method"])
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let res = http.graphql("HTTPBIN_URL/graphql", "mutation { addUser { id } }", null, {
+			operationName: "AddUser", tags: { operation_type: "write" },
+		});
+		let errors = res.graphqlErrors();
+		if (errors.length !== 1 || errors[0].message !== "id is required") { throw new Error("wrong errors"); }
+		if (res.graphqlData() !== undefined) { throw new Error("unexpected data: " + res.graphqlData()); }
+		`))
+		assert.NoError(t, err)
+
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				tags := s.Tags.CloneTags()
+				assert.Equal(t, "write", tags["operation_type"])
+				assert.Equal(t, "AddUser", tags["operation_name"])
+			}
+		}
+	})
+}
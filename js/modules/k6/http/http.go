@@ -60,6 +60,9 @@ type HTTP struct {
 	OCSP_REASON_REMOVE_FROM_CRL        string `js:"OCSP_REASON_REMOVE_FROM_CRL"`
 	OCSP_REASON_PRIVILEGE_WITHDRAWN    string `js:"OCSP_REASON_PRIVILEGE_WITHDRAWN"`
 	OCSP_REASON_AA_COMPROMISE          string `js:"OCSP_REASON_AA_COMPROMISE"`
+
+	// The default response callback of the requests of the VU, see SetResponseCallback().
+	responseCallback func(status int) bool
 }
 
 func New() *HTTP {
@@ -83,9 +86,18 @@ func New() *HTTP {
 		OCSP_REASON_REMOVE_FROM_CRL:        netext.OCSP_REASON_REMOVE_FROM_CRL,
 		OCSP_REASON_PRIVILEGE_WITHDRAWN:    netext.OCSP_REASON_PRIVILEGE_WITHDRAWN,
 		OCSP_REASON_AA_COMPROMISE:          netext.OCSP_REASON_AA_COMPROMISE,
+
+		responseCallback: defaultExpectedStatuses.match,
 	}
 }
 
+// NewModuleInstancePerVU returns a copy of the module for every VU, so that the response
+// callback set by one VU doesn't affect the others.
+func (h *HTTP) NewModuleInstancePerVU() interface{} {
+	instance := *h
+	return &instance
+}
+
 func (*HTTP) XCookieJar(ctx *context.Context) *HTTPCookieJar {
 	return newCookieJar(ctx)
 }
@@ -29,6 +29,8 @@ import (
 	"net/textproto"
 	"net/url"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -37,9 +39,13 @@ import (
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/netext/httpext"
+	"github.com/loadimpact/k6/lib/types"
 	null "gopkg.in/guregu/null.v3"
 )
 
+// The timeout of requests, unless it's set with the httpRequestTimeout option or the timeout param
+const defaultTimeout = 60 * time.Second
+
 // ErrHTTPForbiddenInInitContext is used when a http requests was made in the init context
 var ErrHTTPForbiddenInInitContext = common.NewInitContextError("Making http requests in the init context is not supported")
 
@@ -133,11 +139,16 @@ func (h *HTTP) parseRequest(
 			URL:    reqURL.GetURL(),
 			Header: make(http.Header),
 		},
-		Timeout:   60 * time.Second,
+		Timeout:   defaultTimeout,
 		Throw:     state.Options.Throw.Bool,
 		Redirects: state.Options.MaxRedirects,
 		Cookies:   make(map[string]*httpext.HTTPRequestCookie),
 		Tags:      make(map[string]string),
+
+		ResponseCallback: h.responseCallback,
+	}
+	if state.Options.HTTPRequestTimeout.Valid {
+		result.Timeout = time.Duration(state.Options.HTTPRequestTimeout.Duration)
 	}
 	if state.Options.DiscardResponseBodies.Bool {
 		result.ResponseType = httpext.ResponseTypeNone
@@ -154,6 +165,12 @@ func (h *HTTP) parseRequest(
 		if !requestContainsFile(data) {
 			bodyQuery := make(url.Values, len(data))
 			for k, v := range data {
+				if values, ok := v.([]interface{}); ok {
+					for _, value := range values {
+						bodyQuery.Add(k, formatFormVal(value))
+					}
+					continue
+				}
 				bodyQuery.Set(k, formatFormVal(v))
 			}
 			result.Body = bytes.NewBufferString(bodyQuery.Encode())
@@ -168,7 +185,7 @@ func (h *HTTP) parseRequest(
 		// For parameters of type common.FileData, created with open(file, "b"),
 		// we write the file boundary to the body buffer.
 		// Otherwise parameters are treated as standard form field.
-		for k, v := range data {
+		writePart := func(k string, v interface{}) error {
 			switch ve := v.(type) {
 			case FileData:
 				// writing our own part to handle receiving
@@ -177,7 +194,7 @@ func (h *HTTP) parseRequest(
 				escapedFilename := escapeQuotes(ve.Filename)
 				h.Set("Content-Disposition",
 					fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
-						k, escapedFilename))
+						escapeQuotes(k), escapedFilename))
 				h.Set("Content-Type", ve.ContentType)
 
 				// this writer will be closed either by the next part or
@@ -187,16 +204,33 @@ func (h *HTTP) parseRequest(
 					return err
 				}
 
-				if _, err := fw.Write(ve.Data); err != nil {
-					return err
-				}
+				_, err = fw.Write(ve.Data)
+				return err
 			default:
 				fw, err := mpw.CreateFormField(k)
 				if err != nil {
 					return err
 				}
 
-				if _, err := fw.Write([]byte(formatFormVal(v))); err != nil {
+				_, err = fw.Write([]byte(formatFormVal(v)))
+				return err
+			}
+		}
+
+		// The parts are sorted by their names, so that the bodies are the same for every
+		// request, and arrays become several parts with the same name, e.g. for multiple files.
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			values, ok := data[k].([]interface{})
+			if !ok {
+				values = []interface{}{data[k]}
+			}
+			for _, v := range values {
+				if err := writePart(k, v); err != nil {
 					return err
 				}
 			}
@@ -316,8 +350,12 @@ func (h *HTTP) parseRequest(
 					algo = strings.TrimSpace(algo)
 					result.Compressions[index], err = httpext.CompressionTypeString(algo)
 					if err != nil {
-						return nil, fmt.Errorf("unknown compression algorithm %s, supported algorithms are %s",
-							algo, httpext.CompressionTypeValues())
+						supported := make([]string, 0, len(httpext.CompressionTypeValues()))
+						for _, ct := range httpext.CompressionTypeValues() {
+							supported = append(supported, ct.String())
+						}
+						return nil, fmt.Errorf("unknown compression algorithm '%s', supported algorithms are %s",
+							algo, strings.Join(supported, ", "))
 					}
 				}
 			case "redirects":
@@ -335,9 +373,32 @@ func (h *HTTP) parseRequest(
 					result.Tags[key] = tagObj.Get(key).String()
 				}
 			case "auth":
-				result.Auth = params.Get(k).String()
+				authV := params.Get(k)
+				if goja.IsUndefined(authV) || goja.IsNull(authV) {
+					continue
+				}
+				result.Auth = authV.String()
+				switch result.Auth {
+				case "", "basic", "digest", "ntlm":
+				default:
+					return nil, fmt.Errorf("unknown auth type '%s', supported types are basic, digest and ntlm", result.Auth)
+				}
 			case "timeout":
-				result.Timeout = time.Duration(params.Get(k).ToFloat() * float64(time.Millisecond))
+				value := params.Get(k).Export()
+				// Numeric strings are milliseconds, like numbers, as they always were.
+				if s, ok := value.(string); ok {
+					if ms, err := strconv.ParseFloat(s, 64); err == nil {
+						value = ms
+					}
+				}
+				timeout, err := types.GetDurationValue(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid timeout: %s", err)
+				}
+				if timeout < 0 {
+					return nil, fmt.Errorf("invalid timeout: %s is negative", timeout)
+				}
+				result.Timeout = timeout
 			case "throw":
 				result.Throw = params.Get(k).ToBoolean()
 			case "responseType":
@@ -346,6 +407,12 @@ func (h *HTTP) parseRequest(
 					return nil, err
 				}
 				result.ResponseType = responseType
+			case "responseCallback":
+				callback, err := getResponseCallback(params.Get(k))
+				if err != nil {
+					return nil, err
+				}
+				result.ResponseCallback = callback
 			}
 		}
 	}
@@ -490,9 +557,15 @@ func (h *HTTP) parseBatchRequest(ctx context.Context, key string, val goja.Value
 
 func requestContainsFile(data map[string]interface{}) bool {
 	for _, v := range data {
-		switch v.(type) {
+		switch ve := v.(type) {
 		case FileData:
 			return true
+		case []interface{}:
+			for _, e := range ve {
+				if _, ok := e.(FileData); ok {
+					return true
+				}
+			}
 		}
 	}
 	return false
@@ -67,6 +67,8 @@ func (res *Response) HTML(selector ...string) html.Selection {
 		body = string(b)
 	case string:
 		body = b
+	case nil:
+		common.Throw(common.GetRuntime(res.GetCtx()), httpext.ErrDiscardedBody)
 	default:
 		common.Throw(common.GetRuntime(res.GetCtx()), errors.New("invalid response type"))
 	}
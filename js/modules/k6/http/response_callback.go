@@ -0,0 +1,125 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"context"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+// expectedStatuses is the response callback returned by http.expectedStatuses(), it matches
+// the statuses in its ranges or its exact values.
+type expectedStatuses struct {
+	minmax [][2]int
+	exact  []int
+}
+
+// The statuses that are expected by default, i.e. everything but errors and 4xx/5xx responses.
+var defaultExpectedStatuses = expectedStatuses{minmax: [][2]int{{200, 399}}}
+
+func (e expectedStatuses) match(status int) bool {
+	for _, r := range e.minmax {
+		if r[0] <= status && status <= r[1] {
+			return true
+		}
+	}
+	for _, s := range e.exact {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectedStatuses returns a response callback for the given statuses, which are either integers
+// or objects with the min and max statuses of a range, e.g. http.expectedStatuses(200, {min: 300, max: 399}).
+func (*HTTP) ExpectedStatuses(ctx context.Context, args ...goja.Value) (*expectedStatuses, error) {
+	rt := common.GetRuntime(ctx)
+	if len(args) == 0 {
+		return nil, errors.New("no arguments given to expectedStatuses")
+	}
+
+	const errMsg = "argument number %d to expectedStatuses was neither an integer nor an object like {min:100, max:329}"
+	result := &expectedStatuses{}
+	for i, arg := range args {
+		if isInteger(arg) {
+			result.exact = append(result.exact, int(arg.ToInteger()))
+			continue
+		}
+		if arg == nil || goja.IsUndefined(arg) || goja.IsNull(arg) {
+			return nil, errors.Errorf(errMsg, i+1)
+		}
+		if _, ok := arg.Export().(map[string]interface{}); !ok {
+			return nil, errors.Errorf(errMsg, i+1)
+		}
+		obj := arg.ToObject(rt)
+		min, max := obj.Get("min"), obj.Get("max")
+		if min == nil {
+			min = goja.Undefined()
+		}
+		if max == nil {
+			max = goja.Undefined()
+		}
+		if !isInteger(min) || !isInteger(max) {
+			return nil, errors.Errorf(errMsg+", got min: %s and max: %s", i+1, min, max)
+		}
+		result.minmax = append(result.minmax, [2]int{int(min.ToInteger()), int(max.ToInteger())})
+	}
+	return result, nil
+}
+
+func isInteger(v goja.Value) bool {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return false
+	}
+	switch n := v.Export().(type) {
+	case int64:
+		return true
+	case float64:
+		return n == float64(int64(n))
+	default:
+		return false
+	}
+}
+
+// SetResponseCallback sets the response callback of all the following requests of the VU, which
+// classifies their responses as expected or not for the http_req_failed metric and the
+// expected_response tag. It can be null, to not classify the responses.
+func (h *HTTP) SetResponseCallback(ctx context.Context, val goja.Value) {
+	callback, err := getResponseCallback(val)
+	if err != nil {
+		common.Throw(common.GetRuntime(ctx), err)
+	}
+	h.responseCallback = callback
+}
+
+func getResponseCallback(val goja.Value) (func(int) bool, error) {
+	if val == nil || goja.IsUndefined(val) || goja.IsNull(val) {
+		return nil, nil
+	}
+	if e, ok := val.Export().(*expectedStatuses); ok {
+		return e.match, nil
+	}
+	return nil, errors.Errorf("unsupported response callback %s, only http.expectedStatuses() is supported", val)
+}
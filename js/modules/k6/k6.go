@@ -47,8 +47,17 @@ func New() *K6 {
 	return &K6{}
 }
 
-func (*K6) Fail(msg string) (goja.Value, error) {
-	return goja.Undefined(), errors.New(msg)
+// Fail throws a FailError with the message, which ends the iteration as failed, unless it's caught.
+// Its name is "FailError", so it can be told apart from other errors by the script.
+func (*K6) Fail(ctx context.Context, msg string) {
+	fe := &common.FailError{Message: msg}
+	if state := lib.GetState(ctx); state != nil {
+		fe.Group = state.Group.Path
+	}
+	rt := common.GetRuntime(ctx)
+	e := rt.NewGoError(fe)
+	_ = e.Set("name", "FailError")
+	panic(e)
 }
 
 func (*K6) Sleep(ctx context.Context, secs float64) {
@@ -67,7 +76,9 @@ func (*K6) RandomSeed(ctx context.Context, seed int64) {
 	rt.SetRandSource(randSource)
 }
 
-func (*K6) Group(ctx context.Context, name string, fn goja.Callable) (goja.Value, error) {
+// Group runs fn in the group with the given name. The optional tags are added to all of the
+// samples emitted inside of it, on top of the tags of the enclosing groups.
+func (*K6) Group(ctx context.Context, name string, fn goja.Callable, extras ...goja.Value) (goja.Value, error) {
 	state := lib.GetState(ctx)
 	if state == nil {
 		return nil, ErrGroupInInitContext
@@ -86,6 +97,19 @@ func (*K6) Group(ctx context.Context, name string, fn goja.Callable) (goja.Value
 	state.Group = g
 	defer func() { state.Group = old }()
 
+	if len(extras) > 0 && !goja.IsUndefined(extras[0]) && !goja.IsNull(extras[0]) {
+		rt := common.GetRuntime(ctx)
+		groupTags := state.Options.RunTags.CloneTags()
+		obj := extras[0].ToObject(rt)
+		for _, k := range obj.Keys() {
+			groupTags[k] = obj.Get(k).String()
+		}
+
+		oldTags := state.Options.RunTags
+		state.Options.RunTags = stats.IntoSampleTags(&groupTags)
+		defer func() { state.Options.RunTags = oldTags }()
+	}
+
 	startTime := time.Now()
 	ret, err := fn(goja.Undefined())
 	t := time.Now()
@@ -121,15 +145,15 @@ func (*K6) Check(ctx context.Context, arg0, checks goja.Value, extras ...goja.Va
 
 	// Prepare tags, make sure the `group` tag can't be overwritten.
 	commonTags := state.Options.RunTags.CloneTags()
-	if state.Options.SystemTags["group"] {
-		commonTags["group"] = state.Group.Path
-	}
 	if len(extras) > 0 {
 		obj := extras[0].ToObject(rt)
 		for _, k := range obj.Keys() {
 			commonTags[k] = obj.Get(k).String()
 		}
 	}
+	if state.Options.SystemTags["group"] {
+		commonTags["group"] = state.Group.Path
+	}
 	if state.Options.SystemTags["vu"] {
 		commonTags["vu"] = strconv.FormatInt(state.Vu, 10)
 	}
@@ -161,6 +185,9 @@ func (*K6) Check(ctx context.Context, arg0, checks goja.Value, extras ...goja.Va
 		if ok {
 			tmpVal, err := fn(goja.Undefined(), arg0)
 			if err != nil {
+				if fe := common.GetFailError(err); fe != nil && fe.Check == "" {
+					fe.Check = check.Name
+				}
 				return false, err
 			}
 			val = tmpVal
@@ -39,9 +39,49 @@ import (
 
 func TestFail(t *testing.T) {
 	rt := goja.New()
-	rt.Set("k6", common.Bind(rt, New(), nil))
-	_, err := common.RunString(rt, `k6.fail("blah")`)
-	assert.EqualError(t, err, "GoError: blah")
+	baseCtx := common.WithRuntime(context.Background(), rt)
+	ctx := new(context.Context)
+	*ctx = baseCtx
+	rt.Set("k6", common.Bind(rt, New(), ctx))
+
+	t.Run("InitContext", func(t *testing.T) {
+		_, err := common.RunString(rt, `k6.fail("blah")`)
+		assert.EqualError(t, err, "FailError: blah")
+		assert.Equal(t, &common.FailError{Message: "blah"}, common.GetFailError(err))
+	})
+
+	t.Run("Name", func(t *testing.T) {
+		v, err := common.RunString(rt, `
+		let name;
+		try { k6.fail("blah"); } catch (e) { name = e.name; }
+		name`)
+		require.NoError(t, err)
+		assert.Equal(t, "FailError", v.String())
+	})
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	*ctx = lib.WithState(baseCtx, &lib.State{
+		Group:   root,
+		Options: lib.Options{SystemTags: lib.GetTagSet(lib.DefaultSystemTagList...)},
+		Samples: make(chan stats.SampleContainer, 1000),
+	})
+
+	t.Run("Group", func(t *testing.T) {
+		_, err := common.RunString(rt, `k6.group("login", () => { k6.fail("blah"); })`)
+		assert.Equal(t, &common.FailError{Message: "blah", Group: "::login"}, common.GetFailError(err))
+	})
+
+	t.Run("Check", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		k6.group("login", () => { k6.check(null, { "is ok": () => k6.fail("blah") }); })`)
+		assert.Equal(t, &common.FailError{Message: "blah", Group: "::login", Check: "is ok"}, common.GetFailError(err))
+	})
+
+	t.Run("OtherError", func(t *testing.T) {
+		_, err := common.RunString(rt, `throw new Error("blah")`)
+		assert.Nil(t, common.GetFailError(err))
+	})
 }
 
 func TestSleep(t *testing.T) {
@@ -112,7 +152,8 @@ func TestGroup(t *testing.T) {
 	assert.NoError(t, err)
 
 	rt := goja.New()
-	state := &lib.State{Group: root, Samples: make(chan stats.SampleContainer, 1000)}
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{Group: root, Samples: samples}
 
 	ctx := context.Background()
 	ctx = lib.WithState(ctx, state)
@@ -134,6 +175,35 @@ func TestGroup(t *testing.T) {
 		_, err := common.RunString(rt, `k6.group("::", function() { throw new Error("nooo") })`)
 		assert.EqualError(t, err, "GoError: group and check names may not contain '::'")
 	})
+
+	t.Run("Tags", func(t *testing.T) {
+		stats.GetBufferedSamples(samples) // discard the samples from the previous tests
+		state.Options = lib.Options{
+			SystemTags: lib.GetTagSet("group", "check"),
+			RunTags:    stats.IntoSampleTags(&map[string]string{"run": "1", "step": "none"}),
+		}
+		defer func() { state.Options = lib.Options{} }()
+		_, err := common.RunString(rt, `
+		k6.group("journey", () => {
+			k6.group("login", () => { k6.check(null, { "ok": true }); }, { step: "login", auth: "sso" });
+			k6.check(null, { "ok": true });
+		}, { journey: "buy" })`)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"run": "1", "step": "none"}, state.Options.RunTags.CloneTags())
+
+		var tags []map[string]string
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				tags = append(tags, s.Tags.CloneTags())
+			}
+		}
+		assert.Equal(t, []map[string]string{
+			{"run": "1", "journey": "buy", "step": "login", "auth": "sso", "group": "::journey::login", "check": "ok"},
+			{"run": "1", "journey": "buy", "step": "login", "auth": "sso", "group": "::journey::login"},
+			{"run": "1", "journey": "buy", "step": "none", "group": "::journey", "check": "ok"},
+			{"run": "1", "journey": "buy", "step": "none", "group": "::journey"},
+		}, tags)
+	})
 }
 func TestCheck(t *testing.T) {
 	rt := goja.New()
@@ -364,4 +434,21 @@ func TestCheck(t *testing.T) {
 			}, sample.Tags.CloneTags())
 		}
 	})
+
+	t.Run("GroupTag", func(t *testing.T) {
+		state, samples := getState()
+		state.Group, _ = state.Group.Group("my group")
+		*ctx = lib.WithState(baseCtx, state)
+
+		_, err := common.RunString(rt, `k6.check(null, {"check": true}, {group: "custom"})`)
+		require.NoError(t, err)
+
+		bufSamples := stats.GetBufferedSamples(samples)
+		if assert.Len(t, bufSamples, 1) {
+			assert.Equal(t, map[string]string{
+				"group": "::my group",
+				"check": "check",
+			}, bufSamples[0].(stats.Sample).Tags.CloneTags())
+		}
+	})
 }
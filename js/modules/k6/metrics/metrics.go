@@ -74,16 +74,17 @@ func (m Metric) Add(ctx context.Context, v goja.Value, addTags ...map[string]str
 	}
 
 	tags := state.Options.RunTags.CloneTags()
-	if state.Options.SystemTags["group"] {
-		tags["group"] = state.Group.Path
-	}
-
 	for _, ts := range addTags {
 		for k, v := range ts {
 			tags[k] = v
 		}
 	}
 
+	// The group tag is always the path of the current group, it can't be overwritten.
+	if state.Options.SystemTags["group"] {
+		tags["group"] = state.Group.Path
+	}
+
 	vfloat := v.ToFloat()
 	if vfloat == 0 && v.ToBoolean() {
 		vfloat = 1.0
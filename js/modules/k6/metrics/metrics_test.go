@@ -119,7 +119,7 @@ func TestMetrics(t *testing.T) {
 										}
 									})
 									t.Run("Tags", func(t *testing.T) {
-										_, err := common.RunString(rt, fmt.Sprintf(`m.add(%v, {a:1})`, val.JS))
+										_, err := common.RunString(rt, fmt.Sprintf(`m.add(%v, {a:1, group:"custom"})`, val.JS))
 										assert.NoError(t, err)
 										bufSamples := stats.GetBufferedSamples(samples)
 										if assert.Len(t, bufSamples, 1) {
@@ -0,0 +1,456 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package sse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+)
+
+// ErrSSEInInitContext is returned when event streams are opened in the init context
+var ErrSSEInInitContext = common.NewInitContextError("using server-sent events in the init context is not supported")
+
+// SSE is the k6/sse module, a client for Server-Sent Events streams.
+type SSE struct{}
+
+// Client is passed to the setup function of open(), to register the event handlers and to close
+// the stream.
+type Client struct {
+	ctx           context.Context
+	cancel        context.CancelFunc
+	eventHandlers map[string][]goja.Callable
+	scheduled     chan goja.Callable
+	done          chan struct{}
+	shutdownOnce  sync.Once
+
+	eventTimestamps []time.Time
+}
+
+// Event is a single event received from the stream.
+type Event struct {
+	ID   string `js:"id"`
+	Name string `js:"name"`
+	Data string `js:"data"`
+}
+
+// Response describes the HTTP response that opened the stream. Its body is only read if the
+// stream couldn't be opened.
+type Response struct {
+	URL     string            `js:"url"`
+	Status  int               `js:"status"`
+	Headers map[string]string `js:"headers"`
+	Body    string            `js:"body"`
+	Error   string            `js:"error"`
+}
+
+// New returns the k6/sse module.
+func New() *SSE {
+	return &SSE{}
+}
+
+// Open connects to an event stream and runs the setup function with the client, then dispatches
+// the received events to the registered handlers until the stream is closed by either side.
+func (*SSE) Open(ctx context.Context, url string, args ...goja.Value) (*Response, error) {
+	rt := common.GetRuntime(ctx)
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrSSEInInitContext
+	}
+
+	// The params argument is optional
+	var callableV, paramsV goja.Value
+	switch len(args) {
+	case 2:
+		paramsV = args[0]
+		callableV = args[1]
+	case 1:
+		paramsV = goja.Undefined()
+		callableV = args[0]
+	default:
+		return nil, errors.New("invalid number of arguments to sse.open")
+	}
+
+	setupFn, isFunc := goja.AssertFunction(callableV)
+	if !isFunc {
+		return nil, errors.New("last argument to sse.open must be a function")
+	}
+
+	method := http.MethodGet
+	var body string
+	throw := state.Options.Throw.Bool
+	header := http.Header{}
+	tags := state.Options.RunTags.CloneTags()
+
+	if !goja.IsUndefined(paramsV) && !goja.IsNull(paramsV) {
+		params := paramsV.ToObject(rt)
+		for _, k := range params.Keys() {
+			v := params.Get(k)
+			if goja.IsUndefined(v) || goja.IsNull(v) {
+				continue
+			}
+			switch k {
+			case "method":
+				method = strings.ToUpper(v.String())
+			case "body":
+				body = v.String()
+			case "throw":
+				throw = v.ToBoolean()
+			case "headers":
+				headersObj := v.ToObject(rt)
+				for _, key := range headersObj.Keys() {
+					header.Set(key, headersObj.Get(key).String())
+				}
+			case "tags":
+				tagObj := v.ToObject(rt)
+				for _, key := range tagObj.Keys() {
+					tags[key] = tagObj.Get(key).String()
+				}
+			default:
+				return nil, fmt.Errorf("unknown sse.open param %s", k)
+			}
+		}
+	}
+
+	if state.Options.SystemTags["url"] {
+		tags["url"] = url
+	}
+	if state.Options.SystemTags["group"] {
+		tags["group"] = state.Group.Path
+	}
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(reqCtx)
+	req.Header = header
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+	if userAgent := state.Options.UserAgent; userAgent.String != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", userAgent.String)
+	}
+
+	client := Client{
+		ctx:           ctx,
+		cancel:        cancel,
+		eventHandlers: make(map[string][]goja.Callable),
+		scheduled:     make(chan goja.Callable),
+		done:          make(chan struct{}),
+	}
+
+	// Run the user-provided set up function
+	if _, err := setupFn(goja.Undefined(), rt.ToValue(&client)); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	httpResponse, err := (&http.Client{Transport: state.Transport}).Do(req)
+	if err != nil {
+		// Pass the error to the user script before exiting immediately, like with the http
+		// module, the error is only thrown with the throw option or param
+		client.handleEvent("error", rt.ToValue(err))
+		if throw {
+			return nil, err
+		}
+		return &Response{URL: url, Error: err.Error()}, nil
+	}
+	defer func() { _ = httpResponse.Body.Close() }()
+
+	response := wrapHTTPResponse(url, httpResponse)
+	if state.Options.SystemTags["status"] {
+		tags["status"] = strconv.Itoa(httpResponse.StatusCode)
+	}
+	sampleTags := stats.IntoSampleTags(&tags)
+
+	contentType := httpResponse.Header.Get("Content-Type")
+	if httpResponse.StatusCode != http.StatusOK || !strings.HasPrefix(contentType, "text/event-stream") {
+		if b, err := ioutil.ReadAll(httpResponse.Body); err == nil {
+			response.Body = string(b)
+		}
+		response.Error = fmt.Sprintf("couldn't open the event stream, got status %d and content type '%s'",
+			httpResponse.StatusCode, contentType)
+		client.handleEvent("error", rt.ToValue(response.Error))
+		stats.PushIfNotCancelled(ctx, state.Samples, stats.Sample{
+			Metric: metrics.SSESessions, Time: start, Tags: sampleTags, Value: 1,
+		})
+		return response, nil
+	}
+
+	// The stream is now open, emit the event
+	client.handleEvent("open")
+
+	eventChan := make(chan Event)
+	readErrChan := make(chan error)
+	readEndChan := make(chan struct{})
+	go readPump(httpResponse.Body, eventChan, readErrChan, readEndChan, client.done)
+
+	// This is the main control loop. All JS code (including error handlers)
+	// should only be executed by this thread to avoid race conditions
+	for {
+		select {
+		case event := <-eventChan:
+			client.eventTimestamps = append(client.eventTimestamps, time.Now())
+			client.handleEvent("event", rt.ToValue(event))
+
+		case readErr := <-readErrChan:
+			client.handleEvent("error", rt.ToValue(readErr))
+			client.closeStream()
+
+		case <-readEndChan:
+			// The server ended the stream
+			client.closeStream()
+
+		case scheduledFn := <-client.scheduled:
+			if _, err := scheduledFn(goja.Undefined()); err != nil {
+				client.closeStream()
+				return nil, err
+			}
+
+		case <-ctx.Done():
+			// VU is shutting down during an interrupt
+			client.closeStream()
+
+		case <-client.done:
+			// This is the final exit point normally triggered by closeStream
+			end := time.Now()
+
+			samples := []stats.Sample{
+				{Metric: metrics.SSESessions, Time: start, Tags: sampleTags, Value: 1},
+				{Metric: metrics.SSESessionDuration, Time: start, Tags: sampleTags, Value: stats.D(end.Sub(start))},
+			}
+			if len(client.eventTimestamps) > 0 {
+				samples = append(samples, stats.Sample{
+					Metric: metrics.SSETimeToFirstEvent,
+					Time:   client.eventTimestamps[0],
+					Tags:   sampleTags,
+					Value:  stats.D(client.eventTimestamps[0].Sub(start)),
+				})
+			}
+			stats.PushIfNotCancelled(ctx, state.Samples, stats.ConnectedSamples{
+				Samples: samples,
+				Tags:    sampleTags,
+				Time:    start,
+			})
+
+			for _, eventTimestamp := range client.eventTimestamps {
+				stats.PushIfNotCancelled(ctx, state.Samples, stats.Sample{
+					Metric: metrics.SSEEventsReceived,
+					Time:   eventTimestamp,
+					Tags:   sampleTags,
+					Value:  1,
+				})
+			}
+
+			return response, nil
+		}
+	}
+}
+
+// On registers a handler for the open, event, error or close events.
+func (c *Client) On(event string, handler goja.Value) {
+	if handler, ok := goja.AssertFunction(handler); ok {
+		c.eventHandlers[event] = append(c.eventHandlers[event], handler)
+	}
+}
+
+func (c *Client) handleEvent(event string, args ...goja.Value) {
+	if handlers, ok := c.eventHandlers[event]; ok {
+		for _, handler := range handlers {
+			if _, err := handler(goja.Undefined(), args...); err != nil {
+				common.Throw(common.GetRuntime(c.ctx), err)
+			}
+		}
+	}
+}
+
+// SetTimeout calls fn once from the event loop, after timeoutMs milliseconds.
+func (c *Client) SetTimeout(fn goja.Callable, timeoutMs int) {
+	go func() {
+		select {
+		case <-time.After(time.Duration(timeoutMs) * time.Millisecond):
+			select {
+			case c.scheduled <- fn:
+			case <-c.done:
+			}
+
+		case <-c.done:
+			return
+		}
+	}()
+}
+
+// SetInterval calls fn from the event loop every intervalMs milliseconds, until the stream is
+// closed.
+func (c *Client) SetInterval(fn goja.Callable, intervalMs int) {
+	go func() {
+		ticker := time.NewTicker(time.Duration(intervalMs) * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case c.scheduled <- fn:
+				case <-c.done:
+					return
+				}
+
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close closes the stream.
+func (c *Client) Close() {
+	c.closeStream()
+}
+
+func (c *Client) closeStream() {
+	c.shutdownOnce.Do(func() {
+		c.cancel()
+		c.handleEvent("close")
+
+		// Stops the main control loop
+		close(c.done)
+	})
+}
+
+// readPump parses the events of the stream and passes them to the main loop, until the end of
+// the stream or done is closed.
+func readPump(r io.Reader, eventChan chan<- Event, errChan chan<- error, endChan chan<- struct{}, done <-chan struct{}) {
+	err := parseStream(r, func(event Event) bool {
+		select {
+		case eventChan <- event:
+			return true
+		case <-done:
+			return false
+		}
+	})
+
+	select {
+	case <-done:
+	default:
+		if err != nil {
+			select {
+			case errChan <- err:
+			case <-done:
+			}
+		} else {
+			select {
+			case endChan <- struct{}{}:
+			case <-done:
+			}
+		}
+	}
+}
+
+// parseStream reads the lines of a text/event-stream and calls dispatch for every complete
+// event, until the end of the stream or dispatch returns false. Retry fields are ignored, since
+// the client doesn't reconnect.
+func parseStream(r io.Reader, dispatch func(Event) bool) error {
+	reader := bufio.NewReader(r)
+	var event Event
+	var data strings.Builder
+	hasData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			// A blank line dispatches the event, if it has any data
+			if hasData {
+				if event.Name == "" {
+					event.Name = "message"
+				}
+				event.Data = data.String()
+				if !dispatch(event) {
+					return nil
+				}
+			}
+			event = Event{ID: event.ID}
+			data.Reset()
+			hasData = false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // a comment
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			event.Name = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				event.ID = value
+			}
+		}
+	}
+}
+
+// Wrap the raw HTTP response we received to a Response we can pass to the user
+func wrapHTTPResponse(url string, httpResponse *http.Response) *Response {
+	response := Response{
+		URL:     url,
+		Status:  httpResponse.StatusCode,
+		Headers: make(map[string]string, len(httpResponse.Header)),
+	}
+	for k, vs := range httpResponse.Header {
+		response.Headers[k] = strings.Join(vs, ", ")
+	}
+	return &response
+}
@@ -0,0 +1,246 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package sse
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestParseStream(t *testing.T) {
+	stream := ": a comment\n" +
+		"data: first\n\n" +
+		"event: update\r\nid: 1\r\ndata: line one\r\ndata:line two\r\n\r\n" +
+		"retry: 1000\n\n" +
+		"event: ignored\n\n" +
+		"data: no trailing blank line"
+
+	var events []Event
+	err := parseStream(strings.NewReader(stream), func(e Event) bool {
+		events = append(events, e)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Event{
+		{Name: "message", Data: "first"},
+		{ID: "1", Name: "update", Data: "line one\nline two"},
+	}, events)
+
+	events = nil
+	err = parseStream(strings.NewReader("data: a\n\ndata: b\n\n"), func(e Event) bool {
+		events = append(events, e)
+		return false
+	})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func newRuntime(t *testing.T) (*goja.Runtime, chan stats.SampleContainer) {
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:     root,
+		Transport: http.DefaultTransport,
+		Options: lib.Options{
+			SystemTags: lib.GetTagSet("url", "status", "group"),
+			UserAgent:  null.StringFrom("TestUserAgent"),
+		},
+		Samples: samples,
+	}
+
+	ctx := context.Background()
+	ctx = lib.WithState(ctx, state)
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("sse", common.Bind(rt, New(), &ctx))
+	return rt, samples
+}
+
+func TestOpen(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		assert.Equal(t, "abc", r.Header.Get("X-Token"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			_, _ = fmt.Fprintf(w, "id: %d\nevent: tick\ndata: %d\n\n", i, i)
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/forever", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+				_, _ = fmt.Fprint(w, "data: ping\n\n")
+				w.(http.Flusher).Flush()
+			}
+		}
+	})
+	mux.HandleFunc("/user-agent", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", r.Header.Get("User-Agent"))
+	})
+	mux.HandleFunc("/notfound", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such stream", http.StatusNotFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	rt, samples := newRuntime(t)
+	rt.Set("BASE_URL", srv.URL)
+
+	t.Run("events", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let opened = false, closed = false, received = [];
+		let res = sse.open(BASE_URL + "/events", { headers: { "X-Token": "abc" } }, function(client) {
+			client.on("open", function() { opened = true; });
+			client.on("event", function(e) { received.push(e.id + ":" + e.name + ":" + e.data); });
+			client.on("close", function() { closed = true; });
+		});
+		if (res.status != 200) { throw new Error("wrong status: " + res.status); }
+		if (!opened || !closed) { throw new Error("open or close not fired"); }
+		if (received.join(",") !== "0:tick:0,1:tick:1,2:tick:2") { throw new Error("wrong events: " + received); }
+		`)
+		assert.NoError(t, err)
+
+		url := srv.URL + "/events"
+		seen := map[*stats.Metric]int{}
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				tags := s.Tags.CloneTags()
+				assert.Equal(t, url, tags["url"])
+				assert.Equal(t, "200", tags["status"])
+				seen[s.Metric]++
+			}
+		}
+		assert.Equal(t, 1, seen[metrics.SSESessions])
+		assert.Equal(t, 1, seen[metrics.SSESessionDuration])
+		assert.Equal(t, 1, seen[metrics.SSETimeToFirstEvent])
+		assert.Equal(t, 3, seen[metrics.SSEEventsReceived])
+	})
+
+	t.Run("user agent", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let agents = [];
+		let params = [{}, { headers: { "User-Agent": "Custom" } }];
+		params.forEach(function(p) {
+			sse.open(BASE_URL + "/user-agent", p, function(client) {
+				client.on("event", function(e) { agents.push(e.data); });
+			});
+		});
+		if (agents.join(",") !== "TestUserAgent,Custom") { throw new Error("wrong user agents: " + agents); }
+		`)
+		assert.NoError(t, err)
+		stats.GetBufferedSamples(samples)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let count = 0;
+		sse.open(BASE_URL + "/forever", function(client) {
+			client.on("event", function(e) {
+				count++;
+				if (count == 3) { client.close(); }
+			});
+		});
+		if (count != 3) { throw new Error("wrong count: " + count); }
+		`)
+		assert.NoError(t, err)
+		stats.GetBufferedSamples(samples)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let closed = false;
+		sse.open(BASE_URL + "/forever", function(client) {
+			client.setTimeout(function() { client.close(); }, 50);
+			client.on("close", function() { closed = true; });
+		});
+		if (!closed) { throw new Error("not closed"); }
+		`)
+		assert.NoError(t, err)
+		stats.GetBufferedSamples(samples)
+	})
+
+	t.Run("network error", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let errored = false;
+		let res = sse.open("http://127.0.0.1:1/events", function(client) {
+			client.on("error", function() { errored = true; });
+		});
+		if (res.status != 0 || !errored || res.error === "") { throw new Error("expected an error"); }
+		sse.open("http://127.0.0.1:1/events", { throw: true }, function(client) {});
+		`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "connection refused")
+		assert.Empty(t, stats.GetBufferedSamples(samples))
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let errored = false;
+		let res = sse.open(BASE_URL + "/notfound", function(client) {
+			client.on("error", function() { errored = true; });
+		});
+		if (res.status != 404 || !errored || res.error === "") { throw new Error("expected an error"); }
+		if (res.body.trim() !== "no such stream") { throw new Error("wrong body: " + res.body); }
+		`)
+		assert.NoError(t, err)
+
+		seen := map[*stats.Metric]int{}
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				seen[s.Metric]++
+			}
+		}
+		assert.Equal(t, map[*stats.Metric]int{metrics.SSESessions: 1}, seen)
+	})
+}
+
+func TestOpenInInitContext(t *testing.T) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("sse", common.Bind(rt, New(), &ctx))
+	_, err := common.RunString(rt, `sse.open("http://localhost/", function() {})`)
+	assert.Contains(t, err.Error(), "using server-sent events in the init context is not supported")
+}
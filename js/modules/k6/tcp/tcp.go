@@ -0,0 +1,295 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTimeout  = 60 * time.Second
+	defaultReadSize = 64 * 1024
+)
+
+// ErrTCPInInitContext is returned when connections are opened in the init context
+var ErrTCPInInitContext = common.NewInitContextError("using TCP connections in the init context is not supported")
+
+// TCP is the k6/tcp module, for load testing custom protocols over raw TCP connections.
+type TCP struct{}
+
+// Conn is an open TCP connection. Reads are buffered, so read() and readLine() can be mixed.
+type Conn struct {
+	ctx       context.Context
+	conn      net.Conn
+	reader    *bufio.Reader
+	tags      *stats.SampleTags
+	timeout   time.Duration
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// The time of the last write that hasn't been followed by a read yet, to measure roundtrips
+	lastWrite time.Time
+}
+
+// New returns the k6/tcp module.
+func New() *TCP {
+	return &TCP{}
+}
+
+// Connect opens a connection to a "host:port" address. The optional params can have a timeout,
+// which is also the default for reads, tls: true to connect over TLS and tags for the metrics.
+func (*TCP) Connect(ctx context.Context, addr string, args ...goja.Value) (*Conn, error) {
+	rt := common.GetRuntime(ctx)
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrTCPInInitContext
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, err
+	}
+
+	timeout := defaultTimeout
+	useTLS := false
+	tags := state.Options.RunTags.CloneTags()
+	if len(args) > 0 && !goja.IsUndefined(args[0]) && !goja.IsNull(args[0]) {
+		params := args[0].ToObject(rt)
+		for _, k := range params.Keys() {
+			v := params.Get(k)
+			switch k {
+			case "timeout":
+				d, err := types.GetDurationValue(v.Export())
+				if err != nil {
+					return nil, errors.Wrap(err, "invalid timeout")
+				}
+				timeout = d
+			case "tls":
+				useTLS = v.ToBoolean()
+			case "tags":
+				if goja.IsUndefined(v) || goja.IsNull(v) {
+					continue
+				}
+				tagObj := v.ToObject(rt)
+				for _, key := range tagObj.Keys() {
+					tags[key] = tagObj.Get(key).String()
+				}
+			default:
+				return nil, errors.Errorf("unknown tcp.connect param %s", k)
+			}
+		}
+	}
+
+	if state.Options.SystemTags["url"] {
+		scheme := "tcp"
+		if useTLS {
+			scheme = "tls"
+		}
+		tags["url"] = scheme + "://" + addr
+	}
+	if state.Options.SystemTags["group"] {
+		tags["group"] = state.Group.Path
+	}
+
+	start := time.Now()
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := state.Dialer.DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if useTLS {
+		var tlsConfig *tls.Config
+		if state.TLSConfig != nil {
+			tlsConfig = state.TLSConfig.Clone()
+		} else {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		_ = tlsConn.SetDeadline(time.Now().Add(timeout))
+		if err := tlsConn.Handshake(); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		_ = tlsConn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+	end := time.Now()
+
+	if state.Options.SystemTags["ip"] && conn.RemoteAddr() != nil {
+		if ip, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
+			tags["ip"] = ip
+		}
+	}
+
+	c := &Conn{
+		ctx:     ctx,
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		tags:    stats.IntoSampleTags(&tags),
+		timeout: timeout,
+		done:    make(chan struct{}),
+	}
+	c.push(metrics.TCPConnecting, start, end)
+
+	// Unblock reads and writes if the VU is interrupted
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-c.done:
+		}
+	}()
+	return c, nil
+}
+
+// Write sends data over the connection.
+func (c *Conn) Write(data string, args ...goja.Value) (bool, error) {
+	timeout, err := c.getTimeout(args)
+	if err != nil {
+		return false, err
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	if _, err := c.conn.Write([]byte(data)); err != nil {
+		return false, err
+	}
+	c.lastWrite = time.Now()
+	return true, nil
+}
+
+// Read returns the data that is available on the connection, waiting for it if there isn't any.
+// The optional params can have a timeout and the maximum size of the returned data.
+func (c *Conn) Read(args ...goja.Value) (string, error) {
+	timeout, err := c.getTimeout(args)
+	if err != nil {
+		return "", err
+	}
+	size := defaultReadSize
+	if len(args) > 0 && !goja.IsUndefined(args[0]) && !goja.IsNull(args[0]) {
+		if v := args[0].ToObject(common.GetRuntime(c.ctx)).Get("size"); v != nil && !goja.IsUndefined(v) {
+			if size = int(v.ToInteger()); size <= 0 {
+				return "", errors.New("the read size has to be positive")
+			}
+		}
+	}
+
+	_ = c.conn.SetReadDeadline(time.Now().Add(timeout))
+	if c.reader.Buffered() == 0 {
+		if _, err := c.reader.Peek(1); err != nil {
+			return "", c.readError(err)
+		}
+	}
+	if buffered := c.reader.Buffered(); buffered < size {
+		size = buffered
+	}
+	buf := make([]byte, size)
+	n, err := c.reader.Read(buf)
+	if err != nil {
+		return "", c.readError(err)
+	}
+	c.trackRoundtrip()
+	return string(buf[:n]), nil
+}
+
+// ReadLine returns the next line received on the connection, without the line ending. The
+// optional params can have a timeout.
+func (c *Conn) ReadLine(args ...goja.Value) (string, error) {
+	timeout, err := c.getTimeout(args)
+	if err != nil {
+		return "", err
+	}
+	_ = c.conn.SetReadDeadline(time.Now().Add(timeout))
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", c.readError(err)
+	}
+	c.trackRoundtrip()
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// Close closes the connection.
+func (c *Conn) Close() (bool, error) {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = c.conn.Close()
+	})
+	return err == nil, err
+}
+
+func (c *Conn) getTimeout(args []goja.Value) (time.Duration, error) {
+	if len(args) == 0 || goja.IsUndefined(args[0]) || goja.IsNull(args[0]) {
+		return c.timeout, nil
+	}
+	v := args[0].ToObject(common.GetRuntime(c.ctx)).Get("timeout")
+	if v == nil || goja.IsUndefined(v) {
+		return c.timeout, nil
+	}
+	d, err := types.GetDurationValue(v.Export())
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid timeout")
+	}
+	return d, nil
+}
+
+func (c *Conn) readError(err error) error {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return errors.New("read timeout")
+	}
+	return err
+}
+
+// trackRoundtrip emits the time since the last write, for the first read that follows it.
+func (c *Conn) trackRoundtrip() {
+	if c.lastWrite.IsZero() {
+		return
+	}
+	c.push(metrics.TCPRoundtrip, c.lastWrite, time.Now())
+	c.lastWrite = time.Time{}
+}
+
+func (c *Conn) push(metric *stats.Metric, start, end time.Time) {
+	state := lib.GetState(c.ctx)
+	if state == nil {
+		return
+	}
+	stats.PushIfNotCancelled(c.ctx, state.Samples, stats.Sample{
+		Metric: metric,
+		Time:   end,
+		Tags:   c.tags,
+		Value:  stats.D(end.Sub(start)),
+	})
+}
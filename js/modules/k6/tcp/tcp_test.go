@@ -0,0 +1,174 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer starts a line-based server that replies to "PING" with "PONG", echoes "ECHO x"
+// without a line ending and ignores anything else.
+func startServer(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, _ = conn.Write([]byte("220 ready\r\n"))
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					line := scanner.Text()
+					switch {
+					case line == "PING":
+						_, _ = conn.Write([]byte("PONG\r\n"))
+					case strings.HasPrefix(line, "ECHO "):
+						_, _ = conn.Write([]byte(strings.TrimPrefix(line, "ECHO ")))
+					case line == "QUIT":
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln
+}
+
+func newRuntime(t *testing.T) (*goja.Runtime, chan stats.SampleContainer) {
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:  root,
+		Dialer: netext.NewDialer(net.Dialer{Timeout: 10 * time.Second}),
+		Options: lib.Options{
+			SystemTags: lib.GetTagSet("url", "group", "ip"),
+		},
+		Samples: samples,
+	}
+
+	ctx := context.Background()
+	ctx = lib.WithState(ctx, state)
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("tcp", common.Bind(rt, New(), &ctx))
+	return rt, samples
+}
+
+func TestConn(t *testing.T) {
+	ln := startServer(t)
+	defer func() { _ = ln.Close() }()
+
+	rt, samples := newRuntime(t)
+	rt.Set("ADDR", ln.Addr().String())
+
+	t.Run("lines", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let conn = tcp.connect(ADDR, { timeout: "2s" });
+		let greeting = conn.readLine();
+		if (greeting !== "220 ready") { throw new Error("wrong greeting: " + greeting); }
+		conn.write("PING\r\n");
+		let reply = conn.readLine({ timeout: 1000 });
+		if (reply !== "PONG") { throw new Error("wrong reply: " + reply); }
+		conn.write("ECHO hello\r\n");
+		let data = conn.read({ size: 3 }) + conn.read();
+		if (data !== "hello") { throw new Error("wrong data: " + data); }
+		conn.close();
+		`)
+		assert.NoError(t, err)
+
+		seen := map[*stats.Metric]int{}
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				tags := s.Tags.CloneTags()
+				assert.Equal(t, "tcp://"+ln.Addr().String(), tags["url"])
+				assert.Equal(t, "127.0.0.1", tags["ip"])
+				seen[s.Metric]++
+			}
+		}
+		assert.Equal(t, map[*stats.Metric]int{metrics.TCPConnecting: 1, metrics.TCPRoundtrip: 2}, seen)
+	})
+
+	t.Run("read timeout", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let conn = tcp.connect(ADDR);
+		conn.readLine();
+		conn.write("NOOP\r\n");
+		try {
+			conn.readLine({ timeout: "50ms" });
+			throw new Error("expected a timeout");
+		} catch (e) {
+			if (e.toString().indexOf("read timeout") < 0) { throw e; }
+		} finally {
+			conn.close();
+		}
+		`)
+		assert.NoError(t, err)
+		stats.GetBufferedSamples(samples)
+	})
+
+	t.Run("closed by server", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let conn = tcp.connect(ADDR);
+		conn.readLine();
+		conn.write("QUIT\r\n");
+		conn.read();
+		`)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "EOF")
+		stats.GetBufferedSamples(samples)
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		_, err := common.RunString(rt, `tcp.connect(ADDR, { foo: 1 })`)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown tcp.connect param foo")
+	})
+}
+
+func TestConnectInInitContext(t *testing.T) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("tcp", common.Bind(rt, New(), &ctx))
+	_, err := common.RunString(rt, `tcp.connect("127.0.0.1:25")`)
+	assert.Contains(t, err.Error(), "using TCP connections in the init context is not supported")
+}
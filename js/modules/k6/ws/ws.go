@@ -37,6 +37,7 @@ import (
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
 	"github.com/loadimpact/k6/stats"
 )
 
@@ -146,6 +147,13 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 
 	}
 
+	if userAgent := state.Options.UserAgent; userAgent.String != "" && header.Get("User-Agent") == "" {
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("User-Agent", userAgent.String)
+	}
+
 	if state.Options.SystemTags["url"] {
 		tags["url"] = url
 	}
@@ -166,9 +174,13 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 		tlsConfig.NextProtos = []string{"http/1.1"}
 	}
 
+	proxy, err := netext.ProxyFunc(state.Options.Proxy.String)
+	if err != nil {
+		return nil, err
+	}
 	wsd := websocket.Dialer{
 		NetDial:         netDial,
-		Proxy:           http.ProxyFromEnvironment,
+		Proxy:           proxy,
 		TLSClientConfig: tlsConfig,
 	}
 
@@ -24,12 +24,14 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"net/http"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
@@ -37,6 +39,7 @@ import (
 	"github.com/loadimpact/k6/lib/testutils"
 	"github.com/loadimpact/k6/stats"
 	"github.com/stretchr/testify/assert"
+	null "gopkg.in/guregu/null.v3"
 )
 
 func assertSessionMetricsEmitted(t *testing.T, sampleContainers []stats.SampleContainer, subprotocol, url string, status int, group string) {
@@ -467,3 +470,57 @@ func TestTLSConfig(t *testing.T) {
 	})
 	assertSessionMetricsEmitted(t, stats.GetBufferedSamples(samples), "", url, 101, "")
 }
+
+func TestUserAgent(t *testing.T) {
+	root, err := lib.NewGroup("", nil)
+	assert.NoError(t, err)
+
+	tb := testutils.NewHTTPMultiBin(t)
+	defer tb.Cleanup()
+	tb.Mux.HandleFunc("/ws-echo-useragent", func(w http.ResponseWriter, req *http.Request) {
+		// Echo back the User-Agent header of the handshake as a response header
+		responseHeaders := http.Header{}
+		responseHeaders.Set("Echo-User-Agent", req.Header.Get("User-Agent"))
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, req, responseHeaders)
+		if err != nil {
+			t.Fatalf("/ws-echo-useragent cannot upgrade request: %v", err)
+			return
+		}
+		_ = conn.Close()
+	})
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:  root,
+		Dialer: tb.Dialer,
+		Options: lib.Options{
+			SystemTags: lib.GetTagSet("url", "proto", "status", "subproto"),
+			UserAgent:  null.StringFrom("TestUserAgent"),
+		},
+		Samples: samples,
+	}
+
+	ctx := context.Background()
+	ctx = lib.WithState(ctx, state)
+	ctx = common.WithRuntime(ctx, rt)
+
+	rt.Set("ws", common.Bind(rt, New(), &ctx))
+
+	_, err = common.RunString(rt, tb.Replacer.Replace(`
+		let res = ws.connect("ws://HTTPBIN_DOMAIN:HTTPBIN_PORT/ws-echo-useragent", function(socket){
+			socket.close()
+		});
+		if (res.headers["Echo-User-Agent"] !== "TestUserAgent") {
+			throw new Error("incorrect user agent: " + res.headers["Echo-User-Agent"]);
+		}
+		res = ws.connect("ws://HTTPBIN_DOMAIN:HTTPBIN_PORT/ws-echo-useragent", { headers: { "User-Agent": "Custom" } }, function(socket){
+			socket.close()
+		});
+		if (res.headers["Echo-User-Agent"] !== "Custom") {
+			throw new Error("incorrect custom user agent: " + res.headers["Echo-User-Agent"]);
+		}
+		`))
+	assert.NoError(t, err)
+}
@@ -28,26 +28,39 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
 	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/lib/scheduler"
 	"github.com/loadimpact/k6/stats"
 	"github.com/oxtoacart/bpool"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
-	"github.com/viki-org/dnscache"
 	"golang.org/x/net/http2"
 	"golang.org/x/time/rate"
 )
 
 var errInterrupt = errors.New("context cancelled")
 
-// Ensure Runner implements the lib.Runner interface
+const (
+	handleSummaryFn = "handleSummary"
+
+	// Unlike setup() and teardown(), handleSummary() doesn't have a configurable timeout,
+	// rendering the summary shouldn't take long.
+	handleSummaryTimeout = 2 * time.Minute
+)
+
+// Ensure Runner implements the lib.Runner, lib.SummaryHandler and lib.ScenarioRunner interfaces
 var _ lib.Runner = &Runner{}
+var _ lib.SummaryHandler = &Runner{}
+var _ lib.ScenarioRunner = &Runner{}
 
 type Runner struct {
 	Bundle       *Bundle
@@ -55,11 +68,20 @@ type Runner struct {
 	defaultGroup *lib.Group
 
 	BaseDialer net.Dialer
-	Resolver   *dnscache.Resolver
+	Resolver   *netext.Resolver
 	RPSLimit   *rate.Limiter
 
 	console   *console
 	setupData []byte
+
+	// The numbers of iterations started by the VUs of each scenario, with "" for the VUs that
+	// don't belong to one.
+	iterationsMutex sync.Mutex
+	iterations      map[string]*int64
+
+	// The partition of the execution segment, which the iterations of the scenarios are
+	// numbered in, so that they're unique among the instances that run the segments.
+	partition *lib.ExecutionPartition
 }
 
 func New(src *lib.SourceData, fs afero.Fs, rtOpts lib.RuntimeOptions) (*Runner, error) {
@@ -93,14 +115,26 @@ func NewFromBundle(b *Bundle) (*Runner, error) {
 			KeepAlive: 30 * time.Second,
 			DualStack: true,
 		},
-		console:  newConsole(),
-		Resolver: dnscache.New(0),
+		console:    newConsole(),
+		iterations: make(map[string]*int64),
 	}
 
 	err = r.SetOptions(r.Bundle.Options)
 	return r, err
 }
 
+// iterationCounter returns the counter of the iterations started by the VUs of the scenario.
+func (r *Runner) iterationCounter(scenario string) *int64 {
+	r.iterationsMutex.Lock()
+	defer r.iterationsMutex.Unlock()
+	counter, ok := r.iterations[scenario]
+	if !ok {
+		counter = new(int64)
+		r.iterations[scenario] = counter
+	}
+	return counter
+}
+
 func (r *Runner) MakeArchive() *lib.Archive {
 	return r.Bundle.makeArchive()
 }
@@ -113,9 +147,49 @@ func (r *Runner) NewVU(samplesOut chan<- stats.SampleContainer) (lib.VU, error)
 	return lib.VU(vu), nil
 }
 
+// NewScenarioVU spawns a new VU for the scenario, which runs the exported function named by its
+// exec setting, if it's set, and adds the scenario tags to all of its samples.
+func (r *Runner) NewScenarioVU(
+	samplesOut chan<- stats.SampleContainer, scenario scheduler.BaseConfig,
+) (lib.VU, error) {
+	env := r.Bundle.Env
+	if len(scenario.Env) > 0 {
+		env = make(map[string]string, len(r.Bundle.Env)+len(scenario.Env))
+		for k, v := range r.Bundle.Env {
+			env[k] = v
+		}
+		for k, v := range scenario.Env {
+			env[k] = v
+		}
+	}
+	vu, err := r.newVUWithEnv(samplesOut, env)
+	if err != nil {
+		return nil, err
+	}
+
+	if scenario.Exec.Valid {
+		exports := vu.Runtime.Get("exports").ToObject(vu.Runtime)
+		fn, ok := goja.AssertFunction(exports.Get(scenario.Exec.String))
+		if !ok {
+			return nil, errors.Errorf("exported function '%s' not found", scenario.Exec.String)
+		}
+		vu.Default = fn
+	}
+
+	vu.runTags = lib.ScenarioTags(r.Bundle.Options.RunTags, scenario)
+	vu.scenario = &scenario
+	vu.scenarioIterations = r.iterationCounter(scenario.Name)
+	return vu, nil
+}
+
 func (r *Runner) newVU(samplesOut chan<- stats.SampleContainer) (*VU, error) {
+	return r.newVUWithEnv(samplesOut, r.Bundle.Env)
+}
+
+// newVUWithEnv spawns a new VU, whose __ENV are the supplied environment variables.
+func (r *Runner) newVUWithEnv(samplesOut chan<- stats.SampleContainer, env map[string]string) (*VU, error) {
 	// Instantiate a new bundle, make a VU out of it.
-	bi, err := r.Bundle.Instantiate()
+	bi, err := r.Bundle.instantiateWithEnv(env)
 	if err != nil {
 		return nil, err
 	}
@@ -148,7 +222,9 @@ func (r *Runner) newVU(samplesOut chan<- stats.SampleContainer) (*VU, error) {
 		Dialer:    r.BaseDialer,
 		Resolver:  r.Resolver,
 		Blacklist: r.Bundle.Options.BlacklistIPs,
+		Blocked:   r.Bundle.Options.BlockedHostnames,
 		Hosts:     r.Bundle.Options.Hosts,
+		Faults:    r.Bundle.Options.NetworkFaults,
 	}
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: r.Bundle.Options.InsecureSkipTLSVerify.Bool,
@@ -159,8 +235,12 @@ func (r *Runner) newVU(samplesOut chan<- stats.SampleContainer) (*VU, error) {
 		NameToCertificate:  nameToCert,
 		Renegotiation:      tls.RenegotiateFreelyAsClient,
 	}
+	proxy, err := netext.ProxyFunc(r.Bundle.Options.Proxy.String)
+	if err != nil {
+		return nil, err
+	}
 	transport := &http.Transport{
-		Proxy:               http.ProxyFromEnvironment,
+		Proxy:               proxy,
 		TLSClientConfig:     tlsConfig,
 		DialContext:         dialer.DialContext,
 		DisableCompression:  true,
@@ -185,6 +265,8 @@ func (r *Runner) newVU(samplesOut chan<- stats.SampleContainer) (*VU, error) {
 		Console:        r.console,
 		BPool:          bpool.NewBufferPool(100),
 		Samples:        samplesOut,
+
+		scenarioIterations: r.iterationCounter(""),
 	}
 	vu.Runtime.Set("console", common.Bind(vu.Runtime, vu.Console, vu.Context))
 	common.BindToGlobal(vu.Runtime, map[string]interface{}{
@@ -266,6 +348,19 @@ func (r *Runner) GetOptions() lib.Options {
 func (r *Runner) SetOptions(opts lib.Options) error {
 	r.Bundle.Options = opts
 
+	for _, pattern := range opts.BlockedHostnames {
+		if err := netext.ValidateHostnamePattern(pattern); err != nil {
+			return err
+		}
+	}
+	r.Resolver = netext.NewResolver(opts.DNS)
+
+	partition, err := lib.NewExecutionPartition(opts.ExecutionSegment, opts.ExecutionSegmentSequence)
+	if err != nil {
+		return err
+	}
+	r.partition = partition
+
 	r.RPSLimit = nil
 	if rps := opts.RPS; rps.Valid {
 		r.RPSLimit = rate.NewLimiter(rate.Limit(rps.Int64), 1)
@@ -283,22 +378,75 @@ func (r *Runner) SetOptions(opts lib.Options) error {
 	return nil
 }
 
+// HandleSummary runs the exported handleSummary() function with the end-of-test summary, in
+// the JSON format of ui.WriteJSONReport. It returns what the function returned, the content
+// to write to "stdout", "stderr" or the files with the keys as paths. If the script doesn't
+// export handleSummary(), ok is false.
+func (r *Runner) HandleSummary(ctx context.Context, summary []byte) (result map[string]string, ok bool, err error) {
+	var data interface{}
+	if err = json.Unmarshal(summary, &data); err != nil {
+		return nil, false, errors.Wrap(err, "handleSummary")
+	}
+
+	out := make(chan stats.SampleContainer, 100)
+	go func() {
+		for range out { // samples from handleSummary() aren't part of the test
+		}
+	}()
+	defer close(out)
+
+	vu, fn, err := r.getPart(out, handleSummaryFn)
+	if err != nil || fn == nil {
+		return nil, false, err
+	}
+
+	summaryCtx, summaryCancel := context.WithTimeout(ctx, handleSummaryTimeout)
+	defer summaryCancel()
+	v, err := r.runPartFn(summaryCtx, vu, handleSummaryFn, fn, data)
+	if err != nil {
+		return nil, true, errors.Wrap(err, handleSummaryFn)
+	}
+	if goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, true, nil
+	}
+
+	obj := v.ToObject(vu.Runtime)
+	result = make(map[string]string, len(obj.Keys()))
+	for _, k := range obj.Keys() {
+		result[k] = obj.Get(k).String()
+	}
+	return result, true, nil
+}
+
 // Runs an exported function in its own temporary VU, optionally with an argument. Execution is
 // interrupted if the context expires. No error is returned if the part does not exist.
 func (r *Runner) runPart(ctx context.Context, out chan<- stats.SampleContainer, name string, arg interface{}) (goja.Value, error) {
+	vu, fn, err := r.getPart(out, name)
+	if err != nil || fn == nil {
+		return goja.Undefined(), err
+	}
+	return r.runPartFn(ctx, vu, name, fn, arg)
+}
+
+// Returns a new temporary VU and the exported function with the given name, or nil if it's not
+// exported.
+func (r *Runner) getPart(out chan<- stats.SampleContainer, name string) (*VU, goja.Callable, error) {
 	vu, err := r.newVU(out)
 	if err != nil {
-		return goja.Undefined(), err
+		return nil, nil, err
 	}
 	exp := vu.Runtime.Get("exports").ToObject(vu.Runtime)
 	if exp == nil {
-		return goja.Undefined(), nil
+		return vu, nil, nil
 	}
 	fn, ok := goja.AssertFunction(exp.Get(name))
 	if !ok {
-		return goja.Undefined(), nil
+		return vu, nil, nil
 	}
+	return vu, fn, nil
+}
 
+func (r *Runner) runPartFn(ctx context.Context, vu *VU, name string, fn goja.Callable, arg interface{}) (goja.Value, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	go func() {
@@ -343,6 +491,15 @@ type VU struct {
 
 	setupData goja.Value
 
+	// The run tags of the scenario the VU belongs to, nil if it doesn't belong to one.
+	runTags *stats.SampleTags
+
+	// The scenario the VU belongs to, nil if it doesn't belong to one, the counter of the
+	// iterations started by all of its VUs and the number of the current one.
+	scenario           *scheduler.BaseConfig
+	scenarioIterations *int64
+	scenarioIteration  int64
+
 	// A VU will track the last context it was called with for cancellation.
 	// Note that interruptTrackedCtx is the context that is currently being tracked, while
 	// interruptCancel cancels an unrelated context that terminates the tracking goroutine
@@ -398,10 +555,34 @@ func (u *VU) RunOnce(ctx context.Context) error {
 	}
 
 	// Call the default function.
+	u.scenarioIteration = u.Runner.partition.Index(atomic.AddInt64(u.scenarioIterations, 1) - 1)
 	_, _, err := u.runFn(ctx, u.Runner.defaultGroup, u.Default, u.setupData)
+	if fe := common.GetFailError(err); fe != nil {
+		return failedIterationError{Exception: err.(*goja.Exception), fail: fe}
+	}
 	return err
 }
 
+// failedIterationError is the error of an iteration ended by fail(), which is logged with the group
+// and the check it was called in.
+type failedIterationError struct {
+	*goja.Exception
+	fail *common.FailError
+}
+
+// LogFields returns the group and the check fail() was called in, if it wasn't in the root group
+// or in a check.
+func (e failedIterationError) LogFields() log.Fields {
+	fields := log.Fields{}
+	if e.fail.Group != "" {
+		fields["group"] = e.fail.Group
+	}
+	if e.fail.Check != "" {
+		fields["check"] = e.fail.Check
+	}
+	return fields
+}
+
 func (u *VU) runFn(
 	ctx context.Context, group *lib.Group, fn goja.Callable, args ...goja.Value,
 ) (goja.Value, *lib.State, error) {
@@ -414,9 +595,14 @@ func (u *VU) runFn(
 		cookieJar = u.CookieJar
 	}
 
+	options := u.Runner.Bundle.Options
+	if u.runTags != nil {
+		options.RunTags = u.runTags
+	}
+
 	state := &lib.State{
 		Logger:    u.Runner.Logger,
-		Options:   u.Runner.Bundle.Options,
+		Options:   options,
 		Group:     group,
 		Transport: u.Transport,
 		Dialer:    u.Dialer,
@@ -427,6 +613,10 @@ func (u *VU) runFn(
 		Vu:        u.ID,
 		Samples:   u.Samples,
 		Iteration: u.Iteration,
+
+		Scenario:          u.scenario,
+		ScenarioIteration: u.scenarioIteration,
+		StartTime:         lib.GetExecutionStart(ctx),
 	}
 
 	newctx := common.WithRuntime(ctx, u.Runtime)
@@ -441,6 +631,13 @@ func (u *VU) runFn(
 	v, err := fn(goja.Undefined(), args...) // Actually run the JS script
 	endTime := time.Now()
 
+	// Aborting the test interrupts the script, since that can't be caught by it, unlike exceptions.
+	if e, ok := err.(*goja.InterruptedError); ok {
+		if abortErr, ok := e.Value().(lib.TestAbortError); ok {
+			err = abortErr
+		}
+	}
+
 	var isFullIteration bool
 	select {
 	case <-ctx.Done():
@@ -459,12 +656,24 @@ func (u *VU) runFn(
 	if state.Options.SystemTags["group"] {
 		tags["group"] = group.Path
 	}
+	if !isFullIteration {
+		tags["interrupted"] = "true"
+	}
 
 	if u.Runner.Bundle.Options.NoVUConnectionReuse.Bool {
 		u.Transport.CloseIdleConnections()
 	}
 
-	state.Samples <- u.Dialer.GetTrail(startTime, endTime, isFullIteration, stats.IntoSampleTags(&tags))
+	sampleTags := stats.IntoSampleTags(&tags)
+	state.Samples <- u.Dialer.GetTrail(startTime, endTime, isFullIteration, sampleTags)
+	// Iterations interrupted at the end of the test didn't fail, they just didn't get to finish.
+	if isFullIteration {
+		var failed float64
+		if err != nil {
+			failed = 1
+		}
+		state.Samples <- stats.Sample{Time: endTime, Metric: metrics.IterationFailed, Tags: sampleTags, Value: failed}
+	}
 
 	// If MinIterationDuration is specified and the iteration wasn't cancelled
 	// and was less than it, sleep for the remainder
@@ -46,10 +46,13 @@ import (
 	"github.com/loadimpact/k6/js/modules/k6/ws"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/scheduler"
 	"github.com/loadimpact/k6/lib/testutils"
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 	"github.com/loadimpact/k6/stats/dummy"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
@@ -415,6 +418,282 @@ func TestSetupDataNoReturn(t *testing.T) {
 	}
 	testSetupDataHelper(t, src)
 }
+
+func TestNewScenarioVU(t *testing.T) {
+	r, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+			import { Counter } from "k6/metrics";
+			let calls = new Counter("calls");
+			let initFlow = __ENV.FLOW;
+			export default function() { calls.add(1, { fn: "default" }); };
+			export function checkout() {
+				calls.add(1, { fn: "checkout", flow: __ENV.FLOW, init_flow: initFlow, shop: __ENV.SHOP });
+			};
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{Env: map[string]string{"FLOW": "slow", "SHOP": "main"}})
+	require.NoError(t, err)
+
+	scenario := scheduler.NewBaseConfig("buy", "shared-iterations", false)
+	scenario.Exec = null.StringFrom("checkout")
+	scenario.Env = map[string]string{"FLOW": "fast"}
+	scenario.Tags = map[string]string{"team": "shop"}
+
+	out := make(chan stats.SampleContainer, 100)
+	vu, err := r.NewScenarioVU(out, scenario)
+	require.NoError(t, err)
+	require.NoError(t, vu.RunOnce(context.Background()))
+
+	var found bool
+	for len(out) > 0 {
+		for _, s := range (<-out).GetSamples() {
+			if s.Metric.Name != "calls" {
+				continue
+			}
+			found = true
+			assert.Equal(t, map[string]string{
+				"fn": "checkout", "flow": "fast", "init_flow": "fast", "shop": "main", "scenario": "buy", "team": "shop",
+			}, s.Tags.CloneTags())
+		}
+	}
+	assert.True(t, found)
+
+	scenario.Exec = null.StringFrom("missing")
+	_, err = r.NewScenarioVU(out, scenario)
+	assert.EqualError(t, err, "exported function 'missing' not found")
+}
+
+func TestVUExecutionStats(t *testing.T) {
+	r, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+			import { Counter } from "k6/metrics";
+			import execution from "k6/execution";
+			let iters = new Counter("iters");
+			export default function() {
+				let vu = execution.getVUStats(), scenario = execution.getScenarioStats();
+				if (vu.id !== __VU || vu.iteration !== __ITER) {
+					throw new Error("wrong VU stats: " + JSON.stringify(vu));
+				}
+				if (execution.getTestInstanceStats().startTime !== 1500000000000) {
+					throw new Error("wrong start time: " + JSON.stringify(execution.getTestInstanceStats()));
+				}
+				iters.add(1, { scenario_name: scenario.name, scenario_iter: String(scenario.iteration) });
+			};
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	ctx := lib.WithExecutionStart(context.Background(), time.Unix(1500000000, 0))
+	out := make(chan stats.SampleContainer, 1000)
+	scenario := scheduler.NewBaseConfig("buy", "shared-iterations", false)
+	var vus []lib.VU
+	for i := int64(1); i <= 2; i++ {
+		vu, err := r.NewScenarioVU(out, scenario)
+		require.NoError(t, err)
+		require.NoError(t, vu.Reconfigure(i))
+		vus = append(vus, vu)
+	}
+	for i := 0; i < 3; i++ {
+		for _, vu := range vus {
+			require.NoError(t, vu.RunOnce(ctx))
+		}
+	}
+	vu, err := r.NewVU(out)
+	require.NoError(t, err)
+	require.NoError(t, vu.RunOnce(ctx))
+
+	iterations := map[string][]string{}
+	for len(out) > 0 {
+		for _, s := range (<-out).GetSamples() {
+			if s.Metric.Name == "iters" {
+				tags := s.Tags.CloneTags()
+				iterations[tags["scenario_name"]] = append(iterations[tags["scenario_name"]], tags["scenario_iter"])
+			}
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"buy":     {"0", "1", "2", "3", "4", "5"},
+		"default": {"0"},
+	}, iterations)
+}
+
+func TestVUAbortTest(t *testing.T) {
+	r, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+			import execution from "k6/execution";
+			export let options = { setupTimeout: "10s" };
+			export function setup() {
+				if (__ENV.ABORT_SETUP) { execution.abortTest("bad setup"); }
+			}
+			export default function() {
+				try {
+					execution.abortTest("the target is misconfigured");
+				} catch (e) {
+					throw new Error("the abort was caught: " + e);
+				}
+				throw new Error("the test wasn't aborted");
+			};
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{Env: map[string]string{"ABORT_SETUP": "1"}})
+	require.NoError(t, err)
+
+	out := make(chan stats.SampleContainer, 100)
+	vu, err := r.NewVU(out)
+	require.NoError(t, err)
+	err = vu.RunOnce(context.Background())
+	assert.Equal(t, lib.NewTestAbortError("the target is misconfigured"), err)
+
+	err = r.Setup(context.Background(), out)
+	assert.Equal(t, lib.NewTestAbortError("bad setup"), errors.Cause(err))
+}
+
+func TestVUFailedIterations(t *testing.T) {
+	r, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+			import { group, check, fail } from "k6";
+			export default function() {
+				if (__ITER == 1) {
+					group("login", () => { check(null, { "is logged in": () => fail("login failed") }); });
+				}
+				if (__ITER == 2) {
+					throw new Error("other error");
+				}
+				if (__ITER == 3) {
+					try { fail("ignored"); } catch (e) { }
+				}
+			};
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	out := make(chan stats.SampleContainer, 1000)
+	vu, err := r.NewVU(out)
+	require.NoError(t, err)
+
+	var failed []float64
+	for i := 0; i < 4; i++ {
+		err := vu.RunOnce(context.Background())
+		switch i {
+		case 1:
+			ierr, ok := err.(lib.IterationError)
+			require.True(t, ok, "%#v", err)
+			assert.Contains(t, ierr.Error(), "login failed")
+			assert.Equal(t, logrus.Fields{"group": "::login", "check": "is logged in"}, ierr.LogFields())
+		case 2:
+			require.Error(t, err)
+			_, ok := err.(lib.IterationError)
+			assert.False(t, ok)
+		default:
+			require.NoError(t, err)
+		}
+		for _, s := range stats.GetBufferedSamples(out) {
+			for _, sample := range s.GetSamples() {
+				if sample.Metric == metrics.IterationFailed {
+					failed = append(failed, sample.Value)
+				}
+			}
+		}
+	}
+	assert.Equal(t, []float64{0, 1, 1, 0}, failed)
+}
+
+func TestVUInterruptedIteration(t *testing.T) {
+	r, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+			import { sleep } from "k6";
+			export default function() { sleep(10); };
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	out := make(chan stats.SampleContainer, 1000)
+	vu, err := r.NewVU(out)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_ = vu.RunOnce(ctx)
+
+	var found bool
+	for _, s := range stats.GetBufferedSamples(out) {
+		for _, sample := range s.GetSamples() {
+			found = true
+			interrupted, _ := sample.Tags.Get("interrupted")
+			assert.Equal(t, "true", interrupted, sample.Metric.Name)
+			assert.NotEqual(t, metrics.IterationFailed, sample.Metric, "interrupted iterations didn't fail")
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestHandleSummary(t *testing.T) {
+	summary := []byte(`{"duration": 1000, "metrics": {"iterations": {"values": {"count": 10}}}}`)
+
+	t.Run("Exported", func(t *testing.T) {
+		r, err := New(&lib.SourceData{
+			Filename: "/script.js",
+			Data: []byte(`
+				export default function() {};
+				export function handleSummary(data) {
+					return {
+						"stdout": "iterations: " + data.metrics.iterations.values.count,
+						"/summary.json": JSON.stringify({ duration: data.duration }),
+					};
+				};
+			`),
+		}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+		require.NoError(t, err)
+
+		result, ok, err := r.HandleSummary(context.Background(), summary)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, map[string]string{
+			"stdout":        "iterations: 10",
+			"/summary.json": `{"duration":1000}`,
+		}, result)
+	})
+
+	t.Run("NotExported", func(t *testing.T) {
+		r, err := New(&lib.SourceData{
+			Filename: "/script.js",
+			Data:     []byte(`export default function() {};`),
+		}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+		require.NoError(t, err)
+
+		result, ok, err := r.HandleSummary(context.Background(), summary)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Nil(t, result)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		r, err := New(&lib.SourceData{
+			Filename: "/script.js",
+			Data: []byte(`
+				export default function() {};
+				export function handleSummary(data) { throw new Error("oops"); };
+			`),
+		}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+		require.NoError(t, err)
+
+		_, ok, err := r.HandleSummary(context.Background(), summary)
+		assert.True(t, ok)
+		assert.Error(t, err)
+	})
+
+	t.Run("NotAFunction", func(t *testing.T) {
+		_, err := New(&lib.SourceData{
+			Filename: "/script.js",
+			Data:     []byte(`export default function() {}; export let handleSummary = 1;`),
+		}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+		assert.EqualError(t, err, "exported 'handleSummary' must be a function")
+	})
+}
+
 func TestRunnerIntegrationImports(t *testing.T) {
 	t.Run("Modules", func(t *testing.T) {
 		modules := []string{
@@ -665,10 +944,10 @@ func TestVUIntegrationMetrics(t *testing.T) {
 			err = vu.RunOnce(context.Background())
 			assert.NoError(t, err)
 			sampleCount := 0
-			for i, sampleC := range stats.GetBufferedSamples(samples) {
-				for j, s := range sampleC.GetSamples() {
+			for _, sampleC := range stats.GetBufferedSamples(samples) {
+				for _, s := range sampleC.GetSamples() {
 					sampleCount++
-					switch i + j {
+					switch sampleCount - 1 {
 					case 0:
 						assert.Equal(t, 5.0, s.Value)
 						assert.Equal(t, "my_metric", s.Metric.Name)
@@ -681,10 +960,13 @@ func TestVUIntegrationMetrics(t *testing.T) {
 						assert.Equal(t, metrics.DataReceived, s.Metric, "`data_received` sample is after `data_received`")
 					case 3:
 						assert.Equal(t, metrics.IterationDuration, s.Metric, "`iteration-duration` sample is after `data_received`")
+					case 4:
+						assert.Equal(t, 0.0, s.Value)
+						assert.Equal(t, metrics.IterationFailed, s.Metric, "`iteration_failed` sample is after `iteration_duration`")
 					}
 				}
 			}
-			assert.Equal(t, sampleCount, 4)
+			assert.Equal(t, sampleCount, 5)
 		})
 	}
 }
@@ -835,6 +1117,58 @@ func TestVUIntegrationHosts(t *testing.T) {
 	}
 }
 
+func TestVUIntegrationConnectionReuse(t *testing.T) {
+	tb := testutils.NewHTTPMultiBin(t)
+	defer tb.Cleanup()
+	tb.Mux.HandleFunc("/remote-addr", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, r.RemoteAddr)
+	})
+
+	// Every iteration makes two requests, the expected values tell whether the client address
+	// of each request is the same as the one of the previous request.
+	testdata := map[string]struct {
+		opts     lib.Options
+		expected string
+	}{
+		"Default":             {lib.Options{}, "true,true,true"},
+		"NoConnectionReuse":   {lib.Options{NoConnectionReuse: null.BoolFrom(true)}, "false,false,false"},
+		"NoVUConnectionReuse": {lib.Options{NoVUConnectionReuse: null.BoolFrom(true)}, "true,false,true"},
+	}
+	for name, data := range testdata {
+		data := data
+		t.Run(name, func(t *testing.T) {
+			r, err := New(&lib.SourceData{
+				Filename: "/script.js",
+				Data: []byte(tb.Replacer.Replace(`
+					import http from "k6/http";
+					let addrs = [];
+					export default function() {
+						addrs.push(http.get("HTTPBIN_URL/remote-addr").body);
+						addrs.push(http.get("HTTPBIN_URL/remote-addr").body);
+						if (__ITER == 1) {
+							let got = [1, 2, 3].map(i => addrs[i] == addrs[i - 1]).join(",");
+							if (got != "` + data.expected + `") {
+								throw new Error("wrong connection reuse: " + got + " " + JSON.stringify(addrs));
+							}
+						}
+					}
+				`)),
+			}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+			require.NoError(t, err)
+			require.NoError(t, r.SetOptions(data.opts.Apply(lib.Options{
+				Throw: null.BoolFrom(true),
+				Hosts: tb.Dialer.Hosts,
+			})))
+
+			vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+			require.NoError(t, err)
+			for i := 0; i < 2; i++ {
+				require.NoError(t, vu.RunOnce(context.Background()))
+			}
+		})
+	}
+}
+
 func TestVUIntegrationTLSConfig(t *testing.T) {
 	var unsupportedVersionErrorMsg = "remote error: tls: handshake failure"
 	for _, tag := range build.Default.ReleaseTags {
@@ -0,0 +1,340 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package k6exec allows k6 tests to be run from other Go programs, without having to
+// shell out to the k6 binary. It wires together the same components that `k6 run` uses
+// (a runner for the script, a local executor and an engine), so the tests behave
+// exactly as they would when executed from the command line.
+//
+// A minimal example:
+//
+//	test, err := k6exec.NewFromFile("script.js", k6exec.Config{
+//	    Options: lib.Options{VUs: null.IntFrom(10), Duration: types.NullDurationFrom(time.Minute)},
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	test.Subscribe(func(samples []stats.SampleContainer) { ... })
+//	result, err := test.Run(context.Background())
+package k6exec
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/loadimpact/k6/core"
+	"github.com/loadimpact/k6/core/local"
+	"github.com/loadimpact/k6/js"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/scheduler"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/loader"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// Config contains everything needed to construct a Test, apart from the script itself.
+type Config struct {
+	// The filesystem that scripts, modules and files opened with open() are loaded
+	// from. If nil, the real OS filesystem is used.
+	FS afero.Fs
+
+	// Environment variables and other settings for the JS runtime.
+	RuntimeOptions lib.RuntimeOptions
+
+	// Options that are applied on top of the ones exported by the script, the same
+	// way CLI flags have the highest priority when running `k6 run`.
+	Options lib.Options
+
+	// Collectors that the metric samples should be sent to, in addition to any
+	// subscribers. They should already be initialized.
+	Collectors []lib.Collector
+
+	// Disables the evaluation of thresholds.
+	NoThresholds bool
+
+	// The logger used by the engine and executor. If nil, logrus' standard logger is used.
+	Logger *log.Logger
+}
+
+// SampleHandler is a function that receives the metric samples during a test run. It's
+// never called concurrently, but should return quickly, since it blocks the processing
+// of further samples.
+type SampleHandler func(samples []stats.SampleContainer)
+
+// Result contains the outcome of a finished test run.
+type Result struct {
+	// All of the metrics and their sinks, the data used for the end-of-test summary.
+	Metrics map[string]*stats.Metric
+
+	// The root group, which contains all groups and checks.
+	RootGroup *lib.Group
+
+	// Whether any of the thresholds have failed.
+	ThresholdsFailed bool
+
+	Duration   time.Duration
+	Iterations int64
+}
+
+// Test is a fully configured k6 test, ready to be run.
+type Test struct {
+	Runner lib.Runner
+	Engine *core.Engine
+
+	handlers []SampleHandler
+}
+
+// NewFromFile loads the script or archive with the supplied filename and creates a new Test
+// from it. Relative paths are resolved from the current working directory.
+func NewFromFile(filename string, conf Config) (*Test, error) {
+	if conf.FS == nil {
+		conf.FS = afero.NewOsFs()
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(filename) {
+		if ok, _ := afero.Exists(conf.FS, filepath.Join(pwd, filename)); ok {
+			filename = filepath.Join(pwd, filename)
+		}
+	}
+	src, err := loader.Load(conf.FS, pwd, filename)
+	if err != nil {
+		return nil, err
+	}
+	return New(src, conf)
+}
+
+// New creates a new Test from the supplied script or archive source.
+func New(src *lib.SourceData, conf Config) (*Test, error) {
+	if conf.FS == nil {
+		conf.FS = afero.NewOsFs()
+	}
+
+	r, err := NewRunner(src, conf.FS, conf.RuntimeOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := ApplyExecutionDefaults(DefaultOptions().Apply(r.GetOptions()).Apply(conf.Options))
+	if opts, err = ApplyExecutionSegment(opts); err != nil {
+		return nil, err
+	}
+	if err = r.SetOptions(opts); err != nil {
+		return nil, err
+	}
+
+	engine, err := core.NewEngine(local.NewForExecution(r, opts.Execution), opts)
+	if err != nil {
+		return nil, err
+	}
+	engine.NoThresholds = conf.NoThresholds
+	if conf.Logger != nil {
+		engine.SetLogger(conf.Logger)
+	}
+	engine.Collectors = append(engine.Collectors, conf.Collectors...)
+
+	t := &Test{Runner: r, Engine: engine}
+	engine.Collectors = append(engine.Collectors, &handlerCollector{test: t})
+	return t, nil
+}
+
+// NewRunner creates the appropriate runner for the supplied source, which can be either a
+// JS script or an archive created with `k6 archive`.
+func NewRunner(src *lib.SourceData, fs afero.Fs, rtOpts lib.RuntimeOptions) (lib.Runner, error) {
+	if _, err := tar.NewReader(bytes.NewReader(src.Data)).Next(); err != nil {
+		return js.New(src, fs, rtOpts)
+	}
+
+	arc, err := lib.ReadArchive(bytes.NewReader(src.Data))
+	if err != nil {
+		return nil, err
+	}
+	if arc.Type != "js" {
+		return nil, errors.Errorf("archive requests unsupported runner: %s", arc.Type)
+	}
+	return js.NewFromArchive(arc, rtOpts)
+}
+
+// DefaultOptions returns the defaults for options that need to have a value, even if it
+// wasn't explicitly specified. They are the same as the defaults of the `k6 run` flags.
+func DefaultOptions() lib.Options {
+	return lib.Options{
+		SetupTimeout:            types.NullDuration{Duration: types.Duration(10 * time.Second), Valid: false},
+		TeardownTimeout:         types.NullDuration{Duration: types.Duration(10 * time.Second), Valid: false},
+		MetricSamplesBufferSize: null.NewInt(1000, false),
+	}
+}
+
+// ApplyExecutionDefaults fills in the execution options that weren't specified, so that
+// the engine can use them: if no max VUs are set, they are derived from the VUs and the
+// stages, and if no duration, iterations or stages are set, a single iteration is run.
+// An explicit duration of 0 means that the test should run until it's stopped. With a single
+// scheduler in the execution option, these options default to its settings, and with several
+// scenarios the VUs and max VUs are the sum of theirs.
+func ApplyExecutionDefaults(opts lib.Options) lib.Options {
+	if scenarios := lib.Scenarios(opts.Execution); scenarios != nil {
+		// Every scenario has its own VUs, the VU options can't be used to change them.
+		opts.VUs, opts.VUsMax = null.IntFrom(0), null.IntFrom(0)
+		for _, conf := range scenarios {
+			opts.VUs.Int64 += lib.ApplySchedulerOptions(lib.Options{}, conf).VUs.Int64
+			opts.VUsMax.Int64 += conf.GetMaxVUs()
+		}
+		return opts
+	}
+	if conf, ok := lib.ExecutionConfig(opts.Execution); ok {
+		opts = lib.ApplySchedulerOptions(opts, conf)
+	}
+
+	if !opts.VUsMax.Valid {
+		opts.VUsMax = null.NewInt(opts.VUs.Int64, opts.VUs.Valid)
+		for _, stage := range opts.Stages {
+			if stage.Target.Valid && stage.Target.Int64 > opts.VUsMax.Int64 {
+				opts.VUsMax = stage.Target
+			}
+		}
+	}
+
+	if !opts.Duration.Valid && !opts.Iterations.Valid && len(opts.Stages) == 0 {
+		opts.Iterations = null.IntFrom(1)
+	}
+
+	//TODO: just... handle this differently, e.g. as a part of the manual executor
+	if opts.Duration.Valid && opts.Duration.Duration == 0 {
+		opts.Duration = types.NullDuration{}
+	}
+	return opts
+}
+
+// ApplyExecutionSegment scales the execution options to the execution segment, if there is one,
+// so that only its part of the test is run: the VUs, the max VUs, the iterations, the RPS limit,
+// the stage targets and the settings of the schedulers. It should be applied after the options
+// are validated, since a part of a test can be left without any VUs or iterations.
+func ApplyExecutionSegment(opts lib.Options) (lib.Options, error) {
+	p, err := lib.NewExecutionPartition(opts.ExecutionSegment, opts.ExecutionSegmentSequence)
+	if err != nil || p == nil {
+		return opts, err
+	}
+
+	if opts.Execution != nil {
+		execution := make(scheduler.ConfigMap, len(opts.Execution))
+		for name, conf := range opts.Execution {
+			execution[name] = lib.ScaleSchedulerConfig(conf, p)
+		}
+		opts.Execution = execution
+	}
+	if scenarios := lib.Scenarios(opts.Execution); scenarios != nil {
+		opts.VUs, opts.VUsMax = null.IntFrom(0), null.IntFrom(0)
+		for _, conf := range scenarios {
+			opts.VUs.Int64 += lib.ApplySchedulerOptions(lib.Options{}, conf).VUs.Int64
+			opts.VUsMax.Int64 += conf.GetMaxVUs()
+		}
+		return opts, nil
+	}
+
+	scale := func(v null.Int) null.Int { return null.NewInt(p.Scale(v.Int64), v.Valid) }
+	opts.VUs, opts.VUsMax = scale(opts.VUs), scale(opts.VUsMax)
+	if vuIterations := lib.GetVUIterations(opts.Execution); vuIterations.Valid {
+		opts.Iterations.Int64 = opts.VUs.Int64 * vuIterations.Int64
+	} else {
+		opts.Iterations = scale(opts.Iterations)
+	}
+	if opts.RPS.Valid && opts.RPS.Int64 > 0 {
+		// The RPS limit is split like the VUs, so that all of the parts together make as many
+		// requests per second as the whole test. A part without any can't be run, since 0 would
+		// mean no limit at all.
+		rps := p.Scale(opts.RPS.Int64)
+		if rps == 0 {
+			return opts, errors.Errorf(
+				"the RPS limit of %d is too low to be split, the execution segment %s gets none of it",
+				opts.RPS.Int64, opts.ExecutionSegment,
+			)
+		}
+		opts.RPS.Int64 = rps
+	}
+	if opts.Stages != nil {
+		stages := make([]lib.Stage, len(opts.Stages))
+		for i, s := range opts.Stages {
+			stages[i] = lib.Stage{Duration: s.Duration, Target: scale(s.Target)}
+		}
+		opts.Stages = stages
+	}
+
+	// The shared iterations need a VU to run them, even if the part of the test has none.
+	if opts.Iterations.Int64 > 0 && opts.VUs.Int64 == 0 && len(opts.Stages) == 0 && lib.GetArrivalRate(opts.Execution) == nil {
+		opts.VUs.Int64, opts.VUsMax.Int64 = 1, lib.Max(1, opts.VUsMax.Int64)
+	}
+	return opts, nil
+}
+
+// Subscribe registers a function that will receive all metric samples during the test
+// run. It should be called before Run().
+func (t *Test) Subscribe(fn SampleHandler) {
+	t.handlers = append(t.handlers, fn)
+}
+
+// Run executes the test and blocks until it's finished or the supplied context is done.
+func (t *Test) Run(ctx context.Context) (*Result, error) {
+	if err := t.Engine.Run(ctx); err != nil {
+		return nil, err
+	}
+
+	t.Engine.MetricsLock.Lock()
+	defer t.Engine.MetricsLock.Unlock()
+	return &Result{
+		Metrics:          t.Engine.Metrics,
+		RootGroup:        t.Runner.GetDefaultGroup(),
+		ThresholdsFailed: t.Engine.IsTainted(),
+		Duration:         t.Engine.Executor.GetTime(),
+		Iterations:       t.Engine.Executor.GetIterations(),
+	}, nil
+}
+
+// handlerCollector passes the samples it receives to the subscribers of a Test.
+type handlerCollector struct {
+	test *Test
+}
+
+var _ lib.Collector = &handlerCollector{}
+
+func (c *handlerCollector) Init() error { return nil }
+
+func (c *handlerCollector) Run(ctx context.Context) { <-ctx.Done() }
+
+func (c *handlerCollector) Collect(samples []stats.SampleContainer) {
+	for _, fn := range c.test.handlers {
+		fn(samples)
+	}
+}
+
+func (c *handlerCollector) Link() string { return "" }
+
+func (c *handlerCollector) GetRequiredSystemTags() lib.TagSet { return lib.TagSet{} }
+
+func (c *handlerCollector) SetRunStatus(status lib.RunStatus) {}
@@ -0,0 +1,262 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package k6exec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/scheduler"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestApplyExecutionDefaults(t *testing.T) {
+	opts := ApplyExecutionDefaults(lib.Options{})
+	assert.Equal(t, null.IntFrom(1), opts.Iterations)
+
+	opts = ApplyExecutionDefaults(lib.Options{
+		VUs:    null.IntFrom(5),
+		Stages: []lib.Stage{{Duration: types.NullDurationFrom(time.Second), Target: null.IntFrom(20)}},
+	})
+	assert.Equal(t, null.IntFrom(20), opts.VUsMax)
+	assert.False(t, opts.Iterations.Valid)
+
+	opts = ApplyExecutionDefaults(lib.Options{Duration: types.NullDurationFrom(0)})
+	assert.False(t, opts.Duration.Valid)
+	assert.False(t, opts.Iterations.Valid)
+
+	carc := scheduler.NewConstantArrivalRateConfig(lib.DefaultSchedulerName)
+	carc.Rate = null.IntFrom(10)
+	carc.Duration = types.NullDurationFrom(time.Minute)
+	carc.PreAllocatedVUs = null.IntFrom(5)
+	carc.MaxVUs = null.IntFrom(50)
+	opts = ApplyExecutionDefaults(lib.Options{Execution: scheduler.ConfigMap{lib.DefaultSchedulerName: carc}})
+	assert.Equal(t, null.IntFrom(5), opts.VUs)
+	assert.Equal(t, null.IntFrom(50), opts.VUsMax)
+	assert.Equal(t, types.NullDurationFrom(time.Minute), opts.Duration)
+	assert.False(t, opts.Iterations.Valid)
+
+	varc := scheduler.NewVariableArrivalRateConfig(lib.DefaultSchedulerName)
+	varc.PreAllocatedVUs = null.IntFrom(10)
+	varc.MaxVUs = null.IntFrom(100)
+	varc.Stages = []scheduler.Stage{
+		{Duration: types.NullDurationFrom(10 * time.Minute), Target: null.IntFrom(500)},
+		{Duration: types.NullDurationFrom(time.Minute), Target: null.IntFrom(0)},
+	}
+	opts = ApplyExecutionDefaults(lib.Options{Execution: scheduler.ConfigMap{lib.DefaultSchedulerName: varc}})
+	assert.Equal(t, null.IntFrom(10), opts.VUs)
+	assert.Equal(t, null.IntFrom(100), opts.VUsMax)
+	assert.Equal(t, types.NullDurationFrom(11*time.Minute), opts.Duration)
+
+	pvic := scheduler.NewPerVUIterationsConfig(lib.DefaultSchedulerName)
+	pvic.VUs = null.IntFrom(10)
+	pvic.Iterations = null.IntFrom(100)
+	opts = ApplyExecutionDefaults(lib.Options{Execution: scheduler.ConfigMap{lib.DefaultSchedulerName: pvic}})
+	assert.Equal(t, null.IntFrom(10), opts.VUs)
+	assert.Equal(t, null.IntFrom(10), opts.VUsMax)
+	assert.Equal(t, null.IntFrom(1000), opts.Iterations)
+	assert.False(t, opts.Duration.Valid)
+
+	sic := scheduler.NewSharedIterationsConfig(lib.DefaultSchedulerName)
+	sic.VUs = null.IntFrom(10)
+	sic.Iterations = null.IntFrom(10000)
+	sic.MaxDuration = types.NullDurationFrom(time.Hour)
+	opts = ApplyExecutionDefaults(lib.Options{Execution: scheduler.ConfigMap{lib.DefaultSchedulerName: sic}})
+	assert.Equal(t, null.IntFrom(10), opts.VUs)
+	assert.Equal(t, null.IntFrom(10), opts.VUsMax)
+	assert.Equal(t, null.IntFrom(10000), opts.Iterations)
+	assert.Equal(t, types.NullDurationFrom(time.Hour), opts.Duration)
+
+	ecc := scheduler.NewExternallyControlledConfig(lib.DefaultSchedulerName)
+	ecc.VUs = null.IntFrom(10)
+	ecc.MaxVUs = null.IntFrom(100)
+	opts = ApplyExecutionDefaults(lib.Options{Execution: scheduler.ConfigMap{lib.DefaultSchedulerName: ecc}})
+	assert.Equal(t, null.IntFrom(10), opts.VUs)
+	assert.Equal(t, null.IntFrom(100), opts.VUsMax)
+	assert.False(t, opts.Iterations.Valid)
+	assert.False(t, opts.Duration.Valid)
+
+	opts = ApplyExecutionDefaults(lib.Options{
+		VUs:       null.IntFrom(1),
+		Execution: scheduler.ConfigMap{"browse": sic, "checkout": carc},
+	})
+	assert.Equal(t, null.IntFrom(15), opts.VUs)
+	assert.Equal(t, null.IntFrom(60), opts.VUsMax)
+	assert.False(t, opts.Iterations.Valid)
+	assert.False(t, opts.Duration.Valid)
+}
+
+func TestApplyExecutionSegment(t *testing.T) {
+	opts, err := ApplyExecutionSegment(lib.Options{VUs: null.IntFrom(10)})
+	require.NoError(t, err)
+	assert.Equal(t, null.IntFrom(10), opts.VUs)
+
+	thirds := func(t *testing.T, opts lib.Options) []lib.Options {
+		var parts []lib.Options
+		for _, s := range []string{"0:1/3", "1/3:2/3", "2/3:1"} {
+			es, err := lib.NewExecutionSegmentFromString(s)
+			require.NoError(t, err)
+			opts.ExecutionSegment = es
+			part, err := ApplyExecutionSegment(opts)
+			require.NoError(t, err)
+			parts = append(parts, part)
+		}
+		return parts
+	}
+
+	t.Run("VUs and iterations", func(t *testing.T) {
+		var vus, vusMax, iterations int64
+		for _, part := range thirds(t, lib.Options{
+			VUs: null.IntFrom(10), VUsMax: null.IntFrom(20), Iterations: null.IntFrom(100),
+		}) {
+			vus += part.VUs.Int64
+			vusMax += part.VUsMax.Int64
+			iterations += part.Iterations.Int64
+		}
+		assert.Equal(t, int64(10), vus)
+		assert.Equal(t, int64(20), vusMax)
+		assert.Equal(t, int64(100), iterations)
+	})
+
+	t.Run("stages", func(t *testing.T) {
+		targets := make([]int64, 2)
+		for _, part := range thirds(t, lib.Options{Stages: []lib.Stage{
+			{Duration: types.NullDurationFrom(time.Minute), Target: null.IntFrom(8)},
+			{Duration: types.NullDurationFrom(time.Minute), Target: null.IntFrom(0)},
+		}}) {
+			require.Len(t, part.Stages, 2)
+			assert.Equal(t, types.NullDurationFrom(time.Minute), part.Stages[0].Duration)
+			for i, s := range part.Stages {
+				targets[i] += s.Target.Int64
+			}
+		}
+		assert.Equal(t, []int64{8, 0}, targets)
+	})
+
+	t.Run("per VU iterations", func(t *testing.T) {
+		pvic := scheduler.NewPerVUIterationsConfig(lib.DefaultSchedulerName)
+		pvic.VUs = null.IntFrom(4)
+		pvic.Iterations = null.IntFrom(10)
+		opts := ApplyExecutionDefaults(lib.Options{Execution: scheduler.ConfigMap{lib.DefaultSchedulerName: pvic}})
+		var iterations int64
+		for _, part := range thirds(t, opts) {
+			assert.Equal(t, part.VUs.Int64*10, part.Iterations.Int64)
+			iterations += part.Iterations.Int64
+		}
+		assert.Equal(t, int64(40), iterations)
+	})
+
+	t.Run("scenarios", func(t *testing.T) {
+		sic := scheduler.NewSharedIterationsConfig(lib.DefaultSchedulerName)
+		sic.VUs = null.IntFrom(10)
+		sic.Iterations = null.IntFrom(100)
+		carc := scheduler.NewConstantArrivalRateConfig(lib.DefaultSchedulerName)
+		carc.Rate = null.IntFrom(30)
+		carc.TimeUnit = types.NullDurationFrom(time.Second)
+		carc.Duration = types.NullDurationFrom(time.Minute)
+		carc.PreAllocatedVUs = null.IntFrom(5)
+		carc.MaxVUs = null.IntFrom(50)
+		opts := ApplyExecutionDefaults(lib.Options{Execution: scheduler.ConfigMap{"browse": sic, "checkout": carc}})
+		var vus, vusMax int64
+		for _, part := range thirds(t, opts) {
+			vus += part.VUs.Int64
+			vusMax += part.VUsMax.Int64
+		}
+		assert.Equal(t, opts.VUs.Int64, vus)
+		assert.Equal(t, opts.VUsMax.Int64, vusMax)
+	})
+
+	t.Run("at least one VU for iterations", func(t *testing.T) {
+		for _, part := range thirds(t, lib.Options{VUs: null.IntFrom(1), Iterations: null.IntFrom(3)}) {
+			assert.Equal(t, int64(1), part.Iterations.Int64)
+			assert.Equal(t, int64(1), part.VUs.Int64)
+		}
+	})
+
+	t.Run("RPS", func(t *testing.T) {
+		var rps int64
+		for _, part := range thirds(t, lib.Options{VUs: null.IntFrom(3), RPS: null.IntFrom(10)}) {
+			assert.True(t, part.RPS.Int64 > 0)
+			rps += part.RPS.Int64
+		}
+		assert.Equal(t, int64(10), rps)
+
+		es, err := lib.NewExecutionSegmentFromString("2/3:1")
+		require.NoError(t, err)
+		_, err = ApplyExecutionSegment(lib.Options{ExecutionSegment: es, RPS: null.IntFrom(1)})
+		assert.EqualError(t, err, "the RPS limit of 1 is too low to be split, the execution segment 2/3:1 gets none of it")
+	})
+
+	t.Run("invalid sequence", func(t *testing.T) {
+		es, err := lib.NewExecutionSegmentFromString("1/3:2/3")
+		require.NoError(t, err)
+		seq, err := lib.NewExecutionSegmentSequenceFromString("0,1/2,1")
+		require.NoError(t, err)
+		_, err = ApplyExecutionSegment(lib.Options{ExecutionSegment: es, ExecutionSegmentSequence: seq})
+		assert.Error(t, err)
+	})
+}
+
+func TestRun(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/script.js", []byte(`
+		import { check } from "k6";
+		export let options = { vus: 2, thresholds: { checks: ["rate==1"] } };
+		export default function() { check(null, { "passes": () => true }); }
+	`), 0644))
+
+	logger, _ := logtest.NewNullLogger()
+	test, err := NewFromFile("/script.js", Config{
+		FS:      fs,
+		Options: lib.Options{Iterations: null.IntFrom(10)},
+		Logger:  logger,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, null.IntFrom(2), test.Runner.GetOptions().VUsMax)
+
+	var iterations float64
+	test.Subscribe(func(samples []stats.SampleContainer) {
+		for _, sc := range samples {
+			for _, s := range sc.GetSamples() {
+				if s.Metric == metrics.Iterations {
+					iterations += s.Value
+				}
+			}
+		}
+	})
+
+	result, err := test.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), result.Iterations)
+	assert.Equal(t, 10.0, iterations)
+	assert.False(t, result.ThresholdsFailed)
+	require.Contains(t, result.Metrics, "checks")
+	assert.Contains(t, result.RootGroup.Checks, "passes")
+}
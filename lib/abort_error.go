@@ -0,0 +1,41 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+// TestAbortError is used when the script aborts the whole test, with the reason it gave, e.g. with
+// abortTest() of the k6/execution module.
+type TestAbortError string
+
+// NewTestAbortError returns a new TestAbortError with the provided reason, which can be empty.
+func NewTestAbortError(reason string) TestAbortError {
+	return TestAbortError(reason)
+}
+
+func (t TestAbortError) String() string {
+	if t == "" {
+		return "The test was aborted by the script"
+	}
+	return "The test was aborted by the script: " + (string)(t)
+}
+
+func (t TestAbortError) Error() string {
+	return t.String()
+}
@@ -1,11 +1,15 @@
 package lib
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type ctxKey int
 
 const (
 	ctxKeyState ctxKey = iota
+	ctxKeyExecutionStart
 )
 
 func WithState(ctx context.Context, state *State) context.Context {
@@ -19,3 +23,19 @@ func GetState(ctx context.Context) *State {
 	}
 	return v.(*State)
 }
+
+// WithExecutionStart returns a context with the time when the execution of the test started, which
+// the executors pass to the iterations of their VUs.
+func WithExecutionStart(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, ctxKeyExecutionStart, t)
+}
+
+// GetExecutionStart returns the time when the execution of the test started, or the zero time if
+// it hasn't, e.g. in setup().
+func GetExecutionStart(ctx context.Context) time.Time {
+	v := ctx.Value(ctxKeyExecutionStart)
+	if v == nil {
+		return time.Time{}
+	}
+	return v.(time.Time)
+}
@@ -0,0 +1,152 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// The ways to select one of the IPs of a name that resolves to several of them.
+const (
+	DNSSelectFirst      = "first"
+	DNSSelectRoundRobin = "roundRobin"
+	DNSSelectRandom     = "random"
+)
+
+// The policies for the IP versions of the selected IPs.
+const (
+	DNSPolicyPreferIPv4 = "preferIPv4"
+	DNSPolicyPreferIPv6 = "preferIPv6"
+	DNSPolicyOnlyIPv4   = "onlyIPv4"
+	DNSPolicyOnlyIPv6   = "onlyIPv6"
+	DNSPolicyAny        = "any"
+)
+
+// DNSConfig describes how the hostnames of the tested systems are resolved, so that the load
+// can be spread over all of the IPs of a name, instead of landing on the first one returned by
+// the resolver of the OS.
+type DNSConfig struct {
+	// Addresses of the DNS servers to query, with an optional port (53 by default). The resolver
+	// of the OS is used if there are none.
+	Servers []string `json:"servers"`
+
+	// How an IP is selected when a name resolves to several of them; first by default.
+	Select null.String `json:"select"`
+
+	// Which IP versions are preferred or allowed; any by default, in the order of the resolver.
+	Policy null.String `json:"policy"`
+}
+
+// Validate checks that the servers are valid addresses and that the selection and the policy
+// are known ones.
+func (c DNSConfig) Validate() error {
+	for _, server := range c.Servers {
+		host := server
+		if h, _, err := net.SplitHostPort(server); err == nil {
+			host = h
+		}
+		if net.ParseIP(host) == nil {
+			return errors.Errorf("invalid DNS server '%s', it should be an IP with an optional port", server)
+		}
+	}
+	if c.Select.Valid {
+		switch c.Select.String {
+		case DNSSelectFirst, DNSSelectRoundRobin, DNSSelectRandom:
+		default:
+			return errors.Errorf("unknown DNS selection '%s', it should be %s, %s or %s",
+				c.Select.String, DNSSelectFirst, DNSSelectRoundRobin, DNSSelectRandom)
+		}
+	}
+	if c.Policy.Valid {
+		switch c.Policy.String {
+		case DNSPolicyPreferIPv4, DNSPolicyPreferIPv6, DNSPolicyOnlyIPv4, DNSPolicyOnlyIPv6, DNSPolicyAny:
+		default:
+			return errors.Errorf("unknown DNS policy '%s', it should be %s, %s, %s, %s or %s",
+				c.Policy.String, DNSPolicyPreferIPv4, DNSPolicyPreferIPv6,
+				DNSPolicyOnlyIPv4, DNSPolicyOnlyIPv6, DNSPolicyAny)
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON accepts either an object with the DNS settings, or a string in the same
+// format as UnmarshalText.
+func (c *DNSConfig) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		return c.UnmarshalText([]byte(str))
+	}
+
+	type rawDNSConfig DNSConfig
+	var raw rawDNSConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	if err := DNSConfig(raw).Validate(); err != nil {
+		return err
+	}
+	*c = DNSConfig(raw)
+	return nil
+}
+
+// UnmarshalText parses DNS settings in the `server=1.1.1.1,server=8.8.8.8:53,select=roundRobin,
+// policy=preferIPv4` format, which is used by the CLI flag and the environment variable.
+func (c *DNSConfig) UnmarshalText(data []byte) error {
+	var result DNSConfig
+	for _, part := range strings.Split(string(data), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("invalid DNS setting '%s', it should be in the key=value format", part)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		switch key {
+		case "server":
+			result.Servers = append(result.Servers, value)
+		case "select":
+			result.Select = null.StringFrom(value)
+		case "policy":
+			result.Policy = null.StringFrom(value)
+		default:
+			return errors.Errorf("unknown DNS setting '%s'", key)
+		}
+	}
+	if err := result.Validate(); err != nil {
+		return err
+	}
+	*c = result
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestDNSConfigUnmarshal(t *testing.T) {
+	expected := DNSConfig{
+		Servers: []string{"1.1.1.1", "[2606:4700:4700::1111]:53"},
+		Select:  null.StringFrom(DNSSelectRoundRobin),
+		Policy:  null.StringFrom(DNSPolicyPreferIPv4),
+	}
+
+	t.Run("Text", func(t *testing.T) {
+		var c DNSConfig
+		text := "server=1.1.1.1, server=[2606:4700:4700::1111]:53,select=roundRobin,policy=preferIPv4"
+		require.NoError(t, c.UnmarshalText([]byte(text)))
+		assert.Equal(t, expected, c)
+	})
+	t.Run("JSONObject", func(t *testing.T) {
+		var opts Options
+		data := `{"dns": {"servers": ["1.1.1.1", "[2606:4700:4700::1111]:53"], "select": "roundRobin", "policy": "preferIPv4"}}`
+		require.NoError(t, json.Unmarshal([]byte(data), &opts))
+		require.NotNil(t, opts.DNS)
+		assert.Equal(t, expected, *opts.DNS)
+	})
+	t.Run("JSONString", func(t *testing.T) {
+		var c DNSConfig
+		require.NoError(t, json.Unmarshal([]byte(`"select=random"`), &c))
+		assert.Equal(t, DNSConfig{Select: null.StringFrom(DNSSelectRandom)}, c)
+	})
+	t.Run("Env", func(t *testing.T) {
+		os.Clearenv()
+		require.NoError(t, os.Setenv("K6_DNS", "policy=onlyIPv6"))
+		defer os.Clearenv()
+		var opts Options
+		require.NoError(t, envconfig.Process("k6", &opts))
+		require.NotNil(t, opts.DNS)
+		assert.Equal(t, DNSConfig{Policy: null.StringFrom(DNSPolicyOnlyIPv6)}, *opts.DNS)
+	})
+
+	invalid := map[string]string{
+		"server=dns.example.com": "invalid DNS server 'dns.example.com', it should be an IP with an optional port",
+		"select=nearest":         "unknown DNS selection 'nearest', it should be first, roundRobin or random",
+		"policy=ipv4":            "unknown DNS policy 'ipv4', it should be preferIPv4, preferIPv6, onlyIPv4, onlyIPv6 or any",
+		"ttl=1m":                 "unknown DNS setting 'ttl'",
+		"select":                 "invalid DNS setting 'select', it should be in the key=value format",
+	}
+	for text, errMsg := range invalid {
+		var c DNSConfig
+		assert.EqualError(t, c.UnmarshalText([]byte(text)), errMsg, text)
+	}
+	var c DNSConfig
+	assert.Error(t, json.Unmarshal([]byte(`{"select": "first", "ttl": "1m"}`), &c))
+	assert.EqualError(t, json.Unmarshal([]byte(`{"select": "last"}`), &c),
+		"unknown DNS selection 'last', it should be first, roundRobin or random")
+}
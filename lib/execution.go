@@ -0,0 +1,285 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"sort"
+	"time"
+
+	"github.com/loadimpact/k6/lib/scheduler"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// isRunnable returns whether the scheduler config is of a kind that can be run currently.
+func isRunnable(conf scheduler.Config) bool {
+	switch conf.(type) {
+	case scheduler.ConstantLoopingVUsConfig, scheduler.VariableLoopingVUsConfig,
+		scheduler.PerVUIteationsConfig, scheduler.SharedIteationsConfig,
+		scheduler.ConstantArrivalRateConfig, scheduler.VariableArrivalRateConfig,
+		scheduler.ExternallyControlledConfig:
+		return true
+	default:
+		return false
+	}
+}
+
+// isScenario returns whether the scheduler config uses any settings that only a scenario has,
+// since they can't be expressed with the plain execution options.
+func isScenario(conf scheduler.Config) bool {
+	bc := conf.GetBaseConfig()
+	return bc.Exec.Valid || bc.StartTime.Duration > 0 || len(bc.Tags) > 0 || len(bc.Env) > 0
+}
+
+// ExecutionConfig returns the config of the scheduler in the execution config, if there's only
+// one, it's of a kind that can be run currently and it doesn't need to be run as a scenario.
+// The per-VU and shared iterations ones also need explicitly set VUs or iterations.
+func ExecutionConfig(execution scheduler.ConfigMap) (scheduler.Config, bool) {
+	if len(execution) != 1 {
+		return nil, false
+	}
+	for _, conf := range execution {
+		if !isRunnable(conf) || isScenario(conf) {
+			return nil, false
+		}
+		switch conf := conf.(type) {
+		case scheduler.PerVUIteationsConfig:
+			return conf, conf.VUs.Valid || conf.Iterations.Valid
+		case scheduler.SharedIteationsConfig:
+			return conf, conf.VUs.Valid || conf.Iterations.Valid
+		}
+		return conf, true
+	}
+	return nil, false
+}
+
+// Scenarios returns the scheduler configs of the execution config sorted by name, if they have to
+// be run as separate scenarios: there are several of them, or one uses scenario-only settings like
+// exec, startTime, tags or env. All of them have to be of a kind that can be run currently, and
+// none can be externally controlled, since the VUs of scenarios can't be changed.
+func Scenarios(execution scheduler.ConfigMap) []scheduler.Config {
+	if len(execution) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(execution))
+	scenario := len(execution) > 1
+	for name, conf := range execution {
+		if _, ok := conf.(scheduler.ExternallyControlledConfig); ok || !isRunnable(conf) {
+			return nil
+		}
+		scenario = scenario || isScenario(conf)
+		names = append(names, name)
+	}
+	if !scenario {
+		return nil
+	}
+	sort.Strings(names)
+	result := make([]scheduler.Config, len(names))
+	for i, name := range names {
+		result[i] = execution[name]
+	}
+	return result
+}
+
+// ApplySchedulerOptions fills in the execution options that weren't specified with the settings
+// of the scheduler: the VUs, max VUs, iterations, duration and stages.
+func ApplySchedulerOptions(opts Options, conf scheduler.Config) Options {
+	var vus, vusMax, iterations, vuIterations null.Int
+	var duration types.NullDuration
+	var stages []Stage
+	switch conf := conf.(type) {
+	case scheduler.ConstantLoopingVUsConfig:
+		vus, duration = null.IntFrom(conf.VUs.Int64), conf.Duration
+	case scheduler.VariableLoopingVUsConfig:
+		vus = null.IntFrom(conf.StartVUs.Int64)
+		for _, s := range conf.Stages {
+			stages = append(stages, Stage{Duration: s.Duration, Target: s.Target})
+		}
+	case scheduler.PerVUIteationsConfig:
+		vus, vuIterations = null.IntFrom(conf.VUs.Int64), null.IntFrom(conf.Iterations.Int64)
+		if conf.MaxDuration.Valid {
+			duration = conf.MaxDuration
+		}
+	case scheduler.SharedIteationsConfig:
+		vus, iterations = null.IntFrom(conf.VUs.Int64), null.IntFrom(conf.Iterations.Int64)
+		if conf.MaxDuration.Valid {
+			duration = conf.MaxDuration
+		}
+	case scheduler.ConstantArrivalRateConfig:
+		vus, vusMax, duration = conf.PreAllocatedVUs, conf.MaxVUs, conf.Duration
+	case scheduler.VariableArrivalRateConfig:
+		vus, vusMax = conf.PreAllocatedVUs, conf.MaxVUs
+		var d types.Duration
+		for _, s := range conf.Stages {
+			d += s.Duration.Duration
+		}
+		duration = types.NullDurationFrom(time.Duration(d))
+	case scheduler.ExternallyControlledConfig:
+		// Without a duration, the test runs until it's stopped through the REST API.
+		vus, vusMax = null.IntFrom(conf.VUs.Int64), null.IntFrom(conf.GetMaxVUs())
+		duration = types.NullDurationFrom(time.Duration(conf.Duration.Duration))
+	}
+
+	if !opts.VUs.Valid {
+		opts.VUs = vus
+	}
+	if !opts.VUsMax.Valid {
+		opts.VUsMax = vusMax
+	}
+	if vuIterations.Valid {
+		iterations = null.IntFrom(opts.VUs.Int64 * vuIterations.Int64)
+	}
+	if !opts.Iterations.Valid {
+		opts.Iterations = iterations
+	}
+	if !opts.Duration.Valid {
+		opts.Duration = duration
+	}
+	if len(opts.Stages) == 0 {
+		opts.Stages = stages
+	}
+	if bc := conf.GetBaseConfig(); !opts.GracefulStop.Valid && bc.GracefulStop.Valid {
+		opts.GracefulStop = bc.GracefulStop
+	}
+	return opts
+}
+
+// SchedulerArrivalRate returns the arrival rate of the scheduler, nil if it doesn't have one.
+func SchedulerArrivalRate(conf scheduler.Config) *ArrivalRate {
+	switch conf := conf.(type) {
+	case scheduler.ConstantArrivalRateConfig:
+		return &ArrivalRate{Rate: conf.Rate.Int64, TimeUnit: time.Duration(conf.TimeUnit.Duration)}
+	case scheduler.VariableArrivalRateConfig:
+		return &ArrivalRate{
+			Rate:     conf.StartRate.Int64,
+			TimeUnit: time.Duration(conf.TimeUnit.Duration),
+			Stages:   conf.Stages,
+		}
+	default:
+		return nil
+	}
+}
+
+// SchedulerVUIterations returns the number of iterations per VU of the scheduler, invalid if it
+// doesn't limit them.
+func SchedulerVUIterations(conf scheduler.Config) null.Int {
+	if pvic, ok := conf.(scheduler.PerVUIteationsConfig); ok {
+		return null.IntFrom(pvic.Iterations.Int64)
+	}
+	return null.Int{}
+}
+
+// GetArrivalRate returns the arrival rate from the execution config, nil if it doesn't have one.
+func GetArrivalRate(execution scheduler.ConfigMap) *ArrivalRate {
+	conf, ok := ExecutionConfig(execution)
+	if !ok {
+		return nil
+	}
+	return SchedulerArrivalRate(conf)
+}
+
+// GetVUIterations returns the number of iterations per VU from the execution config, invalid
+// if it doesn't limit them.
+func GetVUIterations(execution scheduler.ConfigMap) null.Int {
+	conf, ok := ExecutionConfig(execution)
+	if !ok {
+		return null.Int{}
+	}
+	return SchedulerVUIterations(conf)
+}
+
+// ScenarioTags returns the run tags with the tags of the scenario, including its name as the
+// scenario tag, added to them.
+func ScenarioTags(runTags *stats.SampleTags, bc scheduler.BaseConfig) *stats.SampleTags {
+	tags := runTags.CloneTags()
+	tags["scenario"] = bc.Name
+	for k, v := range bc.Tags {
+		tags[k] = v
+	}
+	return stats.IntoSampleTags(&tags)
+}
+
+// scaleInt returns the part of the value for the partition, keeping whether it's valid.
+func scaleInt(v null.Int, p *ExecutionPartition) null.Int {
+	return null.NewInt(p.Scale(v.Int64), v.Valid)
+}
+
+func scaleStages(stages []scheduler.Stage, p *ExecutionPartition) []scheduler.Stage {
+	if stages == nil {
+		return nil
+	}
+	result := make([]scheduler.Stage, len(stages))
+	for i, s := range stages {
+		result[i] = scheduler.Stage{Duration: s.Duration, Target: scaleInt(s.Target, p)}
+	}
+	return result
+}
+
+// scaleArrivalRateVUs scales the VUs of an arrival rate scheduler. It gets at least one VU if its
+// rate isn't always 0, since a part of the iterations of the whole rate is started by it.
+func scaleArrivalRateVUs(preAllocated, max null.Int, conf scheduler.Config, p *ExecutionPartition) (null.Int, null.Int) {
+	preAllocated, max = scaleInt(preAllocated, p), scaleInt(max, p)
+	if preAllocated.Int64 == 0 && !SchedulerArrivalRate(conf).isZero() {
+		preAllocated.Int64 = 1
+		if max.Valid && max.Int64 < 1 {
+			max.Int64 = 1
+		}
+	}
+	return preAllocated, max
+}
+
+// ScaleSchedulerConfig returns the config of the scheduler for the partition of the test, with
+// its VUs and iterations scaled. Shared iterations and arrival rate schedulers that get some of
+// the iterations get at least one VU to run them. The arrival rates aren't scaled, since the
+// parts wouldn't add up to the whole rate, instead the partition's part of the iterations of
+// the whole rate is started with ArrivalRate.PartitionIterationsAt.
+func ScaleSchedulerConfig(conf scheduler.Config, p *ExecutionPartition) scheduler.Config {
+	switch conf := conf.(type) {
+	case scheduler.ConstantLoopingVUsConfig:
+		conf.VUs = scaleInt(conf.VUs, p)
+		return conf
+	case scheduler.VariableLoopingVUsConfig:
+		conf.StartVUs = scaleInt(conf.StartVUs, p)
+		conf.Stages = scaleStages(conf.Stages, p)
+		return conf
+	case scheduler.PerVUIteationsConfig:
+		conf.VUs = scaleInt(conf.VUs, p)
+		return conf
+	case scheduler.SharedIteationsConfig:
+		conf.VUs, conf.Iterations = scaleInt(conf.VUs, p), scaleInt(conf.Iterations, p)
+		if conf.VUs.Int64 == 0 && conf.Iterations.Int64 > 0 {
+			conf.VUs.Int64 = 1
+		}
+		return conf
+	case scheduler.ConstantArrivalRateConfig:
+		conf.PreAllocatedVUs, conf.MaxVUs = scaleArrivalRateVUs(conf.PreAllocatedVUs, conf.MaxVUs, conf, p)
+		return conf
+	case scheduler.VariableArrivalRateConfig:
+		conf.PreAllocatedVUs, conf.MaxVUs = scaleArrivalRateVUs(conf.PreAllocatedVUs, conf.MaxVUs, conf, p)
+		return conf
+	case scheduler.ExternallyControlledConfig:
+		conf.VUs, conf.MaxVUs = scaleInt(conf.VUs, p), scaleInt(conf.MaxVUs, p)
+		return conf
+	default:
+		return conf
+	}
+}
@@ -0,0 +1,272 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package lib
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ExecutionSegment is the part of a test that an instance of k6 runs, from one fraction of the
+// whole test to another, e.g. 1/3:2/3 for the second of three load generators. The instances
+// that run segments which cover the whole test, from 0 to 1, together run the same test as a
+// single instance would.
+type ExecutionSegment struct {
+	from, to *big.Rat
+}
+
+// parseFraction parses a fraction of the test, either a rational like 1/3, a decimal like 0.5
+// or a percentage like 50%.
+func parseFraction(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	r := new(big.Rat)
+	if strings.HasSuffix(s, "%") {
+		if _, ok := r.SetString(strings.TrimSuffix(s, "%")); !ok {
+			return nil, errors.Errorf("invalid percentage '%s'", s)
+		}
+		return r.Quo(r, big.NewRat(100, 1)), nil
+	}
+	if _, ok := r.SetString(s); !ok {
+		return nil, errors.Errorf("invalid fraction '%s'", s)
+	}
+	return r, nil
+}
+
+// NewExecutionSegmentFromString parses a segment in the from:to format, where both ends are
+// fractions like 1/3, 0.5 or 50%. A single fraction is the segment from 0 to it.
+func NewExecutionSegmentFromString(s string) (*ExecutionSegment, error) {
+	fromStr, toStr := "0", s
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		fromStr, toStr = s[:i], s[i+1:]
+	}
+	from, err := parseFraction(fromStr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "execution segment '%s'", s)
+	}
+	to, err := parseFraction(toStr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "execution segment '%s'", s)
+	}
+	if from.Sign() < 0 || to.Cmp(big.NewRat(1, 1)) > 0 || from.Cmp(to) >= 0 {
+		return nil, errors.Errorf("invalid execution segment '%s', it should be from:to with 0 <= from < to <= 1", s)
+	}
+	return &ExecutionSegment{from: from, to: to}, nil
+}
+
+// isEmpty returns whether the segment isn't set, e.g. when envconfig allocated an empty one for
+// an environment variable that isn't set.
+func (es *ExecutionSegment) isEmpty() bool {
+	return es == nil || es.from == nil
+}
+
+// isEqualSplit returns whether the segment is one of the equal parts of the test, e.g. 1/3:2/3
+// for the second of three, and one of its ends shows into how many parts, unlike 1/2:2/3. The
+// instances of the other parts split the test in the same way without a sequence, since all of
+// them have the same period.
+func (es *ExecutionSegment) isEqualSplit() bool {
+	length := new(big.Rat).Sub(es.to, es.from)
+	parts := length.Denom()
+	if length.Num().Cmp(big.NewInt(1)) != 0 || !new(big.Rat).Mul(es.from, new(big.Rat).SetInt(parts)).IsInt() {
+		return false
+	}
+	return es.from.Denom().Cmp(parts) == 0 || es.to.Denom().Cmp(parts) == 0
+}
+
+// String returns the segment in the from:to format, with rationals.
+func (es *ExecutionSegment) String() string {
+	if es.isEmpty() {
+		return "0:1"
+	}
+	return es.from.RatString() + ":" + es.to.RatString()
+}
+
+// MarshalText returns the segment in the from:to format.
+func (es *ExecutionSegment) MarshalText() ([]byte, error) {
+	return []byte(es.String()), nil
+}
+
+// UnmarshalText parses a segment in the from:to format.
+func (es *ExecutionSegment) UnmarshalText(data []byte) error {
+	segment, err := NewExecutionSegmentFromString(string(data))
+	if err != nil {
+		return err
+	}
+	*es = *segment
+	return nil
+}
+
+// ExecutionSegmentSequence is the list of the points that a test is split at, including 0 and
+// 1, e.g. 0,1/2,2/3,1 for three segments. The segments of all the instances should be between
+// consecutive points, so that the VUs and iterations are split between them in the same way.
+type ExecutionSegmentSequence []*big.Rat
+
+// NewExecutionSegmentSequenceFromString parses a sequence of comma-separated fractions, from 0
+// to 1 in increasing order.
+func NewExecutionSegmentSequenceFromString(s string) (ExecutionSegmentSequence, error) {
+	parts := strings.Split(s, ",")
+	seq := make(ExecutionSegmentSequence, len(parts))
+	for i, part := range parts {
+		point, err := parseFraction(part)
+		if err != nil {
+			return nil, errors.Wrapf(err, "execution segment sequence '%s'", s)
+		}
+		if i > 0 && point.Cmp(seq[i-1]) <= 0 {
+			return nil, errors.Errorf("the points of the execution segment sequence '%s' should be increasing", s)
+		}
+		seq[i] = point
+	}
+	if len(seq) < 2 || seq[0].Sign() != 0 || seq[len(seq)-1].Cmp(big.NewRat(1, 1)) != 0 {
+		return nil, errors.Errorf("the execution segment sequence '%s' should go from 0 to 1", s)
+	}
+	return seq, nil
+}
+
+// NewEqualExecutionSegmentSequence returns the sequence that splits a test into n segments of
+// the same size. n should be positive.
+func NewEqualExecutionSegmentSequence(n int) ExecutionSegmentSequence {
+	seq := make(ExecutionSegmentSequence, n+1)
+	for i := range seq {
+		seq[i] = big.NewRat(int64(i), int64(n))
+	}
+	return seq
+}
+
+// Segments returns the segments between the consecutive points of the sequence.
+func (seq ExecutionSegmentSequence) Segments() []*ExecutionSegment {
+	var segments []*ExecutionSegment
+	for i := 1; i < len(seq); i++ {
+		segments = append(segments, &ExecutionSegment{from: seq[i-1], to: seq[i]})
+	}
+	return segments
+}
+
+// String returns the comma-separated points of the sequence.
+func (seq ExecutionSegmentSequence) String() string {
+	points := make([]string, len(seq))
+	for i, point := range seq {
+		points[i] = point.RatString()
+	}
+	return strings.Join(points, ",")
+}
+
+// MarshalText returns the comma-separated points of the sequence.
+func (seq ExecutionSegmentSequence) MarshalText() ([]byte, error) {
+	return []byte(seq.String()), nil
+}
+
+// UnmarshalText parses a sequence of comma-separated fractions.
+func (seq *ExecutionSegmentSequence) UnmarshalText(data []byte) error {
+	result, err := NewExecutionSegmentSequenceFromString(string(data))
+	if err != nil {
+		return err
+	}
+	*seq = result
+	return nil
+}
+
+// contains returns whether both ends of the segment are points of the sequence.
+func (seq ExecutionSegmentSequence) contains(es *ExecutionSegment) bool {
+	var from, to bool
+	for _, point := range seq {
+		from = from || point.Cmp(es.from) == 0
+		to = to || point.Cmp(es.to) == 0
+	}
+	return from && to
+}
+
+// An ExecutionPartition splits the VUs, iterations and data indices of a test between the
+// segments of a sequence. The indices are dealt out in periods of the least common multiple
+// of the denominators of the sequence, e.g. 6 for 0,1/2,2/3,1, where the segment from 1/2 to
+// 2/3 gets the fourth index of every period. Since the indices of every segment are always
+// the same ones, both for a limited number of them like the VUs and for an endless stream of
+// iterations, the instances of all segments together have every index exactly once.
+type ExecutionPartition struct {
+	period int64 // The number of indices after which the pattern repeats
+	offset int64 // The first index of the segment in every period
+	count  int64 // The number of indices of the segment in every period
+}
+
+// NewExecutionPartition returns the partition of the segment, which should be in the sequence.
+// Without a sequence, it's the segment's ends with 0 and 1, which is only allowed for the equal
+// parts of a test, since the partitions of uneven segments would overlap with the ones of the
+// other instances. A nil partition is returned for a nil segment, which runs the whole test.
+func NewExecutionPartition(es *ExecutionSegment, seq ExecutionSegmentSequence) (*ExecutionPartition, error) {
+	if es.isEmpty() {
+		return nil, nil
+	}
+	if seq == nil {
+		if !es.isEqualSplit() {
+			return nil, errors.Errorf(
+				"the execution segment %s isn't an equal part of the test, so the execution segment "+
+					"sequence with the segments of all instances should be specified", es,
+			)
+		}
+		seq = ExecutionSegmentSequence{new(big.Rat), es.from, es.to, big.NewRat(1, 1)}
+	} else if !seq.contains(es) {
+		return nil, errors.Errorf("the execution segment %s isn't in the sequence %s", es, seq)
+	}
+
+	period := big.NewInt(1)
+	for _, point := range seq {
+		denom := point.Denom()
+		gcd := new(big.Int).GCD(nil, nil, period, denom)
+		period.Mul(period, new(big.Int).Quo(denom, gcd))
+	}
+	if !period.IsInt64() {
+		return nil, errors.Errorf("the execution segment sequence %s is too fine-grained", seq)
+	}
+	at := func(r *big.Rat) int64 {
+		// The period is a multiple of the denominator, so this is an integer.
+		v := new(big.Rat).Mul(r, new(big.Rat).SetInt(period))
+		return v.Num().Int64()
+	}
+	return &ExecutionPartition{
+		period: period.Int64(),
+		offset: at(es.from),
+		count:  at(es.to) - at(es.from),
+	}, nil
+}
+
+// Scale returns how many of the first n indices belong to the segment, e.g. how many of the
+// test's VUs its instance runs. A nil partition returns n.
+func (p *ExecutionPartition) Scale(n int64) int64 {
+	if p == nil {
+		return n
+	}
+	rest := n%p.period - p.offset
+	if rest < 0 {
+		rest = 0
+	} else if rest > p.count {
+		rest = p.count
+	}
+	return n/p.period*p.count + rest
+}
+
+// Index returns the index in the whole test of the i-th index of the segment, both starting
+// from 0, e.g. the number of an iteration among the ones of all instances. A nil partition
+// returns i.
+func (p *ExecutionPartition) Index(i int64) int64 {
+	if p == nil {
+		return i
+	}
+	return i/p.count*p.period + p.offset + i%p.count
+}
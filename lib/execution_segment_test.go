@@ -0,0 +1,163 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package lib
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExecutionSegmentFromString(t *testing.T) {
+	testdata := map[string]string{
+		"0:1/3":     "0:1/3",
+		"1/3:2/3":   "1/3:2/3",
+		"0.5:1":     "1/2:1",
+		"25%:50%":   "1/4:1/2",
+		"1/4":       "0:1/4",
+		" 0 : 1 ":   "0:1",
+		"2/6:0.5":   "1/3:1/2",
+		"66.6%:1/1": "333/500:1",
+	}
+	for s, expected := range testdata {
+		es, err := NewExecutionSegmentFromString(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, es.String())
+	}
+
+	for _, s := range []string{"", "a:1", "0:b", "1/2:1/3", "1/2:1/2", "-1/2:1", "0:3/2", "0:1:2"} {
+		_, err := NewExecutionSegmentFromString(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestExecutionSegmentJSON(t *testing.T) {
+	var opts Options
+	require.NoError(t, json.Unmarshal(
+		[]byte(`{"executionSegment":"1/3:2/3","executionSegmentSequence":"0,1/3,2/3,1"}`), &opts,
+	))
+	assert.Equal(t, "1/3:2/3", opts.ExecutionSegment.String())
+	assert.Equal(t, "0,1/3,2/3,1", opts.ExecutionSegmentSequence.String())
+
+	data, err := json.Marshal(opts)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"executionSegment":"1/3:2/3","executionSegmentSequence":"0,1/3,2/3,1"`)
+
+	data, err = json.Marshal(Options{})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"executionSegment":null,`)
+	assert.NotContains(t, string(data), `executionSegmentSequence`)
+}
+
+func TestNewExecutionSegmentSequenceFromString(t *testing.T) {
+	seq, err := NewExecutionSegmentSequenceFromString("0, 0.5, 2/3, 100%")
+	require.NoError(t, err)
+	assert.Equal(t, "0,1/2,2/3,1", seq.String())
+
+	for _, s := range []string{"", "0", "0,1/2", "1/2,1", "0,2/3,1/2,1", "0,1/2,1/2,1", "0,x,1"} {
+		_, err := NewExecutionSegmentSequenceFromString(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestNewEqualExecutionSegmentSequence(t *testing.T) {
+	seq := NewEqualExecutionSegmentSequence(3)
+	assert.Equal(t, "0,1/3,2/3,1", seq.String())
+
+	var segments []string
+	for _, es := range seq.Segments() {
+		segments = append(segments, es.String())
+		_, err := NewExecutionPartition(es, seq)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"0:1/3", "1/3:2/3", "2/3:1"}, segments)
+}
+
+func TestExecutionPartition(t *testing.T) {
+	p, err := NewExecutionPartition(nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, p)
+	assert.Equal(t, int64(7), p.Scale(7))
+	assert.Equal(t, int64(7), p.Index(7))
+
+	seq, err := NewExecutionSegmentSequenceFromString("0,1/3,1")
+	require.NoError(t, err)
+	es, err := NewExecutionSegmentFromString("1/3:2/3")
+	require.NoError(t, err)
+	_, err = NewExecutionPartition(es, seq)
+	assert.EqualError(t, err, "the execution segment 1/3:2/3 isn't in the sequence 0,1/3,1")
+
+	// Without a sequence, uneven segments would overlap with the ones of the other instances.
+	for _, segment := range []string{"0:1/2", "1/3:2/3", "2/3:1", "1/4:1/2"} {
+		es, err := NewExecutionSegmentFromString(segment)
+		require.NoError(t, err)
+		_, err = NewExecutionPartition(es, nil)
+		assert.NoError(t, err, segment)
+	}
+	for _, segment := range []string{"1/2:2/3", "1/3:1", "1/10:3/10"} {
+		es, err := NewExecutionSegmentFromString(segment)
+		require.NoError(t, err)
+		_, err = NewExecutionPartition(es, nil)
+		assert.EqualError(t, err, "the execution segment "+segment+" isn't an equal part of the test, "+
+			"so the execution segment sequence with the segments of all instances should be specified")
+	}
+
+	// Without a sequence, an equal part is split from the rest of the test.
+	es, err = NewExecutionSegmentFromString("1/2:3/4")
+	require.NoError(t, err)
+	p, err = NewExecutionPartition(es, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 0, 0, 1, 1, 1, 1, 2}, []int64{
+		p.Scale(0), p.Scale(1), p.Scale(2), p.Scale(3), p.Scale(4), p.Scale(5), p.Scale(6), p.Scale(7),
+	})
+	assert.Equal(t, []int64{2, 6, 10}, []int64{p.Index(0), p.Index(1), p.Index(2)})
+
+	for _, sequence := range []string{"0,1/3,2/3,1", "0,1/2,2/3,1", "0,0.1,0.25,1", "0,1"} {
+		seq, err := NewExecutionSegmentSequenceFromString(sequence)
+		require.NoError(t, err)
+		var partitions []*ExecutionPartition
+		for i := 1; i < len(seq); i++ {
+			es, err := NewExecutionSegmentFromString(seq[i-1].RatString() + ":" + seq[i].RatString())
+			require.NoError(t, err)
+			p, err := NewExecutionPartition(es, seq)
+			require.NoError(t, err)
+			partitions = append(partitions, p)
+		}
+
+		// All segments together have every index once, for any number of them.
+		for n := int64(0); n < 100; n++ {
+			seen := map[int64]bool{}
+			var sum int64
+			for _, p := range partitions {
+				scaled := p.Scale(n)
+				sum += scaled
+				for i := int64(0); i < scaled; i++ {
+					index := p.Index(i)
+					assert.True(t, index < n, "index %d of %s isn't less than %d", index, sequence, n)
+					assert.False(t, seen[index], "index %d of %s is in several segments", index, sequence)
+					seen[index] = true
+				}
+			}
+			assert.Equal(t, n, sum, sequence)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/scheduler"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestGetArrivalRate(t *testing.T) {
+	assert.Nil(t, GetArrivalRate(nil))
+	assert.Nil(t, GetArrivalRate(scheduler.ConfigMap{
+		DefaultSchedulerName: scheduler.NewPerVUIterationsConfig(DefaultSchedulerName),
+	}))
+
+	carc := scheduler.NewConstantArrivalRateConfig(DefaultSchedulerName)
+	carc.Rate = null.IntFrom(20)
+	assert.Equal(t, &ArrivalRate{Rate: 20, TimeUnit: time.Second},
+		GetArrivalRate(scheduler.ConfigMap{DefaultSchedulerName: carc}))
+	assert.Nil(t, GetArrivalRate(scheduler.ConfigMap{DefaultSchedulerName: carc, "other": carc}))
+
+	varc := scheduler.NewVariableArrivalRateConfig(DefaultSchedulerName)
+	varc.StartRate = null.IntFrom(10)
+	varc.TimeUnit = types.NullDurationFrom(time.Minute)
+	varc.Stages = []scheduler.Stage{{Duration: types.NullDurationFrom(10 * time.Minute), Target: null.IntFrom(500)}}
+	assert.Equal(t, &ArrivalRate{Rate: 10, TimeUnit: time.Minute, Stages: varc.Stages},
+		GetArrivalRate(scheduler.ConfigMap{DefaultSchedulerName: varc}))
+}
+
+func TestGetVUIterations(t *testing.T) {
+	pvic := scheduler.NewPerVUIterationsConfig(DefaultSchedulerName)
+	assert.Equal(t, null.Int{}, GetVUIterations(scheduler.ConfigMap{DefaultSchedulerName: pvic}))
+
+	pvic.Iterations = null.IntFrom(100)
+	assert.Equal(t, null.IntFrom(100), GetVUIterations(scheduler.ConfigMap{DefaultSchedulerName: pvic}))
+	assert.Nil(t, GetArrivalRate(scheduler.ConfigMap{DefaultSchedulerName: pvic}))
+
+	carc := scheduler.NewConstantArrivalRateConfig(DefaultSchedulerName)
+	assert.Equal(t, null.Int{}, GetVUIterations(scheduler.ConfigMap{DefaultSchedulerName: carc}))
+}
+
+func TestScenarios(t *testing.T) {
+	carc := scheduler.NewConstantArrivalRateConfig("b")
+	pvic := scheduler.NewPerVUIterationsConfig("a")
+	assert.Nil(t, Scenarios(nil))
+	assert.Nil(t, Scenarios(scheduler.ConfigMap{"b": carc}))
+	assert.Equal(t, []scheduler.Config{pvic, carc}, Scenarios(scheduler.ConfigMap{"b": carc, "a": pvic}))
+
+	carc.Exec = null.StringFrom("checkout")
+	assert.Equal(t, []scheduler.Config{carc}, Scenarios(scheduler.ConfigMap{"b": carc}))
+	_, ok := ExecutionConfig(scheduler.ConfigMap{"b": carc})
+	assert.False(t, ok)
+
+	ecc := scheduler.NewExternallyControlledConfig("c")
+	assert.Nil(t, Scenarios(scheduler.ConfigMap{"a": pvic, "c": ecc}))
+	conf, ok := ExecutionConfig(scheduler.ConfigMap{"c": ecc})
+	assert.True(t, ok)
+	assert.Equal(t, ecc, conf)
+}
+
+func TestApplySchedulerOptions(t *testing.T) {
+	clvc := scheduler.NewConstantLoopingVUsConfig("a")
+	clvc.VUs = null.IntFrom(10)
+	clvc.Duration = types.NullDurationFrom(time.Minute)
+	clvc.GracefulStop = types.NullDurationFrom(5 * time.Second)
+	opts := ApplySchedulerOptions(Options{}, clvc)
+	assert.Equal(t, null.IntFrom(10), opts.VUs)
+	assert.Equal(t, types.NullDurationFrom(time.Minute), opts.Duration)
+	assert.False(t, opts.Iterations.Valid)
+	assert.Equal(t, types.NullDurationFrom(5*time.Second), opts.GracefulStop)
+	opts = ApplySchedulerOptions(Options{GracefulStop: types.NullDurationFrom(time.Second)}, clvc)
+	assert.Equal(t, types.NullDurationFrom(time.Second), opts.GracefulStop)
+
+	vlvc := scheduler.NewVariableLoopingVUsConfig("a")
+	vlvc.StartVUs = null.IntFrom(5)
+	vlvc.Stages = []scheduler.Stage{{Duration: types.NullDurationFrom(time.Minute), Target: null.IntFrom(20)}}
+	opts = ApplySchedulerOptions(Options{}, vlvc)
+	assert.Equal(t, null.IntFrom(5), opts.VUs)
+	assert.Equal(t, []Stage{{Duration: types.NullDurationFrom(time.Minute), Target: null.IntFrom(20)}}, opts.Stages)
+
+	pvic := scheduler.NewPerVUIterationsConfig("a")
+	pvic.VUs = null.IntFrom(10)
+	pvic.Iterations = null.IntFrom(5)
+	opts = ApplySchedulerOptions(Options{VUs: null.IntFrom(2)}, pvic)
+	assert.Equal(t, null.IntFrom(2), opts.VUs)
+	assert.Equal(t, null.IntFrom(10), opts.Iterations)
+	assert.False(t, opts.Duration.Valid)
+
+	ecc := scheduler.NewExternallyControlledConfig("a")
+	ecc.VUs = null.IntFrom(5)
+	ecc.MaxVUs = null.IntFrom(50)
+	opts = ApplySchedulerOptions(Options{}, ecc)
+	assert.Equal(t, null.IntFrom(5), opts.VUs)
+	assert.Equal(t, null.IntFrom(50), opts.VUsMax)
+	assert.Equal(t, types.NullDurationFrom(0), opts.Duration)
+	assert.False(t, opts.Iterations.Valid)
+}
+
+func TestScenarioTags(t *testing.T) {
+	bc := scheduler.NewBaseConfig("checkout", "shared-iterations", false)
+	bc.Tags = map[string]string{"flow": "buy", "env": "test"}
+	tags := ScenarioTags(stats.IntoSampleTags(&map[string]string{"env": "prod", "run": "1"}), bc)
+	assert.Equal(t, map[string]string{"scenario": "checkout", "flow": "buy", "env": "test", "run": "1"},
+		tags.CloneTags())
+}
+
+func TestScaleSchedulerConfigArrivalRate(t *testing.T) {
+	carc := scheduler.NewConstantArrivalRateConfig("a")
+	carc.TimeUnit = types.NullDurationFrom(time.Second)
+	carc.PreAllocatedVUs = null.IntFrom(2)
+	varc := scheduler.NewVariableArrivalRateConfig("b")
+	varc.TimeUnit = types.NullDurationFrom(time.Second)
+	varc.PreAllocatedVUs = null.IntFrom(2)
+	varc.Stages = []scheduler.Stage{
+		{Duration: types.NullDurationFrom(5 * time.Second), Target: null.IntFrom(2)},
+		{Duration: types.NullDurationFrom(5 * time.Second), Target: null.IntFrom(0)},
+	}
+
+	seq, err := NewExecutionSegmentSequenceFromString("0,1/3,2/3,1")
+	require.NoError(t, err)
+	var partitions []*ExecutionPartition
+	for _, segment := range []string{"0:1/3", "1/3:2/3", "2/3:1"} {
+		es, err := NewExecutionSegmentFromString(segment)
+		require.NoError(t, err)
+		p, err := NewExecutionPartition(es, seq)
+		require.NoError(t, err)
+		partitions = append(partitions, p)
+	}
+
+	// The rates are too low to be split between three parts, but together the parts still start
+	// as many iterations as the whole test, and none are started without any rate.
+	for _, rate := range []int64{0, 1, 2} {
+		carc.Rate = null.IntFrom(rate)
+		for _, conf := range []scheduler.Config{carc, varc} {
+			whole := SchedulerArrivalRate(conf)
+			for _, at := range []time.Duration{0, time.Second, 2500 * time.Millisecond, 10 * time.Second} {
+				var total int64
+				for _, p := range partitions {
+					total += SchedulerArrivalRate(ScaleSchedulerConfig(conf, p)).PartitionIterationsAt(at, p)
+				}
+				assert.Equal(t, whole.IterationsAt(at), total, "%s at %s", conf.GetBaseConfig().Name, at)
+			}
+		}
+	}
+
+	// Every part gets a VU to start its iterations with, unless there aren't any.
+	carc.MaxVUs = null.IntFrom(2)
+	scaled := ScaleSchedulerConfig(carc, partitions[2]).(scheduler.ConstantArrivalRateConfig)
+	assert.Equal(t, null.IntFrom(1), scaled.PreAllocatedVUs)
+	assert.Equal(t, null.IntFrom(1), scaled.MaxVUs)
+	carc.Rate = null.IntFrom(0)
+	scaled = ScaleSchedulerConfig(carc, partitions[2]).(scheduler.ConstantArrivalRateConfig)
+	assert.Equal(t, null.IntFrom(0), scaled.PreAllocatedVUs)
+}
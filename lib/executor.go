@@ -24,6 +24,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/loadimpact/k6/lib/scheduler"
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 	log "github.com/sirupsen/logrus"
@@ -84,4 +85,80 @@ type Executor interface {
 	// Set whether or not to run setup/teardown phases. Default is to run all of them.
 	SetRunSetup(r bool)
 	SetRunTeardown(r bool)
+
+	// Get and set the rate at which iterations are started, nil if every VU should start a new
+	// iteration as soon as the previous one is done. With an arrival rate, more of the allocated
+	// VUs are activated if all active ones are busy, and iterations that can't be started
+	// because all VUs are busy are dropped.
+	GetArrivalRate() *ArrivalRate
+	SetArrivalRate(r *ArrivalRate)
+
+	// Get and set how many iterations each VU runs before it stops, invalid for no limit.
+	GetVUIterations() null.Int
+	SetVUIterations(i null.Int)
+
+	// Get and set how long iterations that are still running when the test ends get to finish
+	// before the VUs are interrupted, invalid or zero to interrupt them right away.
+	GetGracefulStop() types.NullDuration
+	SetGracefulStop(d types.NullDuration)
+}
+
+// ArrivalRate is the rate at which an Executor starts iterations, regardless of how long they take.
+type ArrivalRate struct {
+	Rate     int64         // Iterations per TimeUnit, the starting rate if there are stages.
+	TimeUnit time.Duration // How often Rate iterations are started.
+
+	// If there are stages, the rate is linearly ramped to the target of each stage over its
+	// duration, and stays at the target of the last one after they are done.
+	Stages []scheduler.Stage
+}
+
+// IterationsAt returns how many iterations should have been started at the given time,
+// counting the one at the very start. None are started if the rate is always 0, e.g. for a
+// part of a test with a rate that's too low to be split between all parts.
+func (r ArrivalRate) IterationsAt(t time.Duration) int64 {
+	if r.isZero() {
+		return 0
+	}
+
+	// The area under the rate graph, in iterations per TimeUnit multiplied by time.
+	var area float64
+	rate := float64(r.Rate)
+	for _, stage := range r.Stages {
+		if t <= 0 {
+			break
+		}
+		d := time.Duration(stage.Duration.Duration)
+		target := float64(stage.Target.Int64)
+		if t < d {
+			current := rate + (target-rate)*float64(t)/float64(d)
+			area += (rate + current) / 2 * float64(t)
+			t = 0
+			break
+		}
+		area += (rate + target) / 2 * float64(d)
+		rate = target
+		t -= d
+	}
+	area += rate * float64(t)
+	return int64(area/float64(r.TimeUnit)) + 1
+}
+
+// PartitionIterationsAt returns how many of the iterations that should have been started at the
+// given time belong to the partition, so that the parts of the test together start them all.
+func (r ArrivalRate) PartitionIterationsAt(t time.Duration, p *ExecutionPartition) int64 {
+	return p.Scale(r.IterationsAt(t))
+}
+
+// isZero returns whether the rate and the targets of all stages are 0.
+func (r ArrivalRate) isZero() bool {
+	if r.Rate != 0 {
+		return false
+	}
+	for _, stage := range r.Stages {
+		if stage.Target.Int64 != 0 {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,58 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/scheduler"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestArrivalRate(t *testing.T) {
+	r := ArrivalRate{Rate: 3, TimeUnit: time.Second}
+	assert.Equal(t, int64(1), r.IterationsAt(0))
+	assert.Equal(t, int64(1), r.IterationsAt(300*time.Millisecond))
+	assert.Equal(t, int64(2), r.IterationsAt(400*time.Millisecond))
+	assert.Equal(t, int64(31), r.IterationsAt(10*time.Second))
+
+	// 0 -> 10/s over 2s, 10/s for 2s, 10 -> 0/s over 1s
+	r = ArrivalRate{Rate: 0, TimeUnit: time.Second, Stages: []scheduler.Stage{
+		{Duration: types.NullDurationFrom(2 * time.Second), Target: null.IntFrom(10)},
+		{Duration: types.NullDurationFrom(2 * time.Second), Target: null.IntFrom(10)},
+		{Duration: types.NullDurationFrom(1 * time.Second), Target: null.IntFrom(0)},
+	}}
+	assert.Equal(t, int64(1), r.IterationsAt(0))
+	assert.Equal(t, int64(3), r.IterationsAt(1*time.Second))
+	assert.Equal(t, int64(11), r.IterationsAt(2*time.Second))
+	assert.Equal(t, int64(31), r.IterationsAt(4*time.Second))
+	assert.Equal(t, int64(36), r.IterationsAt(5*time.Second))
+	assert.Equal(t, int64(36), r.IterationsAt(10*time.Second))
+
+	r = ArrivalRate{Rate: 0, TimeUnit: time.Second, Stages: []scheduler.Stage{
+		{Duration: types.NullDurationFrom(2 * time.Second), Target: null.IntFrom(0)},
+	}}
+	assert.Equal(t, int64(0), r.IterationsAt(0))
+	assert.Equal(t, int64(0), r.IterationsAt(10*time.Second))
+}
@@ -31,7 +31,9 @@ var (
 	VUs               = stats.New("vus", stats.Gauge)
 	VUsMax            = stats.New("vus_max", stats.Gauge)
 	Iterations        = stats.New("iterations", stats.Counter)
+	DroppedIterations = stats.New("dropped_iterations", stats.Counter)
 	IterationDuration = stats.New("iteration_duration", stats.Trend, stats.Time)
+	IterationFailed   = stats.New("iteration_failed", stats.Rate)
 	Errors            = stats.New("errors", stats.Counter)
 
 	// Runner-emitted.
@@ -47,6 +49,7 @@ var (
 	HTTPReqSending        = stats.New("http_req_sending", stats.Trend, stats.Time)
 	HTTPReqWaiting        = stats.New("http_req_waiting", stats.Trend, stats.Time)
 	HTTPReqReceiving      = stats.New("http_req_receiving", stats.Trend, stats.Time)
+	HTTPReqFailed         = stats.New("http_req_failed", stats.Rate)
 
 	// Websocket-related
 	WSSessions         = stats.New("ws_sessions", stats.Counter)
@@ -56,6 +59,19 @@ var (
 	WSSessionDuration  = stats.New("ws_session_duration", stats.Trend, stats.Time)
 	WSConnecting       = stats.New("ws_connecting", stats.Trend, stats.Time)
 
+	// gRPC-related
+	GRPCReqDuration = stats.New("grpc_req_duration", stats.Trend, stats.Time)
+
+	// Server-Sent Events-related
+	SSESessions         = stats.New("sse_sessions", stats.Counter)
+	SSEEventsReceived   = stats.New("sse_events_received", stats.Counter)
+	SSETimeToFirstEvent = stats.New("sse_time_to_first_event", stats.Trend, stats.Time)
+	SSESessionDuration  = stats.New("sse_session_duration", stats.Trend, stats.Time)
+
+	// TCP-related
+	TCPConnecting = stats.New("tcp_connecting", stats.Trend, stats.Time)
+	TCPRoundtrip  = stats.New("tcp_roundtrip", stats.Trend, stats.Time)
+
 	// Network-related; used for future protocols as well.
 	DataSent     = stats.New("data_sent", stats.Counter, stats.Data)
 	DataReceived = stats.New("data_received", stats.Counter, stats.Data)
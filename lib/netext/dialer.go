@@ -28,10 +28,9 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
 	"github.com/loadimpact/k6/stats"
-
-	"github.com/viki-org/dnscache"
 )
 
 // Dialer wraps net.Dialer and provides k6 specific functionality -
@@ -39,9 +38,11 @@ import (
 type Dialer struct {
 	net.Dialer
 
-	Resolver  *dnscache.Resolver
+	Resolver  *Resolver
 	Blacklist []*net.IPNet
+	Blocked   []string
 	Hosts     map[string]net.IP
+	Faults    *lib.FaultInjection
 
 	BytesRead    int64
 	BytesWritten int64
@@ -51,7 +52,7 @@ type Dialer struct {
 func NewDialer(dialer net.Dialer) *Dialer {
 	return &Dialer{
 		Dialer:   dialer,
-		Resolver: dnscache.New(0),
+		Resolver: NewResolver(nil),
 	}
 }
 
@@ -65,16 +66,61 @@ func (b BlackListedIPError) Error() string {
 	return fmt.Sprintf("IP (%s) is in a blacklisted range (%s)", b.ip, b.net)
 }
 
+// BlockedHostnameError is returned when a hostname matches one of the blocked patterns
+type BlockedHostnameError struct {
+	hostname string
+	pattern  string
+}
+
+func (b BlockedHostnameError) Error() string {
+	return fmt.Sprintf("hostname (%s) matches a blocked pattern (%s)", b.hostname, b.pattern)
+}
+
+// ValidateHostnamePattern checks a pattern of the blockHostnames option, which is either a
+// hostname or a wildcard like `*.example.com`, matching all of the subdomains.
+func ValidateHostnamePattern(pattern string) error {
+	name := strings.TrimPrefix(pattern, "*.")
+	if name == "" || strings.ContainsAny(name, "*:/ ") {
+		return fmt.Errorf("invalid hostname pattern '%s', it should be a hostname or a wildcard like '*.example.com'", pattern)
+	}
+	return nil
+}
+
+// matchHostnamePattern checks if the hostname matches a valid pattern, ignoring the case.
+func matchHostnamePattern(hostname, pattern string) bool {
+	hostname, pattern = strings.ToLower(strings.TrimSuffix(hostname, ".")), strings.ToLower(pattern)
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(hostname, pattern[1:])
+	}
+	return hostname == pattern
+}
+
+// CheckBlockedHostname returns a BlockedHostnameError if the hostname matches one of the
+// patterns. The dialer only sees the address of the proxy when one is used, so the hostnames
+// of the requested URLs have to be checked as well.
+func CheckBlockedHostname(hostname string, patterns []string) error {
+	for _, pattern := range patterns {
+		if matchHostnamePattern(hostname, pattern) {
+			return BlockedHostnameError{hostname: hostname, pattern: pattern}
+		}
+	}
+	return nil
+}
+
 // DialContext wraps the net.Dialer.DialContext and handles the k6 specifics
 func (d *Dialer) DialContext(ctx context.Context, proto, addr string) (net.Conn, error) {
 	delimiter := strings.LastIndex(addr, ":")
 	host := addr[:delimiter]
 
+	if err := CheckBlockedHostname(host, d.Blocked); err != nil {
+		return nil, err
+	}
+
 	// lookup for domain defined in Hosts option before trying to resolve DNS.
 	ip, ok := d.Hosts[host]
 	if !ok {
 		var err error
-		ip, err = d.Resolver.FetchOne(host)
+		ip, err = d.Resolver.LookupIP(ctx, host)
 		if err != nil {
 			return nil, err
 		}
@@ -85,6 +131,19 @@ func (d *Dialer) DialContext(ctx context.Context, proto, addr string) (net.Conn,
 			return nil, BlackListedIPError{ip: ip, net: net}
 		}
 	}
+
+	faulty := d.Faults != nil && affectedByFaults(d.Faults)
+	if faulty {
+		if droppedByFaults(d.Faults) {
+			return nil, DroppedConnectionError(addr)
+		}
+		select {
+		case <-time.After(time.Duration(d.Faults.Latency.Duration)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
 	ipStr := ip.String()
 	if strings.ContainsRune(ipStr, ':') {
 		ipStr = "[" + ipStr + "]"
@@ -93,6 +152,13 @@ func (d *Dialer) DialContext(ctx context.Context, proto, addr string) (net.Conn,
 	if err != nil {
 		return nil, err
 	}
+	if faulty {
+		conn = &faultyConn{
+			Conn:      conn,
+			latency:   time.Duration(d.Faults.Latency.Duration),
+			bandwidth: d.Faults.Bandwidth.Int64,
+		}
+	}
 	conn = &Conn{conn, &d.BytesRead, &d.BytesWritten}
 	return conn, err
 }
@@ -0,0 +1,82 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateHostnamePattern(t *testing.T) {
+	for _, pattern := range []string{"example.com", "*.example.com", "localhost", "*.com"} {
+		assert.NoError(t, ValidateHostnamePattern(pattern), pattern)
+	}
+	for _, pattern := range []string{"", "*.", "*", "*example.com", "a.*.example.com", "example.com:80", "http://example.com"} {
+		assert.EqualError(t, ValidateHostnamePattern(pattern),
+			"invalid hostname pattern '"+pattern+"', it should be a hostname or a wildcard like '*.example.com'")
+	}
+}
+
+func TestMatchHostnamePattern(t *testing.T) {
+	testdata := []struct {
+		hostname, pattern string
+		match             bool
+	}{
+		{"example.com", "example.com", true},
+		{"Example.COM.", "example.com", true},
+		{"www.example.com", "example.com", false},
+		{"www.example.com", "*.example.com", true},
+		{"a.b.example.com", "*.EXAMPLE.com", true},
+		{"example.com", "*.example.com", false},
+		{"badexample.com", "*.example.com", false},
+	}
+	for _, data := range testdata {
+		assert.Equal(t, data.match, matchHostnamePattern(data.hostname, data.pattern), "%s %s", data.hostname, data.pattern)
+	}
+}
+
+func TestDialerBlockedHostnames(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	dialer := NewDialer(net.Dialer{})
+	dialer.Hosts = map[string]net.IP{
+		"allowed.example.com": net.ParseIP("127.0.0.1"),
+		"tracker.example.com": net.ParseIP("127.0.0.1"),
+	}
+	dialer.Blocked = []string{"tracker.example.com"}
+
+	conn, err := dialer.DialContext(context.Background(), "tcp", "allowed.example.com:"+port)
+	require.NoError(t, err)
+	_ = conn.Close()
+
+	_, err = dialer.DialContext(context.Background(), "tcp", "tracker.example.com:"+port)
+	require.Error(t, err)
+	assert.IsType(t, BlockedHostnameError{}, err)
+	assert.EqualError(t, err, "hostname (tracker.example.com) matches a blocked pattern (tracker.example.com)")
+}
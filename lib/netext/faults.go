@@ -0,0 +1,81 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"math/rand"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+)
+
+// DroppedConnectionError is returned when a connection is dropped by the fault injection.
+type DroppedConnectionError string
+
+func (e DroppedConnectionError) Error() string {
+	return "connection to " + string(e) + " was dropped by the fault injection"
+}
+
+// affectedByFaults randomly decides if a new connection should be affected by the faults.
+func affectedByFaults(f *lib.FaultInjection) bool {
+	return !f.Rate.Valid || rand.Float64() < f.Rate.Float64
+}
+
+// droppedByFaults randomly decides if an affected connection should be dropped.
+func droppedByFaults(f *lib.FaultInjection) bool {
+	return f.DropRate.Valid && rand.Float64() < f.DropRate.Float64
+}
+
+// faultyConn wraps an affected net.Conn and slows it down.
+type faultyConn struct {
+	net.Conn
+
+	latency   time.Duration
+	bandwidth int64
+	wrote     int32 // set after a write, so the response to it is delayed
+}
+
+func (c *faultyConn) throttle(n int) {
+	if c.bandwidth > 0 && n > 0 {
+		time.Sleep(time.Duration(int64(n) * int64(time.Second) / c.bandwidth))
+	}
+}
+
+func (c *faultyConn) Read(b []byte) (int, error) {
+	if c.bandwidth > 0 && int64(len(b)) > c.bandwidth {
+		b = b[:c.bandwidth] // so no more than a second of data is read at once
+	}
+	n, err := c.Conn.Read(b)
+	if n > 0 && atomic.CompareAndSwapInt32(&c.wrote, 1, 0) {
+		time.Sleep(c.latency) // the first data after a request is the start of the response
+	}
+	c.throttle(n)
+	return n, err
+}
+
+func (c *faultyConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.throttle(n)
+	atomic.StoreInt32(&c.wrote, 1)
+	return n, err
+}
@@ -0,0 +1,111 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func startEchoServer(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+	return l
+}
+
+func TestDialerFaults(t *testing.T) {
+	l := startEchoServer(t)
+	defer func() { _ = l.Close() }()
+	addr := l.Addr().String()
+
+	roundTrip := func(t *testing.T, faults *lib.FaultInjection) (time.Duration, error) {
+		d := NewDialer(net.Dialer{})
+		d.Faults = faults
+		start := time.Now()
+		conn, err := d.DialContext(context.Background(), "tcp", addr)
+		if err != nil {
+			return 0, err
+		}
+		defer func() { _ = conn.Close() }()
+		_, err = conn.Write([]byte("ping"))
+		require.NoError(t, err)
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		assert.Equal(t, "ping", string(buf))
+		return time.Since(start), nil
+	}
+
+	t.Run("Dropped", func(t *testing.T) {
+		_, err := roundTrip(t, &lib.FaultInjection{DropRate: null.FloatFrom(1)})
+		assert.Equal(t, DroppedConnectionError(addr), err)
+	})
+	t.Run("NotAffected", func(t *testing.T) {
+		elapsed, err := roundTrip(t, &lib.FaultInjection{
+			Rate:     null.FloatFrom(0),
+			Latency:  types.NullDurationFrom(time.Second),
+			DropRate: null.FloatFrom(1),
+		})
+		require.NoError(t, err)
+		assert.True(t, elapsed < time.Second, elapsed)
+	})
+	t.Run("Latency", func(t *testing.T) {
+		elapsed, err := roundTrip(t, &lib.FaultInjection{Latency: types.NullDurationFrom(100 * time.Millisecond)})
+		require.NoError(t, err)
+		assert.True(t, elapsed >= 200*time.Millisecond, elapsed) // for the dial and the response
+	})
+	t.Run("LatencyCancelled", func(t *testing.T) {
+		d := NewDialer(net.Dialer{})
+		d.Faults = &lib.FaultInjection{Latency: types.NullDurationFrom(10 * time.Second)}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := d.DialContext(ctx, "tcp", addr)
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.True(t, time.Since(start) < time.Second, time.Since(start))
+	})
+	t.Run("Bandwidth", func(t *testing.T) {
+		elapsed, err := roundTrip(t, &lib.FaultInjection{Bandwidth: null.IntFrom(40)})
+		require.NoError(t, err)
+		assert.True(t, elapsed >= 200*time.Millisecond, elapsed) // 4 bytes up and 4 down at 40 B/s
+	})
+}
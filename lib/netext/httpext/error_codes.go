@@ -39,12 +39,14 @@ type errCode uint32
 
 const (
 	// non specific
-	defaultErrorCode          errCode = 1000
-	defaultNetNonTCPErrorCode errCode = 1010
+	defaultErrorCode           errCode = 1000
+	defaultNetNonTCPErrorCode  errCode = 1010
+	droppedConnectionErrorCode errCode = 1020
 	// DNS errors
-	defaultDNSErrorCode    errCode = 1100
-	dnsNoSuchHostErrorCode errCode = 1101
-	blackListedIPErrorCode errCode = 1110
+	defaultDNSErrorCode      errCode = 1100
+	dnsNoSuchHostErrorCode   errCode = 1101
+	blackListedIPErrorCode   errCode = 1110
+	blockedHostnameErrorCode errCode = 1111
 	// tcp errors
 	defaultTCPErrorCode      errCode = 1200
 	tcpBrokenPipeErrorCode   errCode = 1201
@@ -75,18 +77,20 @@ const (
 )
 
 const (
-	tcpResetByPeerErrorCodeMsg  = "write: connection reset by peer"
-	tcpDialTimeoutErrorCodeMsg  = "dial: i/o timeout"
-	tcpDialRefusedErrorCodeMsg  = "dial: connection refused"
-	tcpBrokenPipeErrorCodeMsg   = "write: broken pipe"
-	netUnknownErrnoErrorCodeMsg = "%s: unknown errno `%d` on %s with message `%s`"
-	dnsNoSuchHostErrorCodeMsg   = "lookup: no such host"
-	blackListedIPErrorCodeMsg   = "ip is blacklisted"
-	http2GoAwayErrorCodeMsg     = "http2: received GoAway with http2 ErrCode %s"
-	http2StreamErrorCodeMsg     = "http2: stream error with http2 ErrCode %s"
-	http2ConnectionErrorCodeMsg = "http2: connection error with http2 ErrCode %s"
-	x509HostnameErrorCodeMsg    = "x509: certificate doesn't match hostname"
-	x509UnknownAuthority        = "x509: unknown authority"
+	tcpResetByPeerErrorCodeMsg    = "write: connection reset by peer"
+	tcpDialTimeoutErrorCodeMsg    = "dial: i/o timeout"
+	tcpDialRefusedErrorCodeMsg    = "dial: connection refused"
+	tcpBrokenPipeErrorCodeMsg     = "write: broken pipe"
+	netUnknownErrnoErrorCodeMsg   = "%s: unknown errno `%d` on %s with message `%s`"
+	dnsNoSuchHostErrorCodeMsg     = "lookup: no such host"
+	blackListedIPErrorCodeMsg     = "ip is blacklisted"
+	blockedHostnameErrorCodeMsg   = "hostname is blocked"
+	droppedConnectionErrorCodeMsg = "connection dropped by fault injection"
+	http2GoAwayErrorCodeMsg       = "http2: received GoAway with http2 ErrCode %s"
+	http2StreamErrorCodeMsg       = "http2: stream error with http2 ErrCode %s"
+	http2ConnectionErrorCodeMsg   = "http2: connection error with http2 ErrCode %s"
+	x509HostnameErrorCodeMsg      = "x509: certificate doesn't match hostname"
+	x509UnknownAuthority          = "x509: unknown authority"
 )
 
 func http2ErrCodeOffset(code http2.ErrCode) errCode {
@@ -108,6 +112,10 @@ func errorCodeForError(err error) (errCode, string) {
 		}
 	case netext.BlackListedIPError:
 		return blackListedIPErrorCode, blackListedIPErrorCodeMsg
+	case netext.BlockedHostnameError:
+		return blockedHostnameErrorCode, blockedHostnameErrorCodeMsg
+	case netext.DroppedConnectionError:
+		return droppedConnectionErrorCode, droppedConnectionErrorCodeMsg
 	case *http2.GoAwayError:
 		return unknownHTTP2GoAwayErrorCode + http2ErrCodeOffset(e.ErrCode),
 			fmt.Sprintf(http2GoAwayErrorCodeMsg, e.ErrCode)
@@ -87,6 +87,22 @@ func TestBlackListedIPError(t *testing.T) {
 	require.Equal(t, blackListedIPErrorCode, errorCode)
 }
 
+func TestBlockedHostnameError(t *testing.T) {
+	var err = netext.BlockedHostnameError{}
+	testErrorCode(t, blockedHostnameErrorCode, err)
+	var errorCode, errorMsg = errorCodeForError(err)
+	require.Equal(t, blockedHostnameErrorCodeMsg, errorMsg)
+	require.Equal(t, blockedHostnameErrorCode, errorCode)
+}
+
+func TestDroppedConnectionError(t *testing.T) {
+	var err error = netext.DroppedConnectionError("example.com:80")
+	testErrorCode(t, droppedConnectionErrorCode, err)
+	var errorCode, errorMsg = errorCodeForError(err)
+	require.Equal(t, droppedConnectionErrorCodeMsg, errorMsg)
+	require.Equal(t, droppedConnectionErrorCode, errorCode)
+}
+
 type timeoutError bool
 
 func (t timeoutError) Timeout() bool {
@@ -40,6 +40,7 @@ import (
 	ntlmssp "github.com/Azure/go-ntlmssp"
 	digest "github.com/Soontao/goHttpDigestClient"
 	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/netext"
 	"github.com/loadimpact/k6/stats"
 	log "github.com/sirupsen/logrus"
 	null "gopkg.in/guregu/null.v3"
@@ -113,6 +114,10 @@ type ParsedHTTPRequest struct {
 	ActiveJar    *cookiejar.Jar
 	Cookies      map[string]*HTTPRequestCookie
 	Tags         map[string]string
+
+	// ResponseCallback tells whether the status of a response was expected, nil if the
+	// requests shouldn't be classified, i.e. http_req_failed shouldn't be emitted for them.
+	ResponseCallback func(status int) bool
 }
 
 func stdCookiesToHTTPRequestCookies(cookies []*http.Cookie) map[string][]*HTTPRequestCookie {
@@ -248,7 +253,8 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 		}
 	}
 
-	tracerTransport := newTransport(state.Transport, state.Samples, &state.Options, tags)
+	tracerTransport := newTransport(state.Transport, state.Samples, &state.Options, tags, preq.ResponseCallback)
+	tracerTransport.auth = preq.Auth
 	var transport http.RoundTripper = tracerTransport
 	if preq.Auth == "ntlm" {
 		transport = ntlmssp.Negotiator{
@@ -256,13 +262,29 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 		}
 	}
 
-	resp := &Response{ctx: ctx, URL: preq.URL.URL, Request: *respReq}
+	resp := &Response{
+		ctx: ctx, URL: preq.URL.URL, Request: *respReq,
+		Redirects: []Redirect{}, TLSCertificate: netext.NewTLSCertificate(),
+	}
+	if err := netext.CheckBlockedHostname(preq.Req.URL.Hostname(), state.Options.BlockedHostnames); err != nil {
+		errorCode, errorMsg := errorCodeForError(err)
+		resp.Error, resp.ErrorCode = errorMsg, int(errorCode)
+		if preq.Throw {
+			return nil, err
+		}
+		state.Logger.WithField("error", err).Warn("Request Failed")
+		return resp, nil
+	}
 	client := http.Client{
 		Transport: transport,
 		Timeout:   preq.Timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			resp.URL = req.URL.String()
 			debugResponse(state, req.Response, "RedirectResponse")
+			if err := netext.CheckBlockedHostname(req.URL.Hostname(), state.Options.BlockedHostnames); err != nil {
+				tracerTransport.errorCode, tracerTransport.errorMsg = errorCodeForError(err)
+				return err
+			}
 
 			// Update active jar with cookies found in "Set-Cookie" header(s) of redirect response
 			if preq.ActiveJar != nil {
@@ -285,6 +307,11 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 				}
 				return http.ErrUseLastResponse
 			}
+			resp.Redirects = append(resp.Redirects, Redirect{
+				URL:      req.Response.Request.URL.String(),
+				Status:   req.Response.StatusCode,
+				Location: req.Response.Header.Get("Location"),
+			})
 			debugRequest(state, req, "RedirectRequest")
 			return nil
 		},
@@ -301,7 +328,7 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 
 		debugRequest(state, preq.Req, "DigestRequest")
 		res, err := client.Do(preq.Req.WithContext(ctx))
-		debugRequest(state, preq.Req, "DigestResponse")
+		debugResponse(state, res, "DigestResponse")
 		resp.Error = tracerTransport.errorMsg
 		resp.ErrorCode = int(tracerTransport.errorCode)
 		if err != nil {
@@ -309,7 +336,7 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 			select {
 			case <-ctx.Done():
 			default:
-				state.Logger.WithField("error", res).Warn("Digest request failed")
+				state.Logger.WithField("error", err).Warn("Digest request failed")
 			}
 
 			// In case we have an error but resp.Error is not set it means the error is not from
@@ -59,6 +59,10 @@ const (
 	ResponseTypeNone
 )
 
+// ErrDiscardedBody is returned when the body of a response is used after it was discarded.
+var ErrDiscardedBody = errors.New(
+	"the response body was discarded, use the responseType: \"text\" or \"binary\" param to keep it")
+
 // ResponseTimings is a struct to put all timings for a given HTTP response/request
 type ResponseTimings struct {
 	Duration       float64 `json:"duration"`
@@ -79,6 +83,13 @@ type HTTPCookie struct {
 	Expires                   int64
 }
 
+// Redirect is a redirection response that was followed before the final response
+type Redirect struct {
+	URL      string `json:"url"`
+	Status   int    `json:"status"`
+	Location string `json:"location"`
+}
+
 // Response is a representation of an HTTP response
 type Response struct {
 	ctx context.Context
@@ -94,10 +105,12 @@ type Response struct {
 	Timings        ResponseTimings          `json:"timings"`
 	TLSVersion     string                   `json:"tls_version"`
 	TLSCipherSuite string                   `json:"tls_cipher_suite"`
+	TLSCertificate netext.TLSCertificate    `json:"tls_certificate"`
 	OCSP           netext.OCSP              `json:"ocsp"`
 	Error          string                   `json:"error"`
 	ErrorCode      int                      `json:"error_code"`
 	Request        Request                  `json:"request"`
+	Redirects      []Redirect               `json:"redirects"`
 
 	cachedJSON    interface{}
 	validatedJSON bool
@@ -107,6 +120,7 @@ func (res *Response) setTLSInfo(tlsState *tls.ConnectionState) {
 	tlsInfo, oscp := netext.ParseTLSConnState(tlsState)
 	res.TLSVersion = tlsInfo.Version
 	res.TLSCipherSuite = tlsInfo.CipherSuite
+	res.TLSCertificate = netext.ParseTLSCertificate(tlsState)
 	res.OCSP = oscp
 }
 
@@ -136,6 +150,8 @@ func (res *Response) JSON(selector ...string) (interface{}, error) {
 			body = b
 		case string:
 			body = []byte(b)
+		case nil:
+			return nil, ErrDiscardedBody
 		default:
 			return nil, errors.New("invalid response type")
 		}
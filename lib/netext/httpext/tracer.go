@@ -30,6 +30,7 @@ import (
 
 	"github.com/loadimpact/k6/lib/metrics"
 	"github.com/loadimpact/k6/stats"
+	null "gopkg.in/guregu/null.v3"
 )
 
 // A Trail represents detailed information about an HTTP request.
@@ -56,6 +57,9 @@ type Trail struct {
 	ConnRemoteAddr net.Addr
 	Errors         []error
 
+	// Whether the status of the response wasn't expected, invalid if it wasn't checked.
+	Failed null.Bool
+
 	// Populated by SaveSamples()
 	Tags    *stats.SampleTags
 	Samples []stats.Sample
@@ -75,6 +79,15 @@ func (tr *Trail) SaveSamples(tags *stats.SampleTags) {
 		{Metric: metrics.HTTPReqWaiting, Time: tr.EndTime, Tags: tags, Value: stats.D(tr.Waiting)},
 		{Metric: metrics.HTTPReqReceiving, Time: tr.EndTime, Tags: tags, Value: stats.D(tr.Receiving)},
 	}
+	if tr.Failed.Valid {
+		failed := 0.0
+		if tr.Failed.Bool {
+			failed = 1
+		}
+		tr.Samples = append(tr.Samples, stats.Sample{
+			Metric: metrics.HTTPReqFailed, Time: tr.EndTime, Tags: tags, Value: failed,
+		})
+	}
 }
 
 // GetSamples implements the stats.SampleContainer interface.
@@ -25,11 +25,14 @@ import (
 	"net/http"
 	"net/http/httptrace"
 	"strconv"
+	"strings"
 
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/lib/tracing"
 	"github.com/loadimpact/k6/stats"
 	"github.com/pkg/errors"
+	null "gopkg.in/guregu/null.v3"
 )
 
 // transport is an implemenation of http.RoundTripper that will measure different metrics for each
@@ -44,6 +47,12 @@ type transport struct {
 	errorCode errCode
 	tlsInfo   netext.TLSInfo
 	samplesCh chan<- stats.SampleContainer
+	// traceID is shared by the spans of all the roundtrips of the request, if it's traced
+	traceID tracing.TraceID
+
+	responseCallback func(status int) bool
+	// auth is the authentication of the request, the challenges of digest and NTLM are expected
+	auth string
 }
 
 var _ http.RoundTripper = &transport{}
@@ -55,13 +64,19 @@ func newTransport(
 	samplesCh chan<- stats.SampleContainer,
 	options *lib.Options,
 	tags map[string]string,
+	responseCallback func(status int) bool,
 ) *transport {
-	return &transport{
-		roundTripper: roundTripper,
-		tags:         tags,
-		options:      options,
-		samplesCh:    samplesCh,
+	t := &transport{
+		roundTripper:     roundTripper,
+		tags:             tags,
+		options:          options,
+		samplesCh:        samplesCh,
+		responseCallback: responseCallback,
+	}
+	if options.Tracing.String != "" {
+		t.traceID = tracing.NewTraceID()
 	}
+	return t
 }
 
 // SetOptions sets the options that should be used
@@ -79,6 +94,27 @@ func (t *transport) TLSInfo() netext.TLSInfo {
 	return t.tlsInfo
 }
 
+// isAuthChallenge tells whether the response is a challenge of the digest or NTLM authentication,
+// which is answered with another request, instead of the final response to an authenticated one.
+// The NTLM negotiation has two of them: one to the anonymous request, and one with the challenge
+// data to the negotiate message.
+func (t *transport) isAuthChallenge(req *http.Request, resp *http.Response) bool {
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	switch t.auth {
+	case "digest":
+		return req.Header.Get("Authorization") == ""
+	case "ntlm":
+		if req.Header.Get("Authorization") == "" {
+			return true
+		}
+		challenge := strings.Fields(resp.Header.Get("Www-Authenticate"))
+		return len(challenge) == 2 && (challenge[0] == "NTLM" || challenge[0] == "Negotiate")
+	}
+	return false
+}
+
 // RoundTrip is the implementation of http.RoundTripper
 func (t *transport) RoundTrip(req *http.Request) (res *http.Response, err error) {
 	if t.roundTripper == nil {
@@ -94,9 +130,20 @@ func (t *transport) RoundTrip(req *http.Request) (res *http.Response, err error)
 	ctx := req.Context()
 	tracer := Tracer{}
 	reqWithTracer := req.WithContext(httptrace.WithClientTrace(ctx, tracer.Trace()))
+	var spanID tracing.SpanID
+	if propagator := t.options.Tracing.String; propagator != "" {
+		// RoundTrippers mustn't modify the request, so the headers are set on a copy
+		spanID = tracing.NewSpanID()
+		reqWithTracer.Header = req.Header.Clone()
+		if reqWithTracer.Header == nil {
+			reqWithTracer.Header = http.Header{}
+		}
+		tracing.Inject(propagator, reqWithTracer.Header, t.traceID, spanID)
+	}
 
 	resp, err := t.roundTripper.RoundTrip(reqWithTracer)
 	trail := tracer.Done()
+	status := 0
 	if err != nil {
 		t.errorCode, t.errorMsg = errorCodeForError(err)
 		if t.options.SystemTags["error"] {
@@ -114,6 +161,7 @@ func (t *transport) RoundTrip(req *http.Request) (res *http.Response, err error)
 		if t.options.SystemTags["url"] {
 			tags["url"] = req.URL.String()
 		}
+		status = resp.StatusCode
 		if t.options.SystemTags["status"] {
 			tags["status"] = strconv.Itoa(resp.StatusCode)
 		}
@@ -139,6 +187,13 @@ func (t *transport) RoundTrip(req *http.Request) (res *http.Response, err error)
 			t.tlsInfo = tlsInfo
 		}
 	}
+	if t.responseCallback != nil {
+		expected := t.responseCallback(status) || t.isAuthChallenge(req, resp)
+		trail.Failed = null.BoolFrom(!expected)
+		if t.options.SystemTags["expected_response"] {
+			tags["expected_response"] = strconv.FormatBool(expected)
+		}
+	}
 	if t.options.SystemTags["ip"] && trail.ConnRemoteAddr != nil {
 		var ip string
 		if ip, _, err = net.SplitHostPort(trail.ConnRemoteAddr.String()); err == nil {
@@ -150,5 +205,21 @@ func (t *transport) RoundTrip(req *http.Request) (res *http.Response, err error)
 	trail.SaveSamples(stats.IntoSampleTags(&tags))
 	stats.PushIfNotCancelled(ctx, t.samplesCh, trail)
 
+	if t.options.Tracing.String != "" {
+		span := &tracing.Span{
+			TraceID: t.traceID,
+			SpanID:  spanID,
+			Name:    "HTTP " + req.Method,
+			Start:   trail.StartTime,
+			End:     trail.EndTime,
+			Tags:    trail.Tags,
+			Error:   t.errorMsg,
+		}
+		if span.Error == "" && status >= 400 {
+			span.Error = resp.Status
+		}
+		stats.PushIfNotCancelled(ctx, t.samplesCh, span)
+	}
+
 	return resp, err
 }
@@ -0,0 +1,135 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package httpext
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/tracing"
+	"github.com/loadimpact/k6/stats"
+)
+
+func TestTransportTracing(t *testing.T) {
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	samples := make(chan stats.SampleContainer, 10)
+	options := &lib.Options{
+		Tracing:    null.StringFrom(tracing.PropagatorW3C),
+		SystemTags: lib.GetTagSet(lib.DefaultSystemTagList...),
+	}
+	tr := newTransport(http.DefaultTransport, samples, options, map[string]string{}, nil)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	res, err := tr.RoundTrip(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	assert.Empty(t, req.Header, "the request was modified")
+
+	close(samples)
+	var trail *Trail
+	var span *tracing.Span
+	for c := range samples {
+		switch c := c.(type) {
+		case *Trail:
+			trail = c
+		case *tracing.Span:
+			span = c
+		}
+	}
+	require.NotNil(t, trail)
+	require.NotNil(t, span)
+	assert.Equal(t, "00-"+span.TraceID.String()+"-"+span.SpanID.String()+"-01", traceparent)
+	assert.Equal(t, "HTTP GET", span.Name)
+	assert.Equal(t, trail.StartTime, span.Start)
+	assert.Equal(t, trail.EndTime, span.End)
+	assert.Equal(t, trail.Tags, span.Tags)
+	assert.Equal(t, "404 Not Found", span.Error)
+}
+
+func TestTransportNoTracing(t *testing.T) {
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	samples := make(chan stats.SampleContainer, 10)
+	options := &lib.Options{SystemTags: lib.GetTagSet(lib.DefaultSystemTagList...)}
+	tr := newTransport(http.DefaultTransport, samples, options, map[string]string{}, nil)
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	res, err := tr.RoundTrip(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	assert.Empty(t, traceparent)
+
+	close(samples)
+	for c := range samples {
+		_, ok := c.(*tracing.Span)
+		assert.False(t, ok)
+	}
+}
+
+func TestTransportAuthChallenge(t *testing.T) {
+	testdata := map[string]struct {
+		auth, authorization, challenge string
+		status                         int
+		expected                       bool
+	}{
+		"NoAuth":             {"", "", "Digest realm=\"k6\"", 401, false},
+		"DigestChallenge":    {"digest", "", "Digest realm=\"k6\"", 401, true},
+		"DigestFailed":       {"digest", "Digest username=\"bob\"", "Digest realm=\"k6\"", 401, false},
+		"DigestOK":           {"digest", "", "", 200, false},
+		"NTLMAnonymous":      {"ntlm", "", "NTLM", 401, true},
+		"NTLMChallenge":      {"ntlm", "NTLM TlRMTVNTUAABAAAA", "NTLM TlRMTVNTUAACAAAA", 401, true},
+		"NegotiateChallenge": {"ntlm", "Negotiate TlRMTVNTUAABAAAA", "Negotiate TlRMTVNTUAACAAAA", 401, true},
+		"NTLMFailed":         {"ntlm", "NTLM TlRMTVNTUAADAAAA", "NTLM", 401, false},
+		"NTLMBasicFallback":  {"ntlm", "Basic Ym9iOnBhc3M=", "Basic realm=\"k6\"", 401, false},
+	}
+	for name, data := range testdata {
+		data := data
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://example.com/", nil)
+			if data.authorization != "" {
+				req.Header.Set("Authorization", data.authorization)
+			}
+			resp := &http.Response{StatusCode: data.status, Header: http.Header{}}
+			if data.challenge != "" {
+				resp.Header.Set("Www-Authenticate", data.challenge)
+			}
+			tr := &transport{auth: data.auth}
+			assert.Equal(t, data.expected, tr.isAuthChallenge(req, resp))
+		})
+	}
+}
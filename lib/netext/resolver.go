@@ -0,0 +1,165 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/pkg/errors"
+)
+
+// Resolver resolves hostnames and caches their IPs for the whole test. When a name resolves to
+// several IPs, one of them is selected for every connection according to the DNS options.
+type Resolver struct {
+	resolver  *net.Resolver
+	selection string
+	policy    string
+
+	lock  sync.Mutex
+	cache map[string]*resolvedHost
+}
+
+type resolvedHost struct {
+	ips  []net.IP
+	next int
+}
+
+// NewResolver returns a resolver with the given DNS options, which can be nil to use the
+// resolver of the OS and always select the first IP.
+func NewResolver(config *lib.DNSConfig) *Resolver {
+	r := &Resolver{
+		resolver:  net.DefaultResolver,
+		selection: lib.DNSSelectFirst,
+		policy:    lib.DNSPolicyAny,
+		cache:     make(map[string]*resolvedHost),
+	}
+	if config == nil {
+		return r
+	}
+	if config.Select.Valid {
+		r.selection = config.Select.String
+	}
+	if config.Policy.Valid {
+		r.policy = config.Policy.String
+	}
+	if len(config.Servers) > 0 {
+		servers := make([]string, len(config.Servers))
+		for i, server := range config.Servers {
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(server, "53")
+			}
+			servers[i] = server
+		}
+		// The servers are queried in turns, which also spreads the load on them
+		var counter uint32
+		r.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				server := servers[int(atomic.AddUint32(&counter, 1)-1)%len(servers)]
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return r
+}
+
+// LookupIP returns the IP to connect to for the host.
+func (r *Resolver) LookupIP(ctx context.Context, host string) (net.IP, error) {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+
+	r.lock.Lock()
+	resolved, ok := r.cache[host]
+	r.lock.Unlock()
+	if !ok {
+		addrs, err := r.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		ips := make([]net.IP, len(addrs))
+		for i, addr := range addrs {
+			ips[i] = addr.IP
+		}
+		if ips = applyDNSPolicy(ips, r.policy); len(ips) == 0 {
+			return nil, errors.Errorf("no IPs of %s are allowed by the DNS policy %s", host, r.policy)
+		}
+
+		r.lock.Lock()
+		if resolved, ok = r.cache[host]; !ok {
+			resolved = &resolvedHost{ips: ips}
+			r.cache[host] = resolved
+		}
+		r.lock.Unlock()
+	}
+
+	switch r.selection {
+	case lib.DNSSelectRoundRobin:
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		ip := resolved.ips[resolved.next%len(resolved.ips)]
+		resolved.next++
+		return ip, nil
+	case lib.DNSSelectRandom:
+		return resolved.ips[rand.Intn(len(resolved.ips))], nil
+	default:
+		return resolved.ips[0], nil
+	}
+}
+
+// applyDNSPolicy filters the IPs by their versions, keeping their order.
+func applyDNSPolicy(ips []net.IP, policy string) []net.IP {
+	var v4, v6 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+
+	switch policy {
+	case lib.DNSPolicyOnlyIPv4:
+		return v4
+	case lib.DNSPolicyOnlyIPv6:
+		return v6
+	case lib.DNSPolicyPreferIPv4:
+		if len(v4) > 0 {
+			return v4
+		}
+		return v6
+	case lib.DNSPolicyPreferIPv6:
+		if len(v6) > 0 {
+			return v6
+		}
+		return v4
+	default:
+		return ips
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestApplyDNSPolicy(t *testing.T) {
+	v4a, v4b := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")
+	v6 := net.ParseIP("fd00::1")
+	mixed := []net.IP{v6, v4a, v4b}
+
+	assert.Equal(t, mixed, applyDNSPolicy(mixed, lib.DNSPolicyAny))
+	assert.Equal(t, []net.IP{v4a, v4b}, applyDNSPolicy(mixed, lib.DNSPolicyOnlyIPv4))
+	assert.Equal(t, []net.IP{v6}, applyDNSPolicy(mixed, lib.DNSPolicyOnlyIPv6))
+	assert.Equal(t, []net.IP{v4a, v4b}, applyDNSPolicy(mixed, lib.DNSPolicyPreferIPv4))
+	assert.Equal(t, []net.IP{v6}, applyDNSPolicy(mixed, lib.DNSPolicyPreferIPv6))
+	assert.Equal(t, []net.IP{v6}, applyDNSPolicy([]net.IP{v6}, lib.DNSPolicyPreferIPv4))
+	assert.Empty(t, applyDNSPolicy([]net.IP{v6}, lib.DNSPolicyOnlyIPv4))
+}
+
+func TestResolver(t *testing.T) {
+	ips := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")}
+	newResolver := func(selection string) *Resolver {
+		r := NewResolver(&lib.DNSConfig{Select: null.StringFrom(selection)})
+		r.cache["example.com"] = &resolvedHost{ips: ips}
+		return r
+	}
+
+	t.Run("literal IPs", func(t *testing.T) {
+		r := NewResolver(nil)
+		for host, expected := range map[string]string{"127.0.0.1": "127.0.0.1", "[::1]": "::1"} {
+			ip, err := r.LookupIP(context.Background(), host)
+			require.NoError(t, err)
+			assert.Equal(t, expected, ip.String())
+		}
+	})
+
+	t.Run("first", func(t *testing.T) {
+		r := newResolver(lib.DNSSelectFirst)
+		for i := 0; i < 5; i++ {
+			ip, err := r.LookupIP(context.Background(), "example.com")
+			require.NoError(t, err)
+			assert.Equal(t, ips[0], ip)
+		}
+	})
+
+	t.Run("roundRobin", func(t *testing.T) {
+		r := newResolver(lib.DNSSelectRoundRobin)
+		for i := 0; i < 7; i++ {
+			ip, err := r.LookupIP(context.Background(), "example.com")
+			require.NoError(t, err)
+			assert.Equal(t, ips[i%len(ips)], ip)
+		}
+	})
+
+	t.Run("random", func(t *testing.T) {
+		r := newResolver(lib.DNSSelectRandom)
+		seen := map[string]bool{}
+		for i := 0; i < 100; i++ {
+			ip, err := r.LookupIP(context.Background(), "example.com")
+			require.NoError(t, err)
+			assert.Contains(t, ips, ip)
+			seen[ip.String()] = true
+		}
+		assert.Len(t, seen, len(ips))
+	})
+
+	t.Run("localhost", func(t *testing.T) {
+		r := NewResolver(&lib.DNSConfig{Policy: null.StringFrom(lib.DNSPolicyOnlyIPv4)})
+		ip, err := r.LookupIP(context.Background(), "localhost")
+		require.NoError(t, err)
+		assert.True(t, ip.IsLoopback())
+		assert.NotNil(t, ip.To4())
+		assert.Contains(t, r.cache, "localhost")
+	})
+
+	t.Run("custom server", func(t *testing.T) {
+		// Nothing listens on the discard port, so the lookup has to fail instead of using the OS
+		r := NewResolver(&lib.DNSConfig{Servers: []string{"127.0.0.1:9"}})
+		_, err := r.LookupIP(context.Background(), "k6-test.invalid")
+		assert.Error(t, err)
+	})
+}
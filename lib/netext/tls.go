@@ -32,6 +32,22 @@ type TLSInfo struct {
 	Version     string
 	CipherSuite string
 }
+
+// TLSCertificate contains information about the certificate presented by the server.
+type TLSCertificate struct {
+	Subject   string   `json:"subject"`
+	Issuer    string   `json:"issuer"`
+	NotBefore int64    `json:"not_before"`
+	NotAfter  int64    `json:"not_after"`
+	DNSNames  []string `json:"dns_names"`
+}
+
+// NewTLSCertificate returns the certificate info of responses without one, e.g. plain HTTP ones.
+// The DNS names are an empty slice and not nil, so they're an array in JS and JSON and not null.
+func NewTLSCertificate() TLSCertificate {
+	return TLSCertificate{DNSNames: []string{}}
+}
+
 type OCSP struct {
 	ProducedAt       int64  `json:"produced_at"`
 	ThisUpdate       int64  `json:"this_update"`
@@ -98,3 +114,18 @@ func ParseTLSConnState(tlsState *tls.ConnectionState) (TLSInfo, OCSP) {
 
 	return tlsInfo, ocspStapledRes
 }
+
+// ParseTLSCertificate returns information about the leaf certificate of the server.
+func ParseTLSCertificate(tlsState *tls.ConnectionState) TLSCertificate {
+	if len(tlsState.PeerCertificates) == 0 {
+		return NewTLSCertificate()
+	}
+	cert := tlsState.PeerCertificates[0]
+	return TLSCertificate{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		NotBefore: cert.NotBefore.Unix(),
+		NotAfter:  cert.NotAfter.Unix(),
+		DNSNames:  append([]string{}, cert.DNSNames...),
+	}
+}
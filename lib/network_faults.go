@@ -0,0 +1,135 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// FaultInjection describes artificial network degradation that is applied on the client
+// side to a share of the connections made by the VUs, so that the behaviour of the
+// tested system and of the retry logic in the scripts can be observed under bad
+// network conditions.
+type FaultInjection struct {
+	// Share of the connections that are affected, from 0 to 1. All of them by default.
+	Rate null.Float `json:"rate"`
+
+	// Delay added before establishing an affected connection and before every
+	// response that is read from it, i.e. roughly for every request.
+	Latency types.NullDuration `json:"latency"`
+
+	// Maximum upload and download speed of an affected connection, in bytes per second.
+	Bandwidth null.Int `json:"bandwidth"`
+
+	// Share of the affected connections that are dropped before they are established.
+	DropRate null.Float `json:"dropRate"`
+}
+
+// Validate checks that all fault values are in their allowed ranges.
+func (f FaultInjection) Validate() error {
+	if f.Rate.Valid && (f.Rate.Float64 < 0 || f.Rate.Float64 > 1) {
+		return errors.New("the fault injection rate should be between 0 and 1")
+	}
+	if f.DropRate.Valid && (f.DropRate.Float64 < 0 || f.DropRate.Float64 > 1) {
+		return errors.New("the fault injection drop rate should be between 0 and 1")
+	}
+	if f.Latency.Valid && f.Latency.Duration < 0 {
+		return errors.New("the fault injection latency can't be negative")
+	}
+	if f.Bandwidth.Valid && f.Bandwidth.Int64 <= 0 {
+		return errors.New("the fault injection bandwidth should be positive")
+	}
+	return nil
+}
+
+// UnmarshalJSON accepts either an object with the fault values, or a string in the
+// same format as UnmarshalText.
+func (f *FaultInjection) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		return f.UnmarshalText([]byte(str))
+	}
+
+	type rawFaultInjection FaultInjection
+	var raw rawFaultInjection
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	if err := FaultInjection(raw).Validate(); err != nil {
+		return err
+	}
+	*f = FaultInjection(raw)
+	return nil
+}
+
+// UnmarshalText parses faults in the `rate=0.1,latency=200ms,bandwidth=65536,dropRate=0.05`
+// format, which is used by the CLI flag and the environment variable.
+func (f *FaultInjection) UnmarshalText(data []byte) error {
+	var result FaultInjection
+	for _, part := range strings.Split(string(data), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("invalid fault '%s', it should be in the key=value format", part)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		var err error
+		switch key {
+		case "rate":
+			result.Rate.Float64, err = strconv.ParseFloat(value, 64)
+			result.Rate.Valid = true
+		case "dropRate":
+			result.DropRate.Float64, err = strconv.ParseFloat(value, 64)
+			result.DropRate.Valid = true
+		case "latency":
+			err = result.Latency.UnmarshalText([]byte(value))
+		case "bandwidth":
+			result.Bandwidth.Int64, err = strconv.ParseInt(value, 10, 64)
+			result.Bandwidth.Valid = true
+		default:
+			return errors.Errorf("unknown fault '%s'", key)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "fault '%s'", key)
+		}
+	}
+	if err := result.Validate(); err != nil {
+		return err
+	}
+	*f = result
+	return nil
+}
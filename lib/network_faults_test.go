@@ -0,0 +1,88 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestFaultInjectionUnmarshal(t *testing.T) {
+	expected := FaultInjection{
+		Rate:      null.FloatFrom(0.1),
+		Latency:   types.NullDurationFrom(200 * time.Millisecond),
+		Bandwidth: null.IntFrom(65536),
+		DropRate:  null.FloatFrom(0.05),
+	}
+
+	t.Run("Text", func(t *testing.T) {
+		var f FaultInjection
+		require.NoError(t, f.UnmarshalText([]byte("rate=0.1, latency=200ms,bandwidth=65536,dropRate=0.05")))
+		assert.Equal(t, expected, f)
+	})
+	t.Run("JSONObject", func(t *testing.T) {
+		var opts Options
+		data := `{"networkFaults": {"rate": 0.1, "latency": "200ms", "bandwidth": 65536, "dropRate": 0.05}}`
+		require.NoError(t, json.Unmarshal([]byte(data), &opts))
+		require.NotNil(t, opts.NetworkFaults)
+		assert.Equal(t, expected, *opts.NetworkFaults)
+	})
+	t.Run("JSONString", func(t *testing.T) {
+		var f FaultInjection
+		require.NoError(t, json.Unmarshal([]byte(`"latency=1s"`), &f))
+		assert.Equal(t, FaultInjection{Latency: types.NullDurationFrom(time.Second)}, f)
+	})
+	t.Run("Env", func(t *testing.T) {
+		os.Clearenv()
+		require.NoError(t, os.Setenv("K6_NETWORK_FAULTS", "dropRate=0.5"))
+		defer os.Clearenv()
+		var opts Options
+		require.NoError(t, envconfig.Process("k6", &opts))
+		require.NotNil(t, opts.NetworkFaults)
+		assert.Equal(t, FaultInjection{DropRate: null.FloatFrom(0.5)}, *opts.NetworkFaults)
+	})
+
+	invalid := map[string]string{
+		"rate=2":           "the fault injection rate should be between 0 and 1",
+		"dropRate=-0.1":    "the fault injection drop rate should be between 0 and 1",
+		"latency=-1s":      "the fault injection latency can't be negative",
+		"bandwidth=0":      "the fault injection bandwidth should be positive",
+		"jitter=1s":        "unknown fault 'jitter'",
+		"latency":          "invalid fault 'latency', it should be in the key=value format",
+		"rate=a lot":       `fault 'rate': strconv.ParseFloat: parsing "a lot": invalid syntax`,
+		"bandwidth=1.5e10": `fault 'bandwidth': strconv.ParseInt: parsing "1.5e10": invalid syntax`,
+	}
+	for text, errMsg := range invalid {
+		var f FaultInjection
+		assert.EqualError(t, f.UnmarshalText([]byte(text)), errMsg, text)
+	}
+	var f FaultInjection
+	assert.Error(t, json.Unmarshal([]byte(`{"rate": 0.5, "jitter": "1s"}`), &f))
+	assert.EqualError(t, json.Unmarshal([]byte(`{"rate": 5}`), &f), "the fault injection rate should be between 0 and 1")
+}
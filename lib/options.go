@@ -27,9 +27,11 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/loadimpact/k6/lib/scheduler"
+	"github.com/loadimpact/k6/lib/tracing"
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 	"github.com/pkg/errors"
@@ -46,8 +48,13 @@ const DefaultSchedulerName = "default"
 var DefaultSystemTagList = []string{
 
 	"proto", "subproto", "status", "method", "url", "name", "group", "check", "error", "error_code", "tls_version",
+	"expected_response",
 }
 
+// AllSystemTagList includes all of the system tags that can be emitted with metrics, both
+// the default ones and the ones that have to be explicitly enabled.
+var AllSystemTagList = append(append([]string{}, DefaultSystemTagList...), "iter", "vu", "ocsp_status", "ip")
+
 // TagSet is a string to bool map (for lookup efficiency) that is used to keep track
 // which system tags should be included with with metrics.
 type TagSet map[string]bool
@@ -82,6 +89,27 @@ func (t *TagSet) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// Validate returns an error for every tag in the set that isn't a known system tag, since
+// a misspelled tag would otherwise just silently never be emitted.
+func (t TagSet) Validate() []error {
+	known := GetTagSet(AllSystemTagList...)
+	var unknown []string
+	for tag, enabled := range t {
+		if enabled && !known[tag] {
+			unknown = append(unknown, tag)
+		}
+	}
+	sort.Strings(unknown)
+
+	var errs []error
+	for _, tag := range unknown {
+		errs = append(errs, fmt.Errorf(
+			"unknown system tag '%s', the valid ones are: %s", tag, strings.Join(AllSystemTagList, ", "),
+		))
+	}
+	return errs
+}
+
 // UnmarshalText converts the tag list to tagset.
 func (t *TagSet) UnmarshalText(data []byte) error {
 	var list = bytes.Split(data, []byte(","))
@@ -221,10 +249,19 @@ type Options struct {
 
 	Execution scheduler.ConfigMap `json:"execution,omitempty" envconfig:"-"`
 
+	// The part of the test that this instance runs, e.g. 1/3:2/3 for the second of three load
+	// generators, and the points that the test is split at by all of them, 0,1/3,2/3,1 here.
+	ExecutionSegment         *ExecutionSegment        `json:"executionSegment" envconfig:"execution_segment"`
+	ExecutionSegmentSequence ExecutionSegmentSequence `json:"executionSegmentSequence,omitempty" envconfig:"execution_segment_sequence"`
+
 	// Timeouts for the setup() and teardown() functions
 	SetupTimeout    types.NullDuration `json:"setupTimeout" envconfig:"setup_timeout"`
 	TeardownTimeout types.NullDuration `json:"teardownTimeout" envconfig:"teardown_timeout"`
 
+	// How long iterations that are still running when the test ends get to finish before the
+	// VUs are interrupted.
+	GracefulStop types.NullDuration `json:"gracefulStop" envconfig:"graceful_stop"`
+
 	// Limit HTTP requests per second.
 	RPS null.Int `json:"rps" envconfig:"rps"`
 
@@ -234,6 +271,9 @@ type Options struct {
 	// Default User Agent string for HTTP requests.
 	UserAgent null.String `json:"userAgent" envconfig:"user_agent"`
 
+	// Propagate a trace context with HTTP requests, with the w3c, b3 or b3-multi headers.
+	Tracing null.String `json:"tracing" envconfig:"tracing"`
+
 	// How many batch requests are allowed in parallel, in total and per host?
 	Batch        null.Int `json:"batch" envconfig:"batch"`
 	BatchPerHost null.Int `json:"batchPerHost" envconfig:"batch_per_host"`
@@ -260,9 +300,21 @@ type Options struct {
 	// Blacklist IP ranges that tests may not contact. Mainly useful in hosted setups.
 	BlacklistIPs []*net.IPNet `json:"blacklistIPs" envconfig:"blacklist_ips"`
 
+	// Hostnames, or wildcards like *.example.com, that tests may not contact
+	BlockedHostnames []string `json:"blockHostnames" envconfig:"block_hostnames"`
+
 	// Hosts overrides dns entries for given hosts
 	Hosts map[string]net.IP `json:"hosts" envconfig:"hosts"`
 
+	// DNS servers to use and how the IPs of names with several of them are selected
+	DNS *DNSConfig `json:"dns" envconfig:"dns"`
+
+	// Send all requests through this proxy instead of the ones in the HTTP(S)_PROXY env vars
+	Proxy null.String `json:"proxy" envconfig:"proxy"`
+
+	// Artificial latency, bandwidth limits and dropped connections for some of the connections
+	NetworkFaults *FaultInjection `json:"networkFaults" envconfig:"network_faults"`
+
 	// Disable keep-alive connections
 	NoConnectionReuse null.Bool `json:"noConnectionReuse" envconfig:"no_connection_reuse"`
 
@@ -271,6 +323,9 @@ type Options struct {
 	// errors about running out of file handles or sockets, or being unable to bind addresses.
 	NoVUConnectionReuse null.Bool `json:"noVUConnectionReuse" envconfig:"no_vu_connection_reuse"`
 
+	// Default timeout of HTTP requests, which can be overridden with the timeout param
+	HTTPRequestTimeout types.NullDuration `json:"httpRequestTimeout" envconfig:"http_request_timeout"`
+
 	// MinIterationDuration can be used to force VUs to pause between iterations if a specific
 	// iteration is shorter than the specified value.
 	MinIterationDuration types.NullDuration `json:"minIterationDuration" envconfig:"min_iteration_duration"`
@@ -285,6 +340,9 @@ type Options struct {
 	// Summary time unit for summary metrics (response times) in CLI output
 	SummaryTimeUnit null.String `json:"summaryTimeUnit" envconfig:"summary_time_unit"`
 
+	// Relative accuracy of the percentiles of trend metrics with too many values to keep them all
+	TrendAccuracy null.Float `json:"trendAccuracy" envconfig:"trend_accuracy"`
+
 	// Which system tags to include with metrics ("method", "vu" etc.)
 	SystemTags TagSet `json:"systemTags" envconfig:"system_tags"`
 
@@ -358,12 +416,21 @@ func (o Options) Apply(opts Options) Options {
 	if opts.Execution != nil {
 		o.Execution = opts.Execution
 	}
+	if !opts.ExecutionSegment.isEmpty() {
+		o.ExecutionSegment = opts.ExecutionSegment
+	}
+	if opts.ExecutionSegmentSequence != nil {
+		o.ExecutionSegmentSequence = opts.ExecutionSegmentSequence
+	}
 	if opts.SetupTimeout.Valid {
 		o.SetupTimeout = opts.SetupTimeout
 	}
 	if opts.TeardownTimeout.Valid {
 		o.TeardownTimeout = opts.TeardownTimeout
 	}
+	if opts.GracefulStop.Valid {
+		o.GracefulStop = opts.GracefulStop
+	}
 	if opts.RPS.Valid {
 		o.RPS = opts.RPS
 	}
@@ -373,6 +440,9 @@ func (o Options) Apply(opts Options) Options {
 	if opts.UserAgent.Valid {
 		o.UserAgent = opts.UserAgent
 	}
+	if opts.Tracing.Valid {
+		o.Tracing = opts.Tracing
+	}
 	if opts.Batch.Valid {
 		o.Batch = opts.Batch
 	}
@@ -403,15 +473,30 @@ func (o Options) Apply(opts Options) Options {
 	if opts.BlacklistIPs != nil {
 		o.BlacklistIPs = opts.BlacklistIPs
 	}
+	if opts.BlockedHostnames != nil {
+		o.BlockedHostnames = opts.BlockedHostnames
+	}
 	if opts.Hosts != nil {
 		o.Hosts = opts.Hosts
 	}
+	if opts.DNS != nil {
+		o.DNS = opts.DNS
+	}
+	if opts.Proxy.Valid {
+		o.Proxy = opts.Proxy
+	}
+	if opts.NetworkFaults != nil {
+		o.NetworkFaults = opts.NetworkFaults
+	}
 	if opts.NoConnectionReuse.Valid {
 		o.NoConnectionReuse = opts.NoConnectionReuse
 	}
 	if opts.NoVUConnectionReuse.Valid {
 		o.NoVUConnectionReuse = opts.NoVUConnectionReuse
 	}
+	if opts.HTTPRequestTimeout.Valid {
+		o.HTTPRequestTimeout = opts.HTTPRequestTimeout
+	}
 	if opts.MinIterationDuration.Valid {
 		o.MinIterationDuration = opts.MinIterationDuration
 	}
@@ -427,6 +512,9 @@ func (o Options) Apply(opts Options) Options {
 	if opts.SummaryTimeUnit.Valid {
 		o.SummaryTimeUnit = opts.SummaryTimeUnit
 	}
+	if opts.TrendAccuracy.Valid {
+		o.TrendAccuracy = opts.TrendAccuracy
+	}
 	if opts.SystemTags != nil {
 		o.SystemTags = opts.SystemTags
 	}
@@ -450,7 +538,16 @@ func (o Options) Apply(opts Options) Options {
 func (o Options) Validate() []error {
 	//TODO: validate all of the other options... that we should have already been validating...
 	//TODO: maybe integrate an external validation lib: https://github.com/avelino/awesome-go#validation
-	return o.Execution.Validate()
+	errs := o.Execution.Validate()
+	if o.Tracing.String != "" {
+		if err := tracing.ValidatePropagator(o.Tracing.String); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, err := NewExecutionPartition(o.ExecutionSegment, o.ExecutionSegmentSequence); err != nil {
+		errs = append(errs, err)
+	}
+	return append(errs, o.SystemTags.Validate()...)
 }
 
 // ForEachSpecified enumerates all struct fields and calls the supplied function with each
@@ -23,6 +23,7 @@ package lib
 import (
 	"crypto/tls"
 	"encoding/json"
+	"math/big"
 	"net"
 	"os"
 	"reflect"
@@ -114,6 +115,16 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.UserAgent.Valid)
 		assert.Equal(t, "foo", opts.UserAgent.String)
 	})
+	t.Run("Tracing", func(t *testing.T) {
+		opts := Options{}.Apply(Options{Tracing: null.StringFrom("b3")})
+		assert.True(t, opts.Tracing.Valid)
+		assert.Equal(t, "b3", opts.Tracing.String)
+		assert.Empty(t, opts.Validate())
+		errs := Options{Tracing: null.StringFrom("zipkin")}.Validate()
+		if assert.Len(t, errs, 1) {
+			assert.Contains(t, errs[0].Error(), "invalid tracing propagator 'zipkin'")
+		}
+	})
 	t.Run("Batch", func(t *testing.T) {
 		opts := Options{}.Apply(Options{Batch: null.IntFrom(12345)})
 		assert.True(t, opts.Batch.Valid)
@@ -328,6 +339,59 @@ func TestOptions(t *testing.T) {
 		assert.Equal(t, "192.0.2.1", opts.Hosts["test.loadimpact.com"].String())
 	})
 
+	t.Run("Proxy", func(t *testing.T) {
+		opts := Options{}.Apply(Options{Proxy: null.StringFrom("http://proxy.example.com:3128")})
+		assert.True(t, opts.Proxy.Valid)
+		assert.Equal(t, "http://proxy.example.com:3128", opts.Proxy.String)
+	})
+
+	t.Run("NetworkFaults", func(t *testing.T) {
+		faults := &FaultInjection{Rate: null.FloatFrom(0.1)}
+		opts := Options{}.Apply(Options{NetworkFaults: faults})
+		assert.Equal(t, faults, opts.NetworkFaults)
+		assert.Equal(t, faults, opts.Apply(Options{}).NetworkFaults)
+	})
+
+	t.Run("ExecutionSegment", func(t *testing.T) {
+		es, err := NewExecutionSegmentFromString("1/3:2/3")
+		require.NoError(t, err)
+		seq, err := NewExecutionSegmentSequenceFromString("0,1/3,2/3,1")
+		require.NoError(t, err)
+		opts := Options{}.Apply(Options{ExecutionSegment: es, ExecutionSegmentSequence: seq})
+		assert.Equal(t, es, opts.ExecutionSegment)
+		assert.Equal(t, seq, opts.ExecutionSegmentSequence)
+		// envconfig allocates an empty segment when the environment variable isn't set
+		opts = opts.Apply(Options{ExecutionSegment: &ExecutionSegment{}})
+		assert.Equal(t, es, opts.ExecutionSegment)
+		assert.Empty(t, opts.Validate())
+
+		seq, err = NewExecutionSegmentSequenceFromString("0,1/2,1")
+		require.NoError(t, err)
+		errs := opts.Apply(Options{ExecutionSegmentSequence: seq}).Validate()
+		if assert.Len(t, errs, 1) {
+			assert.EqualError(t, errs[0], "the execution segment 1/3:2/3 isn't in the sequence 0,1/2,1")
+		}
+	})
+
+	t.Run("BlockedHostnames", func(t *testing.T) {
+		opts := Options{}.Apply(Options{BlockedHostnames: []string{"*.example.com"}})
+		assert.Equal(t, []string{"*.example.com"}, opts.BlockedHostnames)
+		assert.Equal(t, []string{"*.example.com"}, opts.Apply(Options{}).BlockedHostnames)
+	})
+
+	t.Run("HTTPRequestTimeout", func(t *testing.T) {
+		opts := Options{}.Apply(Options{HTTPRequestTimeout: types.NullDurationFrom(5 * time.Second)})
+		assert.True(t, opts.HTTPRequestTimeout.Valid)
+		assert.Equal(t, types.Duration(5*time.Second), opts.HTTPRequestTimeout.Duration)
+	})
+
+	t.Run("DNS", func(t *testing.T) {
+		dns := &DNSConfig{Select: null.StringFrom(DNSSelectRoundRobin)}
+		opts := Options{}.Apply(Options{DNS: dns})
+		assert.Equal(t, dns, opts.DNS)
+		assert.Equal(t, dns, opts.Apply(Options{}).DNS)
+	})
+
 	t.Run("Throws", func(t *testing.T) {
 		opts := Options{}.Apply(Options{Throw: null.BoolFrom(true)})
 		assert.True(t, opts.Throw.Valid)
@@ -385,12 +449,27 @@ func TestOptions(t *testing.T) {
 				assert.Nil(t, opts.SystemTags)
 			})
 		})
+		t.Run("Validate", func(t *testing.T) {
+			assert.Empty(t, GetTagSet(AllSystemTagList...).Validate())
+			assert.Empty(t, TagSet{"stauts": false}.Validate())
+
+			errs := GetTagSet("url", "stauts", "mehtod").Validate()
+			if assert.Len(t, errs, 2) {
+				assert.Contains(t, errs[0].Error(), "unknown system tag 'mehtod'")
+				assert.Contains(t, errs[1].Error(), "unknown system tag 'stauts'")
+			}
+			assert.Len(t, Options{SystemTags: GetTagSet("stauts")}.Validate(), 1)
+		})
 	})
 	t.Run("SummaryTrendStats", func(t *testing.T) {
 		stats := []string{"myStat1", "myStat2"}
 		opts := Options{}.Apply(Options{SummaryTrendStats: stats})
 		assert.Equal(t, stats, opts.SummaryTrendStats)
 	})
+	t.Run("TrendAccuracy", func(t *testing.T) {
+		opts := Options{}.Apply(Options{TrendAccuracy: null.FloatFrom(0.001)})
+		assert.Equal(t, null.FloatFrom(0.001), opts.TrendAccuracy)
+	})
 	t.Run("RunTags", func(t *testing.T) {
 		tags := stats.IntoSampleTags(&map[string]string{"myTag": "hello"})
 		opts := Options{}.Apply(Options{RunTags: tags})
@@ -466,6 +545,10 @@ func TestOptionsEnv(t *testing.T) {
 			"":    null.String{},
 			"Hi!": null.StringFrom("Hi!"),
 		},
+		{"Tracing", "K6_TRACING"}: {
+			"":    null.String{},
+			"w3c": null.StringFrom("w3c"),
+		},
 		{"Throw", "K6_THROW"}: {
 			"":      null.Bool{},
 			"true":  null.BoolFrom(true),
@@ -476,6 +559,16 @@ func TestOptionsEnv(t *testing.T) {
 			"true":  null.BoolFrom(true),
 			"false": null.BoolFrom(false),
 		},
+		{"TrendAccuracy", "K6_TREND_ACCURACY"}: {
+			"":      null.Float{},
+			"0.005": null.FloatFrom(0.005),
+		},
+		{"ExecutionSegment", "K6_EXECUTION_SEGMENT"}: {
+			"1/3:2/3": &ExecutionSegment{from: big.NewRat(1, 3), to: big.NewRat(2, 3)},
+		},
+		{"RunTags", "K6_TAGS"}: {
+			"testid=release-42, region=eu": stats.IntoSampleTags(&map[string]string{"testid": "release-42", "region": "eu"}),
+		},
 		// Thresholds
 		// External
 	}
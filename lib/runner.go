@@ -23,7 +23,9 @@ package lib
 import (
 	"context"
 
+	"github.com/loadimpact/k6/lib/scheduler"
 	"github.com/loadimpact/k6/stats"
+	log "github.com/sirupsen/logrus"
 )
 
 // Ensure mock implementations conform to the interfaces.
@@ -68,6 +70,21 @@ type Runner interface {
 	SetOptions(opts Options) error
 }
 
+// A SummaryHandler is a Runner that can render its own end-of-test summary, e.g. with the
+// handleSummary() function of a JS script.
+type SummaryHandler interface {
+	// Receives the summary as JSON and returns the content to write, keyed by "stdout",
+	// "stderr" or a file path. ok is false if the runner doesn't handle the summary.
+	HandleSummary(ctx context.Context, summary []byte) (result map[string]string, ok bool, err error)
+}
+
+// A ScenarioRunner is a Runner that can spawn VUs for the scenarios of a test.
+type ScenarioRunner interface {
+	// Spawns a new VU for the scenario, which runs the function named by its exec setting instead
+	// of the default one, if it's set, and adds the tags and env of the scenario to its own.
+	NewScenarioVU(out chan<- stats.SampleContainer, scenario scheduler.BaseConfig) (VU, error)
+}
+
 // A VU is a Virtual User, that can be scheduled by an Executor.
 type VU interface {
 	// Runs the VU once. The VU is responsible for handling the Halting Problem, eg. making sure
@@ -79,6 +96,13 @@ type VU interface {
 	Reconfigure(id int64) error
 }
 
+// An IterationError is an error returned by RunOnce() that has additional fields for its log entry,
+// like the group of the script it happened in.
+type IterationError interface {
+	error
+	LogFields() log.Fields
+}
+
 // MiniRunner wraps a function in a runner whose VUs will simply call that function.
 type MiniRunner struct {
 	Fn         func(ctx context.Context, out chan<- stats.SampleContainer) error
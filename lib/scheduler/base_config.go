@@ -40,11 +40,13 @@ type BaseConfig struct {
 	StartTime        types.NullDuration `json:"startTime"`
 	Interruptible    null.Bool          `json:"interruptible"`
 	IterationTimeout types.NullDuration `json:"iterationTimeout"`
+	GracefulStop     types.NullDuration `json:"gracefulStop"`
 	Env              map[string]string  `json:"env"`
 	Exec             null.String        `json:"exec"` // function name, externally validated
-	Percentage       float64            `json:"-"`    // 100, unless Split() was called
+	Tags             map[string]string  `json:"tags"`
+	Percentage       float64            `json:"-"` // 100, unless Split() was called
 
-	//TODO: future extensions like tags, distribution, others?
+	//TODO: future extensions like distribution, others?
 }
 
 // NewBaseConfig returns a default base config with the default values
@@ -80,6 +82,9 @@ func (bc BaseConfig) Validate() (errors []error) {
 	if bc.StartTime.Duration < 0 {
 		errors = append(errors, fmt.Errorf("scheduler start time can't be negative"))
 	}
+	if bc.GracefulStop.Duration < 0 {
+		errors = append(errors, fmt.Errorf("the graceful stop can't be negative"))
+	}
 	iterTimeout := time.Duration(bc.IterationTimeout.Duration)
 	if iterTimeout < 0 || iterTimeout > maxIterationTimeout {
 		errors = append(errors, fmt.Errorf(
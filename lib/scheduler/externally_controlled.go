@@ -0,0 +1,91 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	null "gopkg.in/guregu/null.v3"
+)
+
+const externallyControlledType = "externally-controlled"
+
+func init() {
+	RegisterConfigType(externallyControlledType, func(name string, rawJSON []byte) (Config, error) {
+		config := NewExternallyControlledConfig(name)
+		err := strictJSONUnmarshal(rawJSON, &config)
+		return config, err
+	})
+}
+
+// ExternallyControlledConfig stores the initial VUs and the VU cap of a test that's controlled
+// through the REST API, which can change its VUs, pause it and stop it at any time. Without a
+// duration, the test runs until it's stopped.
+type ExternallyControlledConfig struct {
+	BaseConfig
+	VUs      null.Int           `json:"vus"`
+	MaxVUs   null.Int           `json:"maxVUs"`
+	Duration types.NullDuration `json:"duration"`
+}
+
+// NewExternallyControlledConfig returns an ExternallyControlledConfig with default values
+func NewExternallyControlledConfig(name string) ExternallyControlledConfig {
+	return ExternallyControlledConfig{
+		BaseConfig: NewBaseConfig(name, externallyControlledType, true),
+		VUs:        null.NewInt(1, false),
+	}
+}
+
+// Make sure we implement the Config interface
+var _ Config = &ExternallyControlledConfig{}
+
+// Validate makes sure all options are configured and valid
+func (ecc ExternallyControlledConfig) Validate() []error {
+	errors := ecc.BaseConfig.Validate()
+	if ecc.VUs.Int64 < 0 {
+		errors = append(errors, fmt.Errorf("the number of VUs shouldn't be negative"))
+	}
+
+	if ecc.MaxVUs.Valid && ecc.MaxVUs.Int64 < ecc.VUs.Int64 {
+		errors = append(errors, fmt.Errorf("maxVUs shouldn't be less than vus"))
+	}
+
+	if ecc.Duration.Duration < 0 {
+		errors = append(errors, fmt.Errorf("the duration shouldn't be negative"))
+	}
+
+	return errors
+}
+
+// GetMaxVUs returns the absolute maximum number of possible concurrently running VUs
+func (ecc ExternallyControlledConfig) GetMaxVUs() int64 {
+	if ecc.MaxVUs.Valid {
+		return ecc.MaxVUs.Int64
+	}
+	return ecc.VUs.Int64
+}
+
+// GetMaxDuration returns the duration of the test, 0 if it runs until it's stopped
+func (ecc ExternallyControlledConfig) GetMaxDuration() time.Duration {
+	return time.Duration(ecc.Duration.Duration)
+}
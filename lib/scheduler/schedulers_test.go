@@ -87,6 +87,7 @@ var configMapTestCases = []configMapTestCase{
 	{`{"aname": {"type": "constant-looping-vus", "vus": 10, "duration": "10s", "startTime": "-10s"}}`, false, true, nil},
 	{`{"aname": {"type": "constant-looping-vus", "vus": 10, "duration": "10s", "exec": ""}}`, false, true, nil},
 	{`{"aname": {"type": "constant-looping-vus", "vus": 10, "duration": "10s", "iterationTimeout": "-2s"}}`, false, true, nil},
+	{`{"aname": {"type": "constant-looping-vus", "vus": 10, "duration": "10s", "gracefulStop": "-2s"}}`, false, true, nil},
 
 	// variable-looping-vus
 	{`{"varloops": {"type": "variable-looping-vus", "startVUs": 20, "iterationTimeout": "15s",
@@ -207,6 +208,23 @@ var configMapTestCases = []configMapTestCase{
 	{`{"varrival": {"type": "variable-arrival-rate", "preAllocatedVUs": 20, "maxVUs": 50, "stages": []}}`, false, true, nil},
 	{`{"varrival": {"type": "variable-arrival-rate", "preAllocatedVUs": 20, "maxVUs": 50, "stages": [{"duration": "5m", "target": 10}], "timeUnit": "-1s"}}`, false, true, nil},
 	{`{"varrival": {"type": "variable-arrival-rate", "preAllocatedVUs": 30, "maxVUs": 20, "stages": [{"duration": "5m", "target": 10}]}}`, false, true, nil},
+
+	// externally-controlled
+	{`{"ext": {"type": "externally-controlled", "vus": 10, "maxVUs": 50}}`,
+		false, false, func(t *testing.T, cm ConfigMap) {
+			sched := NewExternallyControlledConfig("ext")
+			sched.VUs = null.IntFrom(10)
+			sched.MaxVUs = null.IntFrom(50)
+			require.Equal(t, cm, ConfigMap{"ext": sched})
+			assert.Equal(t, int64(50), cm["ext"].GetMaxVUs())
+			assert.Equal(t, time.Duration(0), cm["ext"].GetMaxDuration())
+			assert.Empty(t, cm["ext"].Validate())
+		}},
+	{`{"ext": {"type": "externally-controlled"}}`, false, false, nil},
+	{`{"ext": {"type": "externally-controlled", "vus": 10, "duration": "1h"}}`, false, false, nil},
+	{`{"ext": {"type": "externally-controlled", "vus": -1}}`, false, true, nil},
+	{`{"ext": {"type": "externally-controlled", "vus": 10, "maxVUs": 5}}`, false, true, nil},
+	{`{"ext": {"type": "externally-controlled", "duration": "-1m"}}`, false, true, nil},
 }
 
 func TestConfigMapParsingAndValidation(t *testing.T) {
@@ -71,7 +71,7 @@ func (varc VariableArrivalRateConfig) Validate() []error {
 		errors = append(errors, fmt.Errorf("the startRate value shouldn't be negative"))
 	}
 
-	if time.Duration(varc.TimeUnit.Duration) < 0 {
+	if time.Duration(varc.TimeUnit.Duration) <= 0 {
 		errors = append(errors, fmt.Errorf("the timeUnit should be more than 0"))
 	}
 
@@ -26,7 +26,9 @@ import (
 	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"time"
 
+	"github.com/loadimpact/k6/lib/scheduler"
 	"github.com/loadimpact/k6/stats"
 	"github.com/oxtoacart/bpool"
 	log "github.com/sirupsen/logrus"
@@ -66,4 +68,12 @@ type State struct {
 	BPool *bpool.BufferPool
 
 	Vu, Iteration int64
+
+	// The scenario of the VU, nil if it doesn't belong to one, and the number of the iteration
+	// among the ones started by all the VUs of the scenario, or of the test without scenarios.
+	Scenario          *scheduler.BaseConfig
+	ScenarioIteration int64
+
+	// When the execution of the test started, the zero time in setup() and teardown().
+	StartTime time.Time
 }
@@ -0,0 +1,113 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package tracing propagates a trace context with the HTTP requests of the VUs, so that the
+// traces of the system under test can be correlated with the requests that caused them.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+)
+
+// The headers that the trace context can be propagated with.
+const (
+	// PropagatorW3C is the traceparent header of the W3C Trace Context.
+	PropagatorW3C = "w3c"
+	// PropagatorB3 is the single b3 header of Zipkin.
+	PropagatorB3 = "b3"
+	// PropagatorB3Multi are the X-B3-* headers of Zipkin.
+	PropagatorB3Multi = "b3-multi"
+)
+
+// ValidatePropagator returns an error if the propagator isn't supported.
+func ValidatePropagator(propagator string) error {
+	switch propagator {
+	case PropagatorW3C, PropagatorB3, PropagatorB3Multi:
+		return nil
+	default:
+		return errors.Errorf(
+			"invalid tracing propagator '%s', use %s, %s or %s",
+			propagator, PropagatorW3C, PropagatorB3, PropagatorB3Multi,
+		)
+	}
+}
+
+// TraceID identifies a trace, the same for all spans of an HTTP request, redirects included.
+type TraceID [16]byte
+
+// SpanID identifies a span, a single roundtrip of a request.
+type SpanID [8]byte
+
+// NewTraceID returns a random trace ID.
+func NewTraceID() (id TraceID) {
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+// NewSpanID returns a random span ID.
+func NewSpanID() (id SpanID) {
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// Inject sets the headers of the propagator for the span, which is always sampled.
+func Inject(propagator string, header http.Header, traceID TraceID, spanID SpanID) {
+	switch propagator {
+	case PropagatorW3C:
+		header.Set("traceparent", "00-"+traceID.String()+"-"+spanID.String()+"-01")
+	case PropagatorB3:
+		header.Set("b3", traceID.String()+"-"+spanID.String()+"-1")
+	case PropagatorB3Multi:
+		header.Set("X-B3-TraceId", traceID.String())
+		header.Set("X-B3-SpanId", spanID.String())
+		header.Set("X-B3-Sampled", "1")
+	}
+}
+
+// Span is the client span of an HTTP request roundtrip. It's a sample container without
+// samples, so that it's passed to the outputs with the metrics, and the ones that support
+// traces can export it.
+type Span struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Name    string
+	Start   time.Time
+	End     time.Time
+	Tags    *stats.SampleTags
+	// Error is the error of the roundtrip, or its status for responses with a 4xx or 5xx one.
+	Error string
+}
+
+var _ stats.SampleContainer = &Span{}
+
+// GetSamples returns nothing, the metrics of the request are in its trail.
+func (s *Span) GetSamples() []stats.Sample {
+	return nil
+}
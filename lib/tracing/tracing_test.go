@@ -0,0 +1,57 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tracing
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInject(t *testing.T) {
+	traceID := TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	spanID := SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+
+	testdata := map[string]http.Header{
+		PropagatorW3C: {"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+		PropagatorB3:  {"B3": {"4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1"}},
+		PropagatorB3Multi: {
+			"X-B3-Traceid": {"4bf92f3577b34da6a3ce929d0e0e4736"},
+			"X-B3-Spanid":  {"00f067aa0ba902b7"},
+			"X-B3-Sampled": {"1"},
+		},
+	}
+	for propagator, expected := range testdata {
+		assert.NoError(t, ValidatePropagator(propagator))
+		header := http.Header{}
+		Inject(propagator, header, traceID, spanID)
+		assert.Equal(t, expected, header, propagator)
+	}
+	assert.EqualError(t, ValidatePropagator("jaeger"), "invalid tracing propagator 'jaeger', use w3c, b3 or b3-multi")
+}
+
+func TestNewIDs(t *testing.T) {
+	assert.NotEqual(t, NewTraceID(), NewTraceID())
+	assert.NotEqual(t, NewSpanID(), NewSpanID())
+	assert.NotEqual(t, TraceID{}, NewTraceID())
+	assert.Len(t, NewSpanID().String(), 16)
+}
@@ -125,6 +125,21 @@ func (d Duration) MarshalJSON() ([]byte, error) {
 	return json.Marshal(d.String())
 }
 
+// GetDurationValue converts a duration from a JS script, which is either a string like "1.5s"
+// or a number of milliseconds, to a time.Duration.
+func GetDurationValue(v interface{}) (time.Duration, error) {
+	switch d := v.(type) {
+	case string:
+		return time.ParseDuration(d)
+	case int64:
+		return time.Duration(d) * time.Millisecond, nil
+	case float64:
+		return time.Duration(d * float64(time.Millisecond)), nil
+	default:
+		return 0, fmt.Errorf("invalid duration value %v of type %T", v, v)
+	}
+}
+
 // NullDuration is a nullable Duration, in the same vein as the nullable types provided by
 // package gopkg.in/guregu/null.v3.
 type NullDuration struct {
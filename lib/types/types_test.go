@@ -193,3 +193,22 @@ func TestNullDuration(t *testing.T) {
 func TestNullDurationFrom(t *testing.T) {
 	assert.Equal(t, NullDuration{Duration(10 * time.Second), true}, NullDurationFrom(10*time.Second))
 }
+
+func TestGetDurationValue(t *testing.T) {
+	d, err := GetDurationValue("1.5s")
+	assert.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, d)
+
+	d, err = GetDurationValue(int64(200))
+	assert.NoError(t, err)
+	assert.Equal(t, 200*time.Millisecond, d)
+
+	d, err = GetDurationValue(0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Microsecond, d)
+
+	_, err = GetDurationValue("1 minute")
+	assert.Error(t, err)
+	_, err = GetDurationValue(true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package aggregator
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+)
+
+// Buckets aggregates the samples in time buckets of a period, for every metric and tag set, so
+// that the outputs which send the aggregated values in their own format don't have to. The
+// buckets are taken out with Flush once they're complete. It's safe for concurrent use.
+type Buckets struct {
+	// KeepValues makes the buckets keep all values of their samples, it should be set before
+	// any samples are added.
+	KeepValues bool
+
+	period time.Duration
+	tags   func(*stats.SampleTags) *stats.SampleTags
+
+	lock    sync.Mutex
+	buckets map[time.Time]map[bucketKey]*Bucket
+}
+
+// Bucket is the aggregate of the samples of a metric with the same tags in a period.
+type Bucket struct {
+	Metric *stats.Metric
+	Tags   *stats.SampleTags
+	Time   time.Time // The start of the period
+
+	Count, Sum, Min, Max float64
+	NonZero              float64      // The number of values that aren't 0, e.g. the successes of a rate
+	Last                 stats.Sample // The sample with the latest time, e.g. for the value of a gauge
+
+	// The values of trend metrics, for their percentiles, nil for other metrics.
+	Trend *stats.TrendSink
+	// All values, only if they're kept.
+	Values []float64
+}
+
+// NewBuckets returns buckets of the given period. The samples are aggregated by the tags that
+// the tags function returns for their tags, e.g. only some of them, or by their own tags if
+// it's nil.
+func NewBuckets(period time.Duration, tags func(*stats.SampleTags) *stats.SampleTags) *Buckets {
+	return &Buckets{
+		period:  period,
+		tags:    tags,
+		buckets: make(map[time.Time]map[bucketKey]*Bucket),
+	}
+}
+
+// Add adds the samples to their buckets.
+func (b *Buckets) Add(samples []stats.Sample) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, s := range samples {
+		start := s.Time.Truncate(b.period)
+		buckets, ok := b.buckets[start]
+		if !ok {
+			buckets = make(map[bucketKey]*Bucket)
+			b.buckets[start] = buckets
+		}
+
+		tags := s.Tags
+		if b.tags != nil {
+			tags = b.tags(tags)
+		}
+		key := bucketKey{metric: s.Metric, tags: tagsKey(tags)}
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = &Bucket{Metric: s.Metric, Tags: tags, Time: start, Min: s.Value, Max: s.Value}
+			if s.Metric.Type == stats.Trend {
+				bucket.Trend = &stats.TrendSink{}
+			}
+			buckets[key] = bucket
+		}
+		bucket.add(s, b.KeepValues)
+	}
+}
+
+func (b *Bucket) add(s stats.Sample, keepValue bool) {
+	b.Count++
+	b.Sum += s.Value
+	if s.Value != 0 {
+		b.NonZero++
+	}
+	if s.Value < b.Min {
+		b.Min = s.Value
+	}
+	if s.Value > b.Max {
+		b.Max = s.Value
+	}
+	if !s.Time.Before(b.Last.Time) {
+		b.Last = s
+	}
+	if b.Trend != nil {
+		b.Trend.Add(s)
+	}
+	if keepValue {
+		b.Values = append(b.Values, s.Value)
+	}
+}
+
+// Flush removes the buckets whose period ended at or before the given time, or all of them if
+// it's zero, and returns them ordered by their time.
+func (b *Buckets) Flush(before time.Time) []*Bucket {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var starts []time.Time
+	for start := range b.buckets {
+		if before.IsZero() || !start.Add(b.period).After(before) {
+			starts = append(starts, start)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	var result []*Bucket
+	for _, start := range starts {
+		for _, bucket := range b.buckets[start] {
+			result = append(result, bucket)
+		}
+		delete(b.buckets, start)
+	}
+	return result
+}
+
+// Len returns the number of buckets, of all periods.
+func (b *Buckets) Len() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	n := 0
+	for _, buckets := range b.buckets {
+		n += len(buckets)
+	}
+	return n
+}
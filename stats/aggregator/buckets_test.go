@@ -0,0 +1,79 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuckets(t *testing.T) {
+	b := NewBuckets(time.Second, func(tags *stats.SampleTags) *stats.SampleTags {
+		status, _ := tags.Get("status")
+		return stats.IntoSampleTags(&map[string]string{"status": status})
+	})
+	b.KeepValues = true
+
+	trend := stats.New("trend", stats.Trend)
+	now := time.Now().Truncate(time.Second)
+	tags := func(status, url string) *stats.SampleTags {
+		return stats.IntoSampleTags(&map[string]string{"status": status, "url": url})
+	}
+	b.Add([]stats.Sample{
+		{Time: now.Add(100 * time.Millisecond), Metric: trend, Tags: tags("200", "/a"), Value: 3},
+		{Time: now.Add(300 * time.Millisecond), Metric: trend, Tags: tags("200", "/b"), Value: 1},
+		{Time: now.Add(200 * time.Millisecond), Metric: trend, Tags: tags("200", "/a"), Value: 0},
+		{Time: now.Add(100 * time.Millisecond), Metric: trend, Tags: tags("500", "/a"), Value: 5},
+		{Time: now.Add(-time.Second), Metric: trend, Tags: tags("200", "/a"), Value: 7},
+	})
+	assert.Equal(t, 3, b.Len())
+
+	// Only the buckets that are complete are flushed, the oldest ones first.
+	buckets := b.Flush(now.Add(500 * time.Millisecond))
+	require.Len(t, buckets, 1)
+	assert.Equal(t, now.Add(-time.Second), buckets[0].Time)
+
+	buckets = b.Flush(now.Add(time.Second))
+	require.Len(t, buckets, 2)
+	if s, _ := buckets[0].Tags.Get("status"); s != "200" {
+		buckets[0], buckets[1] = buckets[1], buckets[0]
+	}
+	ok := buckets[0]
+	assert.Equal(t, map[string]string{"status": "200"}, ok.Tags.CloneTags())
+	assert.Equal(t, now, ok.Time)
+	assert.Equal(t, 3.0, ok.Count)
+	assert.Equal(t, 4.0, ok.Sum)
+	assert.Equal(t, 0.0, ok.Min)
+	assert.Equal(t, 3.0, ok.Max)
+	assert.Equal(t, 2.0, ok.NonZero)
+	assert.Equal(t, 1.0, ok.Last.Value)
+	assert.Equal(t, []float64{3, 1, 0}, ok.Values)
+	require.NotNil(t, ok.Trend)
+	assert.Equal(t, uint64(3), ok.Trend.Count)
+	assert.Equal(t, 5.0, buckets[1].Sum)
+
+	assert.Equal(t, 0, b.Len())
+	assert.Empty(t, b.Flush(time.Time{}))
+}
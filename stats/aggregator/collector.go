@@ -0,0 +1,215 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package aggregator has a collector that rolls up the samples into time buckets before they
+// are passed to another collector, so that its backend doesn't have to store every sample.
+package aggregator
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+)
+
+// DefaultStats are the stats of the trend metrics that are sent for every bucket by default.
+var DefaultStats = []string{"count", "min", "max", "avg", "med", "p(90)", "p(95)"}
+
+// StatTag is the tag with the name of the stat of the samples of rates and trends.
+const StatTag = "stat"
+
+// Collector wraps another collector and aggregates the samples for it. For every period and
+// every metric and tag set, counters are sent as a single sample with their sum and gauges with
+// their last value. Rates are sent as two samples, with the rate and the count, and trends as a
+// sample for each of the configured stats, with the name of the stat in the stat tag. Sample
+// containers without samples, like the spans of traced requests, are passed on unchanged.
+type Collector struct {
+	lib.Collector
+
+	period  time.Duration
+	stats   []string
+	buckets *Buckets
+
+	lock sync.Mutex
+	// containers without samples, like the spans of traced requests, are passed as they are
+	passthrough []stats.SampleContainer
+}
+
+var _ lib.Collector = &Collector{}
+
+type bucketKey struct {
+	metric *stats.Metric
+	tags   string
+}
+
+// New returns a collector that aggregates the samples in buckets of the given period, with
+// the given stats for the trend metrics, before it passes them to the wrapped collector.
+func New(collector lib.Collector, period time.Duration, trendStats []string) (*Collector, error) {
+	if period <= 0 {
+		return nil, errors.New("the aggregation period should be positive")
+	}
+	if len(trendStats) == 0 {
+		trendStats = DefaultStats
+	}
+	for _, stat := range trendStats {
+		if _, err := trendStat(&stats.TrendSink{}, stat); err != nil {
+			return nil, err
+		}
+	}
+	return &Collector{
+		Collector: collector,
+		period:    period,
+		stats:     trendStats,
+		buckets:   NewBuckets(period, nil),
+	}, nil
+}
+
+// Run runs the wrapped collector and sends it the buckets that are complete every period. To
+// include the samples that arrive late, a bucket is only sent a period after it's complete.
+// When the context is done, all remaining buckets are sent before the wrapped collector stops.
+func (c *Collector) Run(ctx context.Context) {
+	innerCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Collector.Run(innerCtx)
+		close(done)
+	}()
+
+	ticker := time.NewTicker(c.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			c.flush(now.Add(-c.period))
+		case <-ctx.Done():
+			c.flush(time.Time{})
+			cancel()
+			<-done
+			return
+		}
+	}
+}
+
+// Collect adds the samples to their buckets.
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	for _, sc := range scs {
+		samples := sc.GetSamples()
+		if len(samples) == 0 {
+			c.lock.Lock()
+			c.passthrough = append(c.passthrough, sc)
+			c.lock.Unlock()
+			continue
+		}
+		c.buckets.Add(samples)
+	}
+}
+
+// flush sends the samples of all buckets that ended at or before the given time to the wrapped
+// collector, or of all buckets if it's zero.
+func (c *Collector) flush(before time.Time) {
+	var samples stats.Samples
+	for _, b := range c.buckets.Flush(before) {
+		samples = append(samples, c.bucketSamples(b)...)
+	}
+	c.lock.Lock()
+	containers := c.passthrough
+	c.passthrough = nil
+	c.lock.Unlock()
+
+	if len(samples) > 0 {
+		containers = append(containers, samples)
+	}
+	if len(containers) > 0 {
+		c.Collector.Collect(containers)
+	}
+}
+
+// bucketSamples returns the aggregated samples of a bucket.
+func (c *Collector) bucketSamples(b *Bucket) []stats.Sample {
+	newSample := func(value float64, stat string) stats.Sample {
+		tags := b.Tags
+		if stat != "" {
+			tagMap := b.Tags.CloneTags()
+			tagMap[StatTag] = stat
+			tags = stats.IntoSampleTags(&tagMap)
+		}
+		return stats.Sample{Time: b.Time, Metric: b.Metric, Tags: tags, Value: value}
+	}
+
+	switch b.Metric.Type {
+	case stats.Counter:
+		return []stats.Sample{newSample(b.Sum, "")}
+	case stats.Gauge:
+		return []stats.Sample{newSample(b.Last.Value, "")}
+	case stats.Rate:
+		return []stats.Sample{
+			newSample(b.NonZero/b.Count, "rate"),
+			newSample(b.Count, "count"),
+		}
+	default:
+		b.Trend.Calc()
+		samples := make([]stats.Sample, 0, len(c.stats))
+		for _, stat := range c.stats {
+			value, _ := trendStat(b.Trend, stat)
+			samples = append(samples, newSample(value, stat))
+		}
+		return samples
+	}
+}
+
+// trendStat returns the value of the stat with the given name from a trend sink, one of
+// count, sum, min, max, avg, med or a percentile like p(99.9).
+func trendStat(sink *stats.TrendSink, stat string) (float64, error) {
+	switch stat {
+	case "count":
+		return float64(sink.Count), nil
+	case "sum":
+		return sink.Sum, nil
+	case "min":
+		return sink.Min, nil
+	case "max":
+		return sink.Max, nil
+	case "avg":
+		return sink.Avg, nil
+	case "med":
+		return sink.Med, nil
+	}
+	if strings.HasPrefix(stat, "p(") && strings.HasSuffix(stat, ")") {
+		pct, err := strconv.ParseFloat(stat[2:len(stat)-1], 64)
+		if err == nil && pct >= 0 && pct <= 100 {
+			return sink.P(pct / 100), nil
+		}
+	}
+	return 0, errors.Errorf("invalid aggregation stat '%s', use count, sum, min, max, avg, med or p(N)", stat)
+}
+
+// tagsKey returns a string that is the same for equal sets of tags.
+func tagsKey(tags *stats.SampleTags) string {
+	if tags.IsEmpty() {
+		return ""
+	}
+	data, _ := tags.MarshalJSON() // json.Marshal() sorts the keys of maps
+	return string(data)
+}
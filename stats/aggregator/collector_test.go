@@ -0,0 +1,162 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package aggregator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/tracing"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/stats/dummy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(&dummy.Collector{}, 0, nil)
+	assert.EqualError(t, err, "the aggregation period should be positive")
+	_, err = New(&dummy.Collector{}, time.Second, []string{"avg", "p(101)"})
+	assert.EqualError(t, err, "invalid aggregation stat 'p(101)', use count, sum, min, max, avg, med or p(N)")
+
+	c, err := New(&dummy.Collector{}, time.Second, nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultStats, c.stats)
+}
+
+func TestCollector(t *testing.T) {
+	inner := &dummy.Collector{}
+	c, err := New(inner, time.Hour, []string{"count", "max", "p(50)"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	counter := stats.New("counter", stats.Counter)
+	gauge := stats.New("gauge", stats.Gauge)
+	rate := stats.New("rate", stats.Rate)
+	trend := stats.New("trend", stats.Trend)
+	tagsA := stats.IntoSampleTags(&map[string]string{"a": "1", "b": "2"})
+	tagsA2 := stats.IntoSampleTags(&map[string]string{"b": "2", "a": "1"})
+	tagsB := stats.IntoSampleTags(&map[string]string{"a": "2"})
+
+	start := time.Unix(3600*400000, 0)
+	next := start.Add(time.Hour)
+	c.Collect([]stats.SampleContainer{
+		stats.Sample{Time: start, Metric: counter, Tags: tagsA, Value: 1},
+		stats.Samples{
+			{Time: start.Add(time.Minute), Metric: counter, Tags: tagsA2, Value: 2},
+			{Time: start.Add(time.Minute), Metric: counter, Tags: tagsB, Value: 5},
+			{Time: next, Metric: counter, Tags: tagsA, Value: 4},
+		},
+		stats.Sample{Time: start, Metric: gauge, Value: 3},
+		stats.Sample{Time: start.Add(time.Second), Metric: gauge, Value: 2},
+		stats.Sample{Time: start, Metric: rate, Value: 1},
+		stats.Sample{Time: start, Metric: rate, Value: 0},
+		stats.Sample{Time: start, Metric: rate, Value: 1},
+		stats.Sample{Time: start, Metric: rate, Value: 1},
+		stats.Sample{Time: start, Metric: trend, Value: 30},
+		stats.Sample{Time: start, Metric: trend, Value: 10},
+		stats.Sample{Time: start, Metric: trend, Value: 20},
+	})
+	cancel()
+	<-done
+
+	type result struct {
+		time   time.Time
+		metric string
+		tags   map[string]string
+		value  float64
+	}
+	var results []result
+	for _, s := range inner.Samples {
+		results = append(results, result{s.Time, s.Metric.Name, s.Tags.CloneTags(), s.Value})
+	}
+	assert.ElementsMatch(t, []result{
+		{start, "counter", map[string]string{"a": "1", "b": "2"}, 3},
+		{start, "counter", map[string]string{"a": "2"}, 5},
+		{next, "counter", map[string]string{"a": "1", "b": "2"}, 4},
+		{start, "gauge", map[string]string{}, 2},
+		{start, "rate", map[string]string{"stat": "rate"}, 0.75},
+		{start, "rate", map[string]string{"stat": "count"}, 4},
+		{start, "trend", map[string]string{"stat": "count"}, 3},
+		{start, "trend", map[string]string{"stat": "max"}, 30},
+		{start, "trend", map[string]string{"stat": "p(50)"}, 20},
+	}, results)
+
+	// The older buckets are sent first.
+	assert.Equal(t, next, inner.Samples[len(inner.Samples)-1].Time)
+}
+
+func TestCollectorFlush(t *testing.T) {
+	inner := &dummy.Collector{}
+	c, err := New(inner, time.Second, nil)
+	require.NoError(t, err)
+
+	counter := stats.New("counter", stats.Counter)
+	now := time.Now().Truncate(time.Second)
+	c.Collect([]stats.SampleContainer{
+		stats.Sample{Time: now.Add(-3 * time.Second), Metric: counter, Value: 1},
+		stats.Sample{Time: now.Add(-time.Second), Metric: counter, Value: 2},
+		stats.Sample{Time: now, Metric: counter, Value: 3},
+	})
+
+	// Only the buckets that were complete a period ago are sent.
+	c.flush(now.Add(-2 * time.Second))
+	require.Len(t, inner.Samples, 1)
+	assert.Equal(t, 1.0, inner.Samples[0].Value)
+	assert.Equal(t, 2, c.buckets.Len())
+}
+
+type containerCollector struct {
+	dummy.Collector
+	containers []stats.SampleContainer
+}
+
+func (c *containerCollector) Collect(scs []stats.SampleContainer) {
+	c.containers = append(c.containers, scs...)
+}
+
+func TestCollectorPassthrough(t *testing.T) {
+	inner := &containerCollector{}
+	c, err := New(inner, time.Second, nil)
+	require.NoError(t, err)
+
+	span := &tracing.Span{Name: "HTTP GET"}
+	counter := stats.New("counter", stats.Counter)
+	c.Collect([]stats.SampleContainer{
+		span,
+		stats.Sample{Time: time.Now(), Metric: counter, Value: 1},
+	})
+
+	c.flush(time.Time{})
+	require.Len(t, inner.containers, 2)
+	assert.Equal(t, span, inner.containers[0])
+	assert.Len(t, inner.containers[1].GetSamples(), 1)
+
+	c.flush(time.Time{})
+	assert.Len(t, inner.containers, 2)
+}
@@ -0,0 +1,282 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloudwatch
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/stats/aggregator"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	null "gopkg.in/guregu/null.v3"
+)
+
+const (
+	// CloudWatch limits, the number of dimensions of a metric, the number of metrics in a
+	// PutMetricData request and in an embedded metric format record, and the number of values
+	// of a metric in a record.
+	maxDimensions     = 10
+	maxRequestMetrics = 20
+	maxRecordValues   = 100
+)
+
+// Collector publishes the samples to CloudWatch. They're aggregated per push interval, metric
+// and dimensions, which are the values of the configured tags, before they're published.
+type Collector struct {
+	Config Config
+
+	httpClient  *http.Client
+	credentials *credentialsProvider
+
+	buckets *aggregator.Buckets
+}
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// New creates a new CloudWatch collector.
+func New(conf Config) (*Collector, error) {
+	if !conf.Region.Valid {
+		for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+			if region := os.Getenv(name); region != "" {
+				conf.Region = null.StringFrom(region)
+				break
+			}
+		}
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	c := &Collector{
+		Config:      conf,
+		httpClient:  &http.Client{Timeout: time.Minute},
+		credentials: newCredentialsProvider(),
+	}
+	c.buckets = aggregator.NewBuckets(time.Duration(conf.PushInterval.Duration), c.dimensionTags)
+	// With the embedded metric format, the values themselves are sent.
+	c.buckets.KeepValues = conf.Format.String == FormatEMF
+	return c, nil
+}
+
+// dimensionTags returns the tags of the configured dimensions. CloudWatch doesn't allow empty
+// dimension values, so the missing tags are skipped.
+func (c *Collector) dimensionTags(tags *stats.SampleTags) *stats.SampleTags {
+	dimensions := make(map[string]string, len(c.Config.Dimensions))
+	for _, name := range c.Config.Dimensions {
+		if value, _ := tags.Get(name); value != "" {
+			dimensions[name] = value
+		}
+	}
+	return stats.IntoSampleTags(&dimensions)
+}
+
+// dimensions returns the dimensions of a bucket, in the configured order.
+func (c *Collector) dimensions(b *aggregator.Bucket) [][2]string {
+	dimensions := make([][2]string, 0, len(c.Config.Dimensions))
+	for _, name := range c.Config.Dimensions {
+		if value, ok := b.Tags.Get(name); ok {
+			dimensions = append(dimensions, [2]string{name, value})
+		}
+	}
+	return dimensions
+}
+
+// Init checks that there are credentials the metrics can be published with
+func (c *Collector) Init() error {
+	if c.Config.Format.String != FormatAPI {
+		return nil
+	}
+	_, err := c.credentials.get()
+	return err
+}
+
+// SetRunStatus does nothing in the CloudWatch collector
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+// Run periodically publishes the aggregated samples of the push intervals that are over, until
+// the context is done, when the rest of them are published.
+func (c *Collector) Run(ctx context.Context) {
+	log.WithField("namespace", c.Config.Namespace.String).Debug("CloudWatch: Running!")
+	interval := time.Duration(c.Config.PushInterval.Duration)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			// The samples of the last interval can still be arriving.
+			c.pushMetrics(now.Add(-interval))
+		case <-ctx.Done():
+			c.pushMetrics(time.Time{})
+			return
+		}
+	}
+}
+
+// Collect aggregates the samples until the next push interval
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	for _, sc := range scs {
+		c.buckets.Add(sc.GetSamples())
+	}
+}
+
+// Link returns a dummy string, it's only included to satisfy the lib.Collector interface
+func (c *Collector) Link() string {
+	return ""
+}
+
+// GetRequiredSystemTags returns the system tags that are used as dimensions
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	systemTags := lib.GetTagSet(lib.AllSystemTagList...)
+	required := lib.TagSet{}
+	for _, name := range c.Config.Dimensions {
+		if systemTags[name] {
+			required[name] = true
+		}
+	}
+	return required
+}
+
+// pushMetrics publishes the buckets that ended at or before the given time, or all of them if
+// it's zero.
+func (c *Collector) pushMetrics(before time.Time) {
+	buckets := c.buckets.Flush(before)
+	if len(buckets) == 0 {
+		return
+	}
+
+	startTime := time.Now()
+	var err error
+	if c.Config.Format.String == FormatEMF {
+		err = c.sendEMF(buckets)
+	} else {
+		for len(buckets) > 0 && err == nil {
+			n := len(buckets)
+			if n > maxRequestMetrics {
+				n = maxRequestMetrics
+			}
+			err = c.putMetricData(buckets[:n])
+			buckets = buckets[n:]
+		}
+	}
+	if err != nil {
+		log.WithError(err).Error("CloudWatch: Couldn't publish the metrics")
+		return
+	}
+	log.WithField("t", time.Since(startTime)).Debug("CloudWatch: Published!")
+}
+
+// unit returns the CloudWatch unit of the values of a metric.
+func unit(m *stats.Metric) string {
+	switch {
+	case m.Contains == stats.Time:
+		return "Milliseconds"
+	case m.Contains == stats.Data:
+		return "Bytes"
+	case m.Type == stats.Counter:
+		return "Count"
+	default:
+		return "None"
+	}
+}
+
+// putMetricData publishes the statistic sets of the buckets with a single request.
+func (c *Collector) putMetricData(buckets []*aggregator.Bucket) error {
+	form := url.Values{}
+	form.Set("Action", "PutMetricData")
+	form.Set("Version", "2010-08-01")
+	form.Set("Namespace", c.Config.Namespace.String)
+	formatFloat := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for i, b := range buckets {
+		prefix := "MetricData.member." + strconv.Itoa(i+1) + "."
+		form.Set(prefix+"MetricName", b.Metric.Name)
+		form.Set(prefix+"Timestamp", b.Time.UTC().Format(time.RFC3339))
+		form.Set(prefix+"Unit", unit(b.Metric))
+		form.Set(prefix+"StatisticValues.SampleCount", formatFloat(b.Count))
+		form.Set(prefix+"StatisticValues.Sum", formatFloat(b.Sum))
+		form.Set(prefix+"StatisticValues.Minimum", formatFloat(b.Min))
+		form.Set(prefix+"StatisticValues.Maximum", formatFloat(b.Max))
+		for j, d := range c.dimensions(b) {
+			dimPrefix := prefix + "Dimensions.member." + strconv.Itoa(j+1) + "."
+			form.Set(dimPrefix+"Name", d[0])
+			form.Set(dimPrefix+"Value", d[1])
+		}
+	}
+	body := []byte(form.Encode())
+
+	endpoint := c.Config.Endpoint.String
+	if endpoint == "" {
+		endpoint = "https://monitoring." + c.Config.Region.String + ".amazonaws.com"
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	creds, err := c.credentials.get()
+	if err != nil {
+		return err
+	}
+	signRequest(req, body, creds, c.Config.Region.String, "monitoring", time.Now())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	return nil
+}
+
+// sendEMF sends the values of the buckets to the CloudWatch agent, in embedded metric format
+// records, which are JSON objects separated by new lines.
+func (c *Collector) sendEMF(buckets []*aggregator.Bucket) error {
+	endpoint := c.Config.AgentEndpoint.String
+	network := endpoint[:strings.Index(endpoint, "://")]
+	conn, err := net.DialTimeout(network, endpoint[len(network)+3:], 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	for _, b := range buckets {
+		for _, record := range c.emfRecords(b) {
+			// Every record is written separately, since each UDP datagram has to be a record.
+			if _, err := conn.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
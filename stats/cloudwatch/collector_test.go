@@ -0,0 +1,213 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloudwatch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/stats/aggregator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func runCollector(t *testing.T, c *Collector, samples stats.Samples) {
+	require.NoError(t, c.Init())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	c.Collect([]stats.SampleContainer{samples})
+	cancel()
+	<-done
+}
+
+func testSamples() stats.Samples {
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	reqs := stats.New("http_reqs", stats.Counter)
+	tm := time.Date(2019, 3, 7, 12, 0, 5, 0, time.UTC)
+	ok := stats.IntoSampleTags(&map[string]string{"status": "200", "method": "GET", "url": "http://test.loadimpact.com"})
+	failed := stats.IntoSampleTags(&map[string]string{"status": "500", "method": "GET"})
+	return stats.Samples{
+		{Metric: duration, Time: tm, Value: 10, Tags: ok},
+		{Metric: duration, Time: tm.Add(time.Second), Value: 30, Tags: ok},
+		{Metric: duration, Time: tm, Value: 100, Tags: failed},
+		{Metric: reqs, Time: tm, Value: 1, Tags: ok},
+		{Metric: reqs, Time: tm.Add(time.Second), Value: 1, Tags: ok},
+	}
+}
+
+func TestCollectorAPI(t *testing.T) {
+	defer restoreEnv("AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN")()
+	require.NoError(t, os.Setenv("AWS_ACCESS_KEY_ID", "id"))
+	require.NoError(t, os.Setenv("AWS_SECRET_ACCESS_KEY", "secret"))
+	require.NoError(t, os.Unsetenv("AWS_SESSION_TOKEN"))
+
+	var lock sync.Mutex
+	var forms []url.Values
+	denied := false
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if denied {
+			rw.WriteHeader(http.StatusForbidden)
+			_, _ = rw.Write([]byte("<ErrorResponse>AccessDenied</ErrorResponse>"))
+			return
+		}
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/monitoring/aws4_request")
+		require.NoError(t, r.ParseForm())
+		forms = append(forms, r.PostForm)
+	}))
+	defer srv.Close()
+
+	c, err := New(NewConfig().Apply(Config{
+		Namespace:  null.StringFrom("loadtests"),
+		Region:     null.StringFrom("eu-west-1"),
+		Endpoint:   null.StringFrom(srv.URL),
+		Dimensions: []string{"status", "method"},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, lib.TagSet{"status": true, "method": true}, c.GetRequiredSystemTags())
+	runCollector(t, c, testSamples())
+
+	require.Len(t, forms, 1)
+	form := forms[0]
+	assert.Equal(t, "PutMetricData", form.Get("Action"))
+	assert.Equal(t, "loadtests", form.Get("Namespace"))
+
+	type datum struct {
+		Name, Time, Unit, Count, Sum, Min, Max, Dimensions string
+	}
+	var data []datum
+	for i := 1; form.Get("MetricData.member."+strconv.Itoa(i)+".MetricName") != ""; i++ {
+		prefix := "MetricData.member." + strconv.Itoa(i) + "."
+		data = append(data, datum{
+			Name:  form.Get(prefix + "MetricName"),
+			Time:  form.Get(prefix + "Timestamp"),
+			Unit:  form.Get(prefix + "Unit"),
+			Count: form.Get(prefix + "StatisticValues.SampleCount"),
+			Sum:   form.Get(prefix + "StatisticValues.Sum"),
+			Min:   form.Get(prefix + "StatisticValues.Minimum"),
+			Max:   form.Get(prefix + "StatisticValues.Maximum"),
+			Dimensions: form.Get(prefix+"Dimensions.member.1.Name") + "=" + form.Get(prefix+"Dimensions.member.1.Value") + "," +
+				form.Get(prefix+"Dimensions.member.2.Name") + "=" + form.Get(prefix+"Dimensions.member.2.Value"),
+		})
+	}
+	assert.ElementsMatch(t, []datum{
+		{"http_req_duration", "2019-03-07T12:00:00Z", "Milliseconds", "2", "40", "10", "30", "status=200,method=GET"},
+		{"http_req_duration", "2019-03-07T12:00:00Z", "Milliseconds", "1", "100", "100", "100", "status=500,method=GET"},
+		{"http_reqs", "2019-03-07T12:00:00Z", "Count", "2", "2", "1", "1", "status=200,method=GET"},
+	}, data)
+
+	// The buckets of an interval that isn't over yet are kept for a later push.
+	samples := testSamples()
+	c.Collect([]stats.SampleContainer{samples})
+	c.pushMetrics(samples[1].Time)
+	assert.Len(t, forms, 1)
+	assert.Equal(t, 3, c.buckets.Len())
+	c.pushMetrics(samples[1].Time.Add(5 * time.Second))
+	assert.Len(t, forms, 2)
+	assert.Equal(t, 0, c.buckets.Len())
+
+	lock.Lock()
+	denied = true
+	lock.Unlock()
+	b := &aggregator.Bucket{Metric: testSamples()[0].Metric, Count: 1}
+	assert.EqualError(t, c.putMetricData([]*aggregator.Bucket{b}), "403 Forbidden: <ErrorResponse>AccessDenied</ErrorResponse>")
+}
+
+func TestCollectorEMF(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	records := make(chan map[string]interface{}, 10)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var record map[string]interface{}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+			records <- record
+		}
+		close(records)
+	}()
+
+	c, err := New(NewConfig().Apply(Config{
+		Format:        null.StringFrom("emf"),
+		AgentEndpoint: null.StringFrom("tcp://" + listener.Addr().String()),
+		Dimensions:    []string{"status", "missing"},
+	}))
+	require.NoError(t, err)
+	samples := testSamples()
+	for i := 0; i < 150; i++ {
+		samples = append(samples, stats.Sample{Metric: samples[0].Metric, Time: samples[0].Time, Value: 1})
+	}
+	runCollector(t, c, samples)
+
+	var durations []map[string]interface{}
+	var reqs int
+	for record := range records {
+		metadata := record["_aws"].(map[string]interface{})
+		assert.Equal(t, float64(time.Date(2019, 3, 7, 12, 0, 0, 0, time.UTC).UnixNano()/1e6), metadata["Timestamp"])
+		directive := metadata["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "k6", directive["Namespace"])
+		if _, ok := record["http_reqs"]; ok {
+			reqs++
+			assert.Equal(t, []interface{}{1.0, 1.0}, record["http_reqs"])
+			assert.Equal(t, []interface{}{[]interface{}{"status"}}, directive["Dimensions"])
+			assert.Equal(t, "200", record["status"])
+			continue
+		}
+		durations = append(durations, record)
+	}
+	assert.Equal(t, 1, reqs)
+
+	// 2 values with the 200 status, 1 with 500 and 150 without one, in records of up to 100 values.
+	values := map[string]int{}
+	for _, record := range durations {
+		status, _ := record["status"].(string)
+		values[status] += len(record["http_req_duration"].([]interface{}))
+		assert.True(t, len(record["http_req_duration"].([]interface{})) <= maxRecordValues)
+	}
+	assert.Len(t, durations, 4)
+	assert.Equal(t, map[string]int{"200": 2, "500": 1, "": 150}, values)
+}
@@ -0,0 +1,167 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloudwatch
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kubernetes/helm/pkg/strvals"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
+	null "gopkg.in/guregu/null.v3"
+)
+
+const (
+	// FormatAPI publishes the metrics with the PutMetricData API.
+	FormatAPI = "api"
+	// FormatEMF sends the metrics in the embedded metric format to the CloudWatch agent.
+	FormatEMF = "emf"
+)
+
+// Config is the config for the CloudWatch collector
+type Config struct {
+	Namespace  null.String `json:"namespace" envconfig:"CLOUDWATCH_NAMESPACE"`
+	Dimensions []string    `json:"dimensions" envconfig:"CLOUDWATCH_DIMENSIONS"`
+	Format     null.String `json:"format" envconfig:"CLOUDWATCH_FORMAT"`
+
+	// PutMetricData API, the region defaults to the AWS_REGION environment variable.
+	Region   null.String `json:"region,omitempty" envconfig:"CLOUDWATCH_REGION"`
+	Endpoint null.String `json:"endpoint,omitempty" envconfig:"CLOUDWATCH_ENDPOINT"`
+
+	// Embedded metric format.
+	AgentEndpoint null.String `json:"agent_endpoint" envconfig:"CLOUDWATCH_AGENT_ENDPOINT"`
+
+	PushInterval types.NullDuration `json:"push_interval" envconfig:"CLOUDWATCH_PUSH_INTERVAL"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Namespace:     null.NewString("k6", false),
+		Format:        null.NewString(FormatAPI, false),
+		AgentEndpoint: null.NewString("tcp://127.0.0.1:25888", false),
+		PushInterval:  types.NewNullDuration(10*time.Second, false),
+	}
+}
+
+// Apply saves config non-zero config values from the passed config in the receiver.
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Namespace.Valid {
+		c.Namespace = cfg.Namespace
+	}
+	if len(cfg.Dimensions) > 0 {
+		c.Dimensions = cfg.Dimensions
+	}
+	if cfg.Format.Valid {
+		c.Format = cfg.Format
+	}
+	if cfg.Region.Valid {
+		c.Region = cfg.Region
+	}
+	if cfg.Endpoint.Valid {
+		c.Endpoint = cfg.Endpoint
+	}
+	if cfg.AgentEndpoint.Valid {
+		c.AgentEndpoint = cfg.AgentEndpoint
+	}
+	if cfg.PushInterval.Valid {
+		c.PushInterval = cfg.PushInterval
+	}
+	return c
+}
+
+// Validate checks the values that metrics can't be published with.
+func (c Config) Validate() error {
+	if c.Namespace.String == "" {
+		return errors.New("the CloudWatch namespace can't be empty")
+	}
+	if len(c.Dimensions) > maxDimensions {
+		return errors.Errorf("CloudWatch metrics can have at most %d dimensions, not %d", maxDimensions, len(c.Dimensions))
+	}
+	switch c.Format.String {
+	case FormatAPI:
+		if c.Region.String == "" {
+			return errors.New("the CloudWatch region isn't set, use the region option or the AWS_REGION environment variable")
+		}
+	case FormatEMF:
+		if !strings.HasPrefix(c.AgentEndpoint.String, "tcp://") && !strings.HasPrefix(c.AgentEndpoint.String, "udp://") {
+			return errors.Errorf("invalid CloudWatch agent endpoint '%s', it should be tcp://host:port or udp://host:port", c.AgentEndpoint.String)
+		}
+	default:
+		return errors.Errorf("invalid CloudWatch format '%s', use %s or %s", c.Format.String, FormatAPI, FormatEMF)
+	}
+	if time.Duration(c.PushInterval.Duration) <= 0 {
+		return errors.New("the CloudWatch push interval should be positive")
+	}
+	return nil
+}
+
+// ParseArg takes an arg string and converts it to a config. The arg is either just the
+// namespace, or key=value pairs like namespace=k6,region=eu-west-1,dimensions={scenario,status}
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	if !strings.Contains(arg, "=") {
+		c.Namespace = null.StringFrom(arg)
+		return c, nil
+	}
+
+	params, err := strvals.ParseString(arg)
+	if err != nil {
+		return c, err
+	}
+	for k, v := range params {
+		if k == "dimensions" {
+			dimensions, ok := v.([]interface{})
+			if !ok {
+				dimensions = []interface{}{v}
+			}
+			for _, d := range dimensions {
+				c.Dimensions = append(c.Dimensions, fmt.Sprint(d))
+			}
+			continue
+		}
+		value, ok := v.(string)
+		if !ok {
+			return c, errors.Errorf("invalid value for the CloudWatch option '%s'", k)
+		}
+		switch k {
+		case "namespace":
+			c.Namespace = null.StringFrom(value)
+		case "format":
+			c.Format = null.StringFrom(value)
+		case "region":
+			c.Region = null.StringFrom(value)
+		case "endpoint":
+			c.Endpoint = null.StringFrom(value)
+		case "agent_endpoint":
+			c.AgentEndpoint = null.StringFrom(value)
+		case "push_interval":
+			if err := c.PushInterval.UnmarshalText([]byte(value)); err != nil {
+				return c, err
+			}
+		default:
+			return c, errors.Errorf("unknown CloudWatch option '%s'", k)
+		}
+	}
+	return c, nil
+}
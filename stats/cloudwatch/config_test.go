@@ -0,0 +1,82 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloudwatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestConfigParseArg(t *testing.T) {
+	c, err := ParseArg("loadtests")
+	require.NoError(t, err)
+	assert.Equal(t, Config{Namespace: null.StringFrom("loadtests")}, c)
+
+	c, err = ParseArg("namespace=loadtests,region=eu-west-1,endpoint=http://localhost:4582,dimensions={status,method},push_interval=1m")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Namespace:    null.StringFrom("loadtests"),
+		Region:       null.StringFrom("eu-west-1"),
+		Endpoint:     null.StringFrom("http://localhost:4582"),
+		Dimensions:   []string{"status", "method"},
+		PushInterval: types.NullDurationFrom(time.Minute),
+	}, c)
+
+	c, err = ParseArg("format=emf,agent_endpoint=udp://127.0.0.1:25888,dimensions=status")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Format:        null.StringFrom("emf"),
+		AgentEndpoint: null.StringFrom("udp://127.0.0.1:25888"),
+		Dimensions:    []string{"status"},
+	}, c)
+
+	_, err = ParseArg("namespace=k6,foo=bar")
+	assert.EqualError(t, err, "unknown CloudWatch option 'foo'")
+	_, err = ParseArg("push_interval=often")
+	assert.Error(t, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	region := Config{Region: null.StringFrom("eu-west-1")}
+	assert.NoError(t, NewConfig().Apply(region).Validate())
+	assert.NoError(t, NewConfig().Apply(Config{Format: null.StringFrom("emf")}).Validate())
+
+	testdata := map[string]Config{
+		"the CloudWatch namespace can't be empty": region.Apply(Config{Namespace: null.StringFrom("")}),
+		"CloudWatch metrics can have at most 10 dimensions, not 11": region.Apply(Config{
+			Dimensions: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"},
+		}),
+		"the CloudWatch region isn't set, use the region option or the AWS_REGION environment variable": {},
+		"invalid CloudWatch agent endpoint 'localhost:25888', it should be tcp://host:port or udp://host:port": {
+			Format: null.StringFrom("emf"), AgentEndpoint: null.StringFrom("localhost:25888"),
+		},
+		"invalid CloudWatch format 'log', use api or emf": region.Apply(Config{Format: null.StringFrom("log")}),
+		"the CloudWatch push interval should be positive": region.Apply(Config{PushInterval: types.NullDurationFrom(0)}),
+	}
+	for expErr, conf := range testdata {
+		assert.EqualError(t, NewConfig().Apply(conf).Validate(), expErr)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloudwatch
+
+import (
+	"encoding/json"
+
+	"github.com/loadimpact/k6/stats/aggregator"
+)
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+// emfRecords returns the embedded metric format records with the values of the bucket, which
+// are split into several records when there are more than CloudWatch accepts in one.
+func (c *Collector) emfRecords(b *aggregator.Bucket) [][]byte {
+	dimensions := c.dimensions(b)
+	names := make([]string, 0, len(dimensions))
+	for _, d := range dimensions {
+		names = append(names, d[0])
+	}
+	metadata := emfMetadata{
+		Timestamp: b.Time.UnixNano() / 1e6,
+		CloudWatchMetrics: []emfDirective{{
+			Namespace:  c.Config.Namespace.String,
+			Dimensions: [][]string{names},
+			Metrics:    []emfMetric{{Name: b.Metric.Name, Unit: unit(b.Metric)}},
+		}},
+	}
+
+	var records [][]byte
+	for values := b.Values; len(values) > 0; {
+		n := len(values)
+		if n > maxRecordValues {
+			n = maxRecordValues
+		}
+		record := map[string]interface{}{"_aws": metadata, b.Metric.Name: values[:n]}
+		for _, d := range dimensions {
+			record[d[0]] = d[1]
+		}
+		values = values[n:]
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			// Only NaN and infinite values can't be marshaled, and samples don't have them.
+			continue
+		}
+		records = append(records, append(data, '\n'))
+	}
+	return records
+}
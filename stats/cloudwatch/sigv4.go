@@ -0,0 +1,194 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloudwatch
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// credentials are the AWS credentials that the requests are signed with.
+type credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// metadataEndpoint is the EC2 instance metadata service, a var so it can be changed in tests.
+var metadataEndpoint = "http://169.254.169.254"
+
+// credentialsProvider gets the credentials from the standard AWS environment variables or,
+// when they aren't set, from the IAM role of the EC2 instance, refreshing them before they expire.
+type credentialsProvider struct {
+	client *http.Client
+
+	lock  sync.Mutex
+	creds *credentials
+}
+
+func newCredentialsProvider() *credentialsProvider {
+	p := &credentialsProvider{client: &http.Client{Timeout: 5 * time.Second}}
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		p.creds = &credentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}
+	}
+	return p
+}
+
+func (p *credentialsProvider) get() (credentials, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.creds != nil && (p.creds.Expiration.IsZero() || time.Until(p.creds.Expiration) > 5*time.Minute) {
+		return *p.creds, nil
+	}
+	creds, err := p.fetchInstanceCredentials()
+	if err != nil {
+		return credentials{}, errors.Wrap(err, "couldn't get the AWS credentials from the environment or the EC2 instance metadata")
+	}
+	p.creds = &creds
+	return creds, nil
+}
+
+func (p *credentialsProvider) metadata(method, path, token string) ([]byte, error) {
+	req, err := http.NewRequest(method, metadataEndpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+	} else if method == "PUT" {
+		req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return body, nil
+}
+
+func (p *credentialsProvider) fetchInstanceCredentials() (credentials, error) {
+	// A session token is required with IMDSv2, but IMDSv1 is still tried without one.
+	token, err := p.metadata("PUT", "/latest/api/token", "")
+	if err != nil {
+		token = nil
+	}
+	const path = "/latest/meta-data/iam/security-credentials/"
+	role, err := p.metadata("GET", path, string(token))
+	if err != nil {
+		return credentials{}, err
+	}
+	data, err := p.metadata("GET", path+strings.TrimSpace(strings.SplitN(string(role), "\n", 2)[0]), string(token))
+	if err != nil {
+		return credentials{}, err
+	}
+	var creds struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return credentials{}, err
+	}
+	return credentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.Token,
+		Expiration:      creds.Expiration,
+	}, nil
+}
+
+// signRequest signs the request with the AWS Signature Version 4, for requests without a query.
+func signRequest(req *http.Request, body []byte, creds credentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		_, _ = fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	uri := req.URL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, uri, req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, hashHex(body),
+	}, "\n")
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hashHex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,128 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloudwatch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignRequest(t *testing.T) {
+	// The get-vanilla case of the AWS Signature Version 4 test suite.
+	req, err := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+	creds := credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signRequest(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"),
+	)
+}
+
+// restoreEnv returns a function that restores the environment variables to their current values.
+func restoreEnv(names ...string) func() {
+	values := make(map[string]*string, len(names))
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			values[name] = &value
+		} else {
+			values[name] = nil
+		}
+	}
+	return func() {
+		for name, value := range values {
+			if value != nil {
+				_ = os.Setenv(name, *value)
+			} else {
+				_ = os.Unsetenv(name)
+			}
+		}
+	}
+}
+
+func TestCredentialsProvider(t *testing.T) {
+	defer restoreEnv("AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN")()
+
+	t.Run("Environment", func(t *testing.T) {
+		require.NoError(t, os.Setenv("AWS_ACCESS_KEY_ID", "id"))
+		require.NoError(t, os.Setenv("AWS_SECRET_ACCESS_KEY", "secret"))
+		require.NoError(t, os.Setenv("AWS_SESSION_TOKEN", "token"))
+		creds, err := newCredentialsProvider().get()
+		require.NoError(t, err)
+		assert.Equal(t, credentials{AccessKeyID: "id", SecretAccessKey: "secret", SessionToken: "token"}, creds)
+	})
+
+	t.Run("InstanceMetadata", func(t *testing.T) {
+		require.NoError(t, os.Unsetenv("AWS_ACCESS_KEY_ID"))
+		require.NoError(t, os.Unsetenv("AWS_SECRET_ACCESS_KEY"))
+		expiration := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		fetches := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method == "PUT" && r.URL.Path == "/latest/api/token" {
+				_, _ = rw.Write([]byte("imds-token"))
+				return
+			}
+			if r.Header.Get("X-aws-ec2-metadata-token") != "imds-token" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			switch r.URL.Path {
+			case "/latest/meta-data/iam/security-credentials/":
+				_, _ = rw.Write([]byte("k6-role"))
+			case "/latest/meta-data/iam/security-credentials/k6-role":
+				fetches++
+				_, _ = rw.Write([]byte(`{"Code":"Success","AccessKeyId":"role-id","SecretAccessKey":"role-secret",` +
+					`"Token":"role-token","Expiration":"` + expiration.Format(time.RFC3339) + `"}`))
+			default:
+				rw.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer srv.Close()
+		defer func(endpoint string) { metadataEndpoint = endpoint }(metadataEndpoint)
+		metadataEndpoint = srv.URL
+
+		p := newCredentialsProvider()
+		for i := 0; i < 2; i++ {
+			creds, err := p.get()
+			require.NoError(t, err)
+			assert.Equal(t, credentials{
+				AccessKeyID:     "role-id",
+				SecretAccessKey: "role-secret",
+				SessionToken:    "role-token",
+				Expiration:      expiration,
+			}, creds)
+		}
+		assert.Equal(t, 1, fetches)
+
+		metadataEndpoint = "http://127.0.0.1:1"
+		_, err := newCredentialsProvider().get()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,190 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package csv
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// Collector writes the samples to a CSV file, one row per sample. The system tags get
+// their own columns and all other tags are URL-encoded in the last, extra_tags, column.
+type Collector struct {
+	outfile      io.WriteCloser
+	fname        string
+	csvWriter    *csv.Writer
+	saveInterval time.Duration
+	resTags      []string
+
+	buffer []stats.Sample
+	lock   sync.Mutex
+}
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// Similar to ioutil.NopCloser, but for writers
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// New creates a new CSV collector, with columns for the specified system tags.
+func New(fs afero.Fs, tags lib.TagSet, conf Config) (*Collector, error) {
+	separator, err := conf.GetSeparator()
+	if err != nil {
+		return nil, err
+	}
+	if time.Duration(conf.SaveInterval.Duration) <= 0 {
+		return nil, errors.New("the CSV save interval should be positive")
+	}
+
+	resTags := make([]string, 0, len(tags))
+	for tag, enabled := range tags {
+		if enabled {
+			resTags = append(resTags, tag)
+		}
+	}
+	sort.Strings(resTags)
+
+	c := &Collector{
+		fname:        conf.FileName.String,
+		saveInterval: time.Duration(conf.SaveInterval.Duration),
+		resTags:      resTags,
+	}
+	if c.fname == "" || c.fname == "-" {
+		c.fname = "-"
+		c.outfile = nopCloser{os.Stdout}
+	} else {
+		logfile, err := fs.Create(c.fname)
+		if err != nil {
+			return nil, err
+		}
+		c.outfile = logfile
+	}
+	c.csvWriter = csv.NewWriter(c.outfile)
+	c.csvWriter.Comma = separator
+	return c, nil
+}
+
+// Init writes the header row
+func (c *Collector) Init() error {
+	header := append([]string{"metric_name", "timestamp", "metric_value"}, c.resTags...)
+	header = append(header, "extra_tags")
+	return c.csvWriter.Write(header)
+}
+
+// SetRunStatus does nothing in the CSV collector
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+// Run periodically writes the buffered samples to the file, until the context is done
+func (c *Collector) Run(ctx context.Context) {
+	log.WithField("filename", c.fname).Debug("CSV: Writing CSV metrics")
+	ticker := time.NewTicker(c.saveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.writeSamples()
+		case <-ctx.Done():
+			c.writeSamples()
+			if err := c.outfile.Close(); err != nil {
+				log.WithField("filename", c.fname).WithError(err).Error("CSV: Error closing the file")
+			}
+			return
+		}
+	}
+}
+
+// Collect buffers the samples until the next save interval
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	c.lock.Lock()
+	for _, sc := range scs {
+		c.buffer = append(c.buffer, sc.GetSamples()...)
+	}
+	c.lock.Unlock()
+}
+
+func (c *Collector) writeSamples() {
+	c.lock.Lock()
+	samples := c.buffer
+	c.buffer = nil
+	c.lock.Unlock()
+
+	for _, sample := range samples {
+		if err := c.csvWriter.Write(c.sampleToRow(sample)); err != nil {
+			log.WithField("filename", c.fname).WithError(err).Error("CSV: Error writing to file")
+			return
+		}
+	}
+	c.csvWriter.Flush()
+	if err := c.csvWriter.Error(); err != nil {
+		log.WithField("filename", c.fname).WithError(err).Error("CSV: Error writing to file")
+	}
+}
+
+func (c *Collector) sampleToRow(sample stats.Sample) []string {
+	row := make([]string, 0, len(c.resTags)+4)
+	row = append(row,
+		sample.Metric.Name,
+		strconv.FormatInt(sample.Time.Unix(), 10),
+		strconv.FormatFloat(sample.Value, 'f', -1, 64),
+	)
+
+	var tags map[string]string
+	if sample.Tags != nil {
+		tags = sample.Tags.CloneTags()
+	}
+	for _, tag := range c.resTags {
+		row = append(row, tags[tag])
+		delete(tags, tag)
+	}
+
+	extraTags := url.Values{}
+	for k, v := range tags {
+		extraTags.Set(k, v)
+	}
+	return append(row, extraTags.Encode())
+}
+
+// Link returns a dummy string, it's only included to satisfy the lib.Collector interface
+func (c *Collector) Link() string {
+	return ""
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
@@ -0,0 +1,83 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package csv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestCollector(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	conf := NewConfig().Apply(Config{FileName: null.StringFrom("/results.csv"), Separator: null.StringFrom(";")})
+	c, err := New(fs, lib.GetTagSet("status", "method"), conf)
+	require.NoError(t, err)
+	require.NoError(t, c.Init())
+
+	metric := stats.New("http_reqs", stats.Counter)
+	now := time.Unix(1546300800, 0)
+	c.Collect([]stats.SampleContainer{
+		stats.Sample{
+			Metric: metric,
+			Time:   now,
+			Value:  1,
+			Tags:   stats.IntoSampleTags(&map[string]string{"status": "200", "method": "GET", "tag": "a b"}),
+		},
+		stats.Sample{Metric: metric, Time: now.Add(time.Second), Value: 0.5},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	data, err := afero.ReadFile(fs, "/results.csv")
+	require.NoError(t, err)
+	assert.Equal(t,
+		"metric_name;timestamp;metric_value;method;status;extra_tags\n"+
+			"http_reqs;1546300800;1;GET;200;tag=a+b\n"+
+			"http_reqs;1546300801;0.5;;;\n",
+		string(data))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(afero.NewMemMapFs(), nil, NewConfig().Apply(Config{Separator: null.StringFrom("ab")}))
+	assert.Error(t, err)
+
+	_, err = New(afero.NewMemMapFs(), nil, NewConfig().Apply(Config{SaveInterval: types.NullDurationFrom(0)}))
+	assert.EqualError(t, err, "the CSV save interval should be positive")
+
+	_, err = New(afero.NewReadOnlyFs(afero.NewMemMapFs()), nil, NewConfig())
+	assert.Error(t, err)
+}
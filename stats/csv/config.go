@@ -0,0 +1,112 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package csv
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kubernetes/helm/pkg/strvals"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// Config is the config for the csv collector
+type Config struct {
+	FileName     null.String        `json:"file_name" envconfig:"CSV_FILENAME"`
+	Separator    null.String        `json:"separator" envconfig:"CSV_SEPARATOR"`
+	SaveInterval types.NullDuration `json:"save_interval" envconfig:"CSV_SAVE_INTERVAL"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		FileName:     null.NewString("file.csv", false),
+		Separator:    null.NewString(",", false),
+		SaveInterval: types.NewNullDuration(1*time.Second, false),
+	}
+}
+
+// Apply saves config non-zero config values from the passed config in the receiver.
+func (c Config) Apply(cfg Config) Config {
+	if cfg.FileName.Valid {
+		c.FileName = cfg.FileName
+	}
+	if cfg.Separator.Valid {
+		c.Separator = cfg.Separator
+	}
+	if cfg.SaveInterval.Valid {
+		c.SaveInterval = cfg.SaveInterval
+	}
+	return c
+}
+
+// GetSeparator returns the field separator, "tab" can be used instead of a tab character.
+func (c Config) GetSeparator() (rune, error) {
+	if c.Separator.String == "tab" {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(c.Separator.String)
+	if size == 0 || size != len(c.Separator.String) || r == utf8.RuneError {
+		return 0, errors.Errorf("the CSV separator should be a single character, not '%s'", c.Separator.String)
+	}
+	switch r {
+	case '"', '\r', '\n':
+		return 0, errors.Errorf("'%c' can't be used as a CSV separator", r)
+	}
+	return r, nil
+}
+
+// ParseArg takes an arg string and converts it to a config. The arg is either just the
+// file name, or key=value pairs like file_name=results.csv,separator=tab,save_interval=5s
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	if !strings.Contains(arg, "=") {
+		c.FileName = null.StringFrom(arg)
+		return c, nil
+	}
+
+	params, err := strvals.ParseString(arg)
+	if err != nil {
+		return c, err
+	}
+	for k, v := range params {
+		value, ok := v.(string)
+		if !ok {
+			return c, errors.Errorf("invalid value for the CSV option '%s'", k)
+		}
+		switch k {
+		case "file_name":
+			c.FileName = null.StringFrom(value)
+		case "separator":
+			c.Separator = null.StringFrom(value)
+		case "save_interval":
+			if err := c.SaveInterval.UnmarshalText([]byte(value)); err != nil {
+				return c, err
+			}
+		default:
+			return c, errors.Errorf("unknown CSV option '%s'", k)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,63 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package csv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestConfigParseArg(t *testing.T) {
+	c, err := ParseArg("results.csv")
+	require.NoError(t, err)
+	assert.Equal(t, Config{FileName: null.StringFrom("results.csv")}, c)
+
+	c, err = ParseArg("file_name=results.csv,separator=;,save_interval=5s")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		FileName:     null.StringFrom("results.csv"),
+		Separator:    null.StringFrom(";"),
+		SaveInterval: types.NullDurationFrom(5 * time.Second),
+	}, c)
+
+	_, err = ParseArg("file_name=results.csv,foo=bar")
+	assert.Error(t, err)
+	_, err = ParseArg("save_interval=soon")
+	assert.Error(t, err)
+}
+
+func TestConfigGetSeparator(t *testing.T) {
+	testdata := map[string]rune{"": 0, ",": ',', ";": ';', "tab": '\t', "\t": '\t', "|": '|', ";;": 0, "\"": 0}
+	for sep, expected := range testdata {
+		r, err := Config{Separator: null.StringFrom(sep)}.GetSeparator()
+		if expected == 0 {
+			assert.Error(t, err, sep)
+		} else {
+			assert.NoError(t, err, sep)
+			assert.Equal(t, expected, r, sep)
+		}
+	}
+}
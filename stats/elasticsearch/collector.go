@@ -0,0 +1,280 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Collector indexes the samples in Elasticsearch with its bulk API, one document per sample.
+type Collector struct {
+	Config Config
+
+	index      indexPattern
+	httpClient *http.Client
+
+	buffer []stats.Sample
+	lock   sync.Mutex
+}
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// New creates a new Elasticsearch collector.
+func New(conf Config) (*Collector, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	index, err := parseIndexPattern(conf.Index.String)
+	if err != nil {
+		return nil, err
+	}
+	return &Collector{
+		Config: conf,
+		index:  index,
+		httpClient: &http.Client{
+			Timeout: time.Minute,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: conf.Insecure.Bool},
+			},
+		},
+	}, nil
+}
+
+// Init does nothing, the indexes are created by Elasticsearch when the first document is indexed
+func (c *Collector) Init() error { return nil }
+
+// SetRunStatus does nothing in the Elasticsearch collector
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+// Run periodically indexes the buffered samples, until the context is done
+func (c *Collector) Run(ctx context.Context) {
+	log.WithField("url", c.Config.URL.String).Debug("Elasticsearch: Running!")
+	ticker := time.NewTicker(time.Duration(c.Config.PushInterval.Duration))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.pushMetrics()
+		case <-ctx.Done():
+			c.pushMetrics()
+			return
+		}
+	}
+}
+
+// Collect buffers the samples until the next push interval
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	c.lock.Lock()
+	for _, sc := range scs {
+		c.buffer = append(c.buffer, sc.GetSamples()...)
+	}
+	c.lock.Unlock()
+}
+
+// Link returns a dummy string, it's only included to satisfy the lib.Collector interface
+func (c *Collector) Link() string {
+	return ""
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
+
+func (c *Collector) pushMetrics() {
+	c.lock.Lock()
+	samples := c.buffer
+	c.buffer = nil
+	c.lock.Unlock()
+
+	batchSize := int(c.Config.BatchSize.Int64)
+	for len(samples) > 0 {
+		batch := samples
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		samples = samples[len(batch):]
+
+		startTime := time.Now()
+		if err := c.bulk(batch); err != nil {
+			log.WithError(err).Error("Elasticsearch: Couldn't index the samples")
+			continue
+		}
+		log.WithFields(log.Fields{"t": time.Since(startTime), "samples": len(batch)}).Debug("Elasticsearch: Indexed!")
+	}
+}
+
+// bulk indexes the samples with a single request to the bulk API.
+func (c *Collector) bulk(samples []stats.Sample) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, sample := range samples {
+		action := map[string]string{"_index": c.index.name(sample.Time)}
+		if c.Config.DocumentType.String != "" {
+			action["_type"] = c.Config.DocumentType.String
+		}
+		if err := encoder.Encode(map[string]interface{}{"index": action}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(c.document(sample)); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(c.Config.URL.String, "/")+"/_bulk", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("User-Agent", "k6")
+	if c.Config.Username.Valid || c.Config.Password.Valid {
+		req.SetBasicAuth(c.Config.Username.String, c.Config.Password.String)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+
+	// The bulk API responds with 200 even if some of the documents weren't indexed.
+	var result bulkResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return errors.Wrap(err, "invalid bulk response")
+	}
+	if !result.Errors {
+		return nil
+	}
+	failed := 0
+	var reason string
+	for _, item := range result.Items {
+		for _, res := range item {
+			if res.Error != nil {
+				if failed == 0 {
+					reason = fmt.Sprintf("%s: %s", res.Error.Type, res.Error.Reason)
+				}
+				failed++
+			}
+		}
+	}
+	return errors.Errorf("%d of %d documents weren't indexed, the first error was %s", failed, len(samples), reason)
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Error *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// document returns the document of a sample, with its tags mapped as configured.
+func (c *Collector) document(sample stats.Sample) map[string]interface{} {
+	var tags map[string]string
+	if sample.Tags != nil {
+		tags = sample.Tags.CloneTags()
+	}
+
+	doc := make(map[string]interface{}, len(tags)+4)
+	if c.Config.Mapping.String == MappingFlat {
+		for k, v := range tags {
+			doc[k] = v
+		}
+	} else if len(tags) > 0 {
+		doc["tags"] = tags
+	}
+	// The fields of the samples take precedence over the tags with the same names.
+	doc["metric"] = sample.Metric.Name
+	doc["type"] = sample.Metric.Type
+	doc["value"] = sample.Value
+	doc[c.Config.TimestampField.String] = sample.Time.UTC().Format(time.RFC3339Nano)
+	return doc
+}
+
+// indexPattern is the name of an index, with parts in braces that are Go time layouts, which
+// are formatted with the times of the samples, e.g. k6-{2006.01.02} for daily indexes.
+type indexPattern []indexPart
+
+type indexPart struct {
+	text   string
+	layout bool
+}
+
+func parseIndexPattern(pattern string) (indexPattern, error) {
+	if pattern == "" {
+		return nil, errors.New("the Elasticsearch index can't be empty")
+	}
+	var parts indexPattern
+	for rest := pattern; rest != ""; {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			parts = append(parts, indexPart{text: rest})
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, errors.Errorf("unclosed '{' in the Elasticsearch index '%s'", pattern)
+		}
+		if start > 0 {
+			parts = append(parts, indexPart{text: rest[:start]})
+		}
+		parts = append(parts, indexPart{text: rest[start+1 : start+end], layout: true})
+		rest = rest[start+end+1:]
+	}
+	return parts, nil
+}
+
+func (p indexPattern) name(t time.Time) string {
+	var name strings.Builder
+	for _, part := range p {
+		if part.layout {
+			_, _ = name.WriteString(t.UTC().Format(part.text))
+		} else {
+			_, _ = name.WriteString(part.text)
+		}
+	}
+	return name.String()
+}
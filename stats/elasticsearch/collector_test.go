@@ -0,0 +1,163 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package elasticsearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestIndexPattern(t *testing.T) {
+	tm := time.Date(2019, 3, 7, 23, 30, 0, 0, time.FixedZone("UTC-1", -3600))
+	testdata := map[string]string{
+		"k6":                  "k6",
+		"k6-{2006.01.02}":     "k6-2019.03.08",
+		"{2006}-k6-{01}":      "2019-k6-03",
+		"k6-{2006.01.02}-raw": "k6-2019.03.08-raw",
+	}
+	for pattern, expected := range testdata {
+		p, err := parseIndexPattern(pattern)
+		require.NoError(t, err)
+		assert.Equal(t, expected, p.name(tm), pattern)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	var lock sync.Mutex
+	var requests int
+	var actions []map[string]map[string]string
+	var docs []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/_bulk", r.URL.Path)
+		require.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		user, pass, _ := r.BasicAuth()
+		require.Equal(t, "k6", user)
+		require.Equal(t, "secret", pass)
+
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &action))
+			actions = append(actions, action)
+			require.True(t, scanner.Scan())
+			var doc map[string]interface{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &doc))
+			docs = append(docs, doc)
+		}
+		_, _ = rw.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	metric := stats.New("my_metric", stats.Trend)
+	tm := time.Date(2019, 3, 7, 12, 0, 0, 0, time.UTC)
+	samples := stats.Samples{
+		{Metric: metric, Time: tm, Value: 1.5, Tags: stats.IntoSampleTags(&map[string]string{"a": "1"})},
+		{Metric: metric, Time: tm.Add(24 * time.Hour), Value: 2, Tags: stats.IntoSampleTags(&map[string]string{"value": "tag"})},
+		{Metric: metric, Time: tm, Value: 3},
+	}
+
+	for _, mapping := range []string{MappingNested, MappingFlat} {
+		t.Run(mapping, func(t *testing.T) {
+			requests, actions, docs = 0, nil, nil
+			c, err := New(NewConfig().Apply(Config{
+				URL:          null.StringFrom(srv.URL),
+				Username:     null.StringFrom("k6"),
+				Password:     null.StringFrom("secret"),
+				DocumentType: null.StringFrom("_doc"),
+				Mapping:      null.StringFrom(mapping),
+				BatchSize:    null.IntFrom(2),
+			}))
+			require.NoError(t, err)
+			require.NoError(t, c.Init())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				c.Run(ctx)
+				close(done)
+			}()
+			c.Collect([]stats.SampleContainer{samples})
+			cancel()
+			<-done
+
+			lock.Lock()
+			defer lock.Unlock()
+			assert.Equal(t, 2, requests)
+			assert.Equal(t, []map[string]map[string]string{
+				{"index": {"_index": "k6-2019.03.07", "_type": "_doc"}},
+				{"index": {"_index": "k6-2019.03.08", "_type": "_doc"}},
+				{"index": {"_index": "k6-2019.03.07", "_type": "_doc"}},
+			}, actions)
+			require.Len(t, docs, 3)
+			assert.Equal(t, "2019-03-07T12:00:00Z", docs[0]["@timestamp"])
+			assert.Equal(t, "my_metric", docs[0]["metric"])
+			assert.Equal(t, "trend", docs[0]["type"])
+			assert.Equal(t, 1.5, docs[0]["value"])
+			assert.Equal(t, 2.0, docs[1]["value"])
+			assert.NotContains(t, docs[2], "tags")
+			if mapping == MappingFlat {
+				assert.Equal(t, "1", docs[0]["a"])
+				assert.NotContains(t, docs[0], "tags")
+			} else {
+				assert.Equal(t, map[string]interface{}{"a": "1"}, docs[0]["tags"])
+				assert.Equal(t, map[string]interface{}{"value": "tag"}, docs[1]["tags"])
+			}
+		})
+	}
+}
+
+func TestCollectorBulkErrors(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(status)
+		if status != http.StatusOK {
+			_, _ = rw.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+		_, _ = rw.Write([]byte(`{"errors":true,"items":[` +
+			`{"index":{"status":201}},` +
+			`{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(NewConfig().Apply(Config{URL: null.StringFrom(srv.URL)}))
+	require.NoError(t, err)
+	metric := stats.New("my_metric", stats.Counter)
+	samples := []stats.Sample{{Metric: metric, Value: 1}, {Metric: metric, Value: 2}}
+
+	assert.EqualError(t, c.bulk(samples), "1 of 2 documents weren't indexed, the first error was mapper_parsing_exception: failed to parse")
+	status = http.StatusUnauthorized
+	assert.EqualError(t, c.bulk(samples), `401 Unauthorized: {"error":"unauthorized"}`)
+}
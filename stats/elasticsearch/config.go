@@ -0,0 +1,186 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package elasticsearch
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubernetes/helm/pkg/strvals"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
+	null "gopkg.in/guregu/null.v3"
+)
+
+const (
+	// MappingNested puts the tags of the samples in a "tags" object of the documents.
+	MappingNested = "nested"
+	// MappingFlat puts the tags of the samples as top-level fields of the documents.
+	MappingFlat = "flat"
+)
+
+// Config is the config for the Elasticsearch collector
+type Config struct {
+	// Connection.
+	URL      null.String `json:"url" envconfig:"ELASTICSEARCH_URL"`
+	Username null.String `json:"username,omitempty" envconfig:"ELASTICSEARCH_USERNAME"`
+	Password null.String `json:"password,omitempty" envconfig:"ELASTICSEARCH_PASSWORD"`
+	Insecure null.Bool   `json:"insecure,omitempty" envconfig:"ELASTICSEARCH_INSECURE"`
+
+	// Documents.
+	Index          null.String `json:"index" envconfig:"ELASTICSEARCH_INDEX"`
+	DocumentType   null.String `json:"document_type,omitempty" envconfig:"ELASTICSEARCH_DOCUMENT_TYPE"`
+	Mapping        null.String `json:"mapping" envconfig:"ELASTICSEARCH_MAPPING"`
+	TimestampField null.String `json:"timestamp_field" envconfig:"ELASTICSEARCH_TIMESTAMP_FIELD"`
+
+	// Batching.
+	PushInterval types.NullDuration `json:"push_interval" envconfig:"ELASTICSEARCH_PUSH_INTERVAL"`
+	BatchSize    null.Int           `json:"batch_size" envconfig:"ELASTICSEARCH_BATCH_SIZE"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		URL:            null.NewString("http://localhost:9200", false),
+		Index:          null.NewString("k6-{2006.01.02}", false),
+		Mapping:        null.NewString(MappingNested, false),
+		TimestampField: null.NewString("@timestamp", false),
+		PushInterval:   types.NewNullDuration(1*time.Second, false),
+		BatchSize:      null.NewInt(1000, false),
+	}
+}
+
+// Apply saves config non-zero config values from the passed config in the receiver.
+func (c Config) Apply(cfg Config) Config {
+	if cfg.URL.Valid {
+		c.URL = cfg.URL
+	}
+	if cfg.Username.Valid {
+		c.Username = cfg.Username
+	}
+	if cfg.Password.Valid {
+		c.Password = cfg.Password
+	}
+	if cfg.Insecure.Valid {
+		c.Insecure = cfg.Insecure
+	}
+	if cfg.Index.Valid {
+		c.Index = cfg.Index
+	}
+	if cfg.DocumentType.Valid {
+		c.DocumentType = cfg.DocumentType
+	}
+	if cfg.Mapping.Valid {
+		c.Mapping = cfg.Mapping
+	}
+	if cfg.TimestampField.Valid {
+		c.TimestampField = cfg.TimestampField
+	}
+	if cfg.PushInterval.Valid {
+		c.PushInterval = cfg.PushInterval
+	}
+	if cfg.BatchSize.Valid {
+		c.BatchSize = cfg.BatchSize
+	}
+	return c
+}
+
+// Validate checks the values that can't be used to index documents.
+func (c Config) Validate() error {
+	if c.Mapping.String != MappingNested && c.Mapping.String != MappingFlat {
+		return errors.Errorf("invalid Elasticsearch mapping '%s', use %s or %s", c.Mapping.String, MappingNested, MappingFlat)
+	}
+	if c.TimestampField.String == "" {
+		return errors.New("the Elasticsearch timestamp field can't be empty")
+	}
+	if time.Duration(c.PushInterval.Duration) <= 0 {
+		return errors.New("the Elasticsearch push interval should be positive")
+	}
+	if c.BatchSize.Int64 <= 0 {
+		return errors.New("the Elasticsearch batch size should be positive")
+	}
+	if _, err := parseIndexPattern(c.Index.String); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseArg takes an arg string and converts it to a config. The arg is either just the
+// URL, or key=value pairs like url=http://localhost:9200,index=k6-{2006.01},mapping=flat
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	if !strings.Contains(arg, "=") {
+		c.URL = null.StringFrom(arg)
+		return c, nil
+	}
+
+	params, err := strvals.ParseString(arg)
+	if err != nil {
+		return c, err
+	}
+	for k, v := range params {
+		// Only true and false are parsed, into booleans.
+		var value string
+		switch v := v.(type) {
+		case string:
+			value = v
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			return c, errors.Errorf("invalid value for the Elasticsearch option '%s'", k)
+		}
+		switch k {
+		case "url":
+			c.URL = null.StringFrom(value)
+		case "username":
+			c.Username = null.StringFrom(value)
+		case "password":
+			c.Password = null.StringFrom(value)
+		case "insecure":
+			switch value {
+			case "true", "false":
+				c.Insecure = null.BoolFrom(value == "true")
+			default:
+				return c, errors.Errorf("insecure must be true or false, not %s", value)
+			}
+		case "index":
+			c.Index = null.StringFrom(value)
+		case "document_type":
+			c.DocumentType = null.StringFrom(value)
+		case "mapping":
+			c.Mapping = null.StringFrom(value)
+		case "timestamp_field":
+			c.TimestampField = null.StringFrom(value)
+		case "push_interval":
+			if err := c.PushInterval.UnmarshalText([]byte(value)); err != nil {
+				return c, err
+			}
+		case "batch_size":
+			if err := c.BatchSize.UnmarshalText([]byte(value)); err != nil {
+				return c, err
+			}
+		default:
+			return c, errors.Errorf("unknown Elasticsearch option '%s'", k)
+		}
+	}
+	return c, nil
+}
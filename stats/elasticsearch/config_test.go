@@ -0,0 +1,74 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package elasticsearch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestConfigParseArg(t *testing.T) {
+	c, err := ParseArg("http://localhost:9200")
+	require.NoError(t, err)
+	assert.Equal(t, Config{URL: null.StringFrom("http://localhost:9200")}, c)
+
+	c, err = ParseArg("url=https://es:9200,username=k6,password=secret,insecure=true,index=perf-{2006.01},document_type=_doc,mapping=flat,timestamp_field=time,push_interval=5s,batch_size=500")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		URL:            null.StringFrom("https://es:9200"),
+		Username:       null.StringFrom("k6"),
+		Password:       null.StringFrom("secret"),
+		Insecure:       null.BoolFrom(true),
+		Index:          null.StringFrom("perf-{2006.01}"),
+		DocumentType:   null.StringFrom("_doc"),
+		Mapping:        null.StringFrom("flat"),
+		TimestampField: null.StringFrom("time"),
+		PushInterval:   types.NullDurationFrom(5 * time.Second),
+		BatchSize:      null.IntFrom(500),
+	}, c)
+
+	_, err = ParseArg("url=http://localhost:9200,foo=bar")
+	assert.EqualError(t, err, "unknown Elasticsearch option 'foo'")
+	_, err = ParseArg("insecure=yes")
+	assert.EqualError(t, err, "insecure must be true or false, not yes")
+	_, err = ParseArg("batch_size=many")
+	assert.Error(t, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, NewConfig().Validate())
+	testdata := map[string]Config{
+		"invalid Elasticsearch mapping 'deep', use nested or flat": {Mapping: null.StringFrom("deep")},
+		"the Elasticsearch timestamp field can't be empty":         {TimestampField: null.StringFrom("")},
+		"the Elasticsearch batch size should be positive":          {BatchSize: null.IntFrom(0)},
+		"the Elasticsearch push interval should be positive":       {PushInterval: types.NullDurationFrom(0)},
+		"the Elasticsearch index can't be empty":                   {Index: null.StringFrom("")},
+		"unclosed '{' in the Elasticsearch index 'k6-{2006'":       {Index: null.StringFrom("k6-{2006")},
+	}
+	for expErr, conf := range testdata {
+		assert.EqualError(t, NewConfig().Apply(conf).Validate(), expErr)
+	}
+}
@@ -0,0 +1,271 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/stats/aggregator"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// dialTimeout and writeTimeout limit how long a flush waits for an unavailable server.
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 10 * time.Second
+	// maxBufferedLines is how many lines are kept while the server is unavailable, the oldest
+	// ones are dropped after that.
+	maxBufferedLines = 100000
+)
+
+// Collector sends the samples to Graphite with its plaintext protocol. Graphite keeps a single
+// value per time slot, so they're aggregated per flush interval and metric: counters are summed,
+// gauges keep their last value, rates are the ratio of non-zero values, and trends are sent as
+// the count, min, max and avg sub-metrics.
+type Collector struct {
+	Config Config
+
+	conn    net.Conn
+	pending []string
+
+	buckets *aggregator.Buckets
+}
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// New creates a new Graphite collector.
+func New(conf Config) (*Collector, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	var tags func(*stats.SampleTags) *stats.SampleTags
+	if !conf.Tagged.Bool {
+		// Without tags, the samples of a metric are aggregated together.
+		tags = func(*stats.SampleTags) *stats.SampleTags { return nil }
+	}
+	return &Collector{
+		Config:  conf,
+		buckets: aggregator.NewBuckets(time.Duration(conf.FlushInterval.Duration), tags),
+	}, nil
+}
+
+// Init does nothing, the connection is made with the first flush, so that it's retried
+func (c *Collector) Init() error { return nil }
+
+// SetRunStatus does nothing in the Graphite collector
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+// Run periodically sends the aggregated samples, until the context is done. Graphite keeps the
+// last value for a time slot, so a bucket is only sent once it's complete, and an interval
+// after that to include the samples that arrive late. The rest are sent at the end.
+func (c *Collector) Run(ctx context.Context) {
+	log.WithField("addr", c.Config.Addr.String).Debug("Graphite: Running!")
+	ticker := time.NewTicker(time.Duration(c.Config.FlushInterval.Duration))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			c.flush(now.Add(-time.Duration(c.Config.FlushInterval.Duration)))
+		case <-ctx.Done():
+			c.flush(time.Time{})
+			if c.conn != nil {
+				_ = c.conn.Close()
+			}
+			return
+		}
+	}
+}
+
+// Collect aggregates the samples until the next flush
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	for _, sc := range scs {
+		c.buckets.Add(sc.GetSamples())
+	}
+}
+
+// Link returns a dummy string, it's only included to satisfy the lib.Collector interface
+func (c *Collector) Link() string {
+	return ""
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
+
+// flush sends the lines of the buckets that ended at or before the given time, or of all of
+// them if it's zero, and the ones that couldn't be sent before, in batches. When the connection
+// fails, it's made again once, and the lines that weren't sent are kept for the next flush.
+func (c *Collector) flush(before time.Time) {
+	lines := c.pending
+	for _, b := range c.buckets.Flush(before) {
+		lines = append(lines, c.lines(b)...)
+	}
+	c.pending = nil
+	if len(lines) == 0 {
+		return
+	}
+
+	startTime := time.Now()
+	batchSize := int(c.Config.BatchSize.Int64)
+	for sent := 0; sent < len(lines); {
+		n := len(lines) - sent
+		if n > batchSize {
+			n = batchSize
+		}
+		if err := c.write(lines[sent : sent+n]); err != nil {
+			c.keepPending(lines[sent:])
+			log.WithError(err).WithField("lines", len(c.pending)).Warn("Graphite: Couldn't send the metrics, they'll be retried")
+			return
+		}
+		sent += n
+	}
+	log.WithFields(log.Fields{"t": time.Since(startTime), "lines": len(lines)}).Debug("Graphite: Sent!")
+}
+
+func (c *Collector) keepPending(lines []string) {
+	if dropped := len(lines) - maxBufferedLines; dropped > 0 {
+		log.WithField("lines", dropped).Warn("Graphite: Dropped the oldest metrics, the server has been unavailable for too long")
+		lines = lines[dropped:]
+	}
+	c.pending = append([]string{}, lines...)
+}
+
+// write writes a batch of lines with a single write, reconnecting if the connection was lost.
+func (c *Collector) write(lines []string) error {
+	var buf bytes.Buffer
+	for _, line := range lines {
+		_, _ = buf.WriteString(line)
+		_ = buf.WriteByte('\n')
+	}
+
+	if c.conn != nil && !connAlive(c.conn) {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if c.conn, err = net.DialTimeout("tcp", c.Config.Addr.String, dialTimeout); err != nil {
+				c.conn = nil
+				return err
+			}
+		}
+		_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err = c.conn.Write(buf.Bytes()); err == nil {
+			return nil
+		}
+		// The server may have closed the connection since the last flush, so it's made again.
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+	return err
+}
+
+// connAlive checks if the server has closed the connection. Writes to a closed connection
+// usually succeed the first time, so without it the first batch after that would be lost.
+// Carbon never writes to the connection, so any read that doesn't time out means it's closed.
+func connAlive(conn net.Conn) bool {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	_, err := conn.Read(make([]byte, 1))
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return true
+	}
+	return false
+}
+
+// lines returns the plaintext protocol lines of a bucket.
+func (c *Collector) lines(b *aggregator.Bucket) []string {
+	formatFloat := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	var values [][2]string
+	switch b.Metric.Type {
+	case stats.Counter:
+		values = [][2]string{{"", formatFloat(b.Sum)}}
+	case stats.Gauge:
+		values = [][2]string{{"", formatFloat(b.Last.Value)}}
+	case stats.Rate:
+		values = [][2]string{{"", formatFloat(b.NonZero / b.Count)}}
+	default:
+		values = [][2]string{
+			{".count", formatFloat(b.Count)},
+			{".min", formatFloat(b.Min)},
+			{".max", formatFloat(b.Max)},
+			{".avg", formatFloat(b.Sum / b.Count)},
+		}
+	}
+
+	var tags string
+	if !b.Tags.IsEmpty() {
+		tagMap := b.Tags.CloneTags()
+		keys := make([]string, 0, len(tagMap))
+		for k := range tagMap {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if v := sanitizeTagValue(tagMap[k]); v != "" {
+				tags += ";" + sanitizeName(k) + "=" + v
+			}
+		}
+	}
+
+	path := c.Config.Prefix.String + sanitizeName(b.Metric.Name)
+	timestamp := strconv.FormatInt(b.Time.Unix(), 10)
+	lines := make([]string, 0, len(values))
+	for _, v := range values {
+		lines = append(lines, path+v[0]+tags+" "+v[1]+" "+timestamp)
+	}
+	return lines
+}
+
+// sanitizeName replaces the characters that can't be in a Graphite path or tag name.
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', ';', '!', '^', '=':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// sanitizeTagValue replaces the characters that can't be in a Graphite tag value, which also
+// can't be empty or start with a ~.
+func sanitizeTagValue(value string) string {
+	value = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', ';':
+			return '_'
+		}
+		return r
+	}, value)
+	return strings.TrimLeft(value, "~")
+}
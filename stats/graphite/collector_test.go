@@ -0,0 +1,220 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// testServer is a fake Carbon server, which sends the lines it receives to a channel. Every
+// connection is closed after it has received closeAfter lines, if that's positive.
+func testServer(t *testing.T, addr string, closeAfter int) (net.Listener, chan string) {
+	listener, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+	lines := make(chan string, 1000)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				scanner := bufio.NewScanner(conn)
+				for n := 1; scanner.Scan(); n++ {
+					lines <- scanner.Text()
+					if n == closeAfter {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener, lines
+}
+
+func receive(t *testing.T, lines chan string, n int) []string {
+	var received []string
+	for i := 0; i < n; i++ {
+		select {
+		case line := <-lines:
+			received = append(received, line)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only received %d of %d lines: %v", i, n, received)
+		}
+	}
+	sort.Strings(received)
+	return received
+}
+
+func testSamples(tm time.Time) stats.Samples {
+	ok := stats.IntoSampleTags(&map[string]string{"status": "200", "name": "http://test.k6.io/my page"})
+	failed := stats.IntoSampleTags(&map[string]string{"status": "500"})
+	reqs := stats.New("http_reqs", stats.Counter)
+	vus := stats.New("vus", stats.Gauge)
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	checks := stats.New("checks", stats.Rate)
+	return stats.Samples{
+		{Metric: reqs, Time: tm, Value: 1, Tags: ok},
+		{Metric: reqs, Time: tm, Value: 1, Tags: failed},
+		{Metric: vus, Time: tm.Add(time.Second), Value: 5},
+		{Metric: vus, Time: tm, Value: 3},
+		{Metric: duration, Time: tm, Value: 10, Tags: ok},
+		{Metric: duration, Time: tm, Value: 30, Tags: failed},
+		{Metric: checks, Time: tm, Value: 1},
+		{Metric: checks, Time: tm, Value: 0},
+		{Metric: checks, Time: tm, Value: 1},
+		{Metric: checks, Time: tm, Value: 1},
+	}
+}
+
+func TestCollector(t *testing.T) {
+	listener, lines := testServer(t, "127.0.0.1:0", 0)
+	defer func() { _ = listener.Close() }()
+	tm := time.Unix(1500000005, 0)
+
+	t.Run("Aggregated", func(t *testing.T) {
+		c, err := New(NewConfig().Apply(Config{Addr: null.StringFrom(listener.Addr().String())}))
+		require.NoError(t, err)
+		require.NoError(t, c.Init())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			c.Run(ctx)
+			close(done)
+		}()
+		c.Collect([]stats.SampleContainer{testSamples(tm)})
+		cancel()
+		<-done
+
+		assert.Equal(t, []string{
+			"k6.checks 0.75 1500000000",
+			"k6.http_req_duration.avg 20 1500000000",
+			"k6.http_req_duration.count 2 1500000000",
+			"k6.http_req_duration.max 30 1500000000",
+			"k6.http_req_duration.min 10 1500000000",
+			"k6.http_reqs 2 1500000000",
+			"k6.vus 5 1500000000",
+		}, receive(t, lines, 7))
+	})
+
+	t.Run("Tagged", func(t *testing.T) {
+		c, err := New(NewConfig().Apply(Config{
+			Addr:   null.StringFrom(listener.Addr().String()),
+			Prefix: null.StringFrom("test."),
+			Tagged: null.BoolFrom(true),
+		}))
+		require.NoError(t, err)
+		c.Collect([]stats.SampleContainer{testSamples(tm)[:2]})
+		c.flush(time.Time{})
+		assert.Equal(t, []string{
+			"test.http_reqs;name=http://test.k6.io/my_page;status=200 1 1500000000",
+			"test.http_reqs;status=500 1 1500000000",
+		}, receive(t, lines, 2))
+	})
+}
+
+func TestCollectorPartialBuckets(t *testing.T) {
+	c, err := New(NewConfig().Apply(Config{
+		Addr:          null.StringFrom("127.0.0.1:1"),
+		FlushInterval: types.NullDurationFrom(time.Second),
+	}))
+	require.NoError(t, err)
+	counter := stats.New("http_reqs", stats.Counter)
+	for _, sec := range []int64{1500000000, 1500000001, 1500000002} {
+		c.Collect([]stats.SampleContainer{stats.Sample{Metric: counter, Time: time.Unix(sec, 0), Value: 1}})
+	}
+
+	// The bucket of the last second is still filling and is kept for a later flush.
+	c.flush(time.Unix(1500000002, 0))
+	sort.Strings(c.pending)
+	assert.Equal(t, []string{"k6.http_reqs 1 1500000000", "k6.http_reqs 1 1500000001"}, c.pending)
+	require.Equal(t, 1, c.buckets.Len())
+
+	c.Collect([]stats.SampleContainer{stats.Sample{Metric: counter, Time: time.Unix(1500000002, 0), Value: 1}})
+	c.pending = nil
+	c.flush(time.Time{})
+	assert.Equal(t, []string{"k6.http_reqs 2 1500000002"}, c.pending)
+	assert.Equal(t, 0, c.buckets.Len())
+}
+
+func TestCollectorReconnect(t *testing.T) {
+	// The server closes every connection after the first line.
+	listener, lines := testServer(t, "127.0.0.1:0", 1)
+	addr := listener.Addr().String()
+	c, err := New(NewConfig().Apply(Config{Addr: null.StringFrom(addr)}))
+	require.NoError(t, err)
+	defer func() {
+		if c.conn != nil {
+			_ = c.conn.Close()
+		}
+	}()
+
+	gauge := stats.New("vus", stats.Gauge)
+	send := func(value float64) {
+		c.Collect([]stats.SampleContainer{stats.Sample{Metric: gauge, Time: time.Unix(1500000000, 0), Value: value}})
+		c.flush(time.Time{})
+	}
+	send(1)
+	assert.Equal(t, []string{"k6.vus 1 1500000000"}, receive(t, lines, 1))
+	time.Sleep(50 * time.Millisecond) // Let the server close the connection.
+	send(2)
+	assert.Equal(t, []string{"k6.vus 2 1500000000"}, receive(t, lines, 1))
+
+	// While the server is down, the lines are kept until it's up again.
+	require.NoError(t, listener.Close())
+	time.Sleep(50 * time.Millisecond)
+	send(3)
+	send(4)
+	assert.Equal(t, []string{"k6.vus 3 1500000000", "k6.vus 4 1500000000"}, c.pending)
+
+	listener, lines = testServer(t, addr, 0)
+	defer func() { _ = listener.Close() }()
+	send(5)
+	assert.Equal(t, []string{"k6.vus 3 1500000000", "k6.vus 4 1500000000", "k6.vus 5 1500000000"}, receive(t, lines, 3))
+	assert.Empty(t, c.pending)
+}
+
+func TestCollectorMaxBufferedLines(t *testing.T) {
+	defer func(max int) { maxBufferedLines = max }(maxBufferedLines)
+	maxBufferedLines = 2
+
+	c, err := New(NewConfig().Apply(Config{Addr: null.StringFrom("127.0.0.1:1")}))
+	require.NoError(t, err)
+	gauge := stats.New("vus", stats.Gauge)
+	for i := 1; i <= 3; i++ {
+		c.Collect([]stats.SampleContainer{stats.Sample{Metric: gauge, Time: time.Unix(int64(1500000000+10*i), 0), Value: 1}})
+		c.flush(time.Time{})
+	}
+	assert.Equal(t, []string{"k6.vus 1 1500000020", "k6.vus 1 1500000030"}, c.pending)
+}
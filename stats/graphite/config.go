@@ -0,0 +1,139 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubernetes/helm/pkg/strvals"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// Config is the config for the Graphite collector
+type Config struct {
+	Addr   null.String `json:"addr" envconfig:"GRAPHITE_ADDR"`
+	Prefix null.String `json:"prefix" envconfig:"GRAPHITE_PREFIX"`
+	// Tagged sends the tags of the samples as Graphite 1.1 tags, otherwise they're aggregated.
+	Tagged null.Bool `json:"tagged" envconfig:"GRAPHITE_TAGGED"`
+
+	FlushInterval types.NullDuration `json:"flush_interval" envconfig:"GRAPHITE_FLUSH_INTERVAL"`
+	BatchSize     null.Int           `json:"batch_size" envconfig:"GRAPHITE_BATCH_SIZE"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Addr:          null.NewString("localhost:2003", false),
+		Prefix:        null.NewString("k6.", false),
+		Tagged:        null.NewBool(false, false),
+		FlushInterval: types.NewNullDuration(10*time.Second, false),
+		BatchSize:     null.NewInt(1000, false),
+	}
+}
+
+// Apply saves config non-zero config values from the passed config in the receiver.
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Addr.Valid {
+		c.Addr = cfg.Addr
+	}
+	if cfg.Prefix.Valid {
+		c.Prefix = cfg.Prefix
+	}
+	if cfg.Tagged.Valid {
+		c.Tagged = cfg.Tagged
+	}
+	if cfg.FlushInterval.Valid {
+		c.FlushInterval = cfg.FlushInterval
+	}
+	if cfg.BatchSize.Valid {
+		c.BatchSize = cfg.BatchSize
+	}
+	return c
+}
+
+// Validate checks the values that metrics can't be sent with.
+func (c Config) Validate() error {
+	if c.Addr.String == "" {
+		return errors.New("the Graphite address can't be empty")
+	}
+	if time.Duration(c.FlushInterval.Duration) <= 0 {
+		return errors.New("the Graphite flush interval should be positive")
+	}
+	if c.BatchSize.Int64 <= 0 {
+		return errors.New("the Graphite batch size should be positive")
+	}
+	return nil
+}
+
+// ParseArg takes an arg string and converts it to a config. The arg is either just the
+// address, or key=value pairs like addr=graphite:2003,prefix=loadtest.,flush_interval=1m
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	if !strings.Contains(arg, "=") {
+		c.Addr = null.StringFrom(arg)
+		return c, nil
+	}
+
+	params, err := strvals.ParseString(arg)
+	if err != nil {
+		return c, err
+	}
+	for k, v := range params {
+		// Only true and false are parsed, into booleans.
+		var value string
+		switch v := v.(type) {
+		case string:
+			value = v
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			return c, errors.Errorf("invalid value for the Graphite option '%s'", k)
+		}
+		switch k {
+		case "addr":
+			c.Addr = null.StringFrom(value)
+		case "prefix":
+			c.Prefix = null.StringFrom(value)
+		case "tagged":
+			switch value {
+			case "true", "false":
+				c.Tagged = null.BoolFrom(value == "true")
+			default:
+				return c, errors.Errorf("tagged must be true or false, not %s", value)
+			}
+		case "flush_interval":
+			if err := c.FlushInterval.UnmarshalText([]byte(value)); err != nil {
+				return c, err
+			}
+		case "batch_size":
+			if err := c.BatchSize.UnmarshalText([]byte(value)); err != nil {
+				return c, err
+			}
+		default:
+			return c, errors.Errorf("unknown Graphite option '%s'", k)
+		}
+	}
+	return c, nil
+}
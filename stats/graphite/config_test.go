@@ -0,0 +1,66 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestConfigParseArg(t *testing.T) {
+	c, err := ParseArg("graphite:2003")
+	require.NoError(t, err)
+	assert.Equal(t, Config{Addr: null.StringFrom("graphite:2003")}, c)
+
+	c, err = ParseArg("addr=graphite:2003,prefix=loadtest.,tagged=true,flush_interval=1m,batch_size=500")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Addr:          null.StringFrom("graphite:2003"),
+		Prefix:        null.StringFrom("loadtest."),
+		Tagged:        null.BoolFrom(true),
+		FlushInterval: types.NullDurationFrom(time.Minute),
+		BatchSize:     null.IntFrom(500),
+	}, c)
+
+	_, err = ParseArg("addr=graphite:2003,port=2004")
+	assert.EqualError(t, err, "unknown Graphite option 'port'")
+	_, err = ParseArg("tagged=yes")
+	assert.EqualError(t, err, "tagged must be true or false, not yes")
+	_, err = ParseArg("flush_interval=soon")
+	assert.Error(t, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, NewConfig().Validate())
+	testdata := map[string]Config{
+		"the Graphite address can't be empty":            {Addr: null.StringFrom("")},
+		"the Graphite flush interval should be positive": {FlushInterval: types.NullDurationFrom(0)},
+		"the Graphite batch size should be positive":     {BatchSize: null.IntFrom(-1)},
+	}
+	for expErr, conf := range testdata {
+		assert.EqualError(t, NewConfig().Apply(conf).Validate(), expErr)
+	}
+}
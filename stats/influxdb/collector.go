@@ -31,9 +31,8 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-const (
-	pushInterval = 1 * time.Second
-)
+// The backoff before the first retry of a failed write, it's doubled for every next one.
+var retryBackoff = 1 * time.Second
 
 // Verify that Collector implements lib.Collector
 var _ lib.Collector = &Collector{}
@@ -45,6 +44,7 @@ type Collector struct {
 
 	buffer     []stats.Sample
 	bufferLock sync.Mutex
+	batches    chan []stats.Sample
 }
 
 func New(conf Config) (*Collector, error) {
@@ -61,6 +61,11 @@ func New(conf Config) (*Collector, error) {
 }
 
 func (c *Collector) Init() error {
+	// The buckets of InfluxDB 2.x can't be created with InfluxQL, they have to already exist.
+	if c.Config.Organization.Valid {
+		return nil
+	}
+
 	// Try to create the database if it doesn't exist. Failure to do so is USUALLY harmless; it
 	// usually means we're either a non-admin user to an existing DB or connecting over UDP.
 	_, err := c.Client.Query(client.NewQuery("CREATE DATABASE "+c.BatchConf.Database, "", ""))
@@ -71,15 +76,41 @@ func (c *Collector) Init() error {
 	return nil
 }
 
+// Run commits the buffered samples every push interval, in batches that are written by a
+// number of concurrent writers. When the context is done, the remaining samples are committed
+// and it only returns after all of the batches have been written.
 func (c *Collector) Run(ctx context.Context) {
 	log.Debug("InfluxDB: Running!")
-	ticker := time.NewTicker(pushInterval)
+	writers := int(c.Config.ConcurrentWrites.Int64)
+	if writers < 1 {
+		writers = 1
+	}
+	c.batches = make(chan []stats.Sample, writers)
+	wg := sync.WaitGroup{}
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for samples := range c.batches {
+				c.write(ctx, samples)
+			}
+		}()
+	}
+
+	interval := time.Duration(c.Config.PushInterval.Duration)
+	if interval <= 0 {
+		interval = 1 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			c.commit()
 		case <-ctx.Done():
 			c.commit()
+			close(c.batches)
+			wg.Wait()
 			return
 		}
 	}
@@ -97,26 +128,63 @@ func (c *Collector) Link() string {
 	return c.Config.Addr.String
 }
 
+// commit splits the buffered samples into batches and passes them to the writers. It blocks
+// while all writers are busy, so the samples are buffered until they can keep up again.
 func (c *Collector) commit() {
 	c.bufferLock.Lock()
 	samples := c.buffer
 	c.buffer = nil
 	c.bufferLock.Unlock()
 
-	log.Debug("InfluxDB: Committing...")
+	log.WithField("samples", len(samples)).Debug("InfluxDB: Committing...")
+
+	batchSize := int(c.Config.BatchSize.Int64)
+	if batchSize < 1 {
+		batchSize = len(samples)
+	}
+	for len(samples) > 0 {
+		size := batchSize
+		if size > len(samples) {
+			size = len(samples)
+		}
+		if len(c.batches) == cap(c.batches) {
+			log.Debug("InfluxDB: All writers are busy, the samples are coming in faster than they're written")
+		}
+		c.batches <- samples[:size]
+		samples = samples[size:]
+	}
+}
 
+// write writes a batch of samples, and retries with a backoff if that fails with a network or a
+// server error. Once the context is done, the batch isn't retried anymore.
+func (c *Collector) write(ctx context.Context, samples []stats.Sample) {
 	batch, err := c.batchFromSamples(samples)
 	if err != nil {
 		return
 	}
 
-	log.WithField("points", len(batch.Points())).Debug("InfluxDB: Writing...")
-	startTime := time.Now()
-	if err := c.Client.Write(batch); err != nil {
-		log.WithError(err).Error("InfluxDB: Couldn't write stats")
+	backoff := retryBackoff
+	for retry := int64(0); ; retry++ {
+		log.WithField("points", len(batch.Points())).Debug("InfluxDB: Writing...")
+		startTime := time.Now()
+		err := c.Client.Write(batch)
+		if err == nil {
+			log.WithField("t", time.Since(startTime)).Debug("InfluxDB: Batch written!")
+			return
+		}
+		if retry >= c.Config.MaxRetries.Int64 || !isRetryable(err) || ctx.Err() != nil {
+			log.WithError(err).WithField("points", len(batch.Points())).Error("InfluxDB: Couldn't write stats")
+			return
+		}
+		log.WithError(err).WithField("retry_in", backoff).Warn("InfluxDB: Couldn't write stats, retrying...")
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			log.WithError(err).WithField("points", len(batch.Points())).Error("InfluxDB: Couldn't write stats")
+			return
+		}
+		backoff *= 2
 	}
-	t := time.Since(startTime)
-	log.WithField("t", t).Debug("InfluxDB: Batch written!")
 }
 
 func (c *Collector) extractTagsToValues(tags map[string]string, values map[string]interface{}) map[string]interface{} {
@@ -0,0 +1,179 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// testServer is a fake InfluxDB server that records the points of the writes it accepts, and
+// fails the given number of writes first with the status code.
+func testServer(
+	t *testing.T, failures, status int,
+) (*httptest.Server, func() (writes int, points [][]byte)) {
+	var lock sync.Mutex
+	var writes int
+	var points [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/write" {
+			rw.WriteHeader(http.StatusOK)
+			return
+		}
+		var body bytes.Buffer
+		_, err := io.Copy(&body, r.Body)
+		require.NoError(t, err)
+
+		lock.Lock()
+		defer lock.Unlock()
+		writes++
+		if failures > 0 {
+			failures--
+			rw.WriteHeader(status)
+			return
+		}
+		points = append(points, bytes.Split(bytes.TrimSpace(body.Bytes()), []byte("\n"))...)
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	return srv, func() (int, [][]byte) {
+		lock.Lock()
+		defer lock.Unlock()
+		return writes, points
+	}
+}
+
+func runCollector(t *testing.T, conf Config, samples stats.Samples) {
+	c, err := New(NewConfig().Apply(conf))
+	require.NoError(t, err)
+	require.NoError(t, c.Init())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	c.Collect([]stats.SampleContainer{samples})
+	cancel()
+	<-done
+}
+
+func testSamples(count int) stats.Samples {
+	metric := stats.New("my_metric", stats.Trend)
+	samples := make(stats.Samples, count)
+	for i := range samples {
+		samples[i] = stats.Sample{Time: time.Unix(int64(i), 0), Metric: metric, Value: float64(i)}
+	}
+	return samples
+}
+
+func TestCollectorBatches(t *testing.T) {
+	srv, results := testServer(t, 0, 0)
+	defer srv.Close()
+
+	runCollector(t, Config{
+		Addr:             null.StringFrom(srv.URL),
+		PushInterval:     types.NullDurationFrom(time.Hour),
+		BatchSize:        null.IntFrom(2),
+		ConcurrentWrites: null.IntFrom(2),
+	}, testSamples(5))
+
+	// All of the samples are written when the collector stops, in batches of 2.
+	writes, points := results()
+	assert.Equal(t, 3, writes)
+	assert.Len(t, points, 5)
+}
+
+func TestCollectorRetries(t *testing.T) {
+	defer func(backoff time.Duration) { retryBackoff = backoff }(retryBackoff)
+	retryBackoff = 10 * time.Millisecond
+
+	write := func(ctx context.Context, t *testing.T, srv *httptest.Server, samples stats.Samples) {
+		c, err := New(NewConfig().Apply(Config{Addr: null.StringFrom(srv.URL), MaxRetries: null.IntFrom(2)}))
+		require.NoError(t, err)
+		c.write(ctx, samples)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		srv, results := testServer(t, 2, http.StatusInternalServerError)
+		defer srv.Close()
+
+		write(context.Background(), t, srv, testSamples(3))
+		writes, points := results()
+		assert.Equal(t, 3, writes)
+		assert.Len(t, points, 3)
+	})
+	t.Run("Failure", func(t *testing.T) {
+		srv, results := testServer(t, 3, http.StatusServiceUnavailable)
+		defer srv.Close()
+
+		write(context.Background(), t, srv, testSamples(3))
+		writes, points := results()
+		assert.Equal(t, 3, writes)
+		assert.Len(t, points, 0)
+	})
+	t.Run("ClientError", func(t *testing.T) {
+		srv, results := testServer(t, 1, http.StatusBadRequest)
+		defer srv.Close()
+
+		// Writes that InfluxDB rejects would be rejected again, so they aren't retried.
+		write(context.Background(), t, srv, testSamples(3))
+		writes, points := results()
+		assert.Equal(t, 1, writes)
+		assert.Len(t, points, 0)
+	})
+	t.Run("NetworkError", func(t *testing.T) {
+		srv, results := testServer(t, 0, 0)
+		srv.Close()
+
+		start := time.Now()
+		write(context.Background(), t, srv, testSamples(3))
+		writes, _ := results()
+		assert.Equal(t, 0, writes)
+		assert.True(t, time.Since(start) >= 3*retryBackoff, "network errors should be retried")
+	})
+	t.Run("Cancelled", func(t *testing.T) {
+		defer func(backoff time.Duration) { retryBackoff = backoff }(retryBackoff)
+		retryBackoff = time.Hour
+		srv, results := testServer(t, 1, http.StatusInternalServerError)
+		defer srv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+		write(ctx, t, srv, testSamples(3))
+		writes, _ := results()
+		assert.Equal(t, 1, writes)
+		assert.True(t, time.Since(start) < time.Hour/2, "the backoff should end with the context")
+	})
+}
@@ -24,6 +24,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kubernetes/helm/pkg/strvals"
 	"github.com/loadimpact/k6/lib/types"
@@ -40,6 +41,17 @@ type Config struct {
 	Insecure    null.Bool   `json:"insecure,omitempty" envconfig:"INFLUXDB_INSECURE"`
 	PayloadSize null.Int    `json:"payloadSize,omitempty" envconfig:"INFLUXDB_PAYLOAD_SIZE"`
 
+	// InfluxDB 2.x, its API is used when an organization is set.
+	Token        null.String `json:"token,omitempty" envconfig:"INFLUXDB_TOKEN"`
+	Organization null.String `json:"organization,omitempty" envconfig:"INFLUXDB_ORGANIZATION"`
+	Bucket       null.String `json:"bucket,omitempty" envconfig:"INFLUXDB_BUCKET"`
+
+	// Writes.
+	PushInterval     types.NullDuration `json:"pushInterval,omitempty" envconfig:"INFLUXDB_PUSH_INTERVAL"`
+	BatchSize        null.Int           `json:"batchSize,omitempty" envconfig:"INFLUXDB_BATCH_SIZE"`
+	ConcurrentWrites null.Int           `json:"concurrentWrites,omitempty" envconfig:"INFLUXDB_CONCURRENT_WRITES"`
+	MaxRetries       null.Int           `json:"maxRetries,omitempty" envconfig:"INFLUXDB_MAX_RETRIES"`
+
 	// Samples.
 	DB           null.String `json:"db" envconfig:"INFLUXDB_DB"`
 	Precision    null.String `json:"precision,omitempty" envconfig:"INFLUXDB_PRECISION"`
@@ -53,6 +65,11 @@ func NewConfig() *Config {
 		Addr:         null.NewString("http://localhost:8086", false),
 		DB:           null.NewString("k6", false),
 		TagsAsFields: []string{"vu", "iter", "url"},
+
+		PushInterval:     types.NewNullDuration(1*time.Second, false),
+		BatchSize:        null.NewInt(5000, false),
+		ConcurrentWrites: null.NewInt(4, false),
+		MaxRetries:       null.NewInt(3, false),
 	}
 	return c
 }
@@ -73,6 +90,27 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.PayloadSize.Valid && cfg.PayloadSize.Int64 > 0 {
 		c.PayloadSize = cfg.PayloadSize
 	}
+	if cfg.Token.Valid {
+		c.Token = cfg.Token
+	}
+	if cfg.Organization.Valid {
+		c.Organization = cfg.Organization
+	}
+	if cfg.Bucket.Valid {
+		c.Bucket = cfg.Bucket
+	}
+	if cfg.PushInterval.Valid {
+		c.PushInterval = cfg.PushInterval
+	}
+	if cfg.BatchSize.Valid && cfg.BatchSize.Int64 > 0 {
+		c.BatchSize = cfg.BatchSize
+	}
+	if cfg.ConcurrentWrites.Valid && cfg.ConcurrentWrites.Int64 > 0 {
+		c.ConcurrentWrites = cfg.ConcurrentWrites
+	}
+	if cfg.MaxRetries.Valid {
+		c.MaxRetries = cfg.MaxRetries
+	}
 	if cfg.DB.Valid {
 		c.DB = cfg.DB
 	}
@@ -155,6 +193,28 @@ func ParseURL(text string) (Config, error) {
 			var size int
 			size, err = strconv.Atoi(vs[0])
 			c.PayloadSize = null.IntFrom(int64(size))
+		case "push_interval":
+			var d time.Duration
+			d, err = time.ParseDuration(vs[0])
+			c.PushInterval = types.NullDurationFrom(d)
+		case "batch_size":
+			var size int
+			size, err = strconv.Atoi(vs[0])
+			c.BatchSize = null.IntFrom(int64(size))
+		case "concurrent_writes":
+			var writes int
+			writes, err = strconv.Atoi(vs[0])
+			c.ConcurrentWrites = null.IntFrom(int64(writes))
+		case "max_retries":
+			var retries int
+			retries, err = strconv.Atoi(vs[0])
+			c.MaxRetries = null.IntFrom(int64(retries))
+		case "token":
+			c.Token = null.StringFrom(vs[0])
+		case "org":
+			c.Organization = null.StringFrom(vs[0])
+		case "bucket":
+			c.Bucket = null.StringFrom(vs[0])
 		case "precision":
 			c.Precision = null.StringFrom(vs[0])
 		case "retention":
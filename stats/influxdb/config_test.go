@@ -22,7 +22,9 @@ package influxdb
 
 import (
 	"testing"
+	"time"
 
+	"github.com/loadimpact/k6/lib/types"
 	"github.com/stretchr/testify/assert"
 	null "gopkg.in/guregu/null.v3"
 )
@@ -59,12 +61,21 @@ func TestParseURL(t *testing.T) {
 		Config Config
 		Err    string
 	}{
-		"?":                {Config{}, ""},
-		"?insecure=false":  {Config{Insecure: null.BoolFrom(false)}, ""},
-		"?insecure=true":   {Config{Insecure: null.BoolFrom(true)}, ""},
-		"?insecure=ture":   {Config{}, "insecure must be true or false, not ture"},
-		"?payload_size=69": {Config{PayloadSize: null.IntFrom(69)}, ""},
-		"?payload_size=a":  {Config{}, "strconv.Atoi: parsing \"a\": invalid syntax"},
+		"?":                    {Config{}, ""},
+		"?insecure=false":      {Config{Insecure: null.BoolFrom(false)}, ""},
+		"?insecure=true":       {Config{Insecure: null.BoolFrom(true)}, ""},
+		"?insecure=ture":       {Config{}, "insecure must be true or false, not ture"},
+		"?payload_size=69":     {Config{PayloadSize: null.IntFrom(69)}, ""},
+		"?payload_size=a":      {Config{}, "strconv.Atoi: parsing \"a\": invalid syntax"},
+		"?push_interval=5s":    {Config{PushInterval: types.NullDurationFrom(5 * time.Second)}, ""},
+		"?batch_size=1000":     {Config{BatchSize: null.IntFrom(1000)}, ""},
+		"?concurrent_writes=8": {Config{ConcurrentWrites: null.IntFrom(8)}, ""},
+		"?max_retries=0":       {Config{MaxRetries: null.IntFrom(0)}, ""},
+		"?org=myorg&bucket=k6&token=secret": {Config{
+			Organization: null.StringFrom("myorg"),
+			Bucket:       null.StringFrom("k6"),
+			Token:        null.StringFrom("secret"),
+		}, ""},
 	}
 	for str, data := range testdata {
 		t.Run(str, func(t *testing.T) {
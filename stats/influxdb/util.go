@@ -28,6 +28,12 @@ import (
 )
 
 func MakeClient(conf Config) (client.Client, error) {
+	if conf.Organization.Valid {
+		if conf.Addr.String == "" {
+			conf.Addr = null.StringFrom("http://localhost:8086")
+		}
+		return newV2Client(conf)
+	}
 	if strings.HasPrefix(conf.Addr.String, "udp://") {
 		return client.NewUDPClient(client.UDPConfig{
 			Addr:        strings.TrimPrefix(conf.Addr.String, "udp://"),
@@ -37,7 +43,7 @@ func MakeClient(conf Config) (client.Client, error) {
 	if conf.Addr.String == "" {
 		conf.Addr = null.StringFrom("http://localhost:8086")
 	}
-	return client.NewHTTPClient(client.HTTPConfig{
+	return newV1Client(client.HTTPConfig{
 		Addr:               conf.Addr.String,
 		Username:           conf.Username.String,
 		Password:           conf.Password.String,
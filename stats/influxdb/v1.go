@@ -0,0 +1,130 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+
+	client "github.com/influxdata/influxdb/client/v2"
+	"github.com/pkg/errors"
+)
+
+// statusError is returned when InfluxDB responds to a request with an unexpected status code.
+type statusError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Body)
+}
+
+// isRetryable returns whether a failed write may succeed if it's retried, which is the case for
+// network and server errors, but not for writes that InfluxDB rejected.
+func isRetryable(err error) bool {
+	switch e := errors.Cause(err).(type) {
+	case *statusError:
+		return e.StatusCode >= http.StatusInternalServerError
+	case net.Error:
+		return true
+	default:
+		return false
+	}
+}
+
+// doRequest sends the request, and returns a *statusError if the response isn't successful.
+func doRequest(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp, &statusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: bytes.TrimSpace(body)}
+	}
+	return resp, nil
+}
+
+// v1Client is the HTTP client of the 1.x API. The points are written by it, since the client
+// of the influxdb package drops the status code of failed writes, everything else is left to it.
+type v1Client struct {
+	client.Client
+
+	url        url.URL
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+func newV1Client(conf client.HTTPConfig) (*v1Client, error) {
+	cl, err := client.NewHTTPClient(conf)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(conf.Addr)
+	if err != nil {
+		return nil, err
+	}
+	return &v1Client{
+		Client:     cl,
+		url:        *u,
+		username:   conf.Username,
+		password:   conf.Password,
+		httpClient: newHTTPClient(conf.InsecureSkipVerify),
+	}, nil
+}
+
+// Write writes the points in the batch to the database, in the line protocol.
+func (c *v1Client) Write(bp client.BatchPoints) error {
+	var body bytes.Buffer
+	for _, p := range bp.Points() {
+		_, _ = body.WriteString(p.PrecisionString(bp.Precision()))
+		_ = body.WriteByte('\n')
+	}
+
+	u := c.url
+	u.Path = path.Join(u.Path, "write")
+	req, err := http.NewRequest("POST", u.String(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "k6")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	params := req.URL.Query()
+	params.Set("db", bp.Database())
+	params.Set("rp", bp.RetentionPolicy())
+	params.Set("precision", bp.Precision())
+	params.Set("consistency", bp.WriteConsistency())
+	req.URL.RawQuery = params.Encode()
+
+	_, err = doRequest(c.httpClient, req)
+	return err
+}
@@ -0,0 +1,153 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	client "github.com/influxdata/influxdb/client/v2"
+	"github.com/pkg/errors"
+)
+
+// v2Client writes points with the write API of InfluxDB 2.x, to a bucket of an organization
+// and with token authentication. It doesn't support queries, since 2.x only has Flux ones.
+type v2Client struct {
+	url          url.URL
+	token        string
+	organization string
+	bucket       string
+	httpClient   *http.Client
+}
+
+var _ client.Client = &v2Client{}
+
+func newV2Client(conf Config) (*v2Client, error) {
+	u, err := url.Parse(conf.Addr.String)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("unsupported protocol scheme %s for the InfluxDB 2 API, use http or https", u.Scheme)
+	}
+	if conf.Organization.String == "" {
+		return nil, errors.New("an organization is required for the InfluxDB 2 API")
+	}
+	bucket := conf.Bucket.String
+	if bucket == "" {
+		bucket = conf.DB.String
+	}
+	return &v2Client{
+		url:          *u,
+		token:        conf.Token.String,
+		organization: conf.Organization.String,
+		bucket:       bucket,
+		httpClient:   newHTTPClient(conf.Insecure.Bool),
+	}, nil
+}
+
+func newHTTPClient(insecure bool) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+	}}
+}
+
+func (c *v2Client) newRequest(method, endpoint string, body []byte) (*http.Request, error) {
+	u := c.url
+	u.Path = path.Join(u.Path, endpoint)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "k6")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Token "+c.token)
+	}
+	return req, nil
+}
+
+// Ping checks that the server is up with the /ping endpoint, which InfluxDB 2.x still has.
+func (c *v2Client) Ping(timeout time.Duration) (time.Duration, string, error) {
+	start := time.Now()
+	req, err := c.newRequest("GET", "ping", nil)
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := doRequest(c.httpClient, req)
+	if err != nil {
+		return 0, "", err
+	}
+	return time.Since(start), resp.Header.Get("X-Influxdb-Version"), nil
+}
+
+// Write writes the points in the batch to the bucket, in the line protocol.
+func (c *v2Client) Write(bp client.BatchPoints) error {
+	precision, pointPrecision := v2Precision(bp.Precision())
+	var body bytes.Buffer
+	for _, p := range bp.Points() {
+		_, _ = body.WriteString(p.PrecisionString(pointPrecision))
+		_ = body.WriteByte('\n')
+	}
+
+	req, err := c.newRequest("POST", "api/v2/write", body.Bytes())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	params := req.URL.Query()
+	params.Set("org", c.organization)
+	params.Set("bucket", c.bucket)
+	params.Set("precision", precision)
+	req.URL.RawQuery = params.Encode()
+
+	_, err = doRequest(c.httpClient, req)
+	return err
+}
+
+// Query isn't supported, the buckets of InfluxDB 2.x can't be created with InfluxQL.
+func (c *v2Client) Query(q client.Query) (*client.Response, error) {
+	return nil, errors.New("InfluxQL queries aren't supported with the InfluxDB 2 API")
+}
+
+// Close does nothing, there's nothing to release.
+func (c *v2Client) Close() error {
+	return nil
+}
+
+// v2Precision converts the precision of the 1.x API to the one of the 2.x API, and to the one
+// the points should be formatted with. Minutes and hours aren't supported, so they are seconds.
+func v2Precision(precision string) (string, string) {
+	switch precision {
+	case "u", "us":
+		return "us", "u"
+	case "ms":
+		return "ms", "ms"
+	case "s", "m", "h":
+		return "s", "s"
+	default:
+		return "ns", "n"
+	}
+}
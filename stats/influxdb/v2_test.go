@@ -0,0 +1,96 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package influxdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	client "github.com/influxdata/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestV2Client(t *testing.T) {
+	var query, auth, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/write":
+			data, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			query, auth, body = r.URL.RawQuery, r.Header.Get("Authorization"), string(data)
+			rw.WriteHeader(http.StatusNoContent)
+		case "/ping":
+			rw.WriteHeader(http.StatusNoContent)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			_, _ = rw.Write([]byte(`{"code":"not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	conf := NewConfig().Apply(Config{
+		Addr:         null.StringFrom(srv.URL),
+		Organization: null.StringFrom("myorg"),
+		Bucket:       null.StringFrom("k6"),
+		Token:        null.StringFrom("secret"),
+	})
+	cl, err := MakeClient(conf)
+	require.NoError(t, err)
+	require.IsType(t, &v2Client{}, cl)
+
+	_, _, err = cl.Ping(time.Second)
+	require.NoError(t, err)
+
+	for precision, expected := range map[string]struct{ query, line string }{
+		"ns": {"bucket=k6&org=myorg&precision=ns", "test value=1 1500000000000000000"},
+		"ms": {"bucket=k6&org=myorg&precision=ms", "test value=1 1500000000000"},
+		"m":  {"bucket=k6&org=myorg&precision=s", "test value=1 1500000000"},
+	} {
+		t.Run(precision, func(t *testing.T) {
+			bp, err := client.NewBatchPoints(client.BatchPointsConfig{Precision: precision})
+			require.NoError(t, err)
+			p, err := client.NewPoint("test", nil, map[string]interface{}{"value": 1.0}, time.Unix(1500000000, 0))
+			require.NoError(t, err)
+			bp.AddPoint(p)
+
+			require.NoError(t, cl.Write(bp))
+			assert.Equal(t, expected.query, query)
+			assert.Equal(t, "Token secret", auth)
+			assert.Equal(t, expected.line+"\n", body)
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		v2, err := newV2Client(Config{Addr: null.StringFrom(srv.URL + "/missing"), Organization: null.StringFrom("myorg")})
+		require.NoError(t, err)
+		bp, err := client.NewBatchPoints(client.BatchPointsConfig{})
+		require.NoError(t, err)
+		assert.EqualError(t, v2.Write(bp), `404 Not Found: {"code":"not found"}`)
+
+		_, err = newV2Client(Config{Addr: null.StringFrom("udp://localhost:8089"), Organization: null.StringFrom("myorg")})
+		assert.EqualError(t, err, "unsupported protocol scheme udp for the InfluxDB 2 API, use http or https")
+	})
+}
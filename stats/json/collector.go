@@ -25,6 +25,7 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"sort"
 
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/stats"
@@ -36,6 +37,7 @@ type Collector struct {
 	outfile     io.WriteCloser
 	fname       string
 	seenMetrics []string
+	thresholds  map[string]stats.Thresholds
 }
 
 // Verify that Collector implements lib.Collector
@@ -75,6 +77,15 @@ func New(fs afero.Fs, fname string) (*Collector, error) {
 	}, nil
 }
 
+// NewFromWriter creates a collector that writes to the supplied writer, e.g. to stream the
+// metrics over a network connection. The writer isn't closed when the collector is done.
+func NewFromWriter(w io.Writer) *Collector {
+	return &Collector{
+		outfile: nopCloser{w},
+		fname:   "-",
+	}
+}
+
 func (c *Collector) Init() error {
 	return nil
 }
@@ -87,12 +98,39 @@ func (c *Collector) Run(ctx context.Context) {
 	_ = c.outfile.Close()
 }
 
+// SetThresholds sets the thresholds of the test, which are written with the metrics they are
+// defined on, so that they can be evaluated again when the output is read. An extra metric
+// envelope is written for every threshold on a submetric.
+func (c *Collector) SetThresholds(thresholds map[string]stats.Thresholds) {
+	c.thresholds = thresholds
+}
+
 func (c *Collector) HandleMetric(m *stats.Metric) {
 	if c.HasSeenMetric(m.Name) {
 		return
 	}
 
 	c.seenMetrics = append(c.seenMetrics, m.Name)
+	if ts, ok := c.thresholds[m.Name]; ok {
+		withThresholds := *m
+		withThresholds.Thresholds = ts
+		m = &withThresholds
+	}
+	c.writeMetric(m)
+
+	var submetrics []string
+	for name := range c.thresholds {
+		if parent, _ := stats.NewSubmetric(name); parent == m.Name && name != m.Name {
+			submetrics = append(submetrics, name)
+		}
+	}
+	sort.Strings(submetrics)
+	for _, name := range submetrics {
+		c.writeMetric(&stats.Metric{Name: name, Type: m.Type, Contains: m.Contains, Thresholds: c.thresholds[name]})
+	}
+}
+
+func (c *Collector) writeMetric(m *stats.Metric) {
 	env := WrapMetric(m)
 	row, err := json.Marshal(env)
 
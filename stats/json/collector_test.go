@@ -21,9 +21,11 @@
 package json
 
 import (
+	"bytes"
 	"os"
 	"testing"
 
+	"github.com/loadimpact/k6/stats"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 )
@@ -50,3 +52,13 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestNewFromWriter(t *testing.T) {
+	var buf bytes.Buffer
+	collector := NewFromWriter(&buf)
+	collector.HandleMetric(stats.New("my_counter", stats.Counter))
+
+	res, err := ReadResults(&buf)
+	assert.NoError(t, err)
+	assert.Contains(t, res.Metrics, "my_counter")
+}
@@ -0,0 +1,267 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package json
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// Results contains the metrics that were reconstructed from a JSON output file,
+// along with the time span covered by the samples in it.
+type Results struct {
+	Metrics    map[string]*stats.Metric
+	Start, End time.Time
+
+	// The groups and checks, recreated from the tags of the check samples. The tree is
+	// empty if the group and check system tags were disabled for the original test run.
+	RootGroup *lib.Group
+
+	// The submetrics of the thresholds, by the names of their parent metrics. Their metrics
+	// are created with the first matching point, with the thresholds that were read for them.
+	submetrics map[string][]*stats.Submetric
+	thresholds map[string]stats.Thresholds
+
+	lock sync.Mutex
+}
+
+// Duration returns the amount of time between the first and the last sample.
+func (r *Results) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// metricData is the part of a "Metric" envelope that we need to recreate the metric.
+type metricData struct {
+	Name       string           `json:"name"`
+	Type       stats.MetricType `json:"type"`
+	Contains   stats.ValueType  `json:"contains"`
+	Thresholds json.RawMessage  `json:"thresholds"`
+}
+
+type rawEnvelope struct {
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+	Metric string          `json:"metric"`
+}
+
+// ReadResults parses the newline-delimited envelopes written by the JSON collector and
+// feeds every point into the sink of its metric, so that the same summary data that
+// was available at the end of the original test run can be calculated again.
+func ReadResults(r io.Reader) (*Results, error) {
+	res := NewResults()
+	if err := res.Read(r); err != nil {
+		return nil, err
+	}
+	res.Calc()
+	return res, nil
+}
+
+// NewResults returns empty results, to which the output of one or more test runs can be
+// added with Read().
+func NewResults() *Results {
+	root, _ := lib.NewGroup("", nil) // the root group name is always valid
+	return &Results{
+		Metrics:    make(map[string]*stats.Metric),
+		RootGroup:  root,
+		submetrics: make(map[string][]*stats.Submetric),
+		thresholds: make(map[string]stats.Thresholds),
+	}
+}
+
+// Read adds all envelopes from the supplied reader to the results. It can be called
+// concurrently for multiple readers, e.g. to merge the outputs of several k6 instances.
+func (res *Results) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var env rawEnvelope
+		if err := json.Unmarshal(scanner.Bytes(), &env); err != nil {
+			return errors.Wrapf(err, "line %d", line)
+		}
+		if err := res.add(env); err != nil {
+			return errors.Wrapf(err, "line %d", line)
+		}
+	}
+	return scanner.Err()
+}
+
+// add processes a single envelope.
+func (res *Results) add(env rawEnvelope) error {
+	res.lock.Lock()
+	defer res.lock.Unlock()
+
+	switch env.Type {
+	case "Metric":
+		var data metricData
+		if err := json.Unmarshal(env.Data, &data); err != nil {
+			return err
+		}
+		var ts stats.Thresholds
+		if len(data.Thresholds) > 0 && string(data.Thresholds) != "null" && string(data.Thresholds) != "[]" {
+			if err := json.Unmarshal(data.Thresholds, &ts); err != nil {
+				return errors.Wrapf(err, "thresholds of '%s'", data.Name)
+			}
+		}
+		if strings.Contains(data.Name, "{") {
+			res.addSubmetric(data.Name)
+			if _, ok := res.thresholds[data.Name]; !ok && len(ts.Thresholds) > 0 {
+				res.thresholds[data.Name] = ts
+			}
+		} else if _, ok := res.Metrics[data.Name]; !ok {
+			m := stats.New(data.Name, data.Type, data.Contains)
+			m.Thresholds = ts
+			res.Metrics[data.Name] = m
+		}
+	case "Point":
+		m, ok := res.Metrics[env.Metric]
+		if !ok {
+			return errors.Errorf("point for unknown metric '%s'", env.Metric)
+		}
+		var sample JSONSample
+		if err := json.Unmarshal(env.Data, &sample); err != nil {
+			return err
+		}
+		m.Sink.Add(stats.Sample{Metric: m, Time: sample.Time, Tags: sample.Tags, Value: sample.Value})
+		for _, sm := range res.submetrics[m.Name] {
+			if !sample.Tags.Contains(sm.Tags) {
+				continue
+			}
+			if sm.Metric == nil {
+				sm.Metric = stats.New(sm.Name, m.Type, m.Contains)
+				sm.Metric.Sub = stats.Submetric{Name: sm.Name, Parent: sm.Parent, Suffix: sm.Suffix, Tags: sm.Tags}
+				sm.Metric.Thresholds = res.thresholds[sm.Name]
+				res.Metrics[sm.Name] = sm.Metric
+			}
+			sm.Metric.Sink.Add(stats.Sample{Metric: sm.Metric, Time: sample.Time, Tags: sample.Tags, Value: sample.Value})
+		}
+		if m.Name == metrics.Checks.Name {
+			if err := addCheckResult(res.RootGroup, sample); err != nil {
+				return err
+			}
+		}
+
+		if res.Start.IsZero() || sample.Time.Before(res.Start) {
+			res.Start = sample.Time
+		}
+		if sample.Time.After(res.End) {
+			res.End = sample.Time
+		}
+	default:
+		return errors.Errorf("unknown envelope type '%s'", env.Type)
+	}
+	return nil
+}
+
+// AddSubmetric registers the submetric with the supplied name, e.g. one that has thresholds,
+// so that the matching points of its parent metric are added to it. Only the points that are
+// read after it's registered are added.
+func (res *Results) AddSubmetric(name string) {
+	res.lock.Lock()
+	defer res.lock.Unlock()
+	res.addSubmetric(name)
+}
+
+// addSubmetric registers the submetric with the supplied name, unless it already exists, so
+// that the matching points of its parent metric are added to it.
+func (res *Results) addSubmetric(name string) {
+	parent, sm := stats.NewSubmetric(name)
+	for _, existing := range res.submetrics[parent] {
+		if existing.Name == name {
+			return
+		}
+	}
+	res.submetrics[parent] = append(res.submetrics[parent], sm)
+}
+
+// Calc calculates the final values of all metric sinks and evaluates the thresholds that
+// were read with the metrics. It should be called after all outputs were read.
+func (res *Results) Calc() {
+	res.lock.Lock()
+	defer res.lock.Unlock()
+	t := res.Duration()
+	for name, m := range res.Metrics {
+		m.Sink.Calc()
+		if len(m.Thresholds.Thresholds) == 0 {
+			continue
+		}
+		succ, err := m.Thresholds.Run(m.Sink, t)
+		if err != nil {
+			log.WithField("m", name).WithError(err).Error("Threshold error")
+			continue
+		}
+		m.Tainted = null.BoolFrom(!succ)
+	}
+}
+
+// Process calls fn with the metrics and the time span of the samples read so far, while no
+// envelopes can be added, so that they can be inspected while outputs are still being read.
+func (res *Results) Process(fn func(metrics map[string]*stats.Metric, t time.Duration)) {
+	res.lock.Lock()
+	defer res.lock.Unlock()
+	fn(res.Metrics, res.Duration())
+}
+
+// addCheckResult finds the check from the sample tags in the group tree, creating it and
+// its groups if needed, and counts the sample as a pass or a failure.
+func addCheckResult(root *lib.Group, sample JSONSample) error {
+	name, ok := sample.Tags.Get("check")
+	if !ok {
+		return nil
+	}
+	path, _ := sample.Tags.Get("group")
+
+	group := root
+	if path != "" {
+		for _, groupName := range strings.Split(path, lib.GroupSeparator)[1:] {
+			var err error
+			if group, err = group.Group(groupName); err != nil {
+				return err
+			}
+		}
+	}
+	check, err := group.Check(name)
+	if err != nil {
+		return err
+	}
+	if sample.Value != 0 {
+		check.Passes++
+	} else {
+		check.Fails++
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package json
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadResults(t *testing.T) {
+	var buf bytes.Buffer
+	c := &Collector{outfile: nopCloser{&buf}, fname: "-"}
+
+	trend := stats.New("my_trend", stats.Trend, stats.Time)
+	counter := stats.New("my_counter", stats.Counter)
+	now := time.Now()
+	tags := stats.IntoSampleTags(&map[string]string{"tag": "value"})
+	c.Collect([]stats.SampleContainer{
+		stats.Sample{Metric: trend, Time: now, Tags: tags, Value: 10},
+		stats.Sample{Metric: trend, Time: now.Add(time.Second), Value: 20},
+		stats.Sample{Metric: counter, Time: now.Add(2 * time.Second), Value: 3},
+	})
+
+	res, err := ReadResults(&buf)
+	require.NoError(t, err)
+	require.Len(t, res.Metrics, 2)
+	assert.Equal(t, 2*time.Second, res.Duration())
+
+	m := res.Metrics["my_trend"]
+	require.NotNil(t, m)
+	assert.Equal(t, stats.Time, m.Contains)
+	sink, ok := m.Sink.(*stats.TrendSink)
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), sink.Count)
+	assert.Equal(t, 15.0, sink.Med)
+
+	assert.Equal(t, 3.0, res.Metrics["my_counter"].Sink.(*stats.CounterSink).Value)
+
+	t.Run("Checks", func(t *testing.T) {
+		var buf bytes.Buffer
+		c := &Collector{outfile: nopCloser{&buf}, fname: "-"}
+		checkTags := func(group, check string) *stats.SampleTags {
+			return stats.IntoSampleTags(&map[string]string{"group": group, "check": check})
+		}
+		c.Collect([]stats.SampleContainer{
+			stats.Sample{Metric: metrics.Checks, Time: now, Tags: checkTags("", "root check"), Value: 1},
+			stats.Sample{Metric: metrics.Checks, Time: now, Tags: checkTags("::outer::inner", "status is 200"), Value: 1},
+			stats.Sample{Metric: metrics.Checks, Time: now, Tags: checkTags("::outer::inner", "status is 200"), Value: 0},
+			stats.Sample{Metric: metrics.Checks, Time: now, Value: 1},
+		})
+
+		res, err := ReadResults(&buf)
+		require.NoError(t, err)
+		root := res.RootGroup
+		require.Contains(t, root.Checks, "root check")
+		assert.Equal(t, int64(1), root.Checks["root check"].Passes)
+
+		require.Contains(t, root.Groups, "outer")
+		require.Contains(t, root.Groups["outer"].Groups, "inner")
+		check := root.Groups["outer"].Groups["inner"].Checks["status is 200"]
+		require.NotNil(t, check)
+		assert.Equal(t, "::outer::inner::status is 200", check.Path)
+		assert.Equal(t, int64(1), check.Passes)
+		assert.Equal(t, int64(1), check.Fails)
+	})
+	t.Run("Process", func(t *testing.T) {
+		res.Process(func(metrics map[string]*stats.Metric, d time.Duration) {
+			assert.Len(t, metrics, 2)
+			assert.Equal(t, 2*time.Second, d)
+		})
+	})
+	t.Run("Thresholds", func(t *testing.T) {
+		thresholds := make(map[string]stats.Thresholds)
+		for name, sources := range map[string][]string{
+			"my_trend":             {"p(95)<15", "min<15"},
+			"my_trend{tag:value}":  {"max<15"},
+			"my_trend{tag:other}":  {"max<1"},
+			"my_counter{tag:none}": {"count<1"},
+		} {
+			ts, err := stats.NewThresholds(sources)
+			require.NoError(t, err)
+			thresholds[name] = ts
+		}
+
+		var buf bytes.Buffer
+		c := &Collector{outfile: nopCloser{&buf}, fname: "-"}
+		c.SetThresholds(thresholds)
+		c.Collect([]stats.SampleContainer{
+			stats.Sample{Metric: trend, Time: now, Tags: tags, Value: 10},
+			stats.Sample{Metric: trend, Time: now.Add(time.Second), Value: 20},
+		})
+
+		res, err := ReadResults(&buf)
+		require.NoError(t, err)
+		m := res.Metrics["my_trend"]
+		require.NotNil(t, m)
+		assert.True(t, m.Tainted.Bool)
+		require.Len(t, m.Thresholds.Thresholds, 2)
+		assert.True(t, m.Thresholds.Thresholds[0].LastFailed)
+		assert.False(t, m.Thresholds.Thresholds[1].LastFailed)
+
+		sub := res.Metrics["my_trend{tag:value}"]
+		require.NotNil(t, sub, "the submetric should have its matching point")
+		assert.Equal(t, "my_trend", sub.Sub.Parent)
+		assert.Equal(t, uint64(1), sub.Sink.(*stats.TrendSink).Count)
+		assert.False(t, sub.Tainted.Bool)
+		assert.NotContains(t, res.Metrics, "my_trend{tag:other}", "no point matches the submetric")
+		assert.NotContains(t, res.Metrics, "my_counter{tag:none}", "the metric had no points")
+	})
+	t.Run("UnknownMetric", func(t *testing.T) {
+		_, err := ReadResults(strings.NewReader(`{"type":"Point","metric":"nope","data":{"value":1}}`))
+		assert.EqualError(t, err, "line 1: point for unknown metric 'nope'")
+	})
+	t.Run("Garbage", func(t *testing.T) {
+		_, err := ReadResults(strings.NewReader("\n{"))
+		assert.Error(t, err)
+	})
+}
@@ -113,9 +113,15 @@ func ParseArg(arg string) (Config, error) {
 		return c, err
 	}
 
+	// Only set the values that were specified, so they don't override the ones from the
+	// config file or the environment with empty ones.
 	c.Brokers = cfg.Brokers
-	c.Topic = null.StringFrom(cfg.Topic)
-	c.Format = null.StringFrom(cfg.Format)
+	if cfg.Topic != "" {
+		c.Topic = null.StringFrom(cfg.Topic)
+	}
+	if cfg.Format != "" {
+		c.Format = null.StringFrom(cfg.Format)
+	}
 
 	return c, nil
 }
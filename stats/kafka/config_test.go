@@ -62,4 +62,13 @@ func TestConfigParseArg(t *testing.T) {
 	assert.Equal(t, null.StringFrom("someTopic"), c.Topic)
 	assert.Equal(t, null.StringFrom("influxdb"), c.Format)
 	assert.Equal(t, expInfluxConfig, c.InfluxDBConfig)
+
+	c, err = ParseArg("brokers=broker1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"broker1"}, c.Brokers)
+	assert.False(t, c.Topic.Valid)
+	assert.False(t, c.Format.Valid)
+	conf := NewConfig().Apply(Config{Topic: null.StringFrom("envTopic")}).Apply(c)
+	assert.Equal(t, null.StringFrom("envTopic"), conf.Topic)
+	assert.Equal(t, null.StringFrom("json"), conf.Format)
 }
@@ -0,0 +1,200 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package newrelic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/stats/aggregator"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Collector posts the samples to the New Relic metric API. They're aggregated per push interval,
+// metric and tags: counters are counts, gauges keep their last value, and trends and rates are
+// summaries, which have the count, sum, min and max of the values.
+type Collector struct {
+	Config Config
+
+	url        string
+	httpClient *http.Client
+
+	buckets *aggregator.Buckets
+}
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// New creates a new New Relic collector.
+func New(conf Config) (*Collector, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	url, err := conf.GetURL()
+	if err != nil {
+		return nil, err
+	}
+	return &Collector{
+		Config:     conf,
+		url:        url,
+		httpClient: &http.Client{Timeout: time.Minute},
+		buckets:    aggregator.NewBuckets(time.Duration(conf.PushInterval.Duration), nil),
+	}, nil
+}
+
+// Init does nothing, it's only included to satisfy the lib.Collector interface
+func (c *Collector) Init() error { return nil }
+
+// SetRunStatus does nothing in the New Relic collector
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+// Run periodically posts the aggregated samples of the push intervals that are over, until the
+// context is done, when the rest of them are posted.
+func (c *Collector) Run(ctx context.Context) {
+	log.WithField("url", c.url).Debug("New Relic: Running!")
+	interval := time.Duration(c.Config.PushInterval.Duration)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			// The samples of the last interval can still be arriving.
+			c.pushMetrics(now.Add(-interval))
+		case <-ctx.Done():
+			c.pushMetrics(time.Time{})
+			return
+		}
+	}
+}
+
+// Collect aggregates the samples until the next push interval
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	for _, sc := range scs {
+		c.buckets.Add(sc.GetSamples())
+	}
+}
+
+// Link returns a dummy string, it's only included to satisfy the lib.Collector interface
+func (c *Collector) Link() string {
+	return ""
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
+
+// pushMetrics posts the buckets that ended at or before the given time, or all of them if it's
+// zero.
+func (c *Collector) pushMetrics(before time.Time) {
+	buckets := c.buckets.Flush(before)
+	if len(buckets) == 0 {
+		return
+	}
+
+	startTime := time.Now()
+	metrics := make([]metric, 0, len(buckets))
+	for _, b := range buckets {
+		metrics = append(metrics, c.metric(b))
+	}
+	if err := c.post(metrics); err != nil {
+		log.WithError(err).Error("New Relic: Couldn't post the metrics")
+		return
+	}
+	log.WithFields(log.Fields{"t": time.Since(startTime), "metrics": len(metrics)}).Debug("New Relic: Posted!")
+}
+
+// metric is a metric of the New Relic metric API.
+type metric struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Value      interface{}       `json:"value"`
+	Timestamp  int64             `json:"timestamp"`
+	IntervalMs int64             `json:"interval.ms,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+type summary struct {
+	Count float64 `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+func (c *Collector) metric(b *aggregator.Bucket) metric {
+	m := metric{
+		Name:       c.Config.Prefix.String + b.Metric.Name,
+		Timestamp:  b.Time.UnixNano() / 1e6,
+		IntervalMs: int64(time.Duration(c.Config.PushInterval.Duration) / time.Millisecond),
+		Attributes: b.Tags.CloneTags(),
+	}
+	switch b.Metric.Type {
+	case stats.Counter:
+		m.Type, m.Value = "count", b.Sum
+	case stats.Gauge:
+		// Gauges are instantaneous, so they're at the time of their last value.
+		m.Type, m.Value = "gauge", b.Last.Value
+		m.Timestamp, m.IntervalMs = b.Last.Time.UnixNano()/1e6, 0
+	default:
+		m.Type, m.Value = "summary", summary{Count: b.Count, Sum: b.Sum, Min: b.Min, Max: b.Max}
+	}
+	return m
+}
+
+// post sends the metrics with a single, gzipped, request.
+func (c *Collector) post(metrics []metric) error {
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	if err := json.NewEncoder(gz).Encode([]map[string][]metric{{"metrics": metrics}}); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Api-Key", c.Config.APIKey.String)
+	req.Header.Set("User-Agent", "k6")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	return nil
+}
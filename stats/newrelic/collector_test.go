@@ -0,0 +1,131 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package newrelic
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestCollector(t *testing.T) {
+	payloads := make(chan []map[string][]map[string]interface{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("Api-Key"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		var payload []map[string][]map[string]interface{}
+		require.NoError(t, json.NewDecoder(gz).Decode(&payload))
+		payloads <- payload
+		rw.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	c, err := New(NewConfig().Apply(Config{APIKey: null.StringFrom("secret"), URL: null.StringFrom(srv.URL)}))
+	require.NoError(t, err)
+	require.NoError(t, c.Init())
+
+	tm := time.Date(2019, 3, 7, 12, 0, 5, 0, time.UTC)
+	tags := stats.IntoSampleTags(&map[string]string{"status": "200"})
+	reqs := stats.New("http_reqs", stats.Counter)
+	vus := stats.New("vus", stats.Gauge)
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	checks := stats.New("checks", stats.Rate)
+	samples := stats.Samples{
+		{Metric: reqs, Time: tm, Value: 1, Tags: tags},
+		{Metric: reqs, Time: tm.Add(time.Second), Value: 1, Tags: tags},
+		{Metric: vus, Time: tm.Add(2 * time.Second), Value: 5},
+		{Metric: vus, Time: tm, Value: 3},
+		{Metric: duration, Time: tm, Value: 10, Tags: tags},
+		{Metric: duration, Time: tm, Value: 30, Tags: tags},
+		{Metric: checks, Time: tm, Value: 1},
+		{Metric: checks, Time: tm, Value: 0},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	c.Collect([]stats.SampleContainer{samples})
+	cancel()
+	<-done
+
+	payload := <-payloads
+	require.Len(t, payload, 1)
+	metrics := map[string]map[string]interface{}{}
+	for _, m := range payload[0]["metrics"] {
+		metrics[m["name"].(string)] = m
+	}
+	start := float64(time.Date(2019, 3, 7, 12, 0, 0, 0, time.UTC).UnixNano() / 1e6)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"k6.http_reqs": {
+			"name": "k6.http_reqs", "type": "count", "value": 2.0,
+			"timestamp": start, "interval.ms": 10000.0, "attributes": map[string]interface{}{"status": "200"},
+		},
+		"k6.vus": {
+			"name": "k6.vus", "type": "gauge", "value": 5.0, "timestamp": float64(tm.Add(2*time.Second).UnixNano() / 1e6),
+		},
+		"k6.http_req_duration": {
+			"name": "k6.http_req_duration", "type": "summary",
+			"value":     map[string]interface{}{"count": 2.0, "sum": 40.0, "min": 10.0, "max": 30.0},
+			"timestamp": start, "interval.ms": 10000.0, "attributes": map[string]interface{}{"status": "200"},
+		},
+		"k6.checks": {
+			"name": "k6.checks", "type": "summary",
+			"value":     map[string]interface{}{"count": 2.0, "sum": 1.0, "min": 0.0, "max": 1.0},
+			"timestamp": start, "interval.ms": 10000.0,
+		},
+	}, metrics)
+
+	// The buckets of an interval that isn't over yet are kept for a later push.
+	c.Collect([]stats.SampleContainer{samples})
+	c.pushMetrics(tm.Add(2 * time.Second))
+	assert.Empty(t, payloads)
+	assert.Equal(t, 4, c.buckets.Len())
+	c.pushMetrics(tm.Add(5 * time.Second))
+	assert.Len(t, payloads, 1)
+	assert.Equal(t, 0, c.buckets.Len())
+}
+
+func TestCollectorErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusForbidden)
+		_, _ = rw.Write([]byte(`{"requestId":"1","error":{"title":"Invalid API key"}}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(NewConfig().Apply(Config{APIKey: null.StringFrom("wrong"), URL: null.StringFrom(srv.URL)}))
+	require.NoError(t, err)
+	assert.EqualError(t, c.post([]metric{{Name: "k6.vus", Type: "gauge", Value: 1.0}}),
+		`403 Forbidden: {"requestId":"1","error":{"title":"Invalid API key"}}`)
+}
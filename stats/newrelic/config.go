@@ -0,0 +1,136 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package newrelic
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kubernetes/helm/pkg/strvals"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// The endpoints of the metric API in the New Relic regions.
+var regionURLs = map[string]string{
+	"US": "https://metric-api.newrelic.com/metric/v1",
+	"EU": "https://metric-api.eu.newrelic.com/metric/v1",
+}
+
+// Config is the config for the New Relic collector
+type Config struct {
+	APIKey null.String `json:"api_key,omitempty" envconfig:"NEWRELIC_API_KEY"`
+	Region null.String `json:"region" envconfig:"NEWRELIC_REGION"`
+	// URL overrides the endpoint of the region, for other services with a compatible API.
+	URL null.String `json:"url,omitempty" envconfig:"NEWRELIC_URL"`
+
+	Prefix       null.String        `json:"prefix" envconfig:"NEWRELIC_PREFIX"`
+	PushInterval types.NullDuration `json:"push_interval" envconfig:"NEWRELIC_PUSH_INTERVAL"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Region:       null.NewString("US", false),
+		Prefix:       null.NewString("k6.", false),
+		PushInterval: types.NewNullDuration(10*time.Second, false),
+	}
+}
+
+// Apply saves config non-zero config values from the passed config in the receiver.
+func (c Config) Apply(cfg Config) Config {
+	if cfg.APIKey.Valid {
+		c.APIKey = cfg.APIKey
+	}
+	if cfg.Region.Valid {
+		c.Region = cfg.Region
+	}
+	if cfg.URL.Valid {
+		c.URL = cfg.URL
+	}
+	if cfg.Prefix.Valid {
+		c.Prefix = cfg.Prefix
+	}
+	if cfg.PushInterval.Valid {
+		c.PushInterval = cfg.PushInterval
+	}
+	return c
+}
+
+// GetURL returns the endpoint the metrics are posted to.
+func (c Config) GetURL() (string, error) {
+	if c.URL.String != "" {
+		return c.URL.String, nil
+	}
+	url, ok := regionURLs[strings.ToUpper(c.Region.String)]
+	if !ok {
+		return "", errors.Errorf("invalid New Relic region '%s', use US or EU", c.Region.String)
+	}
+	return url, nil
+}
+
+// Validate checks the values that metrics can't be posted with.
+func (c Config) Validate() error {
+	if c.APIKey.String == "" {
+		return errors.New("the New Relic API key isn't set, use the api_key option or K6_NEWRELIC_API_KEY")
+	}
+	if _, err := c.GetURL(); err != nil {
+		return err
+	}
+	if time.Duration(c.PushInterval.Duration) <= 0 {
+		return errors.New("the New Relic push interval should be positive")
+	}
+	return nil
+}
+
+// ParseArg takes an arg string and converts it to a config. The arg consists of key=value
+// pairs like api_key=...,region=EU,prefix=loadtest.,push_interval=30s
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	params, err := strvals.ParseString(arg)
+	if err != nil {
+		return c, err
+	}
+	for k, v := range params {
+		value, ok := v.(string)
+		if !ok {
+			return c, errors.Errorf("invalid value for the New Relic option '%s'", k)
+		}
+		switch k {
+		case "api_key":
+			c.APIKey = null.StringFrom(value)
+		case "region":
+			c.Region = null.StringFrom(value)
+		case "url":
+			c.URL = null.StringFrom(value)
+		case "prefix":
+			c.Prefix = null.StringFrom(value)
+		case "push_interval":
+			if err := c.PushInterval.UnmarshalText([]byte(value)); err != nil {
+				return c, err
+			}
+		default:
+			return c, errors.Errorf("unknown New Relic option '%s'", k)
+		}
+	}
+	return c, nil
+}
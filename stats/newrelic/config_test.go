@@ -0,0 +1,77 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestConfigParseArg(t *testing.T) {
+	c, err := ParseArg("api_key=secret,region=EU,prefix=loadtest.,push_interval=30s")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		APIKey:       null.StringFrom("secret"),
+		Region:       null.StringFrom("EU"),
+		Prefix:       null.StringFrom("loadtest."),
+		PushInterval: types.NullDurationFrom(30 * time.Second),
+	}, c)
+
+	c, err = ParseArg("api_key=secret,url=https://metrics.example.com/v1")
+	require.NoError(t, err)
+	assert.Equal(t, Config{APIKey: null.StringFrom("secret"), URL: null.StringFrom("https://metrics.example.com/v1")}, c)
+
+	_, err = ParseArg("api_key=secret,account=1")
+	assert.EqualError(t, err, "unknown New Relic option 'account'")
+	_, err = ParseArg("push_interval=later")
+	assert.Error(t, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	key := Config{APIKey: null.StringFrom("secret")}
+	for region, url := range map[string]string{
+		"":   "https://metric-api.newrelic.com/metric/v1",
+		"eu": "https://metric-api.eu.newrelic.com/metric/v1",
+	} {
+		conf := NewConfig().Apply(key)
+		if region != "" {
+			conf.Region = null.StringFrom(region)
+		}
+		require.NoError(t, conf.Validate())
+		u, err := conf.GetURL()
+		require.NoError(t, err)
+		assert.Equal(t, url, u)
+	}
+
+	testdata := map[string]Config{
+		"the New Relic API key isn't set, use the api_key option or K6_NEWRELIC_API_KEY": {},
+		"invalid New Relic region 'APAC', use US or EU":                                  key.Apply(Config{Region: null.StringFrom("APAC")}),
+		"the New Relic push interval should be positive":                                 key.Apply(Config{PushInterval: types.NullDurationFrom(0)}),
+	}
+	for expErr, conf := range testdata {
+		assert.EqualError(t, NewConfig().Apply(conf).Validate(), expErr)
+	}
+}
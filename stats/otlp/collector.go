@@ -0,0 +1,405 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/tracing"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/stats/aggregator"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+)
+
+// The paths of the gRPC methods that the metrics and the spans are exported with.
+const (
+	grpcExportMethod      = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+	grpcTraceExportMethod = "/opentelemetry.proto.collector.trace.v1.TraceService/Export"
+)
+
+// maxSpansPerRequest is the maximum number of spans that are exported with a request.
+var maxSpansPerRequest = 1000
+
+// quantiles are the quantiles of the trend summaries, 0 and 1 are the min and max.
+var quantiles = []float64{0, 0.5, 0.9, 0.95, 0.99, 1}
+
+// Collector exports the samples to an OpenTelemetry receiver with OTLP. They're aggregated per
+// push interval, metric and tags: counters are delta sums, gauges keep their last value, and
+// trends and rates are summaries, the trends with their quantiles. The global run tags are the
+// attributes of the resource, instead of the data points. The spans of the requests that are
+// traced with --tracing are exported too, so they can be sent to Jaeger and other receivers of
+// OTLP traces.
+type Collector struct {
+	Config Config
+
+	url        *url.URL
+	tracesURL  *url.URL
+	httpClient *http.Client
+	resource   resource
+	runTags    map[string]string
+
+	buckets *aggregator.Buckets
+	spans   []*tracing.Span
+	lock    sync.Mutex
+}
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// New creates a new OTLP collector, with the resource attributes from the global run tags.
+func New(conf Config, runTags *stats.SampleTags) (*Collector, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	u, err := conf.GetURL()
+	if err != nil {
+		return nil, err
+	}
+	tracesURL, err := conf.GetTracesURL()
+	if err != nil {
+		return nil, err
+	}
+
+	var transport http.RoundTripper
+	tlsConfig := &tls.Config{InsecureSkipVerify: conf.Insecure.Bool}
+	if conf.Protocol.String == ProtocolGRPC {
+		h2 := &http2.Transport{TLSClientConfig: tlsConfig}
+		if u.Scheme == "http" {
+			// gRPC without TLS is HTTP/2 with prior knowledge, over a plain connection.
+			h2.AllowHTTP = true
+			h2.DialTLS = func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			}
+		}
+		transport = h2
+	} else {
+		transport = &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}
+	}
+
+	tags := map[string]string{}
+	if runTags != nil {
+		tags = runTags.CloneTags()
+	}
+	attributes := map[string]string{"service.name": conf.ServiceName.String}
+	for k, v := range tags {
+		attributes[k] = v
+	}
+
+	return &Collector{
+		Config:     conf,
+		url:        u,
+		tracesURL:  tracesURL,
+		httpClient: &http.Client{Transport: transport, Timeout: time.Minute},
+		resource:   resource{Attributes: toAttributes(attributes)},
+		runTags:    tags,
+		buckets:    aggregator.NewBuckets(time.Duration(conf.PushInterval.Duration), nil),
+	}, nil
+}
+
+// toAttributes returns the attributes of the tags, sorted by key.
+func toAttributes(tags map[string]string) []keyValue {
+	if len(tags) == 0 {
+		return nil
+	}
+	attributes := make([]keyValue, 0, len(tags))
+	for k, v := range tags {
+		attributes = append(attributes, keyValue{Key: k, Value: anyValue{StringValue: v}})
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].Key < attributes[j].Key })
+	return attributes
+}
+
+// Init does nothing, it's only included to satisfy the lib.Collector interface
+func (c *Collector) Init() error { return nil }
+
+// SetRunStatus does nothing in the OTLP collector
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+// Run periodically exports the aggregated samples, until the context is done. A bucket is only
+// exported once it's complete, and an interval after that to include the samples that arrive
+// late, so every data point is exported once. The rest are exported at the end.
+func (c *Collector) Run(ctx context.Context) {
+	log.WithField("url", c.url.String()).Debug("OTLP: Running!")
+	interval := time.Duration(c.Config.PushInterval.Duration)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			c.pushMetrics(now.Add(-interval))
+			c.pushSpans()
+		case <-ctx.Done():
+			c.pushMetrics(time.Time{})
+			c.pushSpans()
+			return
+		}
+	}
+}
+
+// Collect aggregates the samples and keeps the spans until the next push interval
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	for _, sc := range scs {
+		if span, ok := sc.(*tracing.Span); ok {
+			c.lock.Lock()
+			c.spans = append(c.spans, span)
+			c.lock.Unlock()
+			continue
+		}
+		if c.Config.Metrics.Bool {
+			c.buckets.Add(sc.GetSamples())
+		}
+	}
+}
+
+// Link returns a dummy string, it's only included to satisfy the lib.Collector interface
+func (c *Collector) Link() string {
+	return ""
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
+
+// pushMetrics exports the buckets that ended at or before the given time, or all of them if
+// it's zero.
+func (c *Collector) pushMetrics(before time.Time) {
+	buckets := c.buckets.Flush(before)
+	if len(buckets) == 0 {
+		return
+	}
+
+	startTime := time.Now()
+	if err := c.export(c.url, c.request(buckets)); err != nil {
+		log.WithError(err).Error("OTLP: Couldn't export the metrics")
+		return
+	}
+	log.WithFields(log.Fields{"t": time.Since(startTime), "points": len(buckets)}).Debug("OTLP: Exported!")
+}
+
+func (c *Collector) pushSpans() {
+	c.lock.Lock()
+	spans := c.spans
+	c.spans = nil
+	c.lock.Unlock()
+
+	for len(spans) > 0 {
+		batch := spans
+		if len(batch) > maxSpansPerRequest {
+			batch = batch[:maxSpansPerRequest]
+		}
+		spans = spans[len(batch):]
+
+		startTime := time.Now()
+		if err := c.export(c.tracesURL, c.traceRequest(batch)); err != nil {
+			log.WithError(err).Error("OTLP: Couldn't export the spans")
+			continue
+		}
+		log.WithFields(log.Fields{"t": time.Since(startTime), "spans": len(batch)}).Debug("OTLP: Exported spans!")
+	}
+}
+
+// unit returns the UCUM unit of the values of a metric.
+func unit(m *stats.Metric) string {
+	switch m.Contains {
+	case stats.Time:
+		return "ms"
+	case stats.Data:
+		return "By"
+	default:
+		return ""
+	}
+}
+
+// request returns the export request of the buckets, with the data points of a metric in the
+// same metric message. The run tags are left out of the attributes of the data points, since
+// they're the attributes of the resource.
+func (c *Collector) request(buckets []*aggregator.Bucket) exportRequest {
+	interval := uint64(time.Duration(c.Config.PushInterval.Duration))
+	metrics := map[string]*metric{}
+	for _, b := range buckets {
+		m, ok := metrics[b.Metric.Name]
+		if !ok {
+			m = &metric{Name: b.Metric.Name, Unit: unit(b.Metric)}
+			switch b.Metric.Type {
+			case stats.Counter:
+				m.Sum = &sum{AggregationTemporality: aggregationTemporalityDelta, IsMonotonic: true}
+			case stats.Gauge:
+				m.Gauge = &gauge{}
+			default:
+				m.Summary = &summary{}
+			}
+			metrics[b.Metric.Name] = m
+		}
+
+		attributes := toAttributes(c.withoutRunTags(b.Tags))
+		start := uint64(b.Time.UnixNano())
+		switch {
+		case m.Sum != nil:
+			m.Sum.DataPoints = append(m.Sum.DataPoints, numberDataPoint{
+				Attributes: attributes, StartTimeUnixNano: start, TimeUnixNano: start + interval, AsDouble: b.Sum,
+			})
+		case m.Gauge != nil:
+			m.Gauge.DataPoints = append(m.Gauge.DataPoints, numberDataPoint{
+				Attributes: attributes, TimeUnixNano: uint64(b.Last.Time.UnixNano()), AsDouble: b.Last.Value,
+			})
+		default:
+			dp := summaryDataPoint{
+				Attributes: attributes, StartTimeUnixNano: start, TimeUnixNano: start + interval,
+				Count: uint64(b.Count), Sum: b.Sum,
+			}
+			if b.Trend != nil {
+				b.Trend.Calc()
+				for _, q := range quantiles {
+					dp.QuantileValues = append(dp.QuantileValues, valueAtQuantile{Quantile: q, Value: b.Trend.P(q)})
+				}
+			}
+			m.Summary.DataPoints = append(m.Summary.DataPoints, dp)
+		}
+	}
+
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sm := scopeMetrics{Scope: scope{Name: "k6"}, Metrics: make([]metric, 0, len(names))}
+	for _, name := range names {
+		sm.Metrics = append(sm.Metrics, *metrics[name])
+	}
+	return exportRequest{ResourceMetrics: []resourceMetrics{{Resource: c.resource, ScopeMetrics: []scopeMetrics{sm}}}}
+}
+
+// traceRequest returns the export request of the spans, with the tags of their requests as
+// attributes, except for the run tags.
+func (c *Collector) traceRequest(spans []*tracing.Span) exportTraceRequest {
+	ss := scopeSpans{Scope: scope{Name: "k6"}, Spans: make([]span, 0, len(spans))}
+	for _, s := range spans {
+		sp := span{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			Name:              s.Name,
+			Kind:              spanKindClient,
+			StartTimeUnixNano: uint64(s.Start.UnixNano()),
+			EndTimeUnixNano:   uint64(s.End.UnixNano()),
+			Attributes:        toAttributes(c.withoutRunTags(s.Tags)),
+		}
+		if s.Error != "" {
+			sp.Status = spanStatus{Message: s.Error, Code: statusCodeError}
+		}
+		ss.Spans = append(ss.Spans, sp)
+	}
+	return exportTraceRequest{ResourceSpans: []resourceSpans{{Resource: c.resource, ScopeSpans: []scopeSpans{ss}}}}
+}
+
+// withoutRunTags returns the tags, except for the run tags, which are already the attributes
+// of the resource.
+func (c *Collector) withoutRunTags(sampleTags *stats.SampleTags) map[string]string {
+	tags := sampleTags.CloneTags()
+	for k, v := range c.runTags {
+		if tags[k] == v {
+			delete(tags, k)
+		}
+	}
+	return tags
+}
+
+// protoMessage is an export request, of the metrics or of the spans.
+type protoMessage interface {
+	appendProto(buf []byte) []byte
+}
+
+// export sends the request to the URL with the configured protocol.
+func (c *Collector) export(u *url.URL, r protoMessage) error {
+	var body []byte
+	contentType := "application/x-protobuf"
+	switch c.Config.Protocol.String {
+	case ProtocolHTTPJSON:
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		body, contentType = data, "application/json"
+	case ProtocolGRPC:
+		msg := r.appendProto(nil)
+		body = make([]byte, 5, 5+len(msg))
+		binary.BigEndian.PutUint32(body[1:], uint32(len(msg)))
+		body, contentType = append(body, msg...), "application/grpc"
+	default:
+		body = r.appendProto(nil)
+	}
+
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range c.Config.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "k6")
+	if c.Config.Protocol.String == ProtocolGRPC {
+		req.Header.Set("TE", "trailers")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	if c.Config.Protocol.String != ProtocolGRPC {
+		return nil
+	}
+
+	// Trailers-only responses have the status in the headers.
+	status, message := resp.Trailer.Get("grpc-status"), resp.Trailer.Get("grpc-message")
+	if status == "" {
+		status, message = resp.Header.Get("grpc-status"), resp.Header.Get("grpc-message")
+	}
+	if status != "0" {
+		if m, err := url.PathUnescape(message); err == nil {
+			message = m
+		}
+		return errors.Errorf("gRPC status %s: %s", status, message)
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlp
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/tracing"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// fields decodes a protobuf message into its fields, the varints and fixed64 values are
+// uint64s and the length-delimited ones are byte slices.
+func fields(t *testing.T, data []byte) map[int][]interface{} {
+	result := map[int][]interface{}{}
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		require.True(t, n > 0)
+		data = data[n:]
+		number := int(tag >> 3)
+		switch tag & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			require.True(t, n > 0)
+			result[number] = append(result[number], v)
+			data = data[n:]
+		case wireFixed64:
+			result[number] = append(result[number], binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(data)
+			require.True(t, n > 0)
+			result[number] = append(result[number], data[n:n+int(l)])
+			data = data[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+	}
+	return result
+}
+
+// field returns the only value of a field of a message.
+func field(t *testing.T, data []byte, path ...int) interface{} {
+	var value interface{} = data
+	for _, number := range path {
+		values := fields(t, value.([]byte))[number]
+		require.Len(t, values, 1, "field %d of %v", number, path)
+		value = values[0]
+	}
+	return value
+}
+
+func testCollector(t *testing.T, conf Config, handler http.HandlerFunc) {
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	runTags := stats.IntoSampleTags(&map[string]string{"testid": "123"})
+	c, err := New(NewConfig().Apply(conf).Apply(Config{Endpoint: null.StringFrom(srv.URL)}), runTags)
+	require.NoError(t, err)
+	require.NoError(t, c.Init())
+
+	tm := time.Unix(1500000005, 0)
+	tags := stats.IntoSampleTags(&map[string]string{"testid": "123", "status": "200"})
+	reqs := stats.New("http_reqs", stats.Counter)
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	samples := stats.Samples{
+		{Metric: reqs, Time: tm, Value: 1, Tags: tags},
+		{Metric: reqs, Time: tm, Value: 1, Tags: tags},
+		{Metric: duration, Time: tm, Value: 10, Tags: tags},
+		{Metric: duration, Time: tm, Value: 30, Tags: tags},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	c.Collect([]stats.SampleContainer{samples})
+	cancel()
+	<-done
+}
+
+func TestCollectorHTTPProtobuf(t *testing.T) {
+	var body []byte
+	testCollector(t, Config{Headers: map[string]string{"api-key": "secret"}}, func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("api-key"))
+		body, _ = ioutil.ReadAll(r.Body)
+	})
+	require.NotNil(t, body)
+
+	rm := field(t, body, 1).([]byte)
+	attributes := fields(t, field(t, rm, 1).([]byte))[1]
+	require.Len(t, attributes, 2)
+	assert.Equal(t, "service.name", string(field(t, attributes[0].([]byte), 1).([]byte)))
+	assert.Equal(t, "k6", string(field(t, attributes[0].([]byte), 2, 1).([]byte)))
+	assert.Equal(t, "testid", string(field(t, attributes[1].([]byte), 1).([]byte)))
+
+	sm := field(t, rm, 2).([]byte)
+	assert.Equal(t, "k6", string(field(t, sm, 1, 1).([]byte)))
+	metrics := fields(t, sm)[2]
+	require.Len(t, metrics, 2)
+
+	// The metrics are sorted by name, so the trend summary is first.
+	trend := metrics[0].([]byte)
+	assert.Equal(t, "http_req_duration", string(field(t, trend, 1).([]byte)))
+	assert.Equal(t, "ms", string(field(t, trend, 3).([]byte)))
+	dp := field(t, trend, 11, 1).([]byte)
+	start := uint64(time.Unix(1500000000, 0).UnixNano())
+	assert.Equal(t, start, field(t, dp, 2))
+	assert.Equal(t, start+uint64(10*time.Second), field(t, dp, 3))
+	assert.Equal(t, uint64(2), field(t, dp, 4))
+	assert.Equal(t, 40.0, math.Float64frombits(field(t, dp, 5).(uint64)))
+	assert.Len(t, fields(t, dp)[6], len(quantiles))
+	assert.Equal(t, "status", string(field(t, dp, 7, 1).([]byte)), "the run tags shouldn't be attributes")
+
+	counter := metrics[1].([]byte)
+	assert.Equal(t, "http_reqs", string(field(t, counter, 1).([]byte)))
+	assert.Equal(t, uint64(aggregationTemporalityDelta), field(t, counter, 7, 2))
+	assert.Equal(t, uint64(1), field(t, counter, 7, 3))
+	assert.Equal(t, 2.0, math.Float64frombits(field(t, counter, 7, 1, 4).(uint64)))
+}
+
+func TestCollectorHTTPJSON(t *testing.T) {
+	var request map[string]interface{}
+	testCollector(t, Config{Protocol: null.StringFrom("http/json")}, func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+	})
+
+	rm := request["resourceMetrics"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "k6"}},
+		map[string]interface{}{"key": "testid", "value": map[string]interface{}{"stringValue": "123"}},
+	}, rm["resource"].(map[string]interface{})["attributes"])
+	metrics := rm["scopeMetrics"].([]interface{})[0].(map[string]interface{})["metrics"].([]interface{})
+	require.Len(t, metrics, 2)
+	assert.Equal(t, map[string]interface{}{
+		"name": "http_reqs",
+		"sum": map[string]interface{}{
+			"dataPoints": []interface{}{map[string]interface{}{
+				"attributes":        []interface{}{map[string]interface{}{"key": "status", "value": map[string]interface{}{"stringValue": "200"}}},
+				"startTimeUnixNano": "1500000000000000000",
+				"timeUnixNano":      "1500000010000000000",
+				"asDouble":          2.0,
+			}},
+			"aggregationTemporality": 1.0,
+			"isMonotonic":            true,
+		},
+	}, metrics[1])
+	summary := metrics[0].(map[string]interface{})["summary"].(map[string]interface{})
+	dp := summary["dataPoints"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "2", dp["count"])
+	assert.Equal(t, 40.0, dp["sum"])
+	assert.Equal(t, map[string]interface{}{"quantile": 0.0, "value": 10.0}, dp["quantileValues"].([]interface{})[0])
+	assert.Equal(t, map[string]interface{}{"quantile": 1.0, "value": 30.0}, dp["quantileValues"].([]interface{})[5])
+}
+
+func TestCollectorPartialBuckets(t *testing.T) {
+	var points []int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		sum := field(t, body, 1, 2, 2, 7).([]byte)
+		points = append(points, len(fields(t, sum)[1]))
+	}))
+	defer srv.Close()
+
+	conf := NewConfig().Apply(Config{Endpoint: null.StringFrom(srv.URL), PushInterval: types.NullDurationFrom(time.Second)})
+	c, err := New(conf, nil)
+	require.NoError(t, err)
+	counter := stats.New("http_reqs", stats.Counter)
+	for _, sec := range []int64{1500000000, 1500000001, 1500000002} {
+		c.Collect([]stats.SampleContainer{stats.Sample{Metric: counter, Time: time.Unix(sec, 0), Value: 1}})
+	}
+
+	// The bucket of the last second is still filling and is kept for a later push.
+	c.pushMetrics(time.Unix(1500000002, 0))
+	assert.Equal(t, []int{2}, points)
+	require.Equal(t, 1, c.buckets.Len())
+
+	c.pushMetrics(time.Time{})
+	assert.Equal(t, []int{2, 1}, points)
+	assert.Equal(t, 0, c.buckets.Len())
+}
+
+func TestCollectorGRPC(t *testing.T) {
+	var body []byte
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		if r.Header.Get("api-key") != "secret" {
+			w.Header().Set("Grpc-Status", "16")
+			w.Header().Set("Grpc-Message", "invalid%20API%20key")
+			return
+		}
+		assert.Equal(t, grpcExportMethod, r.URL.Path)
+		data, _ := ioutil.ReadAll(r.Body)
+		require.True(t, len(data) > 5)
+		assert.Equal(t, uint32(len(data)-5), binary.BigEndian.Uint32(data[1:5]))
+		body = data[5:]
+		_, _ = w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", "0")
+	})
+
+	tlsSrv := httptest.NewUnstartedServer(handler)
+	require.NoError(t, http2.ConfigureServer(tlsSrv.Config, nil))
+	tlsSrv.TLS = &tls.Config{NextProtos: []string{http2.NextProtoTLS}}
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+
+	// Without TLS, the connections are HTTP/2 with prior knowledge.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go (&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+
+	for name, endpoint := range map[string]string{"TLS": tlsSrv.URL, "Plaintext": "http://" + listener.Addr().String()} {
+		t.Run(name, func(t *testing.T) {
+			body = nil
+			conf := NewConfig().Apply(Config{
+				Endpoint: null.StringFrom(endpoint),
+				Protocol: null.StringFrom("grpc"),
+				Insecure: null.BoolFrom(true),
+				Headers:  map[string]string{"api-key": "secret"},
+			})
+			c, err := New(conf, nil)
+			require.NoError(t, err)
+			c.Collect([]stats.SampleContainer{stats.Sample{Metric: stats.New("vus", stats.Gauge), Time: time.Unix(1500000000, 0), Value: 5}})
+			c.pushMetrics(time.Time{})
+			require.NotNil(t, body)
+			gauge := field(t, body, 1, 2, 2).([]byte)
+			assert.Equal(t, "vus", string(field(t, gauge, 1).([]byte)))
+			assert.Equal(t, 5.0, math.Float64frombits(field(t, gauge, 5, 1, 4).(uint64)))
+
+			conf.Headers = nil
+			c, err = New(conf, nil)
+			require.NoError(t, err)
+			assert.EqualError(t, c.export(c.url, exportRequest{}), "gRPC status 16: invalid API key")
+		})
+	}
+}
+
+func testSpan(tm time.Time, err string) *tracing.Span {
+	return &tracing.Span{
+		TraceID: tracing.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:  tracing.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		Name:    "HTTP GET",
+		Start:   tm,
+		End:     tm.Add(time.Second),
+		Tags:    stats.IntoSampleTags(&map[string]string{"testid": "123", "method": "GET"}),
+		Error:   err,
+	}
+}
+
+func TestCollectorSpansHTTPProtobuf(t *testing.T) {
+	var paths []string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	runTags := stats.IntoSampleTags(&map[string]string{"testid": "123"})
+	conf := NewConfig().Apply(Config{Endpoint: null.StringFrom(srv.URL), Metrics: null.BoolFrom(false)})
+	c, err := New(conf, runTags)
+	require.NoError(t, err)
+
+	tm := time.Unix(1500000000, 0)
+	c.Collect([]stats.SampleContainer{
+		testSpan(tm, "404 Not Found"),
+		stats.Sample{Metric: stats.New("vus", stats.Gauge), Time: tm, Value: 5},
+	})
+	c.pushMetrics(time.Time{})
+	c.pushSpans()
+	require.Equal(t, []string{"/v1/traces"}, paths, "the metrics shouldn't be exported")
+
+	rs := field(t, body, 1).([]byte)
+	assert.Len(t, fields(t, field(t, rs, 1).([]byte))[1], 2)
+	sp := field(t, rs, 2, 2).([]byte)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, field(t, sp, 1))
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}, field(t, sp, 2))
+	assert.Equal(t, "HTTP GET", string(field(t, sp, 5).([]byte)))
+	assert.Equal(t, uint64(spanKindClient), field(t, sp, 6))
+	assert.Equal(t, uint64(tm.UnixNano()), field(t, sp, 7))
+	assert.Equal(t, uint64(tm.Add(time.Second).UnixNano()), field(t, sp, 8))
+	assert.Equal(t, "method", string(field(t, sp, 9, 1).([]byte)), "the run tags shouldn't be attributes")
+	assert.Equal(t, "404 Not Found", string(field(t, sp, 15, 2).([]byte)))
+	assert.Equal(t, uint64(statusCodeError), field(t, sp, 15, 3))
+}
+
+func TestCollectorSpansHTTPJSON(t *testing.T) {
+	var requests []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+	}))
+	defer srv.Close()
+
+	defer func(max int) { maxSpansPerRequest = max }(maxSpansPerRequest)
+	maxSpansPerRequest = 2
+
+	conf := NewConfig().Apply(Config{Endpoint: null.StringFrom(srv.URL), Protocol: null.StringFrom("http/json")})
+	c, err := New(conf, nil)
+	require.NoError(t, err)
+	tm := time.Unix(1500000000, 0)
+	c.Collect([]stats.SampleContainer{testSpan(tm, ""), testSpan(tm, ""), testSpan(tm, "")})
+	c.pushSpans()
+	require.Len(t, requests, 2)
+
+	spans := func(request map[string]interface{}) []interface{} {
+		rs := request["resourceSpans"].([]interface{})[0].(map[string]interface{})
+		return rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	}
+	assert.Len(t, spans(requests[0]), 2)
+	require.Len(t, spans(requests[1]), 1)
+	assert.Equal(t, map[string]interface{}{
+		"traceId":           "0102030405060708090a0b0c0d0e0f10",
+		"spanId":            "0102030405060708",
+		"name":              "HTTP GET",
+		"kind":              3.0,
+		"startTimeUnixNano": "1500000000000000000",
+		"endTimeUnixNano":   "1500000001000000000",
+		"attributes": []interface{}{
+			map[string]interface{}{"key": "method", "value": map[string]interface{}{"stringValue": "GET"}},
+			map[string]interface{}{"key": "testid", "value": map[string]interface{}{"stringValue": "123"}},
+		},
+		"status": map[string]interface{}{},
+	}, spans(requests[1])[0])
+
+	c.pushSpans()
+	assert.Len(t, requests, 2)
+}
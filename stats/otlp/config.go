@@ -0,0 +1,222 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlp
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubernetes/helm/pkg/strvals"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// The OTLP transports.
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+	ProtocolHTTPJSON     = "http/json"
+)
+
+// Config is the config for the OpenTelemetry collector
+type Config struct {
+	// Endpoint is the URL of the OTLP receiver. With the HTTP protocols, /v1/metrics is added
+	// to it when it doesn't have a path, and the spans are exported to /v1/traces.
+	Endpoint null.String       `json:"endpoint,omitempty" envconfig:"OTLP_ENDPOINT"`
+	Protocol null.String       `json:"protocol" envconfig:"OTLP_PROTOCOL"`
+	Headers  map[string]string `json:"headers,omitempty" envconfig:"OTLP_HEADERS"`
+	Insecure null.Bool         `json:"insecure,omitempty" envconfig:"OTLP_INSECURE"`
+
+	ServiceName  null.String        `json:"service_name" envconfig:"OTLP_SERVICE_NAME"`
+	PushInterval types.NullDuration `json:"push_interval" envconfig:"OTLP_PUSH_INTERVAL"`
+
+	// Metrics can be disabled to only export the spans of the requests, with --tracing, to a
+	// receiver that only accepts traces like Jaeger.
+	Metrics null.Bool `json:"metrics" envconfig:"OTLP_METRICS"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Protocol:     null.NewString(ProtocolHTTPProtobuf, false),
+		ServiceName:  null.NewString("k6", false),
+		PushInterval: types.NewNullDuration(10*time.Second, false),
+		Metrics:      null.NewBool(true, false),
+	}
+}
+
+// Apply saves config non-zero config values from the passed config in the receiver.
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Endpoint.Valid {
+		c.Endpoint = cfg.Endpoint
+	}
+	if cfg.Protocol.Valid {
+		c.Protocol = cfg.Protocol
+	}
+	if len(cfg.Headers) > 0 {
+		c.Headers = cfg.Headers
+	}
+	if cfg.Insecure.Valid {
+		c.Insecure = cfg.Insecure
+	}
+	if cfg.ServiceName.Valid {
+		c.ServiceName = cfg.ServiceName
+	}
+	if cfg.PushInterval.Valid {
+		c.PushInterval = cfg.PushInterval
+	}
+	if cfg.Metrics.Valid {
+		c.Metrics = cfg.Metrics
+	}
+	return c
+}
+
+// GetURL returns the URL that the metrics are exported to, which defaults to the standard
+// port of the protocol on localhost.
+func (c Config) GetURL() (*url.URL, error) {
+	endpoint := c.Endpoint.String
+	switch c.Protocol.String {
+	case ProtocolGRPC:
+		if endpoint == "" {
+			endpoint = "http://localhost:4317"
+		}
+	case ProtocolHTTPProtobuf, ProtocolHTTPJSON:
+		if endpoint == "" {
+			endpoint = "http://localhost:4318"
+		}
+	default:
+		return nil, errors.Errorf(
+			"invalid OTLP protocol '%s', use %s, %s or %s",
+			c.Protocol.String, ProtocolGRPC, ProtocolHTTPProtobuf, ProtocolHTTPJSON,
+		)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid OTLP endpoint")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("invalid OTLP endpoint '%s', it should be an http or https URL", endpoint)
+	}
+	if c.Protocol.String == ProtocolGRPC {
+		u.Path = grpcExportMethod
+	} else if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/metrics"
+	}
+	return u, nil
+}
+
+// GetTracesURL returns the URL that the spans are exported to. With the HTTP protocols, it's
+// the metrics URL with /v1/traces instead of /v1/metrics, which is added to custom paths.
+func (c Config) GetTracesURL() (*url.URL, error) {
+	u, err := c.GetURL()
+	if err != nil {
+		return nil, err
+	}
+	if c.Protocol.String == ProtocolGRPC {
+		u.Path = grpcTraceExportMethod
+	} else {
+		u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/v1/metrics"), "/") + "/v1/traces"
+	}
+	return u, nil
+}
+
+// Validate checks the values that metrics can't be exported with.
+func (c Config) Validate() error {
+	if _, err := c.GetURL(); err != nil {
+		return err
+	}
+	if time.Duration(c.PushInterval.Duration) <= 0 {
+		return errors.New("the OTLP push interval should be positive")
+	}
+	return nil
+}
+
+// ParseArg takes an arg string and converts it to a config. The arg is either just the
+// endpoint, or key=value pairs like endpoint=http://otel:4317,protocol=grpc,headers.api-key=...
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	if !strings.Contains(arg, "=") {
+		c.Endpoint = null.StringFrom(arg)
+		return c, nil
+	}
+
+	params, err := strvals.ParseString(arg)
+	if err != nil {
+		return c, err
+	}
+	for k, v := range params {
+		if k == "headers" {
+			headers, ok := v.(map[string]interface{})
+			if !ok {
+				return c, errors.New("the OTLP headers should be set like headers.name=value")
+			}
+			c.Headers = make(map[string]string, len(headers))
+			for name, value := range headers {
+				c.Headers[name] = fmt.Sprint(value)
+			}
+			continue
+		}
+		// Only true and false are parsed, into booleans.
+		var value string
+		switch v := v.(type) {
+		case string:
+			value = v
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			return c, errors.Errorf("invalid value for the OTLP option '%s'", k)
+		}
+		switch k {
+		case "endpoint":
+			c.Endpoint = null.StringFrom(value)
+		case "protocol":
+			c.Protocol = null.StringFrom(value)
+		case "insecure":
+			switch value {
+			case "true", "false":
+				c.Insecure = null.BoolFrom(value == "true")
+			default:
+				return c, errors.Errorf("insecure must be true or false, not %s", value)
+			}
+		case "service_name":
+			c.ServiceName = null.StringFrom(value)
+		case "push_interval":
+			if err := c.PushInterval.UnmarshalText([]byte(value)); err != nil {
+				return c, err
+			}
+		case "metrics":
+			switch value {
+			case "true", "false":
+				c.Metrics = null.BoolFrom(value == "true")
+			default:
+				return c, errors.Errorf("metrics must be true or false, not %s", value)
+			}
+		default:
+			return c, errors.Errorf("unknown OTLP option '%s'", k)
+		}
+	}
+	return c, nil
+}
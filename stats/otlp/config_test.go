@@ -0,0 +1,105 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestConfigParseArg(t *testing.T) {
+	c, err := ParseArg("http://otel:4318")
+	require.NoError(t, err)
+	assert.Equal(t, Config{Endpoint: null.StringFrom("http://otel:4318")}, c)
+
+	c, err = ParseArg("endpoint=https://otel:4317,protocol=grpc,insecure=true,headers.api-key=secret,service_name=loadtest,push_interval=1m,metrics=false")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Endpoint:     null.StringFrom("https://otel:4317"),
+		Protocol:     null.StringFrom("grpc"),
+		Insecure:     null.BoolFrom(true),
+		Headers:      map[string]string{"api-key": "secret"},
+		ServiceName:  null.StringFrom("loadtest"),
+		PushInterval: types.NullDurationFrom(time.Minute),
+		Metrics:      null.BoolFrom(false),
+	}, c)
+
+	_, err = ParseArg("endpoint=http://otel:4318,compression=gzip")
+	assert.EqualError(t, err, "unknown OTLP option 'compression'")
+	_, err = ParseArg("headers=secret")
+	assert.EqualError(t, err, "the OTLP headers should be set like headers.name=value")
+}
+
+func TestConfigGetURL(t *testing.T) {
+	testdata := map[[2]string]string{
+		{"", ""}:                                "http://localhost:4318/v1/metrics",
+		{"http/json", ""}:                       "http://localhost:4318/v1/metrics",
+		{"grpc", ""}:                            "http://localhost:4317" + grpcExportMethod,
+		{"grpc", "https://otel:4317"}:           "https://otel:4317" + grpcExportMethod,
+		{"http/protobuf", "https://otel:4318/"}: "https://otel:4318/v1/metrics",
+		{"http/protobuf", "https://otel/custom/export"}: "https://otel/custom/export",
+	}
+	for data, expected := range testdata {
+		conf := NewConfig()
+		if data[0] != "" {
+			conf.Protocol = null.StringFrom(data[0])
+		}
+		if data[1] != "" {
+			conf.Endpoint = null.StringFrom(data[1])
+		}
+		u, err := conf.GetURL()
+		require.NoError(t, err)
+		assert.Equal(t, expected, u.String())
+	}
+
+	tracesTestdata := map[[2]string]string{
+		{"", ""}:                      "http://localhost:4318/v1/traces",
+		{"grpc", "https://otel:4317"}: "https://otel:4317" + grpcTraceExportMethod,
+		{"http/json", "https://otel/otlp/v1/metrics"}:    "https://otel/otlp/v1/traces",
+		{"http/protobuf", "https://otel/custom/export/"}: "https://otel/custom/export/v1/traces",
+	}
+	for data, expected := range tracesTestdata {
+		conf := NewConfig()
+		if data[0] != "" {
+			conf.Protocol = null.StringFrom(data[0])
+		}
+		if data[1] != "" {
+			conf.Endpoint = null.StringFrom(data[1])
+		}
+		u, err := conf.GetTracesURL()
+		require.NoError(t, err)
+		assert.Equal(t, expected, u.String())
+	}
+
+	errs := map[string]Config{
+		"invalid OTLP protocol 'thrift', use grpc, http/protobuf or http/json": {Protocol: null.StringFrom("thrift")},
+		"invalid OTLP endpoint 'otel:4317', it should be an http or https URL": {Endpoint: null.StringFrom("otel:4317")},
+		"the OTLP push interval should be positive":                            {PushInterval: types.NullDurationFrom(0)},
+	}
+	for expErr, conf := range errs {
+		assert.EqualError(t, NewConfig().Apply(conf).Validate(), expErr)
+	}
+}
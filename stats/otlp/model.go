@@ -0,0 +1,274 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlp
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The messages of the OTLP metrics service, with the JSON names of its HTTP/JSON encoding, and
+// the binary protobuf encoding of the fields that k6 uses. The field numbers are the ones of
+// opentelemetry/proto/collector/metrics/v1 and opentelemetry/proto/metrics/v1.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+
+	// aggregationTemporalityDelta is the value of AGGREGATION_TEMPORALITY_DELTA.
+	aggregationTemporalityDelta = 1
+)
+
+type exportRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type metric struct {
+	Name    string   `json:"name"`
+	Unit    string   `json:"unit,omitempty"`
+	Gauge   *gauge   `json:"gauge,omitempty"`
+	Sum     *sum     `json:"sum,omitempty"`
+	Summary *summary `json:"summary,omitempty"`
+}
+
+type gauge struct {
+	DataPoints []numberDataPoint `json:"dataPoints"`
+}
+
+type sum struct {
+	DataPoints             []numberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type summary struct {
+	DataPoints []summaryDataPoint `json:"dataPoints"`
+}
+
+// The 64-bit integers are strings in JSON, like the protobuf JSON mapping requires.
+type numberDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string,omitempty"`
+	TimeUnixNano      uint64     `json:"timeUnixNano,string"`
+	AsDouble          float64    `json:"asDouble"`
+}
+
+type summaryDataPoint struct {
+	Attributes        []keyValue        `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64            `json:"startTimeUnixNano,string,omitempty"`
+	TimeUnixNano      uint64            `json:"timeUnixNano,string"`
+	Count             uint64            `json:"count,string"`
+	Sum               float64           `json:"sum"`
+	QuantileValues    []valueAtQuantile `json:"quantileValues,omitempty"`
+}
+
+type valueAtQuantile struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func appendTag(buf []byte, number, wire int) []byte {
+	return appendVarint(buf, uint64(number)<<3|uint64(wire))
+}
+
+func appendVarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+func appendBytes(buf []byte, number int, data []byte) []byte {
+	buf = appendTag(buf, number, wireBytes)
+	buf = appendVarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+func appendString(buf []byte, number int, s string) []byte {
+	if s == "" {
+		return buf
+	}
+	return appendBytes(buf, number, []byte(s))
+}
+
+func appendFixed64(buf []byte, number int, v uint64) []byte {
+	buf = appendTag(buf, number, wireFixed64)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendDouble(buf []byte, number int, v float64) []byte {
+	return appendFixed64(buf, number, math.Float64bits(v))
+}
+
+// appendMessage appends an embedded message, which is encoded by the given function.
+func appendMessage(buf []byte, number int, encode func([]byte) []byte) []byte {
+	return appendBytes(buf, number, encode(nil))
+}
+
+func appendAttributes(buf []byte, number int, attributes []keyValue) []byte {
+	for _, kv := range attributes {
+		buf = appendMessage(buf, number, kv.appendProto)
+	}
+	return buf
+}
+
+func (r exportRequest) appendProto(buf []byte) []byte {
+	for _, rm := range r.ResourceMetrics {
+		buf = appendMessage(buf, 1, rm.appendProto)
+	}
+	return buf
+}
+
+func (rm resourceMetrics) appendProto(buf []byte) []byte {
+	buf = appendMessage(buf, 1, rm.Resource.appendProto)
+	for _, sm := range rm.ScopeMetrics {
+		buf = appendMessage(buf, 2, sm.appendProto)
+	}
+	return buf
+}
+
+func (r resource) appendProto(buf []byte) []byte {
+	return appendAttributes(buf, 1, r.Attributes)
+}
+
+func (sm scopeMetrics) appendProto(buf []byte) []byte {
+	buf = appendMessage(buf, 1, sm.Scope.appendProto)
+	for _, m := range sm.Metrics {
+		buf = appendMessage(buf, 2, m.appendProto)
+	}
+	return buf
+}
+
+func (s scope) appendProto(buf []byte) []byte {
+	buf = appendString(buf, 1, s.Name)
+	return appendString(buf, 2, s.Version)
+}
+
+func (m metric) appendProto(buf []byte) []byte {
+	buf = appendString(buf, 1, m.Name)
+	buf = appendString(buf, 3, m.Unit)
+	switch {
+	case m.Gauge != nil:
+		buf = appendMessage(buf, 5, m.Gauge.appendProto)
+	case m.Sum != nil:
+		buf = appendMessage(buf, 7, m.Sum.appendProto)
+	case m.Summary != nil:
+		buf = appendMessage(buf, 11, m.Summary.appendProto)
+	}
+	return buf
+}
+
+func (g gauge) appendProto(buf []byte) []byte {
+	for _, dp := range g.DataPoints {
+		buf = appendMessage(buf, 1, dp.appendProto)
+	}
+	return buf
+}
+
+func (s sum) appendProto(buf []byte) []byte {
+	for _, dp := range s.DataPoints {
+		buf = appendMessage(buf, 1, dp.appendProto)
+	}
+	buf = appendTag(buf, 2, wireVarint)
+	buf = appendVarint(buf, uint64(s.AggregationTemporality))
+	if s.IsMonotonic {
+		buf = appendTag(buf, 3, wireVarint)
+		buf = appendVarint(buf, 1)
+	}
+	return buf
+}
+
+func (s summary) appendProto(buf []byte) []byte {
+	for _, dp := range s.DataPoints {
+		buf = appendMessage(buf, 1, dp.appendProto)
+	}
+	return buf
+}
+
+func (dp numberDataPoint) appendProto(buf []byte) []byte {
+	if dp.StartTimeUnixNano != 0 {
+		buf = appendFixed64(buf, 2, dp.StartTimeUnixNano)
+	}
+	buf = appendFixed64(buf, 3, dp.TimeUnixNano)
+	// as_double is in a oneof, so it's set even when it's 0.
+	buf = appendDouble(buf, 4, dp.AsDouble)
+	return appendAttributes(buf, 7, dp.Attributes)
+}
+
+func (dp summaryDataPoint) appendProto(buf []byte) []byte {
+	if dp.StartTimeUnixNano != 0 {
+		buf = appendFixed64(buf, 2, dp.StartTimeUnixNano)
+	}
+	buf = appendFixed64(buf, 3, dp.TimeUnixNano)
+	buf = appendFixed64(buf, 4, dp.Count)
+	buf = appendDouble(buf, 5, dp.Sum)
+	for _, q := range dp.QuantileValues {
+		buf = appendMessage(buf, 6, q.appendProto)
+	}
+	return appendAttributes(buf, 7, dp.Attributes)
+}
+
+func (q valueAtQuantile) appendProto(buf []byte) []byte {
+	buf = appendDouble(buf, 1, q.Quantile)
+	return appendDouble(buf, 2, q.Value)
+}
+
+func (kv keyValue) appendProto(buf []byte) []byte {
+	buf = appendString(buf, 1, kv.Key)
+	return appendMessage(buf, 2, kv.Value.appendProto)
+}
+
+func (v anyValue) appendProto(buf []byte) []byte {
+	// An empty string_value still has to be set, so that the oneof isn't empty.
+	return appendBytes(buf, 1, []byte(v.StringValue))
+}
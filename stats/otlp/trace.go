@@ -0,0 +1,112 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package otlp
+
+import (
+	"encoding/hex"
+)
+
+// The messages of the OTLP trace service, like the ones of the metrics service in model.go. The
+// field numbers are the ones of opentelemetry/proto/collector/trace/v1 and
+// opentelemetry/proto/trace/v1.
+
+const (
+	// spanKindClient is the value of SPAN_KIND_CLIENT.
+	spanKindClient = 3
+	// statusCodeError is the value of STATUS_CODE_ERROR.
+	statusCodeError = 2
+)
+
+type exportTraceRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+// The IDs are hex strings in JSON, unlike the base64 of other bytes fields.
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano   uint64     `json:"endTimeUnixNano,string"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            spanStatus `json:"status"`
+}
+
+type spanStatus struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
+
+func (r exportTraceRequest) appendProto(buf []byte) []byte {
+	for _, rs := range r.ResourceSpans {
+		buf = appendMessage(buf, 1, rs.appendProto)
+	}
+	return buf
+}
+
+func (rs resourceSpans) appendProto(buf []byte) []byte {
+	buf = appendMessage(buf, 1, rs.Resource.appendProto)
+	for _, ss := range rs.ScopeSpans {
+		buf = appendMessage(buf, 2, ss.appendProto)
+	}
+	return buf
+}
+
+func (ss scopeSpans) appendProto(buf []byte) []byte {
+	buf = appendMessage(buf, 1, ss.Scope.appendProto)
+	for _, s := range ss.Spans {
+		buf = appendMessage(buf, 2, s.appendProto)
+	}
+	return buf
+}
+
+func (s span) appendProto(buf []byte) []byte {
+	traceID, _ := hex.DecodeString(s.TraceID)
+	spanID, _ := hex.DecodeString(s.SpanID)
+	buf = appendBytes(buf, 1, traceID)
+	buf = appendBytes(buf, 2, spanID)
+	buf = appendString(buf, 5, s.Name)
+	buf = appendTag(buf, 6, wireVarint)
+	buf = appendVarint(buf, uint64(s.Kind))
+	buf = appendFixed64(buf, 7, s.StartTimeUnixNano)
+	buf = appendFixed64(buf, 8, s.EndTimeUnixNano)
+	buf = appendAttributes(buf, 9, s.Attributes)
+	return appendMessage(buf, 15, s.Status.appendProto)
+}
+
+func (s spanStatus) appendProto(buf []byte) []byte {
+	buf = appendString(buf, 2, s.Message)
+	if s.Code != 0 {
+		buf = appendTag(buf, 3, wireVarint)
+		buf = appendVarint(buf, uint64(s.Code))
+	}
+	return buf
+}
@@ -85,9 +85,18 @@ func (g *GaugeSink) Format(t time.Duration) map[string]float64 {
 	return map[string]float64{"value": g.Value}
 }
 
+// TrendSinkMaxValues is how many values a TrendSink keeps, before it switches to a histogram
+// with bounded memory use, which gives the percentiles with TrendSinkAccuracy.
+var TrendSinkMaxValues = 100000
+
+// TrendSinkAccuracy is the relative accuracy of the percentiles of the TrendSinks with more
+// values than TrendSinkMaxValues, e.g. 0.01 means that they are within 1% of the exact ones.
+var TrendSinkAccuracy = 0.01
+
 type TrendSink struct {
 	Values  []float64
 	jumbled bool
+	hist    *trendHistogram
 
 	Count    uint64
 	Min, Max float64
@@ -96,7 +105,19 @@ type TrendSink struct {
 }
 
 func (t *TrendSink) Add(s Sample) {
-	t.Values = append(t.Values, s.Value)
+	if t.hist != nil {
+		t.hist.add(s.Value)
+	} else if len(t.Values) >= TrendSinkMaxValues {
+		// Too many values to keep them all in memory, so switch to the histogram.
+		t.hist = newTrendHistogram(TrendSinkAccuracy)
+		for _, v := range t.Values {
+			t.hist.add(v)
+		}
+		t.hist.add(s.Value)
+		t.Values = nil
+	} else {
+		t.Values = append(t.Values, s.Value)
+	}
 	t.jumbled = true
 	t.Count += 1
 	t.Sum += s.Value
@@ -116,17 +137,21 @@ func (t *TrendSink) P(pct float64) float64 {
 	case 0:
 		return 0
 	case 1:
-		return t.Values[0]
+		return t.Min
 	default:
 		// If percentile falls on a value in Values slice, we return that value.
 		// If percentile does not fall on a value in Values slice, we calculate (linear interpolation)
 		// the value that would fall at percentile, given the values above and below that percentile.
 		t.Calc()
 		i := pct * (float64(t.Count) - 1.0)
-		j := t.Values[int(math.Floor(i))]
-		k := t.Values[int(math.Ceil(i))]
+		var j, k float64
+		if t.hist != nil {
+			j, k = t.hist.valueAt(uint64(math.Floor(i))), t.hist.valueAt(uint64(math.Ceil(i)))
+		} else {
+			j, k = t.Values[int(math.Floor(i))], t.Values[int(math.Ceil(i))]
+		}
 		f := i - math.Floor(i)
-		return j + (k-j)*f
+		return math.Min(math.Max(j+(k-j)*f, t.Min), t.Max)
 	}
 }
 
@@ -134,9 +159,15 @@ func (t *TrendSink) Calc() {
 	if !t.jumbled {
 		return
 	}
+	t.jumbled = false
+
+	if t.hist != nil {
+		t.hist.sort()
+		t.Med = t.P(0.5)
+		return
+	}
 
 	sort.Float64s(t.Values)
-	t.jumbled = false
 
 	// The median of an even number of values is the average of the middle two.
 	if (t.Count & 0x01) == 0 {
@@ -158,6 +189,85 @@ func (t *TrendSink) Format(tt time.Duration) map[string]float64 {
 	}
 }
 
+// trendHistogram is a histogram with logarithmic buckets, like the ones of HDR histograms and
+// DDSketch, so its memory use only depends on the range of the values and not on their count.
+// Every value is represented by the middle of its bucket, which is within the accuracy of it.
+type trendHistogram struct {
+	gamma, logGamma float64
+
+	// The buckets of the positive and negative values, by the index of their upper bound.
+	positive, negative map[int]uint64
+	zeros              uint64
+
+	// The values of the non-empty buckets in ascending order, with the cumulative counts.
+	sorted []float64
+	ranks  []uint64
+}
+
+func newTrendHistogram(accuracy float64) *trendHistogram {
+	gamma := (1 + accuracy) / (1 - accuracy)
+	return &trendHistogram{
+		gamma: gamma, logGamma: math.Log(gamma),
+		positive: make(map[int]uint64), negative: make(map[int]uint64),
+	}
+}
+
+func (h *trendHistogram) add(v float64) {
+	switch {
+	case v > 0:
+		h.positive[int(math.Ceil(math.Log(v)/h.logGamma))]++
+	case v < 0:
+		h.negative[int(math.Ceil(math.Log(-v)/h.logGamma))]++
+	default:
+		h.zeros++
+	}
+}
+
+// value returns the value that represents the bucket with the given index.
+func (h *trendHistogram) value(idx int) float64 {
+	return 2 * math.Pow(h.gamma, float64(idx)) / (h.gamma + 1)
+}
+
+// sort prepares the sorted buckets for valueAt(), it has to be called after values are added.
+func (h *trendHistogram) sort() {
+	h.sorted, h.ranks = h.sorted[:0], h.ranks[:0]
+	var rank uint64
+	appendBucket := func(value float64, count uint64) {
+		rank += count
+		h.sorted = append(h.sorted, value)
+		h.ranks = append(h.ranks, rank)
+	}
+
+	negative := make([]int, 0, len(h.negative))
+	for idx := range h.negative {
+		negative = append(negative, idx)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(negative)))
+	for _, idx := range negative {
+		appendBucket(-h.value(idx), h.negative[idx])
+	}
+	if h.zeros > 0 {
+		appendBucket(0, h.zeros)
+	}
+	positive := make([]int, 0, len(h.positive))
+	for idx := range h.positive {
+		positive = append(positive, idx)
+	}
+	sort.Ints(positive)
+	for _, idx := range positive {
+		appendBucket(h.value(idx), h.positive[idx])
+	}
+}
+
+// valueAt returns the value with the given 0-based rank, if all values were sorted.
+func (h *trendHistogram) valueAt(rank uint64) float64 {
+	i := sort.Search(len(h.ranks), func(i int) bool { return h.ranks[i] > rank })
+	if i == len(h.sorted) {
+		i--
+	}
+	return h.sorted[i]
+}
+
 type RateSink struct {
 	Trues int64
 	Total int64
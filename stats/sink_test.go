@@ -21,6 +21,8 @@
 package stats
 
 import (
+	"math"
+	"sort"
 	"testing"
 	"time"
 
@@ -212,6 +214,30 @@ func TestTrendSink(t *testing.T) {
 			"p(95)": 95.49999999999999,
 		}, sink.Format(0))
 	})
+	t.Run("histogram", func(t *testing.T) {
+		// Enough values for the sink to switch to the histogram, with some negative ones and zeros.
+		count := 2 * TrendSinkMaxValues
+		values := make([]float64, 0, count)
+		for i := 0; i < count; i++ {
+			values = append(values, float64((i*7919)%count-count/10)/100)
+		}
+
+		sink := TrendSink{}
+		for _, v := range values {
+			sink.Add(Sample{Metric: &Metric{}, Value: v})
+		}
+		assert.Nil(t, sink.Values)
+		assert.Equal(t, uint64(count), sink.Count)
+		assert.Equal(t, -float64(count/10)/100, sink.Min)
+		assert.Equal(t, float64(count-1-count/10)/100, sink.Max)
+
+		sort.Float64s(values)
+		for _, pct := range []float64{0, 0.01, 0.05, 0.1, 0.25, 0.5, 0.9, 0.95, 0.99, 0.999, 1} {
+			expected := values[int(pct*float64(count-1))]
+			assert.InDelta(t, expected, sink.P(pct), math.Abs(expected)*TrendSinkAccuracy+0.01, "p(%v)", pct)
+		}
+		assert.InDelta(t, values[count/2], sink.Med, math.Abs(values[count/2])*TrendSinkAccuracy+0.01)
+	})
 }
 
 func TestRateSink(t *testing.T) {
@@ -249,6 +249,25 @@ func (st *SampleTags) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &st.tags)
 }
 
+// UnmarshalText deserializes SampleTags from a comma-separated list of name=value pairs,
+// like the one in the K6_TAGS environment variable.
+func (st *SampleTags) UnmarshalText(data []byte) error {
+	tags := map[string]string{}
+	for _, nv := range strings.Split(string(data), ",") {
+		nv = strings.TrimSpace(nv)
+		if nv == "" {
+			continue
+		}
+		idx := strings.IndexRune(nv, '=')
+		if idx <= 0 || idx == len(nv)-1 {
+			return fmt.Errorf("invalid tag '%s', it should be in the name=value format", nv)
+		}
+		tags[nv[:idx]] = nv[idx+1:]
+	}
+	*st = SampleTags{tags: tags}
+	return nil
+}
+
 // CloneTags copies the underlying set of a sample tags and
 // returns it. If the receiver is nil, it returns an empty non-nil map.
 func (st *SampleTags) CloneTags() map[string]string {
@@ -487,26 +506,48 @@ func NewSubmetric(name string) (parentName string, sm *Submetric) {
 		return parts[0], &Submetric{Name: name}
 	}
 
-	kvs := strings.Split(parts[1], ",")
+	kvs := splitSubmetricTags(parts[1])
 	tags := make(map[string]string, len(kvs))
 	for _, kv := range kvs {
-		if kv == "" {
+		if strings.TrimSpace(kv) == "" {
 			continue
 		}
 		parts := strings.SplitN(kv, ":", 2)
 
-		key := strings.TrimSpace(strings.Trim(parts[0], `"'`))
+		key := strings.Trim(strings.TrimSpace(parts[0]), `"'`)
 		if len(parts) != 2 {
 			tags[key] = ""
 			continue
 		}
 
-		value := strings.TrimSpace(strings.Trim(parts[1], `"'`))
+		value := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
 		tags[key] = value
 	}
 	return parts[0], &Submetric{Name: name, Parent: parts[0], Suffix: parts[1], Tags: IntoSampleTags(&tags)}
 }
 
+// splitSubmetricTags splits the tag list of a submetric on the commas that aren't inside quotes,
+// so tag values like check names can contain commas, e.g. `checks{check:"is 200, is JSON"}`.
+func splitSubmetricTags(s string) []string {
+	var kvs []string
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			kvs = append(kvs, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(kvs, s[start:])
+}
+
 func (m *Metric) Summary(t time.Duration) *Summary {
 	return &Summary{
 		Metric:  m,
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMetricHumanizeValue(t *testing.T) {
@@ -172,14 +173,18 @@ func TestNewSubmetric(t *testing.T) {
 		parent string
 		tags   map[string]string
 	}{
-		"my_metric":                 {"my_metric", nil},
-		"my_metric{}":               {"my_metric", nil},
-		"my_metric{a}":              {"my_metric", map[string]string{"a": ""}},
-		"my_metric{a:1}":            {"my_metric", map[string]string{"a": "1"}},
-		"my_metric{ a : 1 }":        {"my_metric", map[string]string{"a": "1"}},
-		"my_metric{a,b}":            {"my_metric", map[string]string{"a": "", "b": ""}},
-		"my_metric{a:1,b:2}":        {"my_metric", map[string]string{"a": "1", "b": "2"}},
-		"my_metric{ a : 1, b : 2 }": {"my_metric", map[string]string{"a": "1", "b": "2"}},
+		"my_metric":                               {"my_metric", nil},
+		"my_metric{}":                             {"my_metric", nil},
+		"my_metric{a}":                            {"my_metric", map[string]string{"a": ""}},
+		"my_metric{a:1}":                          {"my_metric", map[string]string{"a": "1"}},
+		"my_metric{ a : 1 }":                      {"my_metric", map[string]string{"a": "1"}},
+		"my_metric{a,b}":                          {"my_metric", map[string]string{"a": "", "b": ""}},
+		"my_metric{a:1,b:2}":                      {"my_metric", map[string]string{"a": "1", "b": "2"}},
+		"my_metric{ a : 1, b : 2 }":               {"my_metric", map[string]string{"a": "1", "b": "2"}},
+		`checks{check:"status is 200"}`:           {"checks", map[string]string{"check": "status is 200"}},
+		`checks{ check: "status is 200" }`:        {"checks", map[string]string{"check": "status is 200"}},
+		`checks{check:'is 200, is JSON',group:a}`: {"checks", map[string]string{"check": "is 200, is JSON", "group": "a"}},
+		`checks{check:"a:b"}`:                     {"checks", map[string]string{"check": "a:b"}},
 	}
 
 	for name, data := range testdata {
@@ -281,3 +286,14 @@ func TestSampleImplementations(t *testing.T) {
 	assert.Equal(t, now, cSamples.GetTime())
 	assert.Equal(t, sample.GetTags(), sample.GetTags())
 }
+
+func TestSampleTagsUnmarshalText(t *testing.T) {
+	t.Parallel()
+	var tags SampleTags
+	require.NoError(t, tags.UnmarshalText([]byte("testid=release-42, region=eu,")))
+	assert.Equal(t, map[string]string{"testid": "release-42", "region": "eu"}, tags.CloneTags())
+
+	for _, s := range []string{"testid", "=eu", "region="} {
+		assert.EqualError(t, tags.UnmarshalText([]byte(s)), "invalid tag '"+s+"', it should be in the name=value format")
+	}
+}
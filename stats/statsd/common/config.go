@@ -21,9 +21,11 @@
 package common
 
 import (
+	"net"
 	"time"
 
 	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
 	null "gopkg.in/guregu/null.v3"
 )
 
@@ -65,3 +67,14 @@ func (c Config) Apply(cfg Config) Config {
 
 	return c
 }
+
+// ParseArg takes an arg string and converts it to a config, the arg is the address of the
+// StatsD server, as host:port.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	if _, _, err := net.SplitHostPort(arg); err != nil {
+		return c, errors.Wrapf(err, "invalid StatsD address '%s', it should be host:port", arg)
+	}
+	c.Addr = null.StringFrom(arg)
+	return c, nil
+}
@@ -0,0 +1,142 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/loadimpact/k6/stats"
+)
+
+// Supported CI annotation formats.
+const (
+	AnnotationsGitHub = "github"
+	AnnotationsGitLab = "gitlab"
+)
+
+// FailedThreshold describes a single threshold that failed at the end of the test run.
+type FailedThreshold struct {
+	Metric string
+	Source string
+	Line   int // 1-based line of the threshold in the script, 0 if it couldn't be found
+}
+
+// Message returns a human readable description of the failed threshold.
+func (ft FailedThreshold) Message() string {
+	return fmt.Sprintf("threshold '%s' for metric '%s' has failed", ft.Source, ft.Metric)
+}
+
+// GetFailedThresholds returns all failed thresholds of the supplied metrics, sorted by
+// metric name. If the script source is supplied, it is used to find the line where each
+// threshold was defined.
+func GetFailedThresholds(metrics map[string]*stats.Metric, script []byte) []FailedThreshold {
+	names := make([]string, 0, len(metrics))
+	for name, m := range metrics {
+		if m.Tainted.Bool {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var result []FailedThreshold
+	for _, name := range names {
+		for _, th := range metrics[name].Thresholds.Thresholds {
+			if !th.LastFailed {
+				continue
+			}
+			result = append(result, FailedThreshold{
+				Metric: name,
+				Source: th.Source,
+				Line:   findLine(script, th.Source),
+			})
+		}
+	}
+	return result
+}
+
+func findLine(script []byte, needle string) int {
+	idx := bytes.Index(script, []byte(needle))
+	if idx < 0 {
+		return 0
+	}
+	return bytes.Count(script[:idx], []byte{'\n'}) + 1
+}
+
+// WriteGitHubAnnotations writes the failed thresholds as GitHub Actions workflow
+// commands, which are shown as errors in the job log and inline in pull requests.
+func WriteGitHubAnnotations(w io.Writer, filename string, failed []FailedThreshold) error {
+	escaper := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	propEscaper := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+	for _, ft := range failed {
+		props := "file=" + propEscaper.Replace(filename)
+		if ft.Line > 0 {
+			props += fmt.Sprintf(",line=%d", ft.Line)
+		}
+		if _, err := fmt.Fprintf(w, "::error %s::%s\n", props, escaper.Replace(ft.Message())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type gitLabIssue struct {
+	Description string `json:"description"`
+	Fingerprint string `json:"fingerprint"`
+	Severity    string `json:"severity"`
+	Location    struct {
+		Path  string `json:"path"`
+		Lines struct {
+			Begin int `json:"begin"`
+		} `json:"lines"`
+	} `json:"location"`
+}
+
+// WriteGitLabCodeQuality writes the failed thresholds as a GitLab code quality report,
+// which can be uploaded as an artifact so the failures are shown in merge requests.
+func WriteGitLabCodeQuality(w io.Writer, filename string, failed []FailedThreshold) error {
+	issues := make([]gitLabIssue, len(failed))
+	for i, ft := range failed {
+		fingerprint := sha1.Sum([]byte(filename + "\x00" + ft.Metric + "\x00" + ft.Source))
+
+		issues[i].Description = ft.Message()
+		issues[i].Fingerprint = hex.EncodeToString(fingerprint[:])
+		issues[i].Severity = "major"
+		issues[i].Location.Path = filename
+		issues[i].Location.Lines.Begin = ft.Line
+		if ft.Line == 0 {
+			issues[i].Location.Lines.Begin = 1
+		}
+	}
+
+	data, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
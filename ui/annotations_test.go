@@ -0,0 +1,78 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestGetFailedThresholds(t *testing.T) {
+	script := []byte("export let options = {\n  thresholds: {\n    http_req_duration: [\"p(95)<200\", \"avg<100\"],\n  }\n};\n")
+
+	ths, err := stats.NewThresholds([]string{"p(95)<200", "avg<100", "max<1000"})
+	require.NoError(t, err)
+	ths.Thresholds[0].LastFailed = true
+	ths.Thresholds[2].LastFailed = true
+
+	passedThs, err := stats.NewThresholds([]string{"rate>0.9"})
+	require.NoError(t, err)
+
+	metrics := map[string]*stats.Metric{
+		"http_req_duration": {Name: "http_req_duration", Thresholds: ths, Tainted: null.BoolFrom(true)},
+		"checks":            {Name: "checks", Thresholds: passedThs, Tainted: null.BoolFrom(false)},
+	}
+
+	failed := GetFailedThresholds(metrics, script)
+	assert.Equal(t, []FailedThreshold{
+		{Metric: "http_req_duration", Source: "p(95)<200", Line: 3},
+		{Metric: "http_req_duration", Source: "max<1000", Line: 0},
+	}, failed)
+
+	t.Run("GitHub", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteGitHubAnnotations(&buf, "tests/script.js", failed))
+		assert.Equal(t,
+			"::error file=tests/script.js,line=3::threshold 'p(95)<200' for metric 'http_req_duration' has failed\n"+
+				"::error file=tests/script.js::threshold 'max<1000' for metric 'http_req_duration' has failed\n",
+			buf.String(),
+		)
+	})
+
+	t.Run("GitLab", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteGitLabCodeQuality(&buf, "script.js", failed))
+
+		var issues []gitLabIssue
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &issues))
+		require.Len(t, issues, 2)
+		assert.Equal(t, "script.js", issues[0].Location.Path)
+		assert.Equal(t, 3, issues[0].Location.Lines.Begin)
+		assert.Equal(t, 1, issues[1].Location.Lines.Begin)
+		assert.NotEqual(t, issues[0].Fingerprint, issues[1].Fingerprint)
+	})
+}
@@ -0,0 +1,286 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+)
+
+// Supported report formats.
+const (
+	ReportText  = "text"
+	ReportHTML  = "html"
+	ReportJUnit = "junit"
+	ReportJSON  = "json"
+)
+
+// WriteReport writes the summary data in the specified format.
+func WriteReport(w io.Writer, format string, data SummaryData) error {
+	switch format {
+	case ReportText:
+		Summarize(w, "", data)
+		return nil
+	case ReportHTML:
+		return WriteHTMLReport(w, data)
+	case ReportJUnit:
+		return WriteJUnitReport(w, data)
+	case ReportJSON:
+		return WriteJSONReport(w, data)
+	default:
+		return errors.Errorf(
+			"unknown report format '%s', use '%s', '%s', '%s' or '%s'",
+			format, ReportText, ReportHTML, ReportJUnit, ReportJSON,
+		)
+	}
+}
+
+// reportCheck is a check along with the path of the group it belongs to.
+type reportCheck struct {
+	Group string
+	*lib.Check
+}
+
+// getAllChecks returns the checks of the group and all of its subgroups, sorted by group path.
+func getAllChecks(group *lib.Group) []reportCheck {
+	if group == nil {
+		return nil
+	}
+	var result []reportCheck
+	names := make([]string, 0, len(group.Checks))
+	for name := range group.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result = append(result, reportCheck{strings.TrimPrefix(group.Path, lib.GroupSeparator), group.Checks[name]})
+	}
+
+	groupNames := make([]string, 0, len(group.Groups))
+	for name := range group.Groups {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+	for _, name := range groupNames {
+		result = append(result, getAllChecks(group.Groups[name])...)
+	}
+	return result
+}
+
+type reportMetric struct {
+	Name   string
+	Failed bool
+	Values []string
+}
+
+// getReportMetrics returns the metrics sorted by name, with their summary values as strings.
+func getReportMetrics(data SummaryData) []reportMetric {
+	names := make([]string, 0, len(data.Metrics))
+	for name := range data.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]reportMetric, len(names))
+	for i, name := range names {
+		m := data.Metrics[name]
+		m.Sink.Calc()
+		result[i] = reportMetric{Name: name, Failed: m.Tainted.Bool}
+		if sink, ok := m.Sink.(*stats.TrendSink); ok {
+			for _, col := range TrendColumns {
+				result[i].Values = append(result[i].Values,
+					col.Key+"="+m.HumanizeValue(col.Get(sink), data.Opts.SummaryTimeUnit.String))
+			}
+			continue
+		}
+		value, extra := NonTrendMetricValueForSum(data.Time, data.Opts.SummaryTimeUnit.String, m)
+		result[i].Values = append([]string{value}, extra...)
+	}
+	return result
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>k6 report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+.failed { color: #c00; }
+.passed { color: #080; }
+</style>
+</head>
+<body>
+<h1>k6 report</h1>
+<p>Duration: {{.Time}}</p>
+{{if .Checks}}<h2>Checks</h2>
+<table>
+<tr><th>Group</th><th>Check</th><th>Passes</th><th>Fails</th></tr>
+{{range .Checks}}<tr class="{{if .Fails}}failed{{else}}passed{{end}}"><td>{{.Group}}</td><td>{{.Name}}</td><td>{{.Passes}}</td><td>{{.Fails}}</td></tr>
+{{end}}</table>
+{{end}}<h2>Metrics</h2>
+<table>
+<tr><th>Metric</th><th>Values</th></tr>
+{{range .Metrics}}<tr{{if .Failed}} class="failed"{{end}}><td>{{.Name}}</td><td>{{range $i, $v := .Values}}{{if $i}} {{end}}{{$v}}{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTMLReport writes the summary data as a self-contained HTML page.
+func WriteHTMLReport(w io.Writer, data SummaryData) error {
+	return htmlReportTemplate.Execute(w, struct {
+		Time    string
+		Checks  []reportCheck
+		Metrics []reportMetric
+	}{data.Time.String(), getAllChecks(data.Root), getReportMetrics(data)})
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+func (s *junitTestSuite) add(tc junitTestCase) {
+	s.Tests++
+	if tc.Failure != nil {
+		s.Failures++
+	}
+	s.TestCases = append(s.TestCases, tc)
+}
+
+// WriteJUnitReport writes the checks and the thresholds as JUnit XML test cases, so they
+// can be shown by CI servers. A check fails if it failed even once.
+func WriteJUnitReport(w io.Writer, data SummaryData) error {
+	checks := junitTestSuite{Name: "checks"}
+	for _, check := range getAllChecks(data.Root) {
+		tc := junitTestCase{Name: check.Name, ClassName: check.Group}
+		if check.Fails > 0 {
+			tc.Failure = &junitFailure{fmt.Sprintf("%d of %d failed", check.Fails, check.Passes+check.Fails)}
+		}
+		checks.add(tc)
+	}
+
+	thresholds := junitTestSuite{Name: "thresholds"}
+	names := make([]string, 0, len(data.Metrics))
+	for name := range data.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, th := range data.Metrics[name].Thresholds.Thresholds {
+			tc := junitTestCase{Name: th.Source, ClassName: name}
+			if th.LastFailed {
+				tc.Failure = &junitFailure{FailedThreshold{Metric: name, Source: th.Source}.Message()}
+			}
+			thresholds.add(tc)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{checks, thresholds}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type jsonReportThreshold struct {
+	OK bool `json:"ok"`
+}
+
+type jsonReportMetric struct {
+	Type       stats.MetricType               `json:"type"`
+	Contains   stats.ValueType                `json:"contains"`
+	Values     map[string]float64             `json:"values"`
+	Thresholds map[string]jsonReportThreshold `json:"thresholds,omitempty"`
+}
+
+type jsonReport struct {
+	Duration  float64                     `json:"duration"`
+	Metrics   map[string]jsonReportMetric `json:"metrics"`
+	RootGroup *lib.Group                  `json:"root_group"`
+}
+
+// WriteJSONReport writes the summary data as JSON, so it can be processed by other tools. Trend
+// metrics have the same stats as the text summary, the durations are in milliseconds.
+func WriteJSONReport(w io.Writer, data SummaryData) error {
+	report := jsonReport{
+		Duration:  stats.D(data.Time),
+		Metrics:   make(map[string]jsonReportMetric, len(data.Metrics)),
+		RootGroup: data.Root,
+	}
+	for name, m := range data.Metrics {
+		m.Sink.Calc()
+		metric := jsonReportMetric{Type: m.Type, Contains: m.Contains}
+		if sink, ok := m.Sink.(*stats.TrendSink); ok {
+			metric.Values = make(map[string]float64, len(TrendColumns))
+			for _, col := range TrendColumns {
+				metric.Values[col.Key] = col.Get(sink)
+			}
+		} else {
+			metric.Values = m.Sink.Format(data.Time)
+		}
+		if m.Thresholds.Thresholds != nil {
+			metric.Thresholds = make(map[string]jsonReportThreshold, len(m.Thresholds.Thresholds))
+			for _, th := range m.Thresholds.Thresholds {
+				metric.Thresholds[th.Source] = jsonReportThreshold{OK: !th.LastFailed}
+			}
+		}
+		report.Metrics[name] = metric
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
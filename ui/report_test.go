@@ -0,0 +1,144 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestReportData(t *testing.T) SummaryData {
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	group, err := root.Group("login")
+	require.NoError(t, err)
+	check, err := group.Check("status is 200")
+	require.NoError(t, err)
+	check.Passes, check.Fails = 9, 1
+	check, err = root.Check("<b>is ok</b>")
+	require.NoError(t, err)
+	check.Passes = 10
+
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	duration.Sink.Add(stats.Sample{Value: 100})
+	duration.Thresholds, err = stats.NewThresholds([]string{"p(95)<50"})
+	require.NoError(t, err)
+	duration.Thresholds.Thresholds[0].LastFailed = true
+	duration.Tainted.Bool, duration.Tainted.Valid = true, true
+
+	iterations := stats.New("iterations", stats.Counter)
+	iterations.Sink.Add(stats.Sample{Value: 10})
+
+	return SummaryData{
+		Root:    root,
+		Metrics: map[string]*stats.Metric{duration.Name: duration, iterations.Name: iterations},
+		Time:    10 * time.Second,
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteHTMLReport(&buf, getTestReportData(t)))
+	html := buf.String()
+	assert.Contains(t, html, "<td>login</td><td>status is 200</td><td>9</td><td>1</td>")
+	assert.Contains(t, html, "&lt;b&gt;is ok&lt;/b&gt;")
+	assert.Contains(t, html, `<tr class="failed"><td>http_req_duration</td><td>avg=100ms `)
+	assert.Contains(t, html, "<tr><td>iterations</td><td>10 1/s</td></tr>")
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteJUnitReport(&buf, getTestReportData(t)))
+
+	var result junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &result))
+	require.Len(t, result.Suites, 2)
+
+	checks := result.Suites[0]
+	assert.Equal(t, 2, checks.Tests)
+	assert.Equal(t, 1, checks.Failures)
+	require.Len(t, checks.TestCases, 2)
+	assert.Equal(t, junitTestCase{Name: "<b>is ok</b>"}, checks.TestCases[0])
+	assert.Equal(t, junitTestCase{
+		Name: "status is 200", ClassName: "login", Failure: &junitFailure{"1 of 10 failed"},
+	}, checks.TestCases[1])
+
+	thresholds := result.Suites[1]
+	assert.Equal(t, 1, thresholds.Failures)
+	require.Len(t, thresholds.TestCases, 1)
+	assert.Equal(t, "p(95)<50", thresholds.TestCases[0].Name)
+	assert.Equal(t, "http_req_duration", thresholds.TestCases[0].ClassName)
+}
+
+func TestWriteReport(t *testing.T) {
+	assert.EqualError(t, WriteReport(&bytes.Buffer{}, "pdf", SummaryData{}),
+		"unknown report format 'pdf', use 'text', 'html', 'junit' or 'json'")
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSONReport(&buf, getTestReportData(t)))
+
+	var result struct {
+		Duration float64 `json:"duration"`
+		Metrics  map[string]struct {
+			Type       string             `json:"type"`
+			Contains   string             `json:"contains"`
+			Values     map[string]float64 `json:"values"`
+			Thresholds map[string]struct {
+				OK bool `json:"ok"`
+			} `json:"thresholds"`
+		} `json:"metrics"`
+		RootGroup struct {
+			Groups map[string]struct {
+				Checks map[string]lib.Check `json:"checks"`
+			} `json:"groups"`
+			Checks map[string]lib.Check `json:"checks"`
+		} `json:"root_group"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+
+	assert.Equal(t, 10000.0, result.Duration)
+
+	duration := result.Metrics["http_req_duration"]
+	assert.Equal(t, "trend", duration.Type)
+	assert.Equal(t, "time", duration.Contains)
+	assert.Equal(t, 100.0, duration.Values["avg"])
+	assert.Equal(t, 100.0, duration.Values["p(95)"])
+	assert.False(t, duration.Thresholds["p(95)<50"].OK)
+
+	iterations := result.Metrics["iterations"]
+	assert.Equal(t, "counter", iterations.Type)
+	assert.Equal(t, 10.0, iterations.Values["count"])
+	assert.Equal(t, 1.0, iterations.Values["rate"])
+	assert.Nil(t, iterations.Thresholds)
+
+	assert.Equal(t, int64(10), result.RootGroup.Checks["<b>is ok</b>"].Passes)
+	assert.Equal(t, int64(1), result.RootGroup.Groups["login"].Checks["status is 200"].Fails)
+}
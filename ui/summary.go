@@ -34,14 +34,26 @@ import (
 	"golang.org/x/text/unicode/norm"
 )
 
-const (
+// The glyphs used in the summary, they can be replaced with plain ASCII ones with UseASCII().
+var (
 	GroupPrefix   = "█"
 	DetailsPrefix = "↳"
+	DetailsSep    = "—"
 
 	SuccMark = "✓"
 	FailMark = "✗"
 )
 
+// UseASCII replaces the Unicode glyphs in the summary with ASCII ones, for terminals and log
+// archives that can't display Unicode properly.
+func UseASCII() {
+	GroupPrefix = "#"
+	DetailsPrefix = "->"
+	DetailsSep = "-"
+	SuccMark = "+"
+	FailMark = "x"
+}
+
 var (
 	ErrStatEmptyString            = errors.New("invalid stat, empty string")
 	ErrStatUnknownFormat          = errors.New("invalid stat, unknown format")
@@ -174,10 +186,10 @@ func SummarizeCheck(w io.Writer, indent string, check *lib.Check) {
 	}
 	_, _ = color.Fprintf(w, "%s%s %s\n", indent, mark, check.Name)
 	if check.Fails > 0 {
-		_, _ = color.Fprintf(w, "%s %s  %d%% — %s %d / %s %d\n",
+		_, _ = color.Fprintf(w, "%s %s  %d%% %s %s %d / %s %d\n",
 			indent, DetailsPrefix,
 			int(100*(float64(check.Passes)/float64(check.Fails+check.Passes))),
-			SuccMark, check.Passes, FailMark, check.Fails,
+			DetailsSep, SuccMark, check.Passes, FailMark, check.Fails,
 		)
 	}
 }
@@ -230,8 +242,8 @@ func NonTrendMetricValueForSum(t time.Duration, timeUnit string, m *stats.Metric
 		passes := sink.Trues
 		fails := sink.Total - sink.Trues
 		return m.HumanizeValue(value, timeUnit), []string{
-			"✓ " + strconv.FormatInt(passes, 10),
-			"✗ " + strconv.FormatInt(fails, 10),
+			SuccMark + " " + strconv.FormatInt(passes, 10),
+			FailMark + " " + strconv.FormatInt(fails, 10),
 		}
 	default:
 		return "[no data]", nil
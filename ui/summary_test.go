@@ -21,10 +21,13 @@
 package ui
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/stats"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var verifyTests = []struct {
@@ -158,3 +161,27 @@ func TestGeneratePercentileTrendColumn(t *testing.T) {
 		assert.Exactly(t, err, ErrPercentileStatInvalidValue)
 	})
 }
+
+func TestUseASCII(t *testing.T) {
+	defer func(group, details, sep, succ, fail string) {
+		GroupPrefix, DetailsPrefix, DetailsSep, SuccMark, FailMark = group, details, sep, succ, fail
+	}(GroupPrefix, DetailsPrefix, DetailsSep, SuccMark, FailMark)
+	UseASCII()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	group, err := root.Group("login")
+	require.NoError(t, err)
+	check, err := group.Check("status is 200")
+	require.NoError(t, err)
+	check.Passes, check.Fails = 3, 1
+
+	var buf bytes.Buffer
+	SummarizeGroup(&buf, "", root)
+	assert.Equal(t, "# login\n\n  x status is 200\n   ->  75% - + 3 / x 1\n\n", buf.String())
+
+	_, extra := NonTrendMetricValueForSum(0, "", &stats.Metric{
+		Type: stats.Rate, Sink: &stats.RateSink{Trues: 3, Total: 4},
+	})
+	assert.Equal(t, []string{"+ 3", "x 1"}, extra)
+}